@@ -2,7 +2,10 @@ package main
 
 import (
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/replicated-sdk/pkg/apiserver"
@@ -43,6 +46,16 @@ func APICmd() *cobra.Command {
 					return errors.Wrap(err, "failed to read config file")
 				}
 
+				// fail fast on mistakes in the config file instead of when the affected feature is first used.
+				// unknown fields are ignored when the config file is parsed, so they only warn if explicitly allowed
+				warnings, err := config.ValidateReplicatedConfig(configFile, v.GetBool("allow-unknown-config-fields"))
+				if err != nil {
+					return errors.Wrap(err, "invalid config file")
+				}
+				for _, warning := range warnings {
+					logger.Warnf("config file: %s", warning)
+				}
+
 				if replicatedConfig, err = config.ParseReplicatedConfig(configFile); err != nil {
 					return errors.Wrap(err, "failed to parse config file")
 				}
@@ -56,38 +69,59 @@ func APICmd() *cobra.Command {
 				return errors.New("only one of license in the config file or integration license id can be specified")
 			}
 
+			// cancel the context on SIGTERM/SIGINT so the api server can shut down gracefully
+			ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGTERM, os.Interrupt)
+			defer stop()
+
 			params := apiserver.APIServerParams{
-				Context:               cmd.Context(),
-				LicenseBytes:          []byte(replicatedConfig.License),
-				IntegrationLicenseID:  integrationLicenseID,
-				LicenseFields:         replicatedConfig.LicenseFields,
-				AppName:               replicatedConfig.AppName,
-				ChannelID:             replicatedConfig.ChannelID,
-				ChannelName:           replicatedConfig.ChannelName,
-				ChannelSequence:       replicatedConfig.ChannelSequence,
-				ReleaseSequence:       replicatedConfig.ReleaseSequence,
-				ReleaseCreatedAt:      replicatedConfig.ReleaseCreatedAt,
-				ReleaseNotes:          replicatedConfig.ReleaseNotes,
-				VersionLabel:          replicatedConfig.VersionLabel,
-				ReplicatedAppEndpoint: replicatedConfig.ReplicatedAppEndpoint,
-				ReleaseImages:         replicatedConfig.ReleaseImages,
-				StatusInformers:       replicatedConfig.StatusInformers,
-				ReplicatedID:          replicatedConfig.ReplicatedID,
-				AppID:                 replicatedConfig.AppID,
-				TlsCertSecretName:     replicatedConfig.TlsCertSecretName,
-				ReportAllImages:       replicatedConfig.ReportAllImages,
-				ReadOnlyMode:          replicatedConfig.ReadOnlyMode,
-				Namespace:             namespace,
+				Context:                 ctx,
+				LicenseBytes:            []byte(replicatedConfig.License),
+				IntegrationLicenseID:    integrationLicenseID,
+				LicenseFields:           replicatedConfig.LicenseFields,
+				AppName:                 replicatedConfig.AppName,
+				ChannelID:               replicatedConfig.ChannelID,
+				ChannelName:             replicatedConfig.ChannelName,
+				ChannelSequence:         replicatedConfig.ChannelSequence,
+				ReleaseSequence:         replicatedConfig.ReleaseSequence,
+				ReleaseCreatedAt:        replicatedConfig.ReleaseCreatedAt,
+				ReleaseNotes:            replicatedConfig.ReleaseNotes,
+				VersionLabel:            replicatedConfig.VersionLabel,
+				ReplicatedAppEndpoint:   replicatedConfig.ReplicatedAppEndpoint,
+				ReleaseImages:           replicatedConfig.ReleaseImages,
+				StatusInformers:         replicatedConfig.StatusInformers,
+				ReplicatedID:            replicatedConfig.ReplicatedID,
+				AppID:                   replicatedConfig.AppID,
+				TlsCertSecretName:       replicatedConfig.TlsCertSecretName,
+				TlsVerifyClientCerts:    replicatedConfig.TlsVerifyClientCerts,
+				ReportAllImages:         replicatedConfig.ReportAllImages,
+				ReadOnlyMode:            replicatedConfig.ReadOnlyMode,
+				APITokensSecretName:     replicatedConfig.APITokensSecretName,
+				AllowUnauthenticatedAPI: replicatedConfig.AllowUnauthenticatedAPI,
+				Webhooks:                replicatedConfig.Webhooks,
+				LicenseExpiringSoonDays: replicatedConfig.LicenseExpiringSoonDays,
+				LicenseGracePeriodDays:  replicatedConfig.LicenseGracePeriodDays,
+				StrictLicenseFields:     replicatedConfig.StrictLicenseFields,
+				LicenseSource:           replicatedConfig.LicenseSource,
+				UpdateCheckInterval:     time.Duration(replicatedConfig.UpdateCheckIntervalMinutes) * time.Minute,
+				Namespace:               namespace,
 			}
-			apiserver.Start(params)
-
-			return nil
+			if replicatedConfig.Proxy != nil {
+				params.HTTPSProxy = replicatedConfig.Proxy.HTTPSProxy
+				params.HTTPProxy = replicatedConfig.Proxy.HTTPProxy
+				params.NoProxy = replicatedConfig.Proxy.NoProxy
+			}
+			if replicatedConfig.PrivateCASecret != nil {
+				params.PrivateCASecretName = replicatedConfig.PrivateCASecret.Name
+				params.PrivateCASecretKey = replicatedConfig.PrivateCASecret.Key
+			}
+			return apiserver.Start(params)
 		},
 	}
 
 	cmd.Flags().String("config-file", "", "path to the replicated config file")
 	cmd.Flags().String("namespace", "", "the namespace where replicated/application is installed")
 	cmd.Flags().String("integration-license-id", "", "the id of the license to use")
+	cmd.Flags().Bool("allow-unknown-config-fields", false, "only warn about unknown fields in the config file instead of failing to start")
 
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/client"
+	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addClientFlags adds the flags for connecting to a running SDK to the command and its subcommands
+func addClientFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("endpoint", "http://localhost:3000", "the address of the SDK API")
+	cmd.PersistentFlags().String("api-token", "", "the SDK API token to authenticate with, if API tokens are enabled")
+	cmd.PersistentFlags().Bool("port-forward", false, "connect to the SDK in the cluster through a port-forward instead of the endpoint")
+	cmd.PersistentFlags().String("service", "replicated", "the name of the SDK service to port-forward to")
+	k8sutil.AddFlags(cmd.PersistentFlags())
+}
+
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", outputTable, "output format (one of: table, json, yaml)")
+}
+
+// newClient returns a client for the SDK, and a function that closes the port-forward if one was started
+func newClient(ctx context.Context) (*client.Client, func(), error) {
+	v := viper.GetViper()
+
+	endpoint := v.GetString("endpoint")
+	closeFn := func() {}
+	opts := []client.Option{client.WithToken(v.GetString("api-token"))}
+
+	if v.GetBool("port-forward") {
+		cfg, err := k8sutil.GetClusterConfig()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to get cluster config")
+		}
+
+		clientset, err := k8sutil.GetClientset()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to get clientset")
+		}
+
+		namespace, _, err := k8sutil.KubernetesConfigFlags.ToRawKubeConfigLoader().Namespace()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to get namespace")
+		}
+
+		localEndpoint, stop, err := k8sutil.PortForwardService(ctx, cfg, clientset, namespace, v.GetString("service"))
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to port-forward to the sdk")
+		}
+
+		endpoint = localEndpoint
+		closeFn = stop
+
+		if strings.HasPrefix(endpoint, "https://") {
+			// the certificate of the sdk is not issued for localhost, and the port-forward already goes to the sdk pod
+			// through the authenticated connection to the kubernetes api
+			opts = append(opts, client.WithHTTPClient(&http.Client{
+				Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+			}))
+		}
+	}
+
+	c, err := client.New(endpoint, opts...)
+	if err != nil {
+		closeFn()
+		return nil, nil, errors.Wrap(err, "failed to create client")
+	}
+
+	return c, closeFn, nil
+}
+
+// warnIfCached tells the user when the SDK could not reach the Replicated API and returned the last known data
+func warnIfCached(w io.Writer, resp *client.Response) {
+	if resp == nil || !resp.ServedFromCache {
+		return
+	}
+	if resp.UpstreamError != "" {
+		fmt.Fprintf(w, "Warning: the SDK could not reach the Replicated API (%s), showing the last known data\n", resp.UpstreamError)
+		return
+	}
+	fmt.Fprintln(w, "Warning: the SDK could not reach the Replicated API, showing the last known data")
+}
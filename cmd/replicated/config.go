@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func ConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Work with the SDK config file",
+		Long:  `Work with the config file that the SDK is started with`,
+	}
+
+	cmd.AddCommand(ConfigValidateCmd())
+
+	return cmd
+}
+
+func ConfigValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate FILE",
+		Short: "Validate a config file",
+		Long: `Validate a config file before starting the SDK with it.
+Unknown fields, values of the wrong type, malformed status informers, an invalid or incorrectly signed license,
+and invalid endpoint and webhook urls are reported with their line, and make the SDK fail to start.
+With --allow-unknown-config-fields, unknown fields are only reported as warnings, as they are when the SDK is started with it.`,
+		Example:      `  replicated config validate config.yaml`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFilePath := args[0]
+
+			configFile, err := os.ReadFile(configFilePath)
+			if err != nil {
+				return errors.Wrap(err, "failed to read config file")
+			}
+
+			// the config file is validated the same way as when the sdk starts
+			warnings, err := config.ValidateReplicatedConfig(configFile, viper.GetBool("allow-unknown-config-fields"))
+			for _, warning := range warnings {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s: warning: %s\n", configFilePath, warning)
+			}
+			if err != nil {
+				var errs config.ValidationErrors
+				if !errors.As(err, &errs) {
+					return err
+				}
+				for _, validationErr := range errs {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s: %s\n", configFilePath, validationErr)
+				}
+				return errors.Errorf("%s is invalid", configFilePath)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", configFilePath)
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("allow-unknown-config-fields", false, "report unknown fields as warnings instead of making the config file invalid")
+
+	return cmd
+}
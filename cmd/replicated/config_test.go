@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigValidateCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	validPath := filepath.Join(dir, "valid.yaml")
+	require.NoError(t, os.WriteFile(validPath, []byte("appName: test-app\nstatusInformers:\n- deployment/web\n"), 0644))
+
+	stdout, _, err := runCmd(t, "config", "validate", validPath)
+	require.NoError(t, err)
+	require.Equal(t, validPath+" is valid\n", stdout)
+
+	invalidPath := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("appName: test-app\nstatusInfomers:\n- web\n"), 0644))
+
+	_, stderr, err := runCmd(t, "config", "validate", invalidPath)
+	require.EqualError(t, err, invalidPath+" is invalid")
+	require.Equal(t, invalidPath+": line 2: unknown field \"statusInfomers\"\nError: "+invalidPath+" is invalid\n", stderr)
+
+	/* unknown fields only warn when they are allowed, as they do when the sdk is started with the same flag */
+	stdout, stderr, err = runCmd(t, "config", "validate", "--allow-unknown-config-fields", invalidPath)
+	require.NoError(t, err)
+	require.Equal(t, invalidPath+" is valid\n", stdout)
+	require.Equal(t, invalidPath+": warning: line 2: unknown field \"statusInfomers\"\n", stderr)
+}
+
+func TestAPICmdUnknownConfigFields(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("appName: test-app\nstatusInfomers:\n- web\n"), 0644))
+
+	/* the sdk fails to start with unknown fields in the config file, the same as config validate */
+	_, _, err := runCmd(t, "api", "--config-file", configPath)
+	require.EqualError(t, err, "invalid config file: line 2: unknown field \"statusInfomers\"")
+}
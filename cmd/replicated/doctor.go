@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/config"
+	"github.com/replicatedhq/replicated-sdk/pkg/diagnostics"
+	diagnosticstypes "github.com/replicatedhq/replicated-sdk/pkg/diagnostics/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func DoctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the permissions and environment of the SDK",
+		Long: `Check the permissions and environment variables that the SDK needs, and report which features are disabled as a result.
+By default, the checks are run by a running SDK. With --local, they are run by this command, with its own credentials and environment,
+which is useful to diagnose an SDK that does not start when run in its pod.
+The command fails if any check fails.`,
+		Example: `  replicated doctor --port-forward --namespace my-app
+  kubectl exec deploy/replicated -- replicated doctor --local`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			output := v.GetString("output")
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
+			var report *diagnosticstypes.Report
+			if v.GetBool("local") {
+				r, err := runLocalDiagnostics(cmd)
+				if err != nil {
+					return err
+				}
+				report = r
+			} else {
+				c, closeFn, err := newClient(cmd.Context())
+				if err != nil {
+					return err
+				}
+				defer closeFn()
+
+				r, _, err := c.GetDiagnostics(cmd.Context())
+				if err != nil {
+					return errors.Wrap(err, "failed to get diagnostics")
+				}
+				report = r
+			}
+
+			err := printOutput(cmd.OutOrStdout(), output, report, func(w *tabwriter.Writer) {
+				printRow(w, "STATUS", "CATEGORY", "CHECK", "MESSAGE")
+				for _, check := range report.Checks {
+					printRow(w, check.Status, check.Category, check.Name, check.Message)
+				}
+				if len(report.DisabledFeatures) == 0 {
+					return
+				}
+				printRow(w)
+				printRow(w, "DISABLED FEATURES")
+				for _, feature := range report.DisabledFeatures {
+					printRow(w, feature)
+				}
+			})
+			if err != nil {
+				return err
+			}
+
+			if report.Status == diagnosticstypes.StatusFail {
+				return errors.New("one or more checks failed")
+			}
+			return nil
+		},
+	}
+
+	addClientFlags(cmd)
+	addOutputFlag(cmd)
+	cmd.Flags().Bool("local", false, "run the checks with the credentials and environment of this command instead of a running SDK")
+	cmd.Flags().String("config-file", "", "with --local, the SDK config file to read the configuration to check from")
+
+	return cmd
+}
+
+// runLocalDiagnostics runs the checks in this process, reading the configuration from the config file like the api command
+func runLocalDiagnostics(cmd *cobra.Command) (*diagnosticstypes.Report, error) {
+	v := viper.GetViper()
+
+	replicatedConfig := &config.ReplicatedConfig{}
+	if configFilePath := v.GetString("config-file"); configFilePath != "" {
+		configFile, err := os.ReadFile(configFilePath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read config file")
+		}
+		replicatedConfig, err = config.ParseReplicatedConfig(configFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse config file")
+		}
+	}
+
+	namespace, _, err := k8sutil.KubernetesConfigFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get namespace")
+	}
+
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get clientset")
+	}
+
+	opts := diagnostics.Options{
+		Namespace:           namespace,
+		StatusInformers:     replicatedConfig.StatusInformers,
+		ReadOnlyMode:        replicatedConfig.ReadOnlyMode,
+		ReportAllImages:     replicatedConfig.ReportAllImages,
+		TlsCertSecretName:   replicatedConfig.TlsCertSecretName,
+		APITokensSecretName: replicatedConfig.APITokensSecretName,
+	}
+	if replicatedConfig.LicenseSource != nil {
+		opts.LicenseSourceSecretName = replicatedConfig.LicenseSource.SecretName
+	}
+	if replicatedConfig.PrivateCASecret != nil {
+		opts.PrivateCASecretName = replicatedConfig.PrivateCASecret.Name
+	}
+
+	report := diagnostics.Run(cmd.Context(), clientset, opts)
+	return &report, nil
+}
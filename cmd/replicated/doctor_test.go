@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/replicated-sdk/pkg/client/clienttest"
+	diagnosticstypes "github.com/replicatedhq/replicated-sdk/pkg/diagnostics/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoctorCmd(t *testing.T) {
+	tests := []struct {
+		name       string
+		report     diagnosticstypes.Report
+		wantStdout string
+		wantErr    string
+	}{
+		{
+			name: "warnings",
+			report: diagnosticstypes.Report{
+				Status: diagnosticstypes.StatusWarn,
+				Checks: []diagnosticstypes.Check{
+					{Category: diagnosticstypes.CategoryEnvironment, Name: "REPLICATED_NAMESPACE", Status: diagnosticstypes.StatusPass, Message: "set to default"},
+					{Category: diagnosticstypes.CategoryPermission, Name: "list nodes", Status: diagnosticstypes.StatusWarn, Message: "missing permission to list nodes", DisabledFeatures: []string{"kubernetes distribution detection"}},
+				},
+				DisabledFeatures: []string{"kubernetes distribution detection"},
+			},
+			wantStdout: `STATUS  CATEGORY     CHECK                 MESSAGE
+pass    environment  REPLICATED_NAMESPACE  set to default
+warn    permission   list nodes            missing permission to list nodes
+
+DISABLED FEATURES
+kubernetes distribution detection
+`,
+		},
+		{
+			name: "failures",
+			report: diagnosticstypes.Report{
+				Status: diagnosticstypes.StatusFail,
+				Checks: []diagnosticstypes.Check{
+					{Category: diagnosticstypes.CategoryPermission, Name: "get secrets/replicated in namespace default", Status: diagnosticstypes.StatusFail, Message: "missing permission to get secrets/replicated in namespace default"},
+				},
+				DisabledFeatures: []string{},
+			},
+			wantStdout: `STATUS  CATEGORY    CHECK                                        MESSAGE
+fail    permission  get secrets/replicated in namespace default  missing permission to get secrets/replicated in namespace default
+`,
+			wantErr: "one or more checks failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := clienttest.NewServer(clienttest.State{Token: "secret", Diagnostics: tt.report})
+			defer server.Close()
+
+			stdout, _, err := runCmd(t, "doctor", "--endpoint", server.URL, "--api-token", "secret")
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantStdout, stdout)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func HistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "history",
+		Short:        "List the deployed releases",
+		Long:         `List the releases of the application that have been deployed, most recent first`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := viper.GetString("output")
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
+			c, closeFn, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+
+			history, _, err := c.GetAppHistory(cmd.Context())
+			if err != nil {
+				return errors.Wrap(err, "failed to get app history")
+			}
+
+			return printOutput(cmd.OutOrStdout(), output, history, func(w *tabwriter.Writer) {
+				printRow(w, "VERSION", "REVISION", "DEPLOYED", "CREATED")
+				for _, release := range history.Releases {
+					printRow(w, release.VersionLabel, release.HelmReleaseRevision, release.DeployedAt, release.CreatedAt)
+				}
+			})
+		},
+	}
+
+	addClientFlags(cmd)
+	addOutputFlag(cmd)
+
+	return cmd
+}
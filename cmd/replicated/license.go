@@ -0,0 +1,193 @@
+package main
+
+import (
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func LicenseCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "license",
+		Short: "Show the license of a running SDK",
+		Long:  `Show the license of a running SDK`,
+	}
+
+	addClientFlags(cmd)
+
+	cmd.AddCommand(LicenseInfoCmd())
+	cmd.AddCommand(LicenseFieldsCmd())
+	cmd.AddCommand(LicenseChannelsCmd())
+
+	return cmd
+}
+
+func LicenseInfoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "info",
+		Short:        "Show the license details",
+		Long:         `Show the customer, channel and features of the license`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := viper.GetString("output")
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
+			c, closeFn, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+
+			licenseInfo, resp, err := c.GetLicenseInfo(cmd.Context())
+			if err != nil {
+				return errors.Wrap(err, "failed to get license info")
+			}
+			warnIfCached(cmd.ErrOrStderr(), resp)
+
+			return printOutput(cmd.OutOrStdout(), output, licenseInfo, func(w *tabwriter.Writer) {
+				printRow(w, "LICENSE ID:", licenseInfo.LicenseID)
+				printRow(w, "LICENSE TYPE:", licenseInfo.LicenseType)
+				printRow(w, "CUSTOMER:", licenseInfo.CustomerName)
+				printRow(w, "CUSTOMER EMAIL:", licenseInfo.CustomerEmail)
+				printRow(w, "APP:", licenseInfo.AppSlug)
+				printRow(w, "CHANNEL:", licenseInfo.ChannelName)
+				printRow(w, "SEQUENCE:", licenseInfo.LicenseSequence)
+				printRow(w, "AIRGAP:", licenseInfo.IsAirgapSupported)
+				printRow(w, "SUPPORT BUNDLE UPLOAD:", licenseInfo.IsSupportBundleUploadSupported)
+				printRow(w, "SEMVER REQUIRED:", licenseInfo.IsSemverRequired)
+				if expiration := licenseInfo.Expiration; expiration != nil {
+					printRow(w, "EXPIRATION:", expiration.State)
+					if expiration.ExpiresAt != nil {
+						printRow(w, "EXPIRES AT:", expiration.ExpiresAt.Format(time.RFC3339))
+					}
+					if expiration.GracePeriodEndsAt != nil {
+						printRow(w, "GRACE PERIOD ENDS AT:", expiration.GracePeriodEndsAt.Format(time.RFC3339))
+					}
+				}
+				if sync := licenseInfo.Sync; sync != nil && sync.LastSyncedAt != nil {
+					printRow(w, "LAST SYNCED AT:", sync.LastSyncedAt.Format(time.RFC3339))
+					printRow(w, "STALE:", sync.Stale)
+				}
+			})
+		},
+	}
+
+	addOutputFlag(cmd)
+
+	return cmd
+}
+
+func LicenseFieldsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "fields [name]",
+		Short:        "Show the custom license fields",
+		Long:         `Show all custom license fields, or the field with the given name`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := viper.GetString("output")
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
+			c, closeFn, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+
+			var result interface{}
+			var fields []sdklicensetypes.LicenseField
+
+			if len(args) == 1 {
+				field, resp, err := c.GetLicenseField(cmd.Context(), args[0])
+				if err != nil {
+					return errors.Wrapf(err, "failed to get license field %s", args[0])
+				}
+				warnIfCached(cmd.ErrOrStderr(), resp)
+				result = field
+				fields = append(fields, *field)
+			} else {
+				licenseFields, resp, err := c.GetLicenseFields(cmd.Context())
+				if err != nil {
+					return errors.Wrap(err, "failed to get license fields")
+				}
+				warnIfCached(cmd.ErrOrStderr(), resp)
+				result = licenseFields
+				for _, field := range licenseFields {
+					fields = append(fields, field)
+				}
+				sort.Slice(fields, func(i, j int) bool {
+					return fields[i].Name < fields[j].Name
+				})
+			}
+
+			return printOutput(cmd.OutOrStdout(), output, result, func(w *tabwriter.Writer) {
+				printRow(w, "NAME", "TITLE", "TYPE", "VALUE", "VERIFIED")
+				for _, field := range fields {
+					printRow(w, field.Name, field.Title, field.ValueType, field.Value, field.Verified)
+				}
+			})
+		},
+	}
+
+	addOutputFlag(cmd)
+
+	return cmd
+}
+
+func LicenseChannelsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "channels",
+		Short:        "Show the channels of the license",
+		Long:         `Show the channels that the license is entitled to, and the channels that the application is installed from and gets updates from`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := viper.GetString("output")
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
+			c, closeFn, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+
+			channels, resp, err := c.GetLicenseChannels(cmd.Context())
+			if err != nil {
+				return errors.Wrap(err, "failed to get license channels")
+			}
+			warnIfCached(cmd.ErrOrStderr(), resp)
+
+			return printOutput(cmd.OutOrStdout(), output, channels, func(w *tabwriter.Writer) {
+				printRow(w, "ID", "NAME", "DEFAULT", "INSTALLED", "UPDATES")
+				for _, channel := range channels.Channels {
+					printRow(w, channel.ChannelID, channel.ChannelName, channel.IsDefault, channel.ChannelID == channels.InstalledChannelID, channel.ChannelID == channels.UpdatesChannelID)
+				}
+			})
+		},
+	}
+
+	addOutputFlag(cmd)
+
+	return cmd
+}
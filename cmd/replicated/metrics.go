@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func MetricsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "metrics",
+		Short: "Manage the custom metrics of the instance",
+		Long:  `Manage the custom metrics that the SDK reports for the instance`,
+	}
+
+	addClientFlags(cmd)
+
+	cmd.AddCommand(MetricsSetCmd())
+	cmd.AddCommand(MetricsDeleteCmd())
+
+	return cmd
+}
+
+func MetricsSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set KEY=VALUE...",
+		Short: "Set custom metrics",
+		Long: `Set custom metrics. Values that are numbers or booleans are sent as such, all other values are sent as strings.
+Other metrics are kept, unless --replace is set.`,
+		Example:      `  replicated metrics set numProjects=10 activeUsers=4 plan=enterprise`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			values, err := parseKeyValues(args)
+			if err != nil {
+				return err
+			}
+
+			data := types.CustomAppMetricsData{}
+			for key, value := range values {
+				data[key] = parseMetricValue(value)
+			}
+
+			c, closeFn, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+
+			if viper.GetBool("replace") {
+				_, err = c.SendCustomMetrics(cmd.Context(), data)
+			} else {
+				_, err = c.UpdateCustomMetrics(cmd.Context(), data)
+			}
+			if err != nil {
+				return errors.Wrap(err, "failed to set custom metrics")
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "Custom metrics set")
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("replace", false, "replace all custom metrics instead of only setting the given keys")
+
+	return cmd
+}
+
+func MetricsDeleteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "delete KEY...",
+		Short:        "Delete custom metrics",
+		Long:         `Delete the custom metrics with the given keys`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, closeFn, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+
+			for _, key := range args {
+				if _, err := c.DeleteCustomMetric(cmd.Context(), key); err != nil {
+					return errors.Wrapf(err, "failed to delete custom metric %s", key)
+				}
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "Custom metrics deleted")
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// parseKeyValues parses KEY=VALUE arguments. Values may contain '=' and be empty.
+func parseKeyValues(args []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return nil, errors.Errorf("invalid argument %q, expected KEY=VALUE", arg)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+func parseMetricValue(value string) interface{} {
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	if b, err := strconv.ParseBool(value); err == nil {
+		return b
+	}
+	return value
+}
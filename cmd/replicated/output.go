@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+func validateOutput(output string) error {
+	switch output {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	default:
+		return errors.Errorf("output format %s not supported (allowed formats are: table, json, yaml)", output)
+	}
+}
+
+// printOutput prints v as json or yaml, or calls printTable to print it as a table
+func printOutput(w io.Writer, output string, v interface{}, printTable func(w *tabwriter.Writer)) error {
+	switch output {
+	case outputJSON:
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal json")
+		}
+		fmt.Fprintln(w, string(b))
+	case outputYAML:
+		// round trip through json so that the json field names are used
+		b, err := json.Marshal(v)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal json")
+		}
+		var generic interface{}
+		if err := yaml.Unmarshal(b, &generic); err != nil {
+			return errors.Wrap(err, "failed to convert json to yaml")
+		}
+		y, err := yaml.Marshal(generic)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal yaml")
+		}
+		fmt.Fprint(w, string(y))
+	default:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		printTable(tw)
+		if err := tw.Flush(); err != nil {
+			return errors.Wrap(err, "failed to write table")
+		}
+	}
+	return nil
+}
+
+// printRow prints the columns as a tab separated row
+func printRow(w io.Writer, columns ...interface{}) {
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		values[i] = fmt.Sprint(column)
+	}
+	fmt.Fprintln(w, strings.Join(values, "\t"))
+}
+
+// truncate shortens s to its first line, and to at most n characters
+func truncate(s string, n int) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i] + "..."
+	}
+	if len(s) > n {
+		return s[:n-3] + "..."
+	}
+	return s
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/client/clienttest"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+	"github.com/stretchr/testify/require"
+)
+
+func runCmd(t *testing.T, args ...string) (string, string, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := RootCmd()
+	cmd.SetOut(stdout)
+	cmd.SetErr(stderr)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return stdout.String(), stderr.String(), err
+}
+
+func TestQueryCmds(t *testing.T) {
+	server := clienttest.NewServer(clienttest.State{
+		Token: "secret",
+		AppInfo: types.GetCurrentAppInfoResponse{
+			InstanceID:     "instance-id",
+			AppSlug:        "my-app",
+			AppStatus:      appstatetypes.StateReady,
+			CurrentRelease: types.AppRelease{VersionLabel: "1.0.0"},
+			ChannelName:    "Stable",
+		},
+		AppStatus: appstatetypes.AppStatus{
+			ResourceStates: appstatetypes.ResourceStates{
+				{Kind: "deployment", Namespace: "default", Name: "web", State: appstatetypes.StateReady},
+			},
+		},
+		LicenseFields: sdklicensetypes.LicenseFields{
+			"seats": {Name: "seats", Title: "Seats", ValueType: "Integer", Value: 10, Verified: true},
+		},
+		LicenseChannels: types.GetLicenseChannelsResponse{
+			Channels: []sdklicensetypes.LicenseChannel{
+				{ChannelID: "stable-id", ChannelName: "Stable", IsDefault: true},
+				{ChannelID: "beta-id", ChannelName: "Beta"},
+			},
+			InstalledChannelID: "stable-id",
+			UpdatesChannelID:   "stable-id",
+		},
+		Updates: []upstreamtypes.ChannelRelease{{VersionLabel: "1.1.0", ReleaseNotes: "first line\nsecond line"}},
+		ChannelUpdates: map[string][]upstreamtypes.ChannelRelease{
+			"beta-id": {{VersionLabel: "2.0.0-beta.1", ReleaseNotes: "beta"}},
+		},
+		ServedFromCache: true,
+	})
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name: "status table",
+			args: []string{"status"},
+			wantStdout: `APP:       my-app
+STATUS:    ready
+VERSION:   1.0.0
+CHANNEL:   Stable
+INSTANCE:  instance-id
+
+KIND        NAMESPACE  NAME  STATE
+deployment  default    web   ready
+`,
+		},
+		{
+			name: "license field yaml",
+			args: []string{"license", "fields", "seats", "-o", "yaml"},
+			wantStdout: `name: seats
+signature: {}
+title: Seats
+value: 10
+valueType: Integer
+verified: true
+`,
+			wantStderr: "Warning: the SDK could not reach the Replicated API (upstream_unavailable), showing the last known data\n",
+		},
+		{
+			name: "updates json",
+			args: []string{"updates", "-o", "json"},
+			wantStdout: `[
+  {
+    "channelSequence": 0,
+    "versionLabel": "1.1.0",
+    "createdAt": "",
+    "releaseNotes": "first line\nsecond line",
+    "isRequired": false,
+    "airgapBundleAvailable": false,
+    "bump": "minor"
+  }
+]
+`,
+			wantStderr: "Warning: the SDK could not reach the Replicated API (upstream_unavailable), showing the last known data\n",
+		},
+		{
+			name: "updates table",
+			args: []string{"updates"},
+			wantStdout: `VERSION  BUMP   REQUIRED  CREATED  RELEASE NOTES
+1.1.0    minor  false              first line...
+`,
+			wantStderr: "Warning: the SDK could not reach the Replicated API (upstream_unavailable), showing the last known data\n",
+		},
+		{
+			name:       "updates max bump",
+			args:       []string{"updates", "--max-bump", "patch", "-o", "json"},
+			wantStdout: "[]\n",
+			wantStderr: "Warning: the SDK could not reach the Replicated API (upstream_unavailable), showing the last known data\n",
+		},
+		{
+			name: "updates channel",
+			args: []string{"updates", "--channel", "beta-id"},
+			wantStdout: `VERSION       BUMP   REQUIRED  CREATED  RELEASE NOTES
+2.0.0-beta.1  major  false              beta
+`,
+		},
+		{
+			name: "license channels",
+			args: []string{"license", "channels"},
+			wantStdout: `ID         NAME    DEFAULT  INSTALLED  UPDATES
+stable-id  Stable  true     true       true
+beta-id    Beta    false    false      false
+`,
+		},
+		{
+			name:       "metrics set",
+			args:       []string{"metrics", "set", "users=4", "plan=enterprise", "ratio=0.5", "trial=false"},
+			wantStdout: "Custom metrics set\n",
+		},
+		{
+			name:       "tags set",
+			args:       []string{"tags", "set", "env=prod", "--force"},
+			wantStdout: "Instance tags set\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append(tt.args, "--endpoint", server.URL, "--api-token", "secret")
+			stdout, stderr, err := runCmd(t, args...)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantStdout, stdout)
+			require.Equal(t, tt.wantStderr, stderr)
+		})
+	}
+
+	state := server.State()
+	require.Equal(t, types.CustomAppMetricsData{"users": float64(4), "plan": "enterprise", "ratio": 0.5, "trial": false}, state.CustomMetrics)
+	require.Equal(t, map[string]string{"env": "prod"}, state.InstanceTags.Tags)
+	require.True(t, state.InstanceTags.Force)
+}
+
+func TestQueryCmdErrors(t *testing.T) {
+	server := clienttest.NewServer(clienttest.State{Token: "secret"})
+	defer server.Close()
+
+	_, _, err := runCmd(t, "status", "--endpoint", server.URL)
+	require.EqualError(t, err, "failed to get app info: sdk api returned 401 (unauthorized): a valid API token is required")
+
+	_, _, err = runCmd(t, "status", "--endpoint", server.URL, "-o", "xml")
+	require.EqualError(t, err, "output format xml not supported (allowed formats are: table, json, yaml)")
+
+	_, _, err = runCmd(t, "metrics", "set", "users", "--endpoint", server.URL)
+	require.EqualError(t, err, `invalid argument "users", expected KEY=VALUE`)
+}
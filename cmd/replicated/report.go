@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
+	"github.com/replicatedhq/replicated-sdk/pkg/report"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/kubernetes"
+)
+
+func ReportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Export and inspect the reports stored by the SDK in airgap mode",
+		Long:  `In airgap mode, the SDK stores instance and custom metrics reports in secrets instead of sending them to the Replicated API. These commands export them to a portable archive and inspect it.`,
+	}
+
+	cmd.AddCommand(ReportExportCmd())
+	cmd.AddCommand(ReportInspectCmd())
+
+	return cmd
+}
+
+func ReportExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the airgap reports to an archive",
+		Long: `Export the airgap reports to a gzipped tar archive containing the decoded reports and a manifest.
+The reports are read from the report secrets in the cluster, or from a support bundle with --support-bundle.
+With --prune, the exported events are removed from the report secrets once the archive has been written.`,
+		Example: `  replicated report export --namespace my-app
+  replicated report export --support-bundle support-bundle.tar.gz --output-file reports.tar.gz`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			supportBundle := v.GetString("support-bundle")
+			prune := v.GetBool("prune")
+			if supportBundle != "" && prune {
+				return errors.New("--prune cannot be used with --support-bundle")
+			}
+
+			manifest := report.ExportManifest{
+				ExportedAt: time.Now().UTC(),
+			}
+
+			var reports []report.Report
+			var clientset kubernetes.Interface
+			if supportBundle != "" {
+				r, err := report.GetReportsFromSupportBundle(supportBundle)
+				if err != nil {
+					return errors.Wrap(err, "failed to get reports from support bundle")
+				}
+				reports = r
+				manifest.Source = "support-bundle"
+			} else {
+				c, err := k8sutil.GetClientset()
+				if err != nil {
+					return errors.Wrap(err, "failed to get clientset")
+				}
+				clientset = c
+
+				namespace, _, err := k8sutil.KubernetesConfigFlags.ToRawKubeConfigLoader().Namespace()
+				if err != nil {
+					return errors.Wrap(err, "failed to get namespace")
+				}
+
+				r, err := report.GetReportsFromCluster(clientset, namespace)
+				if err != nil {
+					return errors.Wrap(err, "failed to get reports from cluster")
+				}
+				reports = r
+				manifest.Source = "cluster"
+				manifest.Namespace = namespace
+			}
+
+			if err := exportReports(cmd, manifest, reports); err != nil {
+				return err
+			}
+
+			if !prune {
+				return nil
+			}
+
+			// only prune once the archive has been written, so that events are never lost
+			for _, r := range reports {
+				pruned, err := report.PruneReport(clientset, manifest.Namespace, r)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Pruned %d %s events\n", pruned, r.GetType())
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("support-bundle", "", "read the reports from a support bundle archive or directory instead of the cluster")
+	cmd.Flags().String("output-file", "", "the archive to write (defaults to replicated-reports-<timestamp>.tar.gz)")
+	cmd.Flags().Bool("summary", false, "print a summary of the exported events")
+	cmd.Flags().Bool("prune", false, "remove the exported events from the report secrets after a successful export")
+	k8sutil.AddFlags(cmd.Flags())
+
+	return cmd
+}
+
+func ReportInspectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "inspect ARCHIVE",
+		Short:        "Summarize the events in an exported archive",
+		Long:         `Summarize the events in an archive written by "replicated report export"`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := viper.GetString("output")
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
+			f, err := os.Open(args[0])
+			if err != nil {
+				return errors.Wrap(err, "failed to open archive")
+			}
+			defer f.Close()
+
+			_, reports, err := report.ReadExportArchive(f)
+			if err != nil {
+				return errors.Wrap(err, "failed to read archive")
+			}
+
+			return printReportSummaries(cmd.OutOrStdout(), output, reports)
+		},
+	}
+
+	addOutputFlag(cmd)
+
+	return cmd
+}
+
+// exportReports writes the reports to the output file, and prints a summary if requested
+func exportReports(cmd *cobra.Command, manifest report.ExportManifest, reports []report.Report) error {
+	outputFile := viper.GetString("output-file")
+	if outputFile == "" {
+		outputFile = fmt.Sprintf("replicated-reports-%s.tar.gz", manifest.ExportedAt.Format("20060102T150405Z"))
+	}
+
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to create output file")
+	}
+	defer f.Close()
+
+	if err := report.WriteExportArchive(f, manifest, reports); err != nil {
+		return errors.Wrap(err, "failed to write archive")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "failed to close output file")
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Exported %d reports to %s\n", len(reports), outputFile)
+
+	if viper.GetBool("summary") {
+		fmt.Fprintln(cmd.OutOrStdout())
+		return printReportSummaries(cmd.OutOrStdout(), outputTable, reports)
+	}
+
+	return nil
+}
+
+func printReportSummaries(w io.Writer, output string, reports []report.Report) error {
+	summaries := []report.ReportSummary{}
+	for _, r := range reports {
+		summaries = append(summaries, report.SummarizeReport(r))
+	}
+
+	return printOutput(w, output, summaries, func(w *tabwriter.Writer) {
+		printRow(w, "TYPE", "EVENTS", "FIRST", "LAST", "INSTANCES", "DETAILS")
+		for _, summary := range summaries {
+			printRow(w, summary.Type, summary.EventCount, formatReportTime(summary.FirstReportedAt), formatReportTime(summary.LastReportedAt), len(summary.InstanceIDs), reportSummaryDetails(summary))
+		}
+	})
+}
+
+func formatReportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func reportSummaryDetails(summary report.ReportSummary) string {
+	if len(summary.MetricKeys) > 0 {
+		return "metrics: " + strings.Join(summary.MetricKeys, ", ")
+	}
+	if len(summary.AppStatuses) > 0 {
+		statuses := []string{}
+		for status, count := range summary.AppStatuses {
+			statuses = append(statuses, fmt.Sprintf("%s=%d", status, count))
+		}
+		sort.Strings(statuses)
+		return "app statuses: " + strings.Join(statuses, ", ")
+	}
+	return ""
+}
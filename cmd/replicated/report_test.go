@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/replicatedhq/replicated-sdk/pkg/report"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportCmds(t *testing.T) {
+	instanceReport := &report.InstanceReport{
+		Events: []report.InstanceReportEvent{
+			{ReportedAt: 1704164645000, InstanceID: "instance-id", AppStatus: "ready"},
+			{ReportedAt: 1704168245000, InstanceID: "instance-id", AppStatus: "degraded"},
+		},
+	}
+	encoded, err := report.EncodeReport(instanceReport)
+	require.NoError(t, err)
+
+	bundleDir := t.TempDir()
+	secretPath := filepath.Join(bundleDir, "support-bundle", "secrets", "default", "replicated-instance-report", "report.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(secretPath), 0755))
+	require.NoError(t, os.WriteFile(secretPath, []byte(fmt.Sprintf(`{"namespace":"default","name":"replicated-instance-report","key":"report","secretExists":true,"keyExists":true,"value":%q}`, encoded)), 0644))
+
+	archivePath := filepath.Join(t.TempDir(), "reports.tar.gz")
+
+	stdout, _, err := runCmd(t, "report", "export", "--support-bundle", bundleDir, "--output-file", archivePath, "--summary")
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf(`Exported 1 reports to %s
+
+TYPE      EVENTS  FIRST                 LAST                  INSTANCES  DETAILS
+instance  2       2024-01-02T03:04:05Z  2024-01-02T04:04:05Z  1          app statuses: degraded=1, ready=1
+`, archivePath), stdout)
+
+	stdout, _, err = runCmd(t, "report", "inspect", archivePath, "-o", "json")
+	require.NoError(t, err)
+	require.Equal(t, `[
+  {
+    "type": "instance",
+    "eventCount": 2,
+    "firstReportedAt": "2024-01-02T03:04:05Z",
+    "lastReportedAt": "2024-01-02T04:04:05Z",
+    "instanceIds": [
+      "instance-id"
+    ],
+    "appStatuses": {
+      "degraded": 1,
+      "ready": 1
+    },
+    "channelSequences": [
+      0
+    ]
+  }
+]
+`, stdout)
+
+	_, _, err = runCmd(t, "report", "export", "--support-bundle", bundleDir, "--prune")
+	require.EqualError(t, err, "--prune cannot be used with --support-bundle")
+}
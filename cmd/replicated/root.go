@@ -27,6 +27,15 @@ func RootCmd() *cobra.Command {
 
 	cmd.AddCommand(APICmd())
 	cmd.AddCommand(VersionCmd())
+	cmd.AddCommand(StatusCmd())
+	cmd.AddCommand(LicenseCmd())
+	cmd.AddCommand(UpdatesCmd())
+	cmd.AddCommand(HistoryCmd())
+	cmd.AddCommand(MetricsCmd())
+	cmd.AddCommand(TagsCmd())
+	cmd.AddCommand(ReportCmd())
+	cmd.AddCommand(ConfigCmd())
+	cmd.AddCommand(DoctorCmd())
 
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 
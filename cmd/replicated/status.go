@@ -0,0 +1,78 @@
+package main
+
+import (
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type StatusOutput struct {
+	AppInfo   types.GetCurrentAppInfoResponse `json:"appInfo"`
+	AppStatus appstatetypes.AppStatus         `json:"appStatus"`
+}
+
+func StatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "status",
+		Short:        "Show the status of the application",
+		Long:         `Show the current release of the application and the status of its resources, as reported by a running SDK`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := viper.GetString("output")
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
+			c, closeFn, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+
+			appInfo, _, err := c.GetAppInfo(cmd.Context())
+			if err != nil {
+				return errors.Wrap(err, "failed to get app info")
+			}
+
+			appStatus, _, err := c.GetAppStatus(cmd.Context())
+			if err != nil {
+				return errors.Wrap(err, "failed to get app status")
+			}
+
+			status := StatusOutput{
+				AppInfo:   *appInfo,
+				AppStatus: appStatus.AppStatus,
+			}
+
+			return printOutput(cmd.OutOrStdout(), output, status, func(w *tabwriter.Writer) {
+				printRow(w, "APP:", status.AppInfo.AppSlug)
+				printRow(w, "STATUS:", status.AppInfo.AppStatus)
+				printRow(w, "VERSION:", status.AppInfo.CurrentRelease.VersionLabel)
+				printRow(w, "CHANNEL:", status.AppInfo.ChannelName)
+				printRow(w, "INSTANCE:", status.AppInfo.InstanceID)
+
+				if len(status.AppStatus.ResourceStates) == 0 {
+					return
+				}
+				printRow(w)
+				printRow(w, "KIND", "NAMESPACE", "NAME", "STATE")
+				for _, resource := range status.AppStatus.ResourceStates {
+					printRow(w, resource.Kind, resource.Namespace, resource.Name, resource.State)
+				}
+			})
+		},
+	}
+
+	addClientFlags(cmd)
+	addOutputFlag(cmd)
+
+	return cmd
+}
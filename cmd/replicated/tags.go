@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	metatypes "github.com/replicatedhq/replicated-sdk/pkg/meta/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TagsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tags",
+		Short: "Manage the tags of the instance",
+		Long:  `Manage the tags that the SDK reports for the instance`,
+	}
+
+	addClientFlags(cmd)
+
+	cmd.AddCommand(TagsSetCmd())
+
+	return cmd
+}
+
+func TagsSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "set KEY=VALUE...",
+		Short:        "Set instance tags",
+		Long:         `Set the tags that the SDK reports for the instance`,
+		Example:      `  replicated tags set name=production region=us-east-1`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tags, err := parseKeyValues(args)
+			if err != nil {
+				return err
+			}
+
+			c, closeFn, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+
+			data := metatypes.InstanceTagData{
+				Force: viper.GetBool("force"),
+				Tags:  tags,
+			}
+			if _, err := c.SendInstanceTags(cmd.Context(), data); err != nil {
+				return errors.Wrap(err, "failed to set instance tags")
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "Instance tags set")
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("force", false, "force the tags to be updated")
+
+	return cmd
+}
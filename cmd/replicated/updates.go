@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/client"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func UpdatesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "updates",
+		Short:        "List the available updates",
+		Long:         `List the releases that the application can be upgraded to`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := viper.GetString("output")
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
+			c, closeFn, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+
+			updates, resp, err := c.GetAppUpdatesWithOptions(cmd.Context(), client.AppUpdatesOptions{
+				Refresh:           viper.GetBool("refresh"),
+				MaxBump:           upstreamtypes.SemverBump(viper.GetString("max-bump")),
+				ExcludePrerelease: viper.GetBool("exclude-prerelease"),
+				Channel:           viper.GetString("channel"),
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to get updates")
+			}
+			warnIfCached(cmd.ErrOrStderr(), resp)
+
+			return printOutput(cmd.OutOrStdout(), output, updates, func(w *tabwriter.Writer) {
+				printRow(w, "VERSION", "BUMP", "REQUIRED", "CREATED", "RELEASE NOTES")
+				for _, update := range updates {
+					printRow(w, update.VersionLabel, string(update.Bump), strconv.FormatBool(update.IsRequired), update.CreatedAt, truncate(update.ReleaseNotes, 60))
+				}
+			})
+		},
+	}
+
+	addClientFlags(cmd)
+	addOutputFlag(cmd)
+	cmd.Flags().Bool("refresh", false, "have the SDK check for updates now instead of showing the result of its last check")
+	cmd.Flags().String("max-bump", "", "leave out releases with a larger semver bump from the current version (one of: major, minor, patch, prerelease)")
+	cmd.Flags().Bool("exclude-prerelease", false, "leave out releases with a semver prerelease version")
+	cmd.Flags().String("channel", "", "the ID of another channel that the license is entitled to, to list its releases instead")
+
+	return cmd
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
 	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
 	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
 	"github.com/replicatedhq/replicated-sdk/pkg/report"
 	mock_store "github.com/replicatedhq/replicated-sdk/pkg/store/mock"
@@ -59,6 +60,7 @@ func TestSendInstanceData(t *testing.T) {
 					ResourceStates: []appstatetypes.ResourceState{},
 				})
 				mockStore.EXPECT().GetRunningImages().AnyTimes().Return(map[string][]string{})
+				mockStore.EXPECT().GetLicenseExpiration().Return(sdklicensetypes.LicenseExpiration{})
 			},
 			pactInteraction: func() {
 				pact.
@@ -111,6 +113,7 @@ func TestSendInstanceData(t *testing.T) {
 					ResourceStates: []appstatetypes.ResourceState{},
 				})
 				mockStore.EXPECT().GetRunningImages().AnyTimes().Return(map[string][]string{})
+				mockStore.EXPECT().GetLicenseExpiration().Return(sdklicensetypes.LicenseExpiration{})
 			},
 			pactInteraction: func() {
 				pact.
@@ -163,6 +166,7 @@ func TestSendInstanceData(t *testing.T) {
 					ResourceStates: []appstatetypes.ResourceState{},
 				})
 				mockStore.EXPECT().GetRunningImages().AnyTimes().Return(map[string][]string{})
+				mockStore.EXPECT().GetLicenseExpiration().Return(sdklicensetypes.LicenseExpiration{})
 			},
 			pactInteraction: func() {
 				pact.
@@ -215,6 +219,7 @@ func TestSendInstanceData(t *testing.T) {
 					ResourceStates: []appstatetypes.ResourceState{},
 				})
 				mockStore.EXPECT().GetRunningImages().AnyTimes().Return(map[string][]string{})
+				mockStore.EXPECT().GetLicenseExpiration().Return(sdklicensetypes.LicenseExpiration{})
 			},
 			pactInteraction: func() {
 				pact.
@@ -2,6 +2,7 @@ package apiserver
 
 import (
 	"log"
+	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/pkg/errors"
@@ -15,34 +16,53 @@ import (
 	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
 	sdklicense "github.com/replicatedhq/replicated-sdk/pkg/license"
 	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"github.com/replicatedhq/replicated-sdk/pkg/readiness"
 	"github.com/replicatedhq/replicated-sdk/pkg/report"
 	reporttypes "github.com/replicatedhq/replicated-sdk/pkg/report/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
-	"github.com/replicatedhq/replicated-sdk/pkg/upstream"
-	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/updates"
 	"github.com/replicatedhq/replicated-sdk/pkg/util"
+	"github.com/replicatedhq/replicated-sdk/pkg/webhook"
 )
 
 func bootstrap(params APIServerParams) error {
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
-		return errors.Wrap(err, "failed to get clientset")
+		return readiness.Failed(readiness.PhaseClientset, errors.Wrap(err, "failed to get clientset"))
 	}
 
+	upstreamClientOptions := util.UpstreamClientOptions{
+		HTTPSProxy: params.HTTPSProxy,
+		HTTPProxy:  params.HTTPProxy,
+		NoProxy:    params.NoProxy,
+	}
+	if params.PrivateCASecretName != "" {
+		caCerts, err := util.GetPrivateCACerts(params.Context, clientset, params.Namespace, params.PrivateCASecretName, params.PrivateCASecretKey)
+		if err != nil {
+			return readiness.Failed(readiness.PhaseClientset, errors.Wrap(err, "failed to get private ca certs"))
+		}
+		upstreamClientOptions.CACerts = caCerts
+	}
+	if err := util.ConfigureUpstreamClient(upstreamClientOptions); err != nil {
+		return readiness.Failed(readiness.PhaseClientset, errors.Wrap(err, "failed to configure upstream client"))
+	}
+	readiness.Succeeded(readiness.PhaseClientset)
+
 	replicatedID, appID := params.ReplicatedID, params.AppID
 	if replicatedID == "" || appID == "" {
 		// retrieve replicated and app ids
 		replicatedID, appID, err = util.GetReplicatedAndAppIDs(clientset, params.Namespace)
 		if err != nil {
-			return errors.Wrap(err, "failed to get replicated and app ids")
+			return readiness.Failed(readiness.PhaseIDs, errors.Wrap(err, "failed to get replicated and app ids"))
 		}
 	}
 	if replicatedID == "" {
-		return backoff.Permanent(errors.New("Replicated ID not found"))
+		return readiness.Failed(readiness.PhaseIDs, backoff.Permanent(errors.New("Replicated ID not found")))
 	}
 	if appID == "" {
-		return backoff.Permanent(errors.New("App ID not found"))
+		return readiness.Failed(readiness.PhaseIDs, backoff.Permanent(errors.New("App ID not found")))
 	}
+	readiness.Succeeded(readiness.PhaseIDs)
 
 	log.Println("replicatedID:", replicatedID)
 	log.Println("appID:", appID)
@@ -61,16 +81,16 @@ func bootstrap(params APIServerParams) error {
 	if len(params.LicenseBytes) > 0 {
 		wrapper, err := sdklicense.LoadLicenseFromBytes(params.LicenseBytes)
 		if err != nil {
-			return errors.Wrap(err, "failed to parse license from base64")
+			return readiness.Failed(readiness.PhaseLicenseVerify, errors.Wrap(err, "failed to parse license from base64"))
 		}
 		unverifiedWrapper = wrapper
 	} else if params.IntegrationLicenseID != "" {
 		wrapper, err := sdklicense.GetLicenseByID(params.IntegrationLicenseID, params.ReplicatedAppEndpoint)
 		if err != nil {
-			return backoff.Permanent(errors.Wrap(err, "failed to get license by id for integration license id"))
+			return readiness.Failed(readiness.PhaseLicenseVerify, backoff.Permanent(errors.Wrap(err, "failed to get license by id for integration license id")))
 		}
 		if wrapper.GetLicenseType() != "dev" {
-			return errors.New("integration license must be a dev license")
+			return readiness.Failed(readiness.PhaseLicenseVerify, errors.New("integration license must be a dev license"))
 		}
 		unverifiedWrapper = wrapper
 	}
@@ -82,27 +102,66 @@ func bootstrap(params APIServerParams) error {
 			// however, the data inside the signature was still valid, and so the license has been updated to use that data instead
 			log.Println(err.Error())
 		} else {
-			return backoff.Permanent(errors.Wrap(err, "failed to verify license signature"))
+			return readiness.Failed(readiness.PhaseLicenseVerify, backoff.Permanent(errors.Wrap(err, "failed to verify license signature")))
 		}
 	}
 	verifiedWrapper := unverifiedWrapper
+	licenseFields := params.LicenseFields
+
+	if len(params.LicenseBytes) > 0 {
+		// a license that was uploaded through the API replaces the configured license, unless the configured license is newer
+		uploaded, err := sdklicense.GetUploadedLicense(params.Context, clientset, params.Namespace, verifiedWrapper)
+		if err != nil {
+			log.Printf("Failed to load the uploaded license, using the configured license: %v", err)
+		} else if uploaded != nil {
+			log.Printf("Using the uploaded license with sequence %d", uploaded.GetLicenseSequence())
+			verifiedWrapper = *uploaded
+			// the configured license fields belong to the configured license
+			licenseFields = sdklicense.LicenseFieldsFromEntitlements(verifiedWrapper)
+		}
+	}
+	readiness.Succeeded(readiness.PhaseLicenseVerify)
 
+	var syncedLicenseData *sdklicense.LicenseData
+	var lastKnownGood *sdklicense.LastKnownGoodLicense
+	var licenseSyncErr error
 	if !util.IsAirgap() {
 		// sync license
 		licenseData, err := sdklicense.GetLatestLicense(verifiedWrapper, params.ReplicatedAppEndpoint)
 		if err != nil {
-			return errors.Wrap(err, "failed to get latest license")
+			if !util.IsUpstreamUnavailable(err) {
+				// the license was rejected, e.g. because it was revoked, so the last known good license must not be used instead
+				return readiness.Failed(readiness.PhaseLicenseSync, errors.Wrap(err, "failed to get latest license"))
+			}
+
+			// fall back to the license from the last successful sync, so that a restart during an outage does not keep the sdk from becoming ready
+			licenseSyncErr = err
+			var lkgErr error
+			lastKnownGood, lkgErr = sdklicense.LoadLastKnownGoodLicense(params.Context, clientset, params.Namespace, verifiedWrapper)
+			if lkgErr != nil {
+				log.Printf("Failed to load last known good license: %v", lkgErr)
+				return readiness.Failed(readiness.PhaseLicenseSync, errors.Wrap(err, "failed to get latest license"))
+			}
+			log.Printf("Failed to get latest license, using the last known good license synced at %s: %v", lastKnownGood.SyncedAt.Format(time.RFC3339), err)
+			verifiedWrapper = lastKnownGood.License
+			if lastKnownGood.LicenseFields != nil {
+				licenseFields = lastKnownGood.LicenseFields
+			}
+		} else {
+			verifiedWrapper = licenseData.License
+			syncedLicenseData = licenseData
 		}
-		verifiedWrapper = licenseData.License
 	}
 
-	// check license expiration
-	expired, err := sdklicense.LicenseIsExpired(verifiedWrapper)
-	if err != nil {
-		return errors.Wrap(err, "failed to check if license is expired")
+	// an expired license does not fail bootstrap, the api keeps being served as usual
+	// and the expiration state is re-evaluated on every license sync
+	if _, err := sdklicense.GetLicenseExpiration(verifiedWrapper); err != nil {
+		return readiness.Failed(readiness.PhaseLicenseSync, errors.Wrap(err, "failed to get license expiration"))
 	}
-	if expired {
-		return backoff.Permanent(errors.New("License is expired"))
+	if util.IsAirgap() {
+		readiness.Skipped(readiness.PhaseLicenseSync)
+	} else {
+		readiness.Succeeded(readiness.PhaseLicenseSync)
 	}
 
 	channelID := params.ChannelID
@@ -115,9 +174,13 @@ func bootstrap(params APIServerParams) error {
 		channelName = verifiedWrapper.GetChannelName()
 	}
 
+	if updatesChannelID := sdklicense.GetUpdatesChannelID(verifiedWrapper, channelID); updatesChannelID != channelID {
+		logger.Infof("The license is not entitled to installed channel %s, updates are checked on channel %s from the first release", channelID, updatesChannelID)
+	}
+
 	store.InitInMemory(store.InitInMemoryStoreOptions{
 		License:               verifiedWrapper,
-		LicenseFields:         params.LicenseFields,
+		LicenseFields:         sdklicense.VerifyLicenseFields(verifiedWrapper, licenseFields),
 		AppName:               params.AppName,
 		ChannelID:             channelID,
 		ChannelName:           channelName,
@@ -133,25 +196,50 @@ func bootstrap(params APIServerParams) error {
 		AppID:                 appID,
 		ReportAllImages:       reportAllImages,
 		ReadOnlyMode:          params.ReadOnlyMode,
+		StrictLicenseFields:   params.StrictLicenseFields,
+	})
+
+	sdklicense.SetExpirationOptions(sdklicense.ExpirationOptions{
+		ExpiringSoonThreshold: time.Duration(params.LicenseExpiringSoonDays) * 24 * time.Hour,
+		GracePeriod:           time.Duration(params.LicenseGracePeriodDays) * 24 * time.Hour,
 	})
+	sdklicense.SyncLicenseExpiration(store.GetStore(), verifiedWrapper)
+	if syncedLicenseData != nil {
+		sdklicense.RecordLicenseSync(syncedLicenseData)
+	} else if lastKnownGood != nil {
+		sdklicense.MarkLicenseStale(lastKnownGood.SyncedAt)
+	}
+
+	webhook.Init(webhook.InitOptions{
+		Clientset: clientset,
+		Namespace: params.Namespace,
+		Targets:   params.Webhooks,
+	})
+	webhook.NotifyLicenseExpiring(verifiedWrapper)
 
 	isIntegrationModeEnabled, err := integration.IsEnabled(params.Context, clientset, store.GetStore().GetNamespace(), store.GetStore().GetLicense())
 	if err != nil {
-		return errors.Wrap(err, "failed to check if integration mode is enabled")
+		return readiness.Failed(readiness.PhaseUpdates, errors.Wrap(err, "failed to check if integration mode is enabled"))
 	}
 
 	if !util.IsAirgap() && !isIntegrationModeEnabled {
-		// retrieve and cache updates
-		currentCursor := upstreamtypes.ReplicatedCursor{
-			ChannelID:       store.GetStore().GetChannelID(),
-			ChannelName:     store.GetStore().GetChannelName(),
-			ChannelSequence: store.GetStore().GetChannelSequence(),
+		// retrieve and cache updates, and keep checking for updates in the background.
+		// failed checks don't fail bootstrap, the updates are reported as stale until a background check succeeds
+		if lastKnownGood != nil {
+			updates.StartStale(params.UpdateCheckInterval, errors.Wrap(licenseSyncErr, "failed to get latest license"))
+			logger.Infof("Not checking for updates until the Replicated API can be reached")
+		} else if err := updates.Start(params.UpdateCheckInterval); err != nil {
+			logger.Error(errors.Wrap(err, "failed to get updates, checking again in the background"))
 		}
-		updates, err := upstream.GetUpdates(store.GetStore(), store.GetStore().GetLicense(), currentCursor)
-		if err != nil {
-			return errors.Wrap(err, "failed to get updates")
+		readiness.Succeeded(readiness.PhaseUpdates)
+	} else {
+		if util.IsAirgap() && !isIntegrationModeEnabled {
+			// updates can't be checked for in airgap mode, but may have been imported from a release index
+			if err := updates.ImportSavedReleaseIndex(params.Context, clientset, params.Namespace); err != nil {
+				logger.Error(errors.Wrap(err, "failed to import saved release index"))
+			}
 		}
-		store.GetStore().SetUpdates(updates)
+		readiness.Skipped(readiness.PhaseUpdates)
 	}
 
 	appStateOperator := appstate.InitOperator(clientset, params.Namespace)
@@ -162,7 +250,7 @@ func bootstrap(params APIServerParams) error {
 	if informers == nil && helm.IsHelmManaged() {
 		helmRelease, err := helm.GetRelease(helm.GetReleaseName())
 		if err != nil {
-			return errors.Wrap(err, "failed to get helm release")
+			return readiness.Failed(readiness.PhaseInformers, errors.Wrap(err, "failed to get helm release"))
 		}
 		if helmRelease != nil {
 			informers = appstate.GenerateStatusInformersForManifest(helmRelease.Manifest)
@@ -174,9 +262,16 @@ func bootstrap(params APIServerParams) error {
 		Sequence:  store.GetStore().GetReleaseSequence(),
 		Informers: informers,
 	})
+	readiness.Succeeded(readiness.PhaseInformers)
 
 	if err := heartbeat.Start(); err != nil {
-		return errors.Wrap(err, "failed to start heartbeat")
+		return readiness.Failed(readiness.PhaseHeartbeat, errors.Wrap(err, "failed to start heartbeat"))
+	}
+	readiness.Succeeded(readiness.PhaseHeartbeat)
+
+	if params.LicenseSource != nil {
+		reloader := newLicenseReloader(*params.LicenseSource)
+		go reloader.watch(params.Context, clientset, params.Namespace)
 	}
 
 	// this is at the end of the bootstrap function so that it doesn't re-run on retry
@@ -0,0 +1,210 @@
+package apiserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	sdklicense "github.com/replicatedhq/replicated-sdk/pkg/license"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"github.com/replicatedhq/replicated-sdk/pkg/updates"
+	"github.com/replicatedhq/replicated-sdk/pkg/webhook"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// licenseFilePollInterval is how often a license file is checked for changes. Mounted secrets and config maps
+// are updated by the kubelet through a symlink swap, which is picked up by re-reading the file.
+var licenseFilePollInterval = 30 * time.Second
+
+// licenseReloader replaces the license in the store when the license in its source changes,
+// so that a renewed license is picked up without a restart
+type licenseReloader struct {
+	source sdklicensetypes.LicenseSource
+
+	mtx          sync.Mutex
+	lastBytes    []byte
+	lastErrorMsg string
+}
+
+func newLicenseReloader(source sdklicensetypes.LicenseSource) *licenseReloader {
+	return &licenseReloader{
+		source: source,
+	}
+}
+
+// reload validates the license and swaps it into the store. The same license bytes are only processed once,
+// so that an unchanged or rejected license is not reloaded or logged on every resync.
+func (l *licenseReloader) reload(licenseBytes []byte) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if bytes.Equal(l.lastBytes, licenseBytes) {
+		return nil
+	}
+	l.lastBytes = licenseBytes
+
+	updated, err := sdklicense.LoadLicenseFromBytes(licenseBytes)
+	if err != nil {
+		return errors.Wrap(err, "failed to load license")
+	}
+
+	previous := store.GetStore().GetLicense()
+	if err := sdklicense.ValidateLicenseUpdate(previous, updated); err != nil {
+		return errors.Wrap(err, "failed to validate license")
+	}
+	l.lastErrorMsg = ""
+
+	changes := sdklicense.DiffEntitlements(previous, updated)
+	if previous.GetLicenseID() == updated.GetLicenseID() && previous.GetLicenseSequence() == updated.GetLicenseSequence() && len(changes) == 0 {
+		// the source has the license that is already being served, e.g. right after startup
+		return nil
+	}
+
+	sdklicense.SetLicense(store.GetStore(), updated)
+	if previous.GetLicenseSequence() != updated.GetLicenseSequence() {
+		// the releases in an imported release index that are available depend on the license sequence
+		updates.RefilterReleaseIndexUpdates()
+	}
+
+	logger.Infof("Reloaded license from %s, sequence %d -> %d, entitlement changes: %s", l.source, previous.GetLicenseSequence(), updated.GetLicenseSequence(), formatEntitlementChanges(changes))
+	webhook.NotifyLicenseChanged(previous, updated)
+
+	return nil
+}
+
+// onChange reloads the license, the current license keeps being served if the new one is rejected
+func (l *licenseReloader) onChange(licenseBytes []byte) {
+	if err := l.reload(licenseBytes); err != nil {
+		l.logError(err)
+	}
+}
+
+// onError is called when the license source can't be read. The license is processed again once the source
+// can be read, even if it did not change.
+func (l *licenseReloader) onError(err error) {
+	l.mtx.Lock()
+	l.lastBytes = nil
+	l.mtx.Unlock()
+
+	l.logError(err)
+}
+
+// logError logs each distinct error once, rather than on every poll or resync
+func (l *licenseReloader) logError(err error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if err.Error() == l.lastErrorMsg {
+		return
+	}
+	l.lastErrorMsg = err.Error()
+	logger.Error(errors.Wrapf(err, "failed to reload license from %s, continuing to serve the current license", l.source))
+}
+
+// watch reloads the license whenever its source changes, until the context is done
+func (l *licenseReloader) watch(ctx context.Context, clientset kubernetes.Interface, namespace string) {
+	if l.source.Path != "" {
+		l.watchFile(ctx)
+		return
+	}
+	l.watchSecret(ctx, clientset, namespace)
+}
+
+func (l *licenseReloader) watchFile(ctx context.Context) {
+	ticker := time.NewTicker(licenseFilePollInterval)
+	defer ticker.Stop()
+
+	for {
+		licenseBytes, err := os.ReadFile(l.source.Path)
+		if err != nil {
+			l.onError(errors.Wrap(err, "failed to read license file"))
+		} else {
+			l.onChange(licenseBytes)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *licenseReloader) watchSecret(ctx context.Context, clientset kubernetes.Interface, namespace string) {
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", l.source.SecretName).String()
+
+	listwatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return clientset.CoreV1().Secrets(namespace).List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return clientset.CoreV1().Secrets(namespace).Watch(context.TODO(), options)
+		},
+	}
+	informer := cache.NewSharedInformer(
+		listwatch,
+		&corev1.Secret{},
+		time.Minute,
+	)
+
+	onChange := func(obj interface{}) {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok {
+			return
+		}
+		licenseBytes, ok := secret.Data[l.source.GetSecretKey()]
+		if !ok {
+			l.onError(errors.Errorf("key %s not found in secret %s", l.source.GetSecretKey(), l.source.SecretName))
+			return
+		}
+		l.onChange(licenseBytes)
+	}
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: onChange,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSecret, ok := oldObj.(*corev1.Secret)
+			if ok && oldSecret.ResourceVersion == newObj.(*corev1.Secret).ResourceVersion {
+				// periodic resync, nothing changed
+				return
+			}
+			onChange(newObj)
+		},
+	})
+
+	informer.Run(ctx.Done())
+}
+
+func formatEntitlementChanges(changes []sdklicensetypes.EntitlementChange) string {
+	if len(changes) == 0 {
+		return "none"
+	}
+
+	formatted := []string{}
+	for _, change := range changes {
+		switch {
+		case change.Previous == nil:
+			formatted = append(formatted, fmt.Sprintf("%s added (%v)", change.Name, change.Current))
+		case change.Current == nil:
+			formatted = append(formatted, fmt.Sprintf("%s removed (was %v)", change.Name, change.Previous))
+		default:
+			formatted = append(formatted, fmt.Sprintf("%s %v -> %v", change.Name, change.Previous, change.Current))
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,128 @@
+package apiserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/licensetest"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_licenseReloader(t *testing.T) {
+	current := licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+		Spec: v1beta1.LicenseSpec{LicenseID: "1vusOokxAVp1tkRGuyxnF23PJcq", AppSlug: "my-app", LicenseSequence: 6},
+	}}
+
+	tests := []struct {
+		name         string
+		license      string
+		wantErr      string
+		wantSequence int64
+	}{
+		{
+			name:         "renewed license",
+			license:      licensetest.ValidSignedLicense,
+			wantSequence: 7,
+		},
+		{
+			name:    "not a license",
+			license: "not a license",
+			wantErr: "failed to load license",
+		},
+		{
+			name: "license for a different app",
+			license: `apiVersion: kots.io/v1beta1
+kind: License
+spec:
+  licenseID: other-license-id
+  appSlug: other-app
+  licenseSequence: 8
+`,
+			wantErr: "failed to validate license",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			currentFields := sdklicensetypes.LicenseFields{
+				"removed_field": {Name: "removed_field", Value: "old", ValueType: "String"},
+			}
+			store.InitInMemory(store.InitInMemoryStoreOptions{License: current, LicenseFields: currentFields})
+			defer store.SetStore(nil)
+
+			l := newLicenseReloader(sdklicensetypes.LicenseSource{SecretName: "my-license"})
+			err := l.reload([]byte(tt.license))
+			if tt.wantErr == "" {
+				req.NoError(err)
+				req.Equal(tt.wantSequence, store.GetStore().GetLicense().GetLicenseSequence())
+
+				// the fields are rebuilt from the entitlements of the new license
+				fields := store.GetStore().GetLicenseFields()
+				req.NotContains(fields, "removed_field")
+				req.Contains(fields, "int_field")
+				req.Equal("Int Field", fields["int_field"].Title)
+			} else {
+				req.ErrorContains(err, tt.wantErr)
+				req.Equal(current, store.GetStore().GetLicense())
+				req.Equal(currentFields, store.GetStore().GetLicenseFields())
+			}
+
+			// the same license is not processed again
+			req.NoError(l.reload([]byte(tt.license)))
+		})
+	}
+}
+
+func Test_licenseReloaderWatchFile(t *testing.T) {
+	req := require.New(t)
+
+	store.InitInMemory(store.InitInMemoryStoreOptions{License: licensewrapper.LicenseWrapper{V1: &v1beta1.License{}}})
+	defer store.SetStore(nil)
+
+	pollInterval := licenseFilePollInterval
+	licenseFilePollInterval = 10 * time.Millisecond
+	defer func() { licenseFilePollInterval = pollInterval }()
+
+	path := filepath.Join(t.TempDir(), "license.yaml")
+	l := newLicenseReloader(sdklicensetypes.LicenseSource{Path: path})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.watchFile(ctx)
+
+	getLastErrorMsg := func() string {
+		l.mtx.Lock()
+		defer l.mtx.Unlock()
+		return l.lastErrorMsg
+	}
+
+	// a missing file is reported, and the license is loaded once the file exists
+	req.Eventually(func() bool {
+		return strings.Contains(getLastErrorMsg(), "failed to read license file")
+	}, time.Second, 10*time.Millisecond)
+
+	req.NoError(os.WriteFile(path, []byte("not a license"), 0644))
+	req.Eventually(func() bool {
+		return strings.Contains(getLastErrorMsg(), "failed to load license")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_formatEntitlementChanges(t *testing.T) {
+	req := require.New(t)
+
+	req.Equal("none", formatEntitlementChanges(nil))
+	req.Equal("feature_x removed (was true), region added (us-east), seat_count 50 -> 100", formatEntitlementChanges([]sdklicensetypes.EntitlementChange{
+		{Name: "feature_x", Previous: true},
+		{Name: "region", Current: "us-east"},
+		{Name: "seat_count", Previous: int64(50), Current: int64(100)},
+	}))
+}
@@ -0,0 +1,469 @@
+package apiserver
+
+import (
+	"fmt"
+
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/auth"
+	"github.com/replicatedhq/replicated-sdk/pkg/buildversion"
+	diagnosticstypes "github.com/replicatedhq/replicated-sdk/pkg/diagnostics/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	integrationtypes "github.com/replicatedhq/replicated-sdk/pkg/integration/types"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/openapi"
+	"github.com/replicatedhq/replicated-sdk/pkg/readiness"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+)
+
+const bearerAuth = "bearerAuth"
+
+// newAPIDocument describes every route served by the API. Schemas are generated from the handler types,
+// and the document is checked against the router in tests, so that it can't drift from the implementation.
+func newAPIDocument() *openapi.Document {
+	g := openapi.NewGenerator()
+
+	g.Override(appstatetypes.State(""), &openapi.Schema{
+		Type: "string",
+		Enum: []interface{}{
+			appstatetypes.StateReady,
+			appstatetypes.StateUpdating,
+			appstatetypes.StateDegraded,
+			appstatetypes.StateUnavailable,
+			appstatetypes.StateMissing,
+		},
+	})
+
+	g.Override(diagnosticstypes.Status(""), &openapi.Schema{
+		Type: "string",
+		Enum: []interface{}{
+			diagnosticstypes.StatusPass,
+			diagnosticstypes.StatusWarn,
+			diagnosticstypes.StatusFail,
+		},
+	})
+
+	entitlementOperators := []interface{}{}
+	for _, op := range sdklicensetypes.EntitlementOperators {
+		entitlementOperators = append(entitlementOperators, op)
+	}
+	g.Override(sdklicensetypes.EntitlementOperator(""), &openapi.Schema{
+		Type: "string",
+		Enum: entitlementOperators,
+	})
+
+	g.Override(sdklicensetypes.SignatureStatus(""), &openapi.Schema{
+		Type: "string",
+		Enum: []interface{}{
+			sdklicensetypes.SignatureStatusVerified,
+			sdklicensetypes.SignatureStatusUnverified,
+			sdklicensetypes.SignatureStatusInvalid,
+		},
+	})
+
+	g.Override(sdklicensetypes.ExpirationState(""), &openapi.Schema{
+		Type: "string",
+		Enum: []interface{}{
+			sdklicensetypes.ExpirationStateValid,
+			sdklicensetypes.ExpirationStateExpiringSoon,
+			sdklicensetypes.ExpirationStateExpiredInGrace,
+			sdklicensetypes.ExpirationStateExpired,
+		},
+	})
+
+	g.Override(upstreamtypes.SemverBump(""), &openapi.Schema{
+		Type: "string",
+		Enum: []interface{}{
+			upstreamtypes.SemverBumpMajor,
+			upstreamtypes.SemverBumpMinor,
+			upstreamtypes.SemverBumpPatch,
+			upstreamtypes.SemverBumpPrerelease,
+		},
+	})
+
+	minProperties := 1
+	g.Override(types.CustomAppMetricsData{}, &openapi.Schema{
+		Type:          "object",
+		Description:   "Custom metric values by name. Only scalar values are allowed.",
+		MinProperties: &minProperties,
+		AdditionalProperties: &openapi.Schema{
+			AnyOf: []*openapi.Schema{
+				{Type: "string"},
+				{Type: "number"},
+				{Type: "boolean"},
+			},
+		},
+	})
+
+	mockData := &openapi.Schema{
+		Description: "Mock data for integration mode. The version field selects the format, and defaults to v1.",
+		AnyOf: []*openapi.Schema{
+			g.SchemaOf(integrationtypes.MockDataV1{}),
+			g.SchemaOf(integrationtypes.MockDataV2{}),
+		},
+	}
+
+	doc := openapi.NewDocument(openapi.Info{
+		Title:       "Replicated SDK API",
+		Description: "The API served by the Replicated SDK to the application it is installed with.",
+		Version:     buildversion.Version(),
+	})
+
+	errorResponse := openapi.JSONResponse("The request failed", g.SchemaOf(types.ErrorResponse{}))
+	notInitializedResponse := openapi.JSONResponse("The SDK is still starting", g.SchemaOf(types.NotInitializedResponse{}))
+	emptyResponse := openapi.Response{Description: "The request succeeded"}
+	noAuth := []map[string][]string{{}}
+
+	dataResponses := func(responses map[string]openapi.Response) map[string]openapi.Response {
+		responses["503"] = notInitializedResponse
+		responses["default"] = errorResponse
+		return responses
+	}
+	pathParam := func(name string, description string) openapi.Parameter {
+		return openapi.Parameter{Name: name, In: "path", Description: description, Required: true, Schema: &openapi.Schema{Type: "string"}}
+	}
+
+	// health
+
+	doc.Add("GET", "/healthz", &openapi.Operation{
+		OperationID: "getHealth",
+		Summary:     "Check that the API is up",
+		Tags:        []string{"health"},
+		Security:    noAuth,
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("The API is up", g.SchemaOf(types.HealthzResponse{})),
+		},
+	})
+	doc.Add("GET", "/readyz", &openapi.Operation{
+		OperationID: "getReadiness",
+		Summary:     "Check that the SDK has finished starting",
+		Tags:        []string{"health"},
+		Security:    noAuth,
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("The SDK is ready", g.SchemaOf(readiness.Status{})),
+			"503": openapi.JSONResponse("The SDK is still starting", g.SchemaOf(readiness.Status{})),
+		},
+	})
+	doc.Add("GET", "/metrics", &openapi.Operation{
+		OperationID: "getMetrics",
+		Summary:     "Get SDK and application metrics in the Prometheus text format",
+		Tags:        []string{"health"},
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "The metrics",
+				Content: map[string]openapi.MediaType{
+					"text/plain": {Schema: &openapi.Schema{Type: "string"}},
+				},
+			},
+			"default": errorResponse,
+		},
+	})
+	doc.Add("GET", "/api/v1/diagnostics", &openapi.Operation{
+		OperationID: "getDiagnostics",
+		Summary:     "Check the permissions and environment of the SDK",
+		Description: "Reports which permissions and environment variables the SDK is missing, and which features are disabled as a result. Served while the SDK is starting as well.",
+		Tags:        []string{"health"},
+		Responses: map[string]openapi.Response{
+			"200":     openapi.JSONResponse("The diagnostics report", g.SchemaOf(diagnosticstypes.Report{})),
+			"default": errorResponse,
+		},
+	})
+	doc.Add("GET", "/api/v1/openapi.json", &openapi.Operation{
+		OperationID: "getOpenAPIDocument",
+		Summary:     "Get this document",
+		Tags:        []string{"health"},
+		Security:    noAuth,
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("The OpenAPI document", &openapi.Schema{Type: "object"}),
+		},
+	})
+
+	// license
+
+	doc.Add("GET", "/api/v1/license/info", &openapi.Operation{
+		OperationID: "getLicenseInfo",
+		Summary:     "Get the license",
+		Description: requiresScope(auth.ScopeReadLicense),
+		Tags:        []string{"license"},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The license", g.SchemaOf(types.LicenseInfo{})),
+		}),
+	})
+	doc.Add("GET", "/api/v1/license/channels", &openapi.Operation{
+		OperationID: "getLicenseChannels",
+		Summary:     "Get the channels that the license is entitled to",
+		Description: requiresScope(auth.ScopeReadLicense) + " Licenses that don't list their channels are only entitled to the channel of the license." +
+			" Updates are checked on the installed channel if the license is entitled to it, and on the default channel of the license otherwise.",
+		Tags: []string{"license"},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The license channels", g.SchemaOf(types.GetLicenseChannelsResponse{})),
+		}),
+	})
+	doc.Add("GET", "/api/v1/license/fields", &openapi.Operation{
+		OperationID: "getLicenseFields",
+		Summary:     "Get the custom license fields",
+		Description: requiresScope(auth.ScopeReadLicense) + " Each field is verified with the public key of the app, and fields that fail verification are left out in strict mode.",
+		Tags:        []string{"license"},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The license fields by name", g.SchemaOf(sdklicensetypes.LicenseFields{})),
+		}),
+	})
+	doc.Add("GET", "/api/v1/license/fields/{fieldName}", &openapi.Operation{
+		OperationID: "getLicenseField",
+		Summary:     "Get a custom license field",
+		Description: requiresScope(auth.ScopeReadLicense) + " The field is verified with the public key of the app.",
+		Tags:        []string{"license"},
+		Parameters:  []openapi.Parameter{pathParam("fieldName", "The name of the license field")},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The license field", g.SchemaOf(sdklicensetypes.LicenseField{})),
+			"403": openapi.JSONResponse("The license field failed signature verification in strict mode", g.SchemaOf(types.ErrorResponse{})),
+			"404": openapi.JSONResponse("The license field does not exist", g.SchemaOf(types.ErrorResponse{})),
+		}),
+	})
+	doc.Add("POST", "/api/v1/license/entitlements/evaluate", &openapi.Operation{
+		OperationID: "evaluateEntitlements",
+		Summary:     "Evaluate checks against the custom license fields",
+		Description: requiresScope(auth.ScopeReadLicense) + " Field values are coerced by their value type (Integer, Boolean, String or Text) before they are compared." +
+			" The enabled and disabled operators take no value and only apply to Boolean fields, and the ordering operators only apply to Integer fields." +
+			" A check that can't be evaluated fails with an error in its result.",
+		Tags:        []string{"license"},
+		RequestBody: openapi.JSONBody("The checks to evaluate", g.SchemaOf(types.EvaluateEntitlementsRequest{})),
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The result of each check", g.SchemaOf(types.EvaluateEntitlementsResponse{})),
+		}),
+	})
+
+	doc.Add("PUT", "/api/v1/license", &openapi.Operation{
+		OperationID: "uploadLicense",
+		Summary:     "Replace the license",
+		Description: fmt.Sprintf("Always requires a token with the %s scope, and is forbidden if token authentication is not configured.", auth.ScopeWriteLicense) +
+			" The license must have a valid signature, and must be the current license with the same or a later sequence, e.g. a renewed license in an airgap install." +
+			" It is persisted, and is served instead of the configured license after a restart unless the configured license is newer.",
+		Tags: []string{"license"},
+		RequestBody: &openapi.RequestBody{
+			Description: "The license YAML",
+			Required:    true,
+			Content: map[string]openapi.MediaType{
+				"application/yaml": {Schema: &openapi.Schema{Type: "string"}},
+			},
+		},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The new license", g.SchemaOf(types.LicenseInfo{})),
+			"422": openapi.JSONResponse("The license can't replace the current license", g.SchemaOf(types.ErrorResponse{})),
+		}),
+	})
+
+	// app
+
+	doc.Add("GET", "/api/v1/app/info", &openapi.Operation{
+		OperationID: "getAppInfo",
+		Summary:     "Get the app and its current release",
+		Tags:        []string{"app"},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The app", g.SchemaOf(types.GetCurrentAppInfoResponse{})),
+		}),
+	})
+	doc.Add("GET", "/api/v1/app/status", &openapi.Operation{
+		OperationID: "getAppStatus",
+		Summary:     "Get the status of the app resources",
+		Tags:        []string{"app"},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The app status", g.SchemaOf(types.GetCurrentAppStatusResponse{})),
+		}),
+	})
+	doc.Add("GET", "/api/v1/app/status/stream", &openapi.Operation{
+		OperationID: "streamAppStatus",
+		Summary:     "Stream app status changes",
+		Description: "Streams appStatus server-sent events. Each event has the same data as the app status response. " +
+			"Clients that reconnect with a Last-Event-ID header only receive the changes they missed. " +
+			"Event IDs from before a restart or from another replica are not resumed from, and the current status is sent instead.",
+		Tags: []string{"app"},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": {
+				Description: "The event stream",
+				Content: map[string]openapi.MediaType{
+					"text/event-stream": {Schema: &openapi.Schema{Type: "string"}},
+				},
+			},
+		}),
+	})
+	doc.Add("GET", "/api/v1/app/updates", &openapi.Operation{
+		OperationID: "getAppUpdates",
+		Summary:     "Get the releases that are available to update to",
+		Description: "Updates are checked in the background, and the result of the last check is returned. " +
+			"The X-Replicated-Updates-Last-Checked-At header is when updates were last checked successfully, " +
+			"and X-Replicated-Updates-Stale is true if the last check failed or was not recent. " +
+			"In airgap mode, the releases in the last imported release index are returned.",
+		Tags: []string{"app"},
+		Parameters: []openapi.Parameter{
+			{Name: "refresh", In: "query", Description: "Check for updates now instead of returning the result of the last check", Schema: &openapi.Schema{Type: "boolean"}},
+			{Name: "maxBump", In: "query", Description: "Leave out releases with a larger semver bump from the current version. Required releases are always returned.", Schema: g.SchemaOf(upstreamtypes.SemverBump(""))},
+			{Name: "includePrerelease", In: "query", Description: "Include releases with a semver prerelease version, defaults to true", Schema: &openapi.Schema{Type: "boolean"}},
+			{Name: "channel", In: "query", Description: "The ID of another channel that the license is entitled to, to get its releases from the Replicated API instead of the last check", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The available releases", g.SchemaOf([]upstreamtypes.ChannelRelease{})),
+		}),
+	})
+	doc.Add("PUT", "/api/v1/app/updates/index", &openapi.Operation{
+		OperationID: "importReleaseIndex",
+		Summary:     "Import a release index to discover updates in airgap mode",
+		Description: fmt.Sprintf("Always requires a token with the %s scope, and is forbidden if token authentication is not configured.", auth.ScopeImportUpdates) +
+			" Only available in airgap mode, and not in read-only mode." +
+			" The index must be signed with the app key from the license, must not be older than the imported index, and its releases are filtered by the channel and sequence of the license." +
+			" It is persisted, and is imported again after a restart.",
+		Tags:        []string{"app"},
+		RequestBody: openapi.JSONBody("The signed release index", g.SchemaOf(upstreamtypes.SignedReleaseIndex{})),
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The releases in the index that are available to update to", g.SchemaOf([]upstreamtypes.ChannelRelease{})),
+			"422": openapi.JSONResponse("The release index signature or app is not valid for the license, the index is older than the imported index, or the SDK is in read-only mode", g.SchemaOf(types.ErrorResponse{})),
+		}),
+	})
+	doc.Add("GET", "/api/v1/app/history", &openapi.Operation{
+		OperationID: "getAppHistory",
+		Summary:     "Get the releases that have been deployed",
+		Tags:        []string{"app"},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The deployed releases, newest first", g.SchemaOf(types.GetAppHistoryResponse{})),
+		}),
+	})
+	for _, op := range []struct{ method, id, summary string }{
+		{"POST", "sendCustomMetrics", "Replace the custom metrics"},
+		{"PATCH", "updateCustomMetrics", "Update the given custom metrics"},
+	} {
+		doc.Add(op.method, "/api/v1/app/custom-metrics", &openapi.Operation{
+			OperationID: op.id,
+			Summary:     op.summary,
+			Description: requiresScope(auth.ScopeWriteMetrics),
+			Tags:        []string{"app"},
+			RequestBody: openapi.JSONBody("The custom metrics", g.SchemaOf(types.SendCustomAppMetricsRequest{})),
+			Responses: dataResponses(map[string]openapi.Response{
+				"200": emptyResponse,
+			}),
+		})
+	}
+	doc.Add("DELETE", "/api/v1/app/custom-metrics/{key}", &openapi.Operation{
+		OperationID: "deleteCustomMetric",
+		Summary:     "Delete a custom metric",
+		Description: requiresScope(auth.ScopeWriteMetrics),
+		Tags:        []string{"app"},
+		Parameters:  []openapi.Parameter{pathParam("key", "The name of the custom metric")},
+		Responses: dataResponses(map[string]openapi.Response{
+			"204": emptyResponse,
+		}),
+	})
+	doc.Add("POST", "/api/v1/app/instance-tags", &openapi.Operation{
+		OperationID: "sendInstanceTags",
+		Summary:     "Set the instance tags",
+		Description: requiresScope(auth.ScopeWriteMetrics),
+		Tags:        []string{"app"},
+		RequestBody: openapi.JSONBody("The instance tags", g.SchemaOf(types.SendAppInstanceTagsRequest{})),
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": emptyResponse,
+		}),
+	})
+
+	// webhooks
+
+	doc.Add("POST", "/api/v1/webhooks/test", &openapi.Operation{
+		OperationID: "testWebhooks",
+		Summary:     "Send a test event to every configured webhook",
+		Description: fmt.Sprintf("Always requires a token with the %s scope, and is forbidden if token authentication is not configured.", auth.ScopeWebhooks),
+		Tags:        []string{"webhooks"},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The outcome of each delivery", g.SchemaOf(types.TestWebhooksResponse{})),
+		}),
+	})
+	doc.Add("GET", "/api/v1/webhooks/dead-letters", &openapi.Operation{
+		OperationID: "getWebhookDeadLetters",
+		Summary:     "Get the most recent events that could not be delivered",
+		Description: fmt.Sprintf("Always requires a token with the %s scope, and is forbidden if token authentication is not configured.", auth.ScopeWebhooks),
+		Tags:        []string{"webhooks"},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The undelivered events", g.SchemaOf(types.GetWebhookDeadLettersResponse{})),
+		}),
+	})
+
+	// support bundle
+
+	doc.Add("POST", "/api/v1/supportbundle", &openapi.Operation{
+		OperationID: "uploadSupportBundle",
+		Summary:     "Upload a support bundle",
+		Description: requiresScope(auth.ScopeUploadBundle) + " The Content-Length header is required.",
+		Tags:        []string{"supportbundle"},
+		RequestBody: &openapi.RequestBody{
+			Description: "The support bundle archive",
+			Required:    true,
+			Content: map[string]openapi.MediaType{
+				"application/octet-stream": {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+			},
+		},
+		Responses: dataResponses(map[string]openapi.Response{
+			"201": openapi.JSONResponse("The uploaded support bundle", g.SchemaOf(types.UploadSupportBundleResponse{})),
+		}),
+	})
+	for _, op := range []struct{ method, id, summary string }{
+		{"POST", "setSupportBundleMetadata", "Replace the support bundle metadata"},
+		{"PATCH", "updateSupportBundleMetadata", "Update the given support bundle metadata"},
+	} {
+		doc.Add(op.method, "/api/v1/supportbundle/metadata", &openapi.Operation{
+			OperationID: op.id,
+			Summary:     op.summary,
+			Description: requiresScope(auth.ScopeUploadBundle),
+			Tags:        []string{"supportbundle"},
+			RequestBody: openapi.JSONBody("The support bundle metadata", g.SchemaOf(types.SupportBundleMetadataRequest{})),
+			Responses: dataResponses(map[string]openapi.Response{
+				"200": emptyResponse,
+			}),
+		})
+	}
+
+	// integration
+
+	doc.Add("POST", "/api/v1/integration/mock-data", &openapi.Operation{
+		OperationID: "setMockData",
+		Summary:     "Set the mock data served in integration mode",
+		Description: requiresScope(auth.ScopeMockData) + " Only available with a development license.",
+		Tags:        []string{"integration"},
+		RequestBody: openapi.JSONBody("The mock data", mockData),
+		Responses: dataResponses(map[string]openapi.Response{
+			"201": emptyResponse,
+		}),
+	})
+	doc.Add("GET", "/api/v1/integration/mock-data", &openapi.Operation{
+		OperationID: "getMockData",
+		Summary:     "Get the mock data served in integration mode",
+		Description: requiresScope(auth.ScopeMockData) + " Only available with a development license.",
+		Tags:        []string{"integration"},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The mock data", mockData),
+		}),
+	})
+	doc.Add("GET", "/api/v1/integration/status", &openapi.Operation{
+		OperationID: "getIntegrationStatus",
+		Summary:     "Check whether integration mode is enabled",
+		Description: requiresScope(auth.ScopeMockData) + " Only available with a development license.",
+		Tags:        []string{"integration"},
+		Responses: dataResponses(map[string]openapi.Response{
+			"200": openapi.JSONResponse("The integration status", g.SchemaOf(types.GetIntegrationStatusResponse{})),
+		}),
+	})
+
+	doc.Components = openapi.Components{
+		Schemas: g.Schemas(),
+		SecuritySchemes: map[string]openapi.SecurityScheme{
+			bearerAuth: {
+				Type:        "http",
+				Scheme:      "bearer",
+				Description: "An API token, required when token authentication is configured",
+			},
+		},
+	}
+	// tokens are optional unless token authentication is configured
+	doc.Security = []map[string][]string{{bearerAuth: {}}, {}}
+
+	return doc
+}
+
+func requiresScope(scope auth.Scope) string {
+	return fmt.Sprintf("Requires a token with the %s scope when token authentication is configured.", scope)
+}
@@ -0,0 +1,83 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/replicatedhq/replicated-sdk/pkg/openapi"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newAPIDocument(t *testing.T) {
+	req := require.New(t)
+
+	doc := newAPIDocument()
+
+	routes := map[string]bool{}
+	err := newRouter(APIServerParams{}, doc).Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			// subrouters without a path of their own
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{"GET"}
+		}
+		for _, method := range methods {
+			routes[method+" "+path] = true
+		}
+		return nil
+	})
+	req.NoError(err)
+
+	documented := map[string]bool{}
+	operationIDs := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			documented[strings.ToUpper(method)+" "+path] = true
+
+			req.NotEmpty(op.OperationID, "%s %s has no operation id", method, path)
+			req.False(operationIDs[op.OperationID], "operation id %s is not unique", op.OperationID)
+			operationIDs[op.OperationID] = true
+		}
+	}
+
+	for route := range routes {
+		req.True(documented[route], "route %s is not in the api document", route)
+	}
+	for route := range documented {
+		req.True(routes[route], "%s is in the api document but is not routed", route)
+	}
+
+	// every reference must resolve to a component schema
+	b, err := json.Marshal(doc)
+	req.NoError(err)
+
+	var raw interface{}
+	req.NoError(json.Unmarshal(b, &raw))
+	for _, ref := range findRefs(raw) {
+		req.NotNil(doc.Resolve(&openapi.Schema{Ref: ref}), "reference %s does not resolve", ref)
+	}
+}
+
+func findRefs(v interface{}) []string {
+	refs := []string{}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				refs = append(refs, ref)
+				continue
+			}
+			refs = append(refs, findRefs(value)...)
+		}
+	case []interface{}:
+		for _, value := range v {
+			refs = append(refs, findRefs(value)...)
+		}
+	}
+	return refs
+}
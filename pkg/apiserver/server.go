@@ -2,7 +2,6 @@ package apiserver
 
 import (
 	"context"
-	"crypto/tls"
 	"log"
 	"net/http"
 	"time"
@@ -11,140 +10,270 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/auth"
 	"github.com/replicatedhq/replicated-sdk/pkg/buildversion"
+	"github.com/replicatedhq/replicated-sdk/pkg/diagnostics"
 	"github.com/replicatedhq/replicated-sdk/pkg/handlers"
 	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
 	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/logger"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"github.com/replicatedhq/replicated-sdk/pkg/openapi"
+	webhooktypes "github.com/replicatedhq/replicated-sdk/pkg/webhook/types"
 )
 
 type APIServerParams struct {
-	Context               context.Context
-	LicenseBytes          []byte
-	IntegrationLicenseID  string
-	LicenseFields         sdklicensetypes.LicenseFields
-	AppName               string
-	ChannelID             string
-	ChannelName           string
-	ChannelSequence       int64
-	ReleaseSequence       int64
-	ReleaseCreatedAt      string
-	ReleaseNotes          string
-	VersionLabel          string
-	ReplicatedAppEndpoint string
-	ReleaseImages         []string
-	StatusInformers       []appstatetypes.StatusInformerString
-	ReplicatedID          string
-	AppID                 string
-	Namespace             string
-	TlsCertSecretName     string
-	ReportAllImages       bool
-	ReadOnlyMode          bool
+	Context                 context.Context
+	LicenseBytes            []byte
+	IntegrationLicenseID    string
+	LicenseFields           sdklicensetypes.LicenseFields
+	AppName                 string
+	ChannelID               string
+	ChannelName             string
+	ChannelSequence         int64
+	ReleaseSequence         int64
+	ReleaseCreatedAt        string
+	ReleaseNotes            string
+	VersionLabel            string
+	ReplicatedAppEndpoint   string
+	ReleaseImages           []string
+	StatusInformers         []appstatetypes.StatusInformerString
+	ReplicatedID            string
+	AppID                   string
+	Namespace               string
+	TlsCertSecretName       string
+	TlsVerifyClientCerts    bool
+	ReportAllImages         bool
+	ReadOnlyMode            bool
+	APITokensSecretName     string
+	AllowUnauthenticatedAPI bool
+	Webhooks                []webhooktypes.Target
+	LicenseExpiringSoonDays int
+	LicenseGracePeriodDays  int
+	StrictLicenseFields     bool
+	LicenseSource           *sdklicensetypes.LicenseSource
+	UpdateCheckInterval     time.Duration
+	HTTPSProxy              string
+	HTTPProxy               string
+	NoProxy                 string
+	PrivateCASecretName     string
+	PrivateCASecretKey      string
 }
 
-func Start(params APIServerParams) {
+// Start serves the API and bootstraps the SDK in the background until the params context is done,
+// at which point the server and background workers are gracefully shut down.
+// Until bootstrap has initialized the store, data endpoints respond with a 503 and /readyz reports progress.
+func Start(params APIServerParams) error {
 	log.Println("Replicated version:", buildversion.Version())
 
-	backoffDuration := 10 * time.Second
-	bootstrapFn := func() error {
-		return bootstrap(params)
+	if params.Context == nil {
+		params.Context = context.Background()
 	}
-	err := backoff.RetryNotify(bootstrapFn, backoff.NewConstantBackOff(backoffDuration), func(err error, d time.Duration) {
-		log.Printf("failed to bootstrap, retrying in %s: %v", d, err)
-	})
-	if err != nil {
-		log.Fatalf("failed to bootstrap: %v", err)
-	}
-
-	r := mux.NewRouter()
-	r.Use(handlers.CorsMiddleware)
-
-	// TODO: make all routes authenticated
-	authRouter := r.NewRoute().Subrouter()
-	authRouter.Use(handlers.RequireValidLicenseIDMiddleware)
-
-	cacheHandler := handlers.CacheMiddleware(handlers.NewCache(), handlers.CacheMiddlewareDefaultTTL)
-	cachedRouter := r.NewRoute().Subrouter()
-	cachedRouter.Use(cacheHandler)
-
-	r.HandleFunc("/healthz", handlers.Healthz)
-
-	// license
-	r.HandleFunc("/api/v1/license/info", handlers.GetLicenseInfo).Methods("GET")
-	r.HandleFunc("/api/v1/license/fields", handlers.GetLicenseFields).Methods("GET")
-	r.HandleFunc("/api/v1/license/fields/{fieldName}", handlers.GetLicenseField).Methods("GET")
 
-	// app
-	r.HandleFunc("/api/v1/app/info", handlers.GetCurrentAppInfo).Methods("GET")
-	r.HandleFunc("/api/v1/app/status", handlers.GetCurrentAppStatus).Methods("GET")
-	r.HandleFunc("/api/v1/app/updates", handlers.GetAppUpdates).Methods("GET")
-	r.HandleFunc("/api/v1/app/history", handlers.GetAppHistory).Methods("GET")
-	cachedRouter.HandleFunc("/api/v1/app/custom-metrics", handlers.SendCustomAppMetrics).Methods("POST", "PATCH")
-	cachedRouter.HandleFunc("/api/v1/app/custom-metrics/{key}", handlers.DeleteCustomAppMetricsKey).Methods("DELETE")
-	cachedRouter.HandleFunc("/api/v1/app/instance-tags", handlers.SendAppInstanceTags).Methods("POST")
+	r := newRouter(params, newAPIDocument())
 
-	// support bundle
-	r.HandleFunc("/api/v1/supportbundle", handlers.UploadSupportBundle).Methods("POST")
-	r.HandleFunc("/api/v1/supportbundle/metadata", handlers.PostSupportBundleMetadata).Methods("POST")
-	r.HandleFunc("/api/v1/supportbundle/metadata", handlers.PatchSupportBundleMetadata).Methods("PATCH")
-
-	// integration
-	r.HandleFunc("/api/v1/integration/mock-data", handlers.EnforceMockAccess(handlers.PostIntegrationMockData)).Methods("POST")
-	r.HandleFunc("/api/v1/integration/mock-data", handlers.EnforceMockAccess(handlers.GetIntegrationMockData)).Methods("GET")
-	r.HandleFunc("/api/v1/integration/status", handlers.EnforceMockAccess(handlers.GetIntegrationStatus)).Methods("GET")
+	if params.APITokensSecretName != "" {
+		clientset, err := k8sutil.GetClientset()
+		if err != nil {
+			return errors.Wrap(err, "failed to get clientset")
+		}
+		auth.Init(auth.InitOptions{
+			Clientset:            clientset,
+			Namespace:            params.Namespace,
+			TokensSecretName:     params.APITokensSecretName,
+			AllowUnauthenticated: params.AllowUnauthenticatedAPI,
+		})
+		if params.AllowUnauthenticatedAPI {
+			logger.Warnf("API token authentication is enabled, but requests without a token are still allowed")
+		}
+	}
 
 	srv := &http.Server{
 		Handler: r,
 		Addr:    ":3000",
 	}
+	// event streams are long-lived, so they are ended explicitly for the server to drain
+	srv.RegisterOnShutdown(handlers.CloseStreams)
 
 	// Configure TLS if certificate name is provided
 	if params.TlsCertSecretName != "" {
 		clientset, err := k8sutil.GetClientset()
 		if err != nil {
-			logger.Error(errors.Wrap(err, "failed to get clientset"))
-			return
+			return errors.Wrap(err, "failed to get clientset")
 		}
 
-		tlsConfig, err := loadTLSConfig(clientset, params.Namespace, params.TlsCertSecretName)
+		reloader, err := newCertReloader(clientset, params.Namespace, params.TlsCertSecretName, params.TlsVerifyClientCerts)
 		if err != nil {
-			log.Fatalf("failed to load TLS config: %v", err)
+			return errors.Wrap(err, "failed to load TLS config")
 		}
-		srv.TLSConfig = tlsConfig
+		srv.TLSConfig = reloader.TLSConfig()
 
-		log.Printf("Starting Replicated API on port %d with TLS...\n", 3000)
-		log.Fatal(srv.ListenAndServeTLS("", ""))
-	} else {
-		log.Printf("Starting Replicated API on port %d...\n", 3000)
-		log.Fatal(srv.ListenAndServe())
+		go reloader.watch(params.Context, clientset, params.Namespace)
 	}
-}
 
-// loadTLSConfig loads TLS certificate and key from a Kubernetes secret
-func loadTLSConfig(clientset kubernetes.Interface, namespace, secretName string) (*tls.Config, error) {
-	secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to get TLS secret %s", secretName)
-	}
+	serverErrCh := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			log.Printf("Starting Replicated API on port %d with TLS...\n", 3000)
+			serverErrCh <- srv.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Starting Replicated API on port %d...\n", 3000)
+			serverErrCh <- srv.ListenAndServe()
+		}
+	}()
+
+	bootstrapErrCh := make(chan error, 1)
+	go func() {
+		backoffDuration := 10 * time.Second
+		bootstrapFn := func() error {
+			return bootstrap(params)
+		}
+		bootstrapErrCh <- backoff.RetryNotify(bootstrapFn, backoff.WithContext(backoff.NewConstantBackOff(backoffDuration), params.Context), func(err error, d time.Duration) {
+			log.Printf("failed to bootstrap, retrying in %s: %v", d, err)
+		})
+	}()
 
-	certData, ok := secret.Data["tls.crt"]
-	if !ok {
-		return nil, errors.Errorf("tls.crt not found in secret %s", secretName)
+	for {
+		select {
+		case err := <-bootstrapErrCh:
+			bootstrapErrCh = nil
+			if err == nil {
+				log.Println("Replicated API is ready")
+				continue
+			}
+			if params.Context.Err() != nil {
+				// shutdown was requested before bootstrap completed
+				continue
+			}
+			if shutdownErr := shutdown(srv); shutdownErr != nil {
+				logger.Error(shutdownErr)
+			}
+			return errors.Wrap(err, "failed to bootstrap")
+
+		case err := <-serverErrCh:
+			if shutdownErr := shutdown(nil); shutdownErr != nil {
+				logger.Error(shutdownErr)
+			}
+			return errors.Wrap(err, "failed to serve api")
+
+		case <-params.Context.Done():
+			log.Println("Received shutdown signal")
+			if bootstrapErrCh != nil {
+				// let the in-progress bootstrap attempt finish so that any workers it started are stopped during shutdown
+				<-bootstrapErrCh
+			}
+			return shutdown(srv)
+		}
 	}
+}
+
+// newRouter returns the router for all API routes. Every route must be described in the API document.
+func newRouter(params APIServerParams, doc *openapi.Document) *mux.Router {
+	r := mux.NewRouter()
+	r.Use(handlers.RequestIDMiddleware, handlers.CorsMiddleware)
+	// middleware doesn't run for unmatched requests, so the request id is assigned here as well
+	r.NotFoundHandler = handlers.RequestIDMiddleware(http.HandlerFunc(handlers.NotFound))
+	r.MethodNotAllowedHandler = handlers.RequestIDMiddleware(http.HandlerFunc(handlers.MethodNotAllowed))
+
+	r.HandleFunc("/healthz", handlers.Healthz)
+	r.HandleFunc("/readyz", handlers.Readyz)
+	r.HandleFunc("/api/v1/openapi.json", handlers.GetOpenAPIDocument(doc)).Methods("GET")
 
-	keyData, ok := secret.Data["tls.key"]
-	if !ok {
-		return nil, errors.Errorf("tls.key not found in secret %s", secretName)
+	// metrics and diagnostics are served during bootstrap as well, so that a stuck bootstrap can be alerted on and diagnosed
+	metricsRouter := r.NewRoute().Subrouter()
+	if params.TlsCertSecretName != "" && params.TlsVerifyClientCerts {
+		metricsRouter.Use(handlers.RequireClientCertMiddleware)
+	}
+	metricsRouter.Use(handlers.RequireScopeMiddleware(""))
+	metricsRouter.HandleFunc("/metrics", handlers.Metrics).Methods("GET")
+	diagnosticsOpts := diagnostics.Options{
+		Namespace:           params.Namespace,
+		StatusInformers:     params.StatusInformers,
+		ReadOnlyMode:        params.ReadOnlyMode,
+		ReportAllImages:     params.ReportAllImages,
+		TlsCertSecretName:   params.TlsCertSecretName,
+		APITokensSecretName: params.APITokensSecretName,
+		PrivateCASecretName: params.PrivateCASecretName,
 	}
+	if params.LicenseSource != nil {
+		diagnosticsOpts.LicenseSourceSecretName = params.LicenseSource.SecretName
+	}
+	metricsRouter.HandleFunc("/api/v1/diagnostics", handlers.GetDiagnostics(diagnosticsOpts)).Methods("GET")
 
-	cert, err := tls.X509KeyPair(certData, keyData)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse TLS certificate and key")
+	// all other routes serve data from the store, which is only available once bootstrap has initialized it
+	// callers without a client certificate are rejected first, so that they get the same response whether or not the store is ready
+	dataRouter := r.NewRoute().Subrouter()
+	if params.TlsCertSecretName != "" && params.TlsVerifyClientCerts {
+		dataRouter.Use(handlers.RequireClientCertMiddleware)
 	}
+	dataRouter.Use(handlers.RequireStoreInitializedMiddleware)
+
+	// requests are authorized per scope when API tokens are configured, and then validated against the API document
+	validateRequest := handlers.ValidateRequestMiddleware(doc)
+
+	appRouter := dataRouter.NewRoute().Subrouter()
+	appRouter.Use(handlers.RequireScopeMiddleware(""), validateRequest)
+
+	licenseRouter := dataRouter.NewRoute().Subrouter()
+	licenseRouter.Use(handlers.RequireScopeMiddleware(auth.ScopeReadLicense), validateRequest)
+
+	// authorize before caching so that cached responses are never served to unauthorized callers
+	customMetricsRouter := dataRouter.NewRoute().Subrouter()
+	customMetricsRouter.Use(handlers.RequireScopeMiddleware(auth.ScopeWriteMetrics), validateRequest)
+
+	cacheHandler := handlers.CacheMiddleware(handlers.NewCache(), handlers.CacheMiddlewareDefaultTTL)
+	cachedRouter := customMetricsRouter.NewRoute().Subrouter()
+	cachedRouter.Use(cacheHandler)
+
+	supportBundleRouter := dataRouter.NewRoute().Subrouter()
+	supportBundleRouter.Use(handlers.RequireScopeMiddleware(auth.ScopeUploadBundle), validateRequest)
+
+	// uploading a license always requires a token, since it replaces the license that every other route is served with
+	licenseUploadRouter := dataRouter.NewRoute().Subrouter()
+	licenseUploadRouter.Use(handlers.RequireTokenMiddleware(auth.ScopeWriteLicense), validateRequest)
+
+	// importing a release index always requires a token, like uploading a license, since it changes the updates that are served
+	updatesImportRouter := dataRouter.NewRoute().Subrouter()
+	updatesImportRouter.Use(handlers.RequireTokenMiddleware(auth.ScopeImportUpdates), validateRequest)
+
+	// webhook tests send signed events, and dead letters include event payloads, so both always require a token
+	webhooksRouter := dataRouter.NewRoute().Subrouter()
+	webhooksRouter.Use(handlers.RequireTokenMiddleware(auth.ScopeWebhooks), validateRequest)
+
+	integrationRouter := dataRouter.NewRoute().Subrouter()
+	integrationRouter.Use(handlers.RequireScopeMiddleware(auth.ScopeMockData), validateRequest)
+
+	// license
+	licenseRouter.HandleFunc("/api/v1/license/info", handlers.GetLicenseInfo).Methods("GET")
+	licenseRouter.HandleFunc("/api/v1/license/fields", handlers.GetLicenseFields).Methods("GET")
+	licenseRouter.HandleFunc("/api/v1/license/fields/{fieldName}", handlers.GetLicenseField).Methods("GET")
+	licenseRouter.HandleFunc("/api/v1/license/channels", handlers.GetLicenseChannels).Methods("GET")
+	licenseRouter.HandleFunc("/api/v1/license/entitlements/evaluate", handlers.EvaluateEntitlements).Methods("POST")
+	licenseUploadRouter.HandleFunc("/api/v1/license", handlers.UploadLicense).Methods("PUT")
+
+	// app
+	appRouter.HandleFunc("/api/v1/app/info", handlers.GetCurrentAppInfo).Methods("GET")
+	appRouter.HandleFunc("/api/v1/app/status", handlers.GetCurrentAppStatus).Methods("GET")
+	appRouter.HandleFunc("/api/v1/app/status/stream", handlers.StreamAppStatus).Methods("GET")
+	appRouter.HandleFunc("/api/v1/app/updates", handlers.GetAppUpdates).Methods("GET")
+	updatesImportRouter.HandleFunc("/api/v1/app/updates/index", handlers.ImportReleaseIndex).Methods("PUT")
+	appRouter.HandleFunc("/api/v1/app/history", handlers.GetAppHistory).Methods("GET")
+	cachedRouter.HandleFunc("/api/v1/app/custom-metrics", handlers.SendCustomAppMetrics).Methods("POST", "PATCH")
+	cachedRouter.HandleFunc("/api/v1/app/custom-metrics/{key}", handlers.DeleteCustomAppMetricsKey).Methods("DELETE")
+	cachedRouter.HandleFunc("/api/v1/app/instance-tags", handlers.SendAppInstanceTags).Methods("POST")
+
+	// webhooks
+	webhooksRouter.HandleFunc("/api/v1/webhooks/test", handlers.TestWebhooks).Methods("POST")
+	webhooksRouter.HandleFunc("/api/v1/webhooks/dead-letters", handlers.GetWebhookDeadLetters).Methods("GET")
+
+	// support bundle
+	supportBundleRouter.HandleFunc("/api/v1/supportbundle", handlers.UploadSupportBundle).Methods("POST")
+	supportBundleRouter.HandleFunc("/api/v1/supportbundle/metadata", handlers.PostSupportBundleMetadata).Methods("POST")
+	supportBundleRouter.HandleFunc("/api/v1/supportbundle/metadata", handlers.PatchSupportBundleMetadata).Methods("PATCH")
+
+	// integration
+	integrationRouter.HandleFunc("/api/v1/integration/mock-data", handlers.EnforceMockAccess(handlers.PostIntegrationMockData)).Methods("POST")
+	integrationRouter.HandleFunc("/api/v1/integration/mock-data", handlers.EnforceMockAccess(handlers.GetIntegrationMockData)).Methods("GET")
+	integrationRouter.HandleFunc("/api/v1/integration/status", handlers.EnforceMockAccess(handlers.GetIntegrationStatus)).Methods("GET")
 
-	return &tls.Config{
-		Certificates: []tls.Certificate{cert},
-	}, nil
+	return r
 }
@@ -0,0 +1,26 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newRouterClientCertBeforeReadiness(t *testing.T) {
+	req := require.New(t)
+	req.False(store.IsInitialized())
+
+	router := newRouter(APIServerParams{
+		TlsCertSecretName:    "replicated-tls",
+		TlsVerifyClientCerts: true,
+	}, newAPIDocument())
+
+	// callers without a client certificate are rejected before the readiness of the store is reported to them
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/app/info", nil))
+	req.Equal(http.StatusUnauthorized, rec.Code)
+	req.NotContains(rec.Body.String(), "readiness")
+}
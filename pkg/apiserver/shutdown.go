@@ -0,0 +1,64 @@
+package apiserver
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/appstate"
+	"github.com/replicatedhq/replicated-sdk/pkg/heartbeat"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"github.com/replicatedhq/replicated-sdk/pkg/report"
+	"github.com/replicatedhq/replicated-sdk/pkg/updates"
+	"github.com/replicatedhq/replicated-sdk/pkg/webhook"
+)
+
+// shutdownTimeout is kept below the default pod termination grace period (30s)
+// so that pending work can be flushed before the kubelet sends SIGKILL.
+const shutdownTimeout = 25 * time.Second
+
+// shutdown drains in-flight requests, stops the background workers started during bootstrap
+// and flushes pending instance reports. The server is drained first since handlers can enqueue reports.
+func shutdown(srv *http.Server) error {
+	log.Println("Shutting down Replicated API...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var shutdownErr error
+
+	if srv != nil {
+		if err := srv.Shutdown(ctx); err != nil {
+			shutdownErr = errors.Wrap(err, "failed to drain in-flight requests")
+		}
+	}
+
+	heartbeat.Stop(ctx)
+	updates.Stop(ctx)
+
+	if operator := appstate.GetOperator(); operator != nil {
+		operator.Shutdown()
+	}
+
+	if err := report.Flush(ctx); err != nil {
+		if shutdownErr != nil {
+			logger.Error(err)
+		} else {
+			shutdownErr = errors.Wrap(err, "failed to flush reports")
+		}
+	}
+
+	if err := webhook.Flush(ctx); err != nil {
+		if shutdownErr != nil {
+			logger.Error(err)
+		} else {
+			shutdownErr = errors.Wrap(err, "failed to flush webhooks")
+		}
+	}
+
+	log.Println("Replicated API shut down")
+
+	return shutdownErr
+}
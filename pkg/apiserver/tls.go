@@ -0,0 +1,167 @@
+package apiserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// certReloader serves the TLS certificate (and optionally the client CA) from a Kubernetes secret,
+// and swaps them in place when the secret changes so that renewed certificates are picked up without a restart.
+type certReloader struct {
+	secretName        string
+	verifyClientCerts bool
+
+	mtx       sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// newCertReloader loads the TLS certificate from the secret. When verifyClientCerts is true the secret must also
+// contain a ca.crt key, which is used to verify client certificates.
+func newCertReloader(clientset kubernetes.Interface, namespace string, secretName string, verifyClientCerts bool) (*certReloader, error) {
+	c := &certReloader{
+		secretName:        secretName,
+		verifyClientCerts: verifyClientCerts,
+	}
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get TLS secret %s", secretName)
+	}
+
+	if err := c.update(secret); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *certReloader) update(secret *corev1.Secret) error {
+	certData, ok := secret.Data["tls.crt"]
+	if !ok {
+		return errors.Errorf("tls.crt not found in secret %s", c.secretName)
+	}
+
+	keyData, ok := secret.Data["tls.key"]
+	if !ok {
+		return errors.Errorf("tls.key not found in secret %s", c.secretName)
+	}
+
+	cert, err := tls.X509KeyPair(certData, keyData)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse TLS certificate and key")
+	}
+
+	var clientCAs *x509.CertPool
+	if c.verifyClientCerts {
+		caData, ok := secret.Data["ca.crt"]
+		if !ok {
+			return errors.Errorf("ca.crt not found in secret %s", c.secretName)
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caData) {
+			return errors.Errorf("failed to parse ca.crt in secret %s", c.secretName)
+		}
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.cert = &cert
+	c.clientCAs = clientCAs
+
+	return nil
+}
+
+// watch updates the certificate whenever the secret changes, until the context is done.
+// Invalid updates are logged and the previous certificate keeps being served.
+func (c *certReloader) watch(ctx context.Context, clientset kubernetes.Interface, namespace string) {
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", c.secretName).String()
+
+	listwatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return clientset.CoreV1().Secrets(namespace).List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return clientset.CoreV1().Secrets(namespace).Watch(context.TODO(), options)
+		},
+	}
+	informer := cache.NewSharedInformer(
+		listwatch,
+		&corev1.Secret{},
+		time.Minute,
+	)
+
+	onChange := func(obj interface{}) {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok {
+			return
+		}
+		if err := c.update(secret); err != nil {
+			logger.Error(errors.Wrap(err, "failed to reload TLS certificate, continuing to serve the previous certificate"))
+			return
+		}
+		logger.Infof("Reloaded TLS certificate from secret %s", c.secretName)
+	}
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: onChange,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSecret, ok := oldObj.(*corev1.Secret)
+			if ok && oldSecret.ResourceVersion == newObj.(*corev1.Secret).ResourceVersion {
+				// periodic resync, nothing changed
+				return
+			}
+			onChange(newObj)
+		},
+	})
+
+	informer.Run(ctx.Done())
+}
+
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.cert, nil
+}
+
+// GetConfigForClient returns a config with the current certificate and client CAs for each handshake.
+// Client certificates are verified if presented but not required at the TLS layer, so that kubelet probes
+// can still reach the health endpoints. RequireClientCertMiddleware enforces them for API routes.
+func (c *certReloader) GetConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{*c.cert},
+	}
+	if c.clientCAs != nil {
+		config.ClientCAs = c.clientCAs
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+func (c *certReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate:     c.GetCertificate,
+		GetConfigForClient: c.GetConfigForClient,
+	}
+}
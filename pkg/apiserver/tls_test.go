@@ -0,0 +1,186 @@
+package apiserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func generateTestCert(t *testing.T, commonName string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM
+}
+
+func leafCommonName(t *testing.T, cert *tls.Certificate) string {
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func Test_certReloader(t *testing.T) {
+	certPEM, keyPEM := generateTestCert(t, "first")
+	caPEM, _ := generateTestCert(t, "ca")
+
+	tests := []struct {
+		name              string
+		data              map[string][]byte
+		verifyClientCerts bool
+		wantClientAuth    tls.ClientAuthType
+		wantErr           bool
+	}{
+		{
+			name: "certificate and key",
+			data: map[string][]byte{
+				"tls.crt": certPEM,
+				"tls.key": keyPEM,
+			},
+			wantClientAuth: tls.NoClientCert,
+		},
+		{
+			name: "ca.crt is ignored unless client certs are verified",
+			data: map[string][]byte{
+				"tls.crt": certPEM,
+				"tls.key": keyPEM,
+				"ca.crt":  caPEM,
+			},
+			wantClientAuth: tls.NoClientCert,
+		},
+		{
+			name: "verify client certs",
+			data: map[string][]byte{
+				"tls.crt": certPEM,
+				"tls.key": keyPEM,
+				"ca.crt":  caPEM,
+			},
+			verifyClientCerts: true,
+			wantClientAuth:    tls.VerifyClientCertIfGiven,
+		},
+		{
+			name: "verify client certs without ca.crt",
+			data: map[string][]byte{
+				"tls.crt": certPEM,
+				"tls.key": keyPEM,
+			},
+			verifyClientCerts: true,
+			wantErr:           true,
+		},
+		{
+			name: "invalid ca.crt",
+			data: map[string][]byte{
+				"tls.crt": certPEM,
+				"tls.key": keyPEM,
+				"ca.crt":  []byte("not a certificate"),
+			},
+			verifyClientCerts: true,
+			wantErr:           true,
+		},
+		{
+			name: "missing key",
+			data: map[string][]byte{
+				"tls.crt": certPEM,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			clientset := fake.NewSimpleClientset(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "replicated-tls",
+					Namespace: "default",
+				},
+				Data: tt.data,
+			})
+
+			reloader, err := newCertReloader(clientset, "default", "replicated-tls", tt.verifyClientCerts)
+			if tt.wantErr {
+				req.Error(err)
+				return
+			}
+			req.NoError(err)
+
+			config, err := reloader.GetConfigForClient(nil)
+			req.NoError(err)
+			req.Len(config.Certificates, 1)
+			req.Equal("first", leafCommonName(t, &config.Certificates[0]))
+			req.Equal(tt.wantClientAuth, config.ClientAuth)
+		})
+	}
+}
+
+func Test_certReloaderUpdate(t *testing.T) {
+	req := require.New(t)
+
+	firstCert, firstKey := generateTestCert(t, "first")
+	secondCert, secondKey := generateTestCert(t, "second")
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "replicated-tls",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"tls.crt": firstCert,
+			"tls.key": firstKey,
+		},
+	}
+
+	reloader, err := newCertReloader(fake.NewSimpleClientset(secret), "default", "replicated-tls", false)
+	req.NoError(err)
+
+	/* a renewed certificate should be served for new handshakes */
+	renewed := secret.DeepCopy()
+	renewed.Data["tls.crt"] = secondCert
+	renewed.Data["tls.key"] = secondKey
+	req.NoError(reloader.update(renewed))
+
+	cert, err := reloader.GetCertificate(nil)
+	req.NoError(err)
+	req.Equal("second", leafCommonName(t, cert))
+
+	/* an invalid update should keep the previous certificate */
+	invalid := secret.DeepCopy()
+	invalid.Data["tls.key"] = firstKey
+	invalid.Data["tls.crt"] = secondCert
+	req.Error(reloader.update(invalid))
+
+	cert, err = reloader.GetCertificate(nil)
+	req.NoError(err)
+	req.Equal("second", leafCommonName(t, cert))
+}
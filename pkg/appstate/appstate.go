@@ -175,7 +175,7 @@ func (m *AppMonitor) run(ctx context.Context) {
 type runControllerFunc func(context.Context, kubernetes.Interface, string, []types.StatusInformer, chan<- types.ResourceState)
 
 func (m *AppMonitor) runInformers(ctx context.Context, informers []types.StatusInformer) {
-	informers = normalizeStatusInformers(informers, m.targetNamespace)
+	informers = NormalizeStatusInformers(informers, m.targetNamespace)
 
 	log.Printf("Running informers: %#v", informers)
 
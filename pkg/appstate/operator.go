@@ -9,11 +9,10 @@ import (
 	"github.com/mitchellh/hashstructure"
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
-	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
-	"github.com/replicatedhq/replicated-sdk/pkg/logger"
 	"github.com/replicatedhq/replicated-sdk/pkg/report"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
 	"github.com/replicatedhq/replicated-sdk/pkg/util"
+	"github.com/replicatedhq/replicated-sdk/pkg/webhook"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -36,6 +35,11 @@ func InitOperator(clientset kubernetes.Interface, targetNamespace string) *Opera
 	return operator
 }
 
+// GetOperator returns the operator if it has been initialized, or nil otherwise.
+func GetOperator() *Operator {
+	return operator
+}
+
 func MustGetOperator() *Operator {
 	if operator != nil {
 		return operator
@@ -125,18 +129,16 @@ func (o *Operator) setAppStatus(newAppStatus types.AppStatus) error {
 	currentAppStatus := store.GetStore().GetAppStatus()
 	store.GetStore().SetAppStatus(newAppStatus)
 
+	currentHash, _ := hashstructure.Hash(currentAppStatus, nil)
+	newHash, _ := hashstructure.Hash(newAppStatus, nil)
+	if currentHash != newHash {
+		PublishAppStatus(newAppStatus)
+	}
+
 	if newAppStatus.State != currentAppStatus.State {
 		log.Printf("app state changed from %q to %q", currentAppStatus.State, newAppStatus.State)
-		go func() {
-			clientset, err := k8sutil.GetClientset()
-			if err != nil {
-				logger.Error(errors.Wrap(err, "failed to get clientset"))
-				return
-			}
-			if err := report.SendInstanceData(clientset, store.GetStore()); err != nil {
-				logger.Error(errors.Wrap(err, "failed to send instance data"))
-			}
-		}()
+		report.SendInstanceDataAsync(store.GetStore())
+		webhook.NotifyAppStateChanged(currentAppStatus, newAppStatus)
 	}
 
 	return nil
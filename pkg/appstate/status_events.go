@@ -0,0 +1,131 @@
+package appstate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+)
+
+const (
+	// appStatusEventHistorySize is the number of recent events kept so that reconnecting subscribers can catch up
+	appStatusEventHistorySize = 100
+	// appStatusSubscriberBufferSize is the number of events buffered per subscriber before it is considered too slow
+	appStatusSubscriberBufferSize = 16
+)
+
+// AppStatusEvent is a change to the app status. IDs are "<boot id>-<sequence>", where the boot id is unique to the
+// process and the sequence increases monotonically for its lifetime, so that IDs from a previous process or from
+// another replica never match an event in the history.
+type AppStatusEvent struct {
+	ID        string
+	AppStatus types.AppStatus
+}
+
+type appStatusBroadcaster struct {
+	mtx         sync.Mutex
+	bootID      string
+	lastSeq     int64
+	history     []AppStatusEvent
+	subscribers map[chan AppStatusEvent]struct{}
+}
+
+var statusBroadcaster = newAppStatusBroadcaster()
+
+func newAppStatusBroadcaster() *appStatusBroadcaster {
+	return &appStatusBroadcaster{
+		bootID:      newBootID(),
+		subscribers: map[chan AppStatusEvent]struct{}{},
+	}
+}
+
+func newBootID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// PublishAppStatus notifies subscribers of a new app status
+func PublishAppStatus(appStatus types.AppStatus) AppStatusEvent {
+	return statusBroadcaster.publish(appStatus)
+}
+
+// SubscribeAppStatus returns the events a subscriber needs to catch up and a channel of subsequent events.
+// If lastEventID is found in the recent history only the events after it are returned, otherwise a single
+// event with the current app status is, e.g. if lastEventID is from before a restart or from another replica. The channel is closed if the subscriber falls too far behind,
+// in which case it should resubscribe with the ID of the last event it received.
+// The returned function must be called to unsubscribe.
+func SubscribeAppStatus(lastEventID string) ([]AppStatusEvent, <-chan AppStatusEvent, func()) {
+	return statusBroadcaster.subscribe(lastEventID)
+}
+
+func (b *appStatusBroadcaster) publish(appStatus types.AppStatus) AppStatusEvent {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.lastSeq++
+	event := AppStatusEvent{ID: b.eventID(b.lastSeq), AppStatus: appStatus}
+
+	b.history = append(b.history, event)
+	if len(b.history) > appStatusEventHistorySize {
+		b.history = b.history[len(b.history)-appStatusEventHistorySize:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// drop slow subscribers rather than blocking status updates
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return event
+}
+
+func (b *appStatusBroadcaster) subscribe(lastEventID string) ([]AppStatusEvent, <-chan AppStatusEvent, func()) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	ch := make(chan AppStatusEvent, appStatusSubscriberBufferSize)
+	b.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return b.replay(lastEventID), ch, unsubscribe
+}
+
+func (b *appStatusBroadcaster) replay(lastEventID string) []AppStatusEvent {
+	if lastEventID != "" {
+		for i, event := range b.history {
+			if event.ID == lastEventID {
+				return append([]AppStatusEvent{}, b.history[i+1:]...)
+			}
+		}
+	}
+
+	if len(b.history) > 0 {
+		return []AppStatusEvent{b.history[len(b.history)-1]}
+	}
+
+	return []AppStatusEvent{{ID: b.eventID(b.lastSeq), AppStatus: store.GetStore().GetAppStatus()}}
+}
+
+func (b *appStatusBroadcaster) eventID(seq int64) string {
+	return fmt.Sprintf("%s-%d", b.bootID, seq)
+}
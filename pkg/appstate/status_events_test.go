@@ -0,0 +1,102 @@
+package appstate
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_appStatusBroadcaster(t *testing.T) {
+	req := require.New(t)
+
+	store.InitInMemory(store.InitInMemoryStoreOptions{})
+	defer store.SetStore(nil)
+	store.GetStore().SetAppStatus(types.AppStatus{State: types.StateMissing})
+
+	b := newAppStatusBroadcaster()
+
+	/* the current status from the store is replayed before anything has been published */
+	replay, _, unsubscribe := b.subscribe("")
+	unsubscribe()
+	req.Equal([]AppStatusEvent{{ID: b.bootID + "-0", AppStatus: types.AppStatus{State: types.StateMissing}}}, replay)
+
+	replay, events, unsubscribe := b.subscribe("")
+	defer unsubscribe()
+	req.Len(replay, 1)
+
+	first := b.publish(types.AppStatus{State: types.StateUpdating})
+	second := b.publish(types.AppStatus{State: types.StateReady})
+	req.Equal(b.bootID+"-1", first.ID)
+	req.Equal(b.bootID+"-2", second.ID)
+
+	req.Equal(first, <-events)
+	req.Equal(second, <-events)
+
+	/* a subscriber resuming from a known event only receives the events after it */
+	replay, _, unsubscribeResumed := b.subscribe(first.ID)
+	unsubscribeResumed()
+	req.Equal([]AppStatusEvent{second}, replay)
+
+	replay, _, unsubscribeResumed = b.subscribe(second.ID)
+	unsubscribeResumed()
+	req.Empty(replay)
+
+	/* a subscriber resuming from an unknown event receives the latest status */
+	replay, _, unsubscribeResumed = b.subscribe(b.bootID + "-100")
+	unsubscribeResumed()
+	req.Equal([]AppStatusEvent{second}, replay)
+
+	replay, _, unsubscribeResumed = b.subscribe("not-an-id")
+	unsubscribeResumed()
+	req.Equal([]AppStatusEvent{second}, replay)
+}
+
+func Test_appStatusBroadcasterOtherBoot(t *testing.T) {
+	req := require.New(t)
+
+	previous := newAppStatusBroadcaster()
+	previous.publish(types.AppStatus{State: types.StateMissing})
+	stale := previous.publish(types.AppStatus{State: types.StateUpdating})
+
+	/* a restarted process or another replica has its own boot id, and publishes events with the same sequences */
+	b := newAppStatusBroadcaster()
+	req.NotEqual(previous.bootID, b.bootID)
+	b.publish(types.AppStatus{State: types.StateUpdating})
+	b.publish(types.AppStatus{State: types.StateReady})
+	latest := b.publish(types.AppStatus{State: types.StateDegraded})
+
+	/* an ID from another boot is not resumed from, even though its sequence is in the history */
+	replay, _, unsubscribe := b.subscribe(stale.ID)
+	unsubscribe()
+	req.Equal([]AppStatusEvent{latest}, replay)
+}
+
+func Test_appStatusBroadcasterSlowSubscriber(t *testing.T) {
+	req := require.New(t)
+
+	b := newAppStatusBroadcaster()
+
+	_, events, unsubscribe := b.subscribe("")
+	defer unsubscribe()
+
+	for i := 0; i < appStatusSubscriberBufferSize+1; i++ {
+		b.publish(types.AppStatus{Sequence: int64(i)})
+	}
+
+	/* buffered events are still delivered, then the channel is closed */
+	for i := 0; i < appStatusSubscriberBufferSize; i++ {
+		event, ok := <-events
+		req.True(ok)
+		req.Equal(int64(i), event.AppStatus.Sequence)
+	}
+	_, ok := <-events
+	req.False(ok)
+
+	/* the history is capped */
+	for i := 0; i < appStatusEventHistorySize; i++ {
+		b.publish(types.AppStatus{})
+	}
+	req.Len(b.history, appStatusEventHistorySize)
+}
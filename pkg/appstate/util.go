@@ -12,7 +12,8 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
-func normalizeStatusInformers(informers []types.StatusInformer, targetNamespace string) (next []types.StatusInformer) {
+// NormalizeStatusInformers uses the common name for the kind of each informer, and defaults its namespace to the target namespace
+func NormalizeStatusInformers(informers []types.StatusInformer, targetNamespace string) (next []types.StatusInformer) {
 	for _, informer := range informers {
 		informer.Kind = getResourceKindCommonName(informer.Kind)
 		if informer.Namespace == "" {
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type Scope string
+
+const (
+	ScopeReadLicense   Scope = "read-license"
+	ScopeWriteMetrics  Scope = "write-metrics"
+	ScopeUploadBundle  Scope = "upload-bundle"
+	ScopeMockData      Scope = "mock-data"
+	ScopeWriteLicense  Scope = "write-license"
+	ScopeImportUpdates Scope = "import-updates"
+	ScopeWebhooks      Scope = "webhooks"
+)
+
+// TokensSecretKey is the key in the tokens secret that holds the list of API tokens
+const TokensSecretKey = "tokens.yaml"
+
+// tokensRefreshInterval is how often the tokens secret is re-read so that tokens can be rotated without a restart
+const tokensRefreshInterval = 30 * time.Second
+
+var (
+	ErrMissingToken      = errors.New("missing bearer token")
+	ErrInvalidToken      = errors.New("token is not valid")
+	ErrInsufficientScope = errors.New("token does not have the required scope")
+)
+
+type Token struct {
+	Name   string  `yaml:"name"`
+	Token  string  `yaml:"token"`
+	Scopes []Scope `yaml:"scopes"`
+}
+
+func (t Token) HasScope(scope Scope) bool {
+	if scope == "" {
+		return true
+	}
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type InitOptions struct {
+	Clientset        kubernetes.Interface
+	Namespace        string
+	TokensSecretName string
+	// AllowUnauthenticated lets requests without an authorization header through, so that existing
+	// callers keep working while they are migrated to tokens. Requests that do present a token are still validated.
+	AllowUnauthenticated bool
+}
+
+type authenticator struct {
+	opts     InitOptions
+	tokens   []Token
+	loadedAt time.Time
+	mtx      sync.Mutex
+}
+
+var (
+	current *authenticator
+	mtx     sync.RWMutex
+)
+
+// Init enables token authentication for the API. Authentication stays disabled if no tokens secret is configured.
+func Init(opts InitOptions) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if opts.TokensSecretName == "" {
+		current = nil
+		return
+	}
+	current = &authenticator{opts: opts}
+}
+
+func IsEnabled() bool {
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	return current != nil
+}
+
+// Authorize validates the bearer token in the given authorization header and checks that it grants the requested scope.
+// An empty scope only requires a valid token. It returns the matched token, which is nil if authentication
+// is disabled or the request was allowed through unauthenticated.
+func Authorize(ctx context.Context, authorizationHeader string, scope Scope) (*Token, error) {
+	mtx.RLock()
+	a := current
+	mtx.RUnlock()
+
+	if a == nil {
+		return nil, nil
+	}
+
+	if authorizationHeader == "" {
+		if a.opts.AllowUnauthenticated {
+			return nil, nil
+		}
+		return nil, ErrMissingToken
+	}
+
+	presented, ok := parseBearerToken(authorizationHeader)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	tokens, err := a.getTokens(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get api tokens")
+	}
+
+	token := findToken(tokens, presented)
+	if token == nil {
+		return nil, ErrInvalidToken
+	}
+
+	if !token.HasScope(scope) {
+		return token, ErrInsufficientScope
+	}
+
+	return token, nil
+}
+
+func parseBearerToken(header string) (string, bool) {
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return "", false
+	}
+	token := strings.TrimSpace(parts[1])
+	return token, token != ""
+}
+
+func findToken(tokens []Token, presented string) *Token {
+	// compare hashes so that the comparison takes the same time regardless of the token lengths
+	presentedHash := sha256.Sum256([]byte(presented))
+
+	var match *Token
+	for i := range tokens {
+		hash := sha256.Sum256([]byte(tokens[i].Token))
+		if subtle.ConstantTimeCompare(presentedHash[:], hash[:]) == 1 && match == nil {
+			match = &tokens[i]
+		}
+	}
+	return match
+}
+
+func (a *authenticator) getTokens(ctx context.Context) ([]Token, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.tokens != nil && time.Since(a.loadedAt) < tokensRefreshInterval {
+		return a.tokens, nil
+	}
+
+	tokens, err := LoadTokens(ctx, a.opts.Clientset, a.opts.Namespace, a.opts.TokensSecretName)
+	if err != nil {
+		if a.tokens != nil {
+			// keep serving with the previously loaded tokens rather than locking every caller out
+			logger.Error(errors.Wrap(err, "failed to refresh api tokens, using previously loaded tokens"))
+			a.loadedAt = time.Now()
+			return a.tokens, nil
+		}
+		return nil, err
+	}
+
+	a.tokens = tokens
+	a.loadedAt = time.Now()
+
+	return a.tokens, nil
+}
+
+// LoadTokens reads and validates the API tokens from the given secret
+func LoadTokens(ctx context.Context, clientset kubernetes.Interface, namespace string, secretName string) ([]Token, error) {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get secret %s", secretName)
+	}
+
+	data, ok := secret.Data[TokensSecretKey]
+	if !ok {
+		return nil, errors.Errorf("%s not found in secret %s", TokensSecretKey, secretName)
+	}
+
+	tokens := []Token{}
+	if err := yaml.Unmarshal(data, &tokens); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal %s", TokensSecretKey)
+	}
+
+	for i, token := range tokens {
+		if token.Name == "" {
+			return nil, errors.Errorf("token at index %d is missing a name", i)
+		}
+		if token.Token == "" {
+			return nil, errors.Errorf("token %s is empty", token.Name)
+		}
+		for _, scope := range token.Scopes {
+			if !isKnownScope(scope) {
+				return nil, errors.Errorf("token %s has unknown scope %q", token.Name, scope)
+			}
+		}
+	}
+
+	return tokens, nil
+}
+
+func isKnownScope(scope Scope) bool {
+	switch scope {
+	case ScopeReadLicense, ScopeWriteMetrics, ScopeUploadBundle, ScopeMockData, ScopeWriteLicense, ScopeImportUpdates, ScopeWebhooks:
+		return true
+	}
+	return false
+}
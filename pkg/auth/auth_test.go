@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func tokensSecret(data string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "replicated-api-tokens",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			TokensSecretKey: []byte(data),
+		},
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	validTokens := `
+- name: app
+  token: app-token
+  scopes: ["read-license", "write-metrics"]
+- name: bundles
+  token: bundle-token
+  scopes: ["upload-bundle"]
+`
+
+	tests := []struct {
+		name                 string
+		tokensSecretName     string
+		allowUnauthenticated bool
+		header               string
+		scope                Scope
+		wantToken            string
+		wantErr              error
+	}{
+		{
+			name:             "authentication disabled",
+			tokensSecretName: "",
+			header:           "",
+			scope:            ScopeReadLicense,
+		},
+		{
+			name:             "missing header",
+			tokensSecretName: "replicated-api-tokens",
+			header:           "",
+			scope:            ScopeReadLicense,
+			wantErr:          ErrMissingToken,
+		},
+		{
+			name:                 "missing header allowed during migration",
+			tokensSecretName:     "replicated-api-tokens",
+			allowUnauthenticated: true,
+			header:               "",
+			scope:                ScopeReadLicense,
+		},
+		{
+			name:                 "invalid token is rejected during migration",
+			tokensSecretName:     "replicated-api-tokens",
+			allowUnauthenticated: true,
+			header:               "Bearer not-a-token",
+			scope:                ScopeReadLicense,
+			wantErr:              ErrInvalidToken,
+		},
+		{
+			name:             "not a bearer token",
+			tokensSecretName: "replicated-api-tokens",
+			header:           "app-token",
+			scope:            ScopeReadLicense,
+			wantErr:          ErrInvalidToken,
+		},
+		{
+			name:             "valid token with scope",
+			tokensSecretName: "replicated-api-tokens",
+			header:           "Bearer app-token",
+			scope:            ScopeWriteMetrics,
+			wantToken:        "app",
+		},
+		{
+			name:             "valid token without scope",
+			tokensSecretName: "replicated-api-tokens",
+			header:           "bearer bundle-token",
+			scope:            ScopeReadLicense,
+			wantToken:        "bundles",
+			wantErr:          ErrInsufficientScope,
+		},
+		{
+			name:             "valid token with no required scope",
+			tokensSecretName: "replicated-api-tokens",
+			header:           "Bearer bundle-token",
+			scope:            "",
+			wantToken:        "bundles",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			Init(InitOptions{
+				Clientset:            fake.NewSimpleClientset(tokensSecret(validTokens)),
+				Namespace:            "default",
+				TokensSecretName:     tt.tokensSecretName,
+				AllowUnauthenticated: tt.allowUnauthenticated,
+			})
+			defer Init(InitOptions{})
+
+			token, err := Authorize(context.Background(), tt.header, tt.scope)
+			if tt.wantErr != nil {
+				req.ErrorIs(err, tt.wantErr)
+			} else {
+				req.NoError(err)
+			}
+
+			if tt.wantToken == "" {
+				req.Nil(token)
+			} else {
+				req.NotNil(token)
+				req.Equal(tt.wantToken, token.Name)
+			}
+		})
+	}
+}
+
+func TestLoadTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []Token
+		wantErr bool
+	}{
+		{
+			name: "valid tokens",
+			data: `
+- name: app
+  token: app-token
+  scopes: ["read-license", "mock-data"]
+`,
+			want: []Token{
+				{Name: "app", Token: "app-token", Scopes: []Scope{ScopeReadLicense, ScopeMockData}},
+			},
+		},
+		{
+			name: "unknown scope",
+			data: `
+- name: app
+  token: app-token
+  scopes: ["admin"]
+`,
+			wantErr: true,
+		},
+		{
+			name: "empty token",
+			data: `
+- name: app
+  scopes: ["read-license"]
+`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			data:    `name: app`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			clientset := fake.NewSimpleClientset(tokensSecret(tt.data))
+			got, err := LoadTokens(context.Background(), clientset, "default", "replicated-api-tokens")
+			if tt.wantErr {
+				req.Error(err)
+				return
+			}
+			req.NoError(err)
+			req.Equal(tt.want, got)
+		})
+	}
+}
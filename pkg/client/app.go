@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	metatypes "github.com/replicatedhq/replicated-sdk/pkg/meta/types"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+)
+
+func (c *Client) GetAppInfo(ctx context.Context) (*types.GetCurrentAppInfoResponse, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/app/info", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	appInfo := &types.GetCurrentAppInfoResponse{}
+	resp, err := c.do(req, appInfo)
+	if err != nil {
+		return nil, resp, err
+	}
+	return appInfo, resp, nil
+}
+
+func (c *Client) GetAppStatus(ctx context.Context) (*types.GetCurrentAppStatusResponse, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/app/status", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	appStatus := &types.GetCurrentAppStatusResponse{}
+	resp, err := c.do(req, appStatus)
+	if err != nil {
+		return nil, resp, err
+	}
+	return appStatus, resp, nil
+}
+
+// AppUpdatesOptions filters the releases returned by GetAppUpdatesWithOptions
+type AppUpdatesOptions struct {
+	// Refresh has the SDK check for updates now
+	Refresh bool
+	// MaxBump leaves out releases with a larger semver bump from the current version
+	MaxBump upstreamtypes.SemverBump
+	// ExcludePrerelease leaves out releases with a semver prerelease version
+	ExcludePrerelease bool
+	// Channel gets the releases on another channel that the license is entitled to, from the Replicated API
+	Channel string
+}
+
+// GetAppUpdates returns the releases that are available to upgrade to, as of the last time the SDK checked for updates
+func (c *Client) GetAppUpdates(ctx context.Context) ([]upstreamtypes.ChannelRelease, *Response, error) {
+	return c.GetAppUpdatesWithOptions(ctx, AppUpdatesOptions{})
+}
+
+// RefreshAppUpdates has the SDK check for updates now, and returns the releases that are available to upgrade to
+func (c *Client) RefreshAppUpdates(ctx context.Context) ([]upstreamtypes.ChannelRelease, *Response, error) {
+	return c.GetAppUpdatesWithOptions(ctx, AppUpdatesOptions{Refresh: true})
+}
+
+// GetAppUpdatesWithOptions returns the releases that are available to upgrade to that match the options.
+// Required releases are always returned, since they can't be skipped.
+func (c *Client) GetAppUpdatesWithOptions(ctx context.Context, opts AppUpdatesOptions) ([]upstreamtypes.ChannelRelease, *Response, error) {
+	query := url.Values{}
+	if opts.Refresh {
+		query.Set("refresh", "true")
+	}
+	if opts.MaxBump != "" {
+		query.Set("maxBump", string(opts.MaxBump))
+	}
+	if opts.ExcludePrerelease {
+		query.Set("includePrerelease", "false")
+	}
+	if opts.Channel != "" {
+		query.Set("channel", opts.Channel)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/app/updates", query, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updates := []upstreamtypes.ChannelRelease{}
+	resp, err := c.do(req, &updates)
+	if err != nil {
+		return nil, resp, err
+	}
+	return updates, resp, nil
+}
+
+// ImportReleaseIndex imports a signed release index file in airgap mode, and returns the releases in it that are available to upgrade to.
+// It requires a token with the import-updates scope. The index is rejected with a release_index_rejected error if its signature
+// is not valid for the license or if it is older than the imported index, and with a read_only_mode error in read-only mode.
+func (c *Client) ImportReleaseIndex(ctx context.Context, signedIndex []byte) ([]upstreamtypes.ChannelRelease, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodPut, "/api/v1/app/updates/index", nil, json.RawMessage(signedIndex))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updates := []upstreamtypes.ChannelRelease{}
+	resp, err := c.do(req, &updates)
+	if err != nil {
+		return nil, resp, err
+	}
+	return updates, resp, nil
+}
+
+func (c *Client) GetAppHistory(ctx context.Context) (*types.GetAppHistoryResponse, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/app/history", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	history := &types.GetAppHistoryResponse{}
+	resp, err := c.do(req, history)
+	if err != nil {
+		return nil, resp, err
+	}
+	return history, resp, nil
+}
+
+// SendCustomMetrics replaces all custom metrics of the instance with data
+func (c *Client) SendCustomMetrics(ctx context.Context, data types.CustomAppMetricsData) (*Response, error) {
+	return c.sendCustomMetrics(ctx, http.MethodPost, data)
+}
+
+// UpdateCustomMetrics sets the custom metrics in data, and keeps the others
+func (c *Client) UpdateCustomMetrics(ctx context.Context, data types.CustomAppMetricsData) (*Response, error) {
+	return c.sendCustomMetrics(ctx, http.MethodPatch, data)
+}
+
+func (c *Client) sendCustomMetrics(ctx context.Context, method string, data types.CustomAppMetricsData) (*Response, error) {
+	req, err := c.newRequest(ctx, method, "/api/v1/app/custom-metrics", nil, types.SendCustomAppMetricsRequest{Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req, nil)
+}
+
+func (c *Client) DeleteCustomMetric(ctx context.Context, key string) (*Response, error) {
+	req, err := c.newRequest(ctx, http.MethodDelete, "/api/v1/app/custom-metrics/"+url.PathEscape(key), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req, nil)
+}
+
+func (c *Client) SendInstanceTags(ctx context.Context, data metatypes.InstanceTagData) (*Response, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, "/api/v1/app/instance-tags", nil, types.SendAppInstanceTagsRequest{Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req, nil)
+}
@@ -0,0 +1,267 @@
+// Package client is a typed client for the Replicated SDK API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/retry"
+)
+
+const (
+	// DefaultEndpoint is the address of the SDK service from within the namespace it is installed in
+	DefaultEndpoint = "http://replicated:3000"
+
+	RequestIDHeader       = "X-Request-ID"
+	ServedFromCacheHeader = "X-Replicated-Served-From-Cache"
+	MockDataHeader        = "X-Replicated-Mock-Data"
+	RateLimitedHeader     = "X-Replicated-Rate-Limited"
+	UpstreamErrorHeader   = "X-Replicated-Upstream-Error"
+
+	UpdatesLastCheckedAtHeader = "X-Replicated-Updates-Last-Checked-At"
+	UpdatesStaleHeader         = "X-Replicated-Updates-Stale"
+
+	defaultMaxRetries   = 3
+	defaultMinRetryWait = 500 * time.Millisecond
+	defaultMaxRetryWait = 10 * time.Second
+)
+
+type Client struct {
+	endpoint     *url.URL
+	httpClient   *http.Client
+	token        string
+	userAgent    string
+	maxRetries   int
+	minRetryWait time.Duration
+	maxRetryWait time.Duration
+}
+
+type Option func(*Client)
+
+// WithToken authenticates requests with an API token
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient sends requests with the given http client, for example to configure TLS.
+// The client should not have a timeout if the app status is streamed, use request contexts instead.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how many times an idempotent request that failed with a retryable error is retried,
+// and the bounds of the exponential backoff between attempts. A Retry-After header from the SDK takes precedence.
+func WithRetries(maxRetries int, minWait time.Duration, maxWait time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.minRetryWait = minWait
+		c.maxRetryWait = maxWait
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New returns a client for the SDK API at the endpoint, such as DefaultEndpoint
+func New(endpoint string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse endpoint")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("endpoint must be an http or https url: %s", endpoint)
+	}
+
+	c := &Client{
+		endpoint:     u,
+		httpClient:   &http.Client{},
+		maxRetries:   defaultMaxRetries,
+		minRetryWait: defaultMinRetryWait,
+		maxRetryWait: defaultMaxRetryWait,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Response is the http response for a call, with the SDK specific headers parsed. The body has already been read and closed.
+type Response struct {
+	*http.Response
+	RequestID string
+	// ServedFromCache is true if the SDK could not reach the Replicated API and returned the last known data
+	ServedFromCache bool
+	// MockData is true if the SDK is in integration mode and the data comes from the mock data
+	MockData bool
+	// RateLimited is true if an identical request was sent recently and the earlier response was returned
+	RateLimited bool
+	// UpstreamError is the code of the error that kept the data from being refreshed, when it is served from the cache
+	UpstreamError types.ErrorCode
+	// UpdatesLastCheckedAt is when the available updates were last checked successfully, for the app updates
+	UpdatesLastCheckedAt *time.Time
+	// UpdatesStale is true if the last check for updates failed or was not recent, for the app updates
+	UpdatesStale bool
+}
+
+func newResponse(r *http.Response) *Response {
+	resp := &Response{
+		Response:        r,
+		RequestID:       r.Header.Get(RequestIDHeader),
+		ServedFromCache: r.Header.Get(ServedFromCacheHeader) == "true",
+		MockData:        r.Header.Get(MockDataHeader) == "true",
+		RateLimited:     r.Header.Get(RateLimitedHeader) == "true",
+		UpstreamError:   types.ErrorCode(r.Header.Get(UpstreamErrorHeader)),
+		UpdatesStale:    r.Header.Get(UpdatesStaleHeader) == "true",
+	}
+	if checkedAt, err := time.Parse(time.RFC3339, r.Header.Get(UpdatesLastCheckedAtHeader)); err == nil {
+		resp.UpdatesLastCheckedAt = &checkedAt
+	}
+	return resp
+}
+
+func (c *Client) newRequest(ctx context.Context, method string, path string, query url.Values, body interface{}) (*http.Request, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal request body")
+		}
+		bodyReader = bytes.NewReader(b)
+	}
+
+	// path is already escaped, so that values in it can contain slashes
+	u := *c.endpoint
+	u.RawPath = c.endpoint.EscapedPath() + path
+	unescaped, err := url.PathUnescape(u.RawPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unescape path")
+	}
+	u.Path = unescaped
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bodyReader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	return req, nil
+}
+
+// do sends the request and decodes a successful response into v, unless v is nil
+func (c *Client) do(req *http.Request, v interface{}) (*Response, error) {
+	resp, err := c.send(req)
+	if err != nil {
+		return resp, err
+	}
+	defer resp.Body.Close()
+
+	switch v := v.(type) {
+	case nil:
+		io.Copy(io.Discard, resp.Body)
+	case *[]byte:
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return resp, errors.Wrap(err, "failed to read response body")
+		}
+		*v = b
+	default:
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return resp, errors.Wrap(err, "failed to decode response body")
+		}
+	}
+
+	return resp, nil
+}
+
+// send sends the request, and retries it while it fails with a connection error or a retryable error response.
+// Only idempotent requests whose body can be sent again are retried, since other requests may have been processed
+// before the error, e.g. by the SDK before the Replicated API failed. The body of a successful response must be closed by the caller.
+func (c *Client) send(req *http.Request) (*Response, error) {
+	b := c.newBackOff()
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to rewind request body")
+			}
+			req.Body = body
+		}
+
+		canRetry := attempt < c.maxRetries && isIdempotent(req.Method) && retry.IsReplayable(req)
+
+		httpResp, err := c.httpClient.Do(req)
+		if err != nil {
+			if canRetry && req.Context().Err() == nil {
+				if waitErr := retry.Wait(req.Context(), b.NextBackOff()); waitErr != nil {
+					return nil, waitErr
+				}
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to send %s request", req.Method)
+		}
+
+		resp := newResponse(httpResp)
+		if httpResp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		apiErr := newError(resp)
+		if canRetry && apiErr.isRetryable() {
+			delay := b.NextBackOff()
+			if apiErr.RetryAfter > delay {
+				delay = apiErr.RetryAfter
+			}
+			if waitErr := retry.Wait(req.Context(), delay); waitErr != nil {
+				return resp, waitErr
+			}
+			continue
+		}
+		return resp, apiErr
+	}
+}
+
+func (c *Client) newBackOff() backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = c.minRetryWait
+	b.MaxInterval = c.maxRetryWait
+	b.MaxElapsedTime = 0
+	b.Reset()
+	return b
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
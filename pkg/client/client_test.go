@@ -0,0 +1,539 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/client/clienttest"
+	diagnosticstypes "github.com/replicatedhq/replicated-sdk/pkg/diagnostics/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	integrationtypes "github.com/replicatedhq/replicated-sdk/pkg/integration/types"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	metatypes "github.com/replicatedhq/replicated-sdk/pkg/meta/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/openapi"
+	"github.com/replicatedhq/replicated-sdk/pkg/readiness"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, server *clienttest.Server, opts ...Option) *Client {
+	opts = append([]Option{WithRetries(2, time.Millisecond, time.Millisecond)}, opts...)
+	c, err := New(server.URL, opts...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient(t *testing.T) {
+	server := clienttest.NewServer(clienttest.State{
+		Version: "1.2.3",
+		AppInfo: types.GetCurrentAppInfoResponse{
+			AppSlug:        "my-app",
+			CurrentRelease: types.AppRelease{VersionLabel: "1.0.0"},
+		},
+		AppStatus: appstatetypes.AppStatus{AppSlug: "my-app", State: appstatetypes.StateReady},
+		Updates:   []upstreamtypes.ChannelRelease{{VersionLabel: "1.1.0"}},
+		History:   []types.AppRelease{{VersionLabel: "1.0.0", HelmReleaseRevision: 1}},
+		// the last check for updates failed
+		UpdatesStale: true,
+		LicenseInfo: types.LicenseInfo{
+			LicenseID:    "license-id",
+			CustomerName: "Customer",
+		},
+		LicenseFields: sdklicensetypes.LicenseFields{
+			"seats": {Name: "seats", Value: float64(10), ValueType: "Integer"},
+		},
+		LicenseChannels: types.GetLicenseChannelsResponse{
+			Channels: []sdklicensetypes.LicenseChannel{
+				{ChannelID: "stable-id", ChannelName: "Stable", IsDefault: true},
+				{ChannelID: "beta-id", ChannelName: "Beta"},
+			},
+			InstalledChannelID: "stable-id",
+			UpdatesChannelID:   "stable-id",
+		},
+		ChannelUpdates: map[string][]upstreamtypes.ChannelRelease{
+			"beta-id": {{VersionLabel: "2.0.0-beta.1"}},
+		},
+		Metrics: "replicated_sdk_info 1\n",
+		Diagnostics: diagnosticstypes.Report{
+			Status:           diagnosticstypes.StatusWarn,
+			Checks:           []diagnosticstypes.Check{{Category: diagnosticstypes.CategoryPermission, Name: "list nodes", Status: diagnosticstypes.StatusWarn}},
+			DisabledFeatures: []string{"kubernetes distribution detection"},
+		},
+	})
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	ctx := context.Background()
+
+	healthz, _, err := c.Healthz(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "1.2.3", healthz.Version)
+
+	metrics, _, err := c.GetMetrics(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "replicated_sdk_info 1\n", string(metrics))
+
+	doc, _, err := c.GetOpenAPIDocument(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "1.2.3", doc.Info.Version)
+
+	appInfo, resp, err := c.GetAppInfo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "my-app", appInfo.AppSlug)
+	require.Equal(t, "1.0.0", appInfo.CurrentRelease.VersionLabel)
+	require.Equal(t, "fake-4", resp.RequestID)
+	require.False(t, resp.MockData)
+
+	appStatus, _, err := c.GetAppStatus(ctx)
+	require.NoError(t, err)
+	require.Equal(t, appstatetypes.StateReady, appStatus.AppStatus.State)
+
+	updates, resp, err := c.GetAppUpdates(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []upstreamtypes.ChannelRelease{{VersionLabel: "1.1.0", Bump: upstreamtypes.SemverBumpMinor}}, updates)
+	require.True(t, resp.UpdatesStale)
+	require.Nil(t, resp.UpdatesLastCheckedAt)
+
+	updates, resp, err = c.RefreshAppUpdates(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []upstreamtypes.ChannelRelease{{VersionLabel: "1.1.0", Bump: upstreamtypes.SemverBumpMinor}}, updates)
+	require.False(t, resp.UpdatesStale)
+	require.NotNil(t, resp.UpdatesLastCheckedAt)
+
+	updates, _, err = c.GetAppUpdatesWithOptions(ctx, AppUpdatesOptions{MaxBump: upstreamtypes.SemverBumpPatch})
+	require.NoError(t, err)
+	require.Empty(t, updates)
+
+	updates, _, err = c.GetAppUpdatesWithOptions(ctx, AppUpdatesOptions{Channel: "beta-id"})
+	require.NoError(t, err)
+	require.Equal(t, []upstreamtypes.ChannelRelease{{VersionLabel: "2.0.0-beta.1", Bump: upstreamtypes.SemverBumpMajor}}, updates)
+
+	updates, _, err = c.GetAppUpdatesWithOptions(ctx, AppUpdatesOptions{Channel: "stable-id"})
+	require.NoError(t, err)
+	require.Equal(t, []upstreamtypes.ChannelRelease{{VersionLabel: "1.1.0", Bump: upstreamtypes.SemverBumpMinor}}, updates)
+
+	_, _, err = c.GetAppUpdatesWithOptions(ctx, AppUpdatesOptions{Channel: "unknown-id"})
+	require.Equal(t, types.ErrorCodeInvalidRequest, ErrorCode(err))
+
+	updates, _, err = c.ImportReleaseIndex(ctx, []byte(`{"index": "e30=", "signature": "c2ln"}`))
+	require.NoError(t, err)
+	require.Equal(t, []upstreamtypes.ChannelRelease{{VersionLabel: "1.1.0", Bump: upstreamtypes.SemverBumpMinor}}, updates)
+	require.JSONEq(t, `{"index": "e30=", "signature": "c2ln"}`, string(server.State().ImportedReleaseIndex))
+
+	history, _, err := c.GetAppHistory(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []types.AppRelease{{VersionLabel: "1.0.0", HelmReleaseRevision: 1}}, history.Releases)
+
+	licenseInfo, _, err := c.GetLicenseInfo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "license-id", licenseInfo.LicenseID)
+
+	licenseChannels, _, err := c.GetLicenseChannels(ctx)
+	require.NoError(t, err)
+	require.Len(t, licenseChannels.Channels, 2)
+	require.Equal(t, "stable-id", licenseChannels.UpdatesChannelID)
+
+	licenseFields, _, err := c.GetLicenseFields(ctx)
+	require.NoError(t, err)
+	require.Len(t, licenseFields, 1)
+
+	licenseField, _, err := c.GetLicenseField(ctx, "seats")
+	require.NoError(t, err)
+	require.Equal(t, float64(10), licenseField.Value)
+
+	_, _, err = c.GetLicenseField(ctx, "missing")
+	require.True(t, IsNotFound(err))
+	require.Equal(t, types.ErrorCodeNotFound, ErrorCode(err))
+
+	evaluation, _, err := c.EvaluateEntitlements(ctx, []sdklicensetypes.EntitlementCheck{
+		{Field: "seats", Op: sdklicensetypes.EntitlementOperatorGreaterThanOrEqual, Value: 5},
+		{Field: "missing", Op: sdklicensetypes.EntitlementOperatorEnabled},
+	})
+	require.NoError(t, err)
+	require.False(t, evaluation.Passed)
+	require.Len(t, evaluation.Results, 2)
+	require.True(t, evaluation.Results[0].Passed)
+	require.Equal(t, sdklicensetypes.SignatureStatusVerified, evaluation.Results[0].Signature)
+	require.Equal(t, `license field "missing" not found`, evaluation.Results[1].Error)
+
+	_, _, err = c.EvaluateEntitlements(ctx, nil)
+	require.Equal(t, types.ErrorCodeInvalidRequest, ErrorCode(err))
+
+	uploaded, _, err := c.UploadLicense(ctx, []byte("apiVersion: kots.io/v1beta1\nkind: License\n"))
+	require.NoError(t, err)
+	require.Equal(t, licenseInfo.LicenseID, uploaded.LicenseID)
+	require.Equal(t, "apiVersion: kots.io/v1beta1\nkind: License\n", string(server.State().UploadedLicense))
+
+	_, err = c.SendCustomMetrics(ctx, types.CustomAppMetricsData{"a": 1, "b": 2})
+	require.NoError(t, err)
+	_, err = c.UpdateCustomMetrics(ctx, types.CustomAppMetricsData{"c": "three"})
+	require.NoError(t, err)
+	_, err = c.DeleteCustomMetric(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, types.CustomAppMetricsData{"b": float64(2), "c": "three"}, server.State().CustomMetrics)
+
+	_, err = c.SendInstanceTags(ctx, metatypes.InstanceTagData{Tags: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"env": "prod"}, server.State().InstanceTags.Tags)
+
+	_, err = c.SetSupportBundleMetadata(ctx, map[string]string{"a": "1"})
+	require.NoError(t, err)
+	_, err = c.UpdateSupportBundleMetadata(ctx, map[string]string{"b": "2"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"a": "1", "b": "2"}, server.State().SupportBundleMetadata)
+
+	_, _, err = c.TestWebhooks(ctx)
+	require.Equal(t, types.ErrorCodeNoWebhooks, ErrorCode(err))
+
+	deadLetters, _, err := c.GetWebhookDeadLetters(ctx)
+	require.NoError(t, err)
+	require.Empty(t, deadLetters.DeadLetters)
+
+	integrationStatus, _, err := c.GetIntegrationStatus(ctx)
+	require.NoError(t, err)
+	require.False(t, integrationStatus.IsEnabled)
+
+	diagnostics, _, err := c.GetDiagnostics(ctx)
+	require.NoError(t, err)
+	require.Equal(t, server.State().Diagnostics, *diagnostics)
+}
+
+func TestClientResponseHeaders(t *testing.T) {
+	server := clienttest.NewServer(clienttest.State{
+		ServedFromCache: true,
+		IntegrationMode: true,
+	})
+	defer server.Close()
+
+	c := newTestClient(t, server)
+
+	_, resp, err := c.GetLicenseInfo(context.Background())
+	require.NoError(t, err)
+	require.True(t, resp.ServedFromCache)
+	require.Equal(t, types.ErrorCodeUpstreamUnavailable, resp.UpstreamError)
+	require.NotEmpty(t, resp.RequestID)
+
+	_, resp, err = c.GetAppInfo(context.Background())
+	require.NoError(t, err)
+	require.True(t, resp.MockData)
+	require.False(t, resp.ServedFromCache)
+}
+
+func TestClientToken(t *testing.T) {
+	server := clienttest.NewServer(clienttest.State{Token: "secret"})
+	defer server.Close()
+
+	_, _, err := newTestClient(t, server).GetLicenseInfo(context.Background())
+	require.Equal(t, types.ErrorCodeUnauthorized, ErrorCode(err))
+
+	_, _, err = newTestClient(t, server, WithToken("secret")).GetLicenseInfo(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, "Bearer secret", server.Requests()[1].Header.Get("Authorization"))
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     []clienttest.Failure
+		wantErr      types.ErrorCode
+		wantRequests int
+	}{
+		{
+			name: "retryable errors are retried",
+			failures: []clienttest.Failure{
+				{StatusCode: http.StatusServiceUnavailable, Code: types.ErrorCodeNotReady, Retryable: true},
+				{StatusCode: http.StatusBadGateway, Code: types.ErrorCodeUpstreamUnavailable, Retryable: true},
+			},
+			wantRequests: 3,
+		},
+		{
+			name: "rate limited requests are retried after the requested delay",
+			failures: []clienttest.Failure{
+				{StatusCode: http.StatusTooManyRequests, RetryAfter: "1"},
+			},
+			wantRequests: 2,
+		},
+		{
+			name: "errors that are not retryable are returned",
+			failures: []clienttest.Failure{
+				{StatusCode: http.StatusBadRequest, Code: types.ErrorCodeInvalidRequest},
+			},
+			wantErr:      types.ErrorCodeInvalidRequest,
+			wantRequests: 1,
+		},
+		{
+			name: "the last error is returned when retries are exhausted",
+			failures: []clienttest.Failure{
+				{StatusCode: http.StatusServiceUnavailable, Code: types.ErrorCodeNotReady, Retryable: true},
+				{StatusCode: http.StatusServiceUnavailable, Code: types.ErrorCodeNotReady, Retryable: true},
+				{StatusCode: http.StatusServiceUnavailable, Code: types.ErrorCodeNotReady, Retryable: true},
+			},
+			wantErr:      types.ErrorCodeNotReady,
+			wantRequests: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := clienttest.NewServer(clienttest.State{})
+			defer server.Close()
+
+			server.Fail(http.MethodPut, "/api/v1/app/updates/index", tt.failures...)
+
+			_, _, err := newTestClient(t, server).ImportReleaseIndex(context.Background(), []byte(`{"index":"e30=","signature":"c2ln"}`))
+			if tt.wantErr != "" {
+				require.Equal(t, tt.wantErr, ErrorCode(err))
+			} else {
+				require.NoError(t, err)
+			}
+
+			requests := server.Requests()
+			require.Len(t, requests, tt.wantRequests)
+			for _, req := range requests {
+				require.JSONEq(t, `{"index":"e30=","signature":"c2ln"}`, string(req.Body))
+			}
+		})
+	}
+}
+
+func TestClientDoesNotRetryNonIdempotentRequests(t *testing.T) {
+	server := clienttest.NewServer(clienttest.State{})
+	defer server.Close()
+
+	// the request may have been processed before the error, so sending it again could e.g. report metrics twice
+	server.Fail(http.MethodPost, "/api/v1/app/custom-metrics", clienttest.Failure{StatusCode: http.StatusBadGateway, Code: types.ErrorCodeUpstreamUnavailable, Retryable: true})
+
+	_, err := newTestClient(t, server).SendCustomMetrics(context.Background(), types.CustomAppMetricsData{"a": 1})
+	require.Equal(t, types.ErrorCodeUpstreamUnavailable, ErrorCode(err))
+	require.Len(t, server.Requests(), 1)
+}
+
+func TestClientErrorResponse(t *testing.T) {
+	server := clienttest.NewServer(clienttest.State{ReadOnlyMode: true})
+	defer server.Close()
+
+	_, err := newTestClient(t, server).SendInstanceTags(context.Background(), metatypes.InstanceTagData{})
+	require.Error(t, err)
+
+	apiErr, ok := err.(*Error)
+	require.True(t, ok)
+	require.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+	require.Equal(t, types.ErrorCodeReadOnlyMode, apiErr.Code)
+	require.Equal(t, "fake-1", apiErr.RequestID)
+	require.Equal(t, "sdk api returned 422 (read_only_mode): the SDK is in read-only mode", err.Error())
+}
+
+func TestReadyz(t *testing.T) {
+	server := clienttest.NewServer(clienttest.State{
+		Readiness: readiness.Status{
+			Ready: false,
+			Phases: []readiness.PhaseStatus{
+				{Name: readiness.PhaseLicenseSync, State: readiness.PhaseStateFailed, LastError: "timeout"},
+			},
+		},
+	})
+	defer server.Close()
+
+	c := newTestClient(t, server)
+
+	status, _, err := c.Readyz(context.Background())
+	require.NoError(t, err)
+	require.False(t, status.Ready)
+	require.Equal(t, "timeout", status.Phases[0].LastError)
+	require.Len(t, server.Requests(), 1)
+
+	server.Update(func(state *clienttest.State) {
+		state.Readiness = readiness.Status{Ready: true, Phases: []readiness.PhaseStatus{}}
+	})
+
+	status, _, err = c.Readyz(context.Background())
+	require.NoError(t, err)
+	require.True(t, status.Ready)
+}
+
+func TestUploadSupportBundle(t *testing.T) {
+	bundle := []byte("support bundle contents")
+
+	server := clienttest.NewServer(clienttest.State{})
+	defer server.Close()
+
+	c := newTestClient(t, server)
+
+	uploaded, _, err := c.UploadSupportBundle(context.Background(), bytes.NewReader(bundle), int64(len(bundle)))
+	require.NoError(t, err)
+	require.Equal(t, "fake-bundle-1", uploaded.BundleID)
+	require.Equal(t, [][]byte{bundle}, server.State().SupportBundles)
+
+	// failed uploads are not retried, since the bundle may have been uploaded before the request failed
+	server.Fail(http.MethodPost, "/api/v1/supportbundle", clienttest.Failure{
+		StatusCode: http.StatusBadGateway,
+		Code:       types.ErrorCodeUpstreamUnavailable,
+		Retryable:  true,
+	})
+
+	_, _, err = c.UploadSupportBundle(context.Background(), bytes.NewReader(bundle), int64(len(bundle)))
+	require.Equal(t, types.ErrorCodeUpstreamUnavailable, ErrorCode(err))
+	require.Len(t, server.Requests(), 2)
+	require.Len(t, server.State().SupportBundles, 1)
+}
+
+func TestMockData(t *testing.T) {
+	server := clienttest.NewServer(clienttest.State{IntegrationMode: true})
+	defer server.Close()
+
+	c := newTestClient(t, server)
+	ctx := context.Background()
+
+	_, _, err := c.GetMockData(ctx)
+	require.True(t, IsNotFound(err))
+
+	_, err = c.SetMockData(ctx, &integrationtypes.MockDataV2{
+		MockDataVersion: integrationtypes.MockDataVersion{Version: "v2"},
+		HelmChartURL:    "oci://registry/app",
+	})
+	require.NoError(t, err)
+
+	mockData, _, err := c.GetMockData(ctx)
+	require.NoError(t, err)
+	require.IsType(t, &integrationtypes.MockDataV2{}, mockData)
+	require.Equal(t, "oci://registry/app", mockData.(*integrationtypes.MockDataV2).HelmChartURL)
+}
+
+func TestStreamAppStatus(t *testing.T) {
+	server := clienttest.NewServer(clienttest.State{
+		AppStatus: appstatetypes.AppStatus{State: appstatetypes.StateMissing},
+	})
+	defer server.Close()
+
+	c := newTestClient(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events := make(chan AppStatusEvent)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.StreamAppStatus(ctx, "", func(event AppStatusEvent) error {
+			events <- event
+			if event.AppStatus.State == appstatetypes.StateReady {
+				return io.EOF
+			}
+			return nil
+		})
+	}()
+
+	event := <-events
+	require.Equal(t, appstatetypes.StateMissing, event.AppStatus.State)
+
+	server.Update(func(state *clienttest.State) {
+		state.AppStatus.State = appstatetypes.StateReady
+	})
+
+	event = <-events
+	require.Equal(t, appstatetypes.StateReady, event.AppStatus.State)
+	require.Equal(t, "2", event.ID)
+
+	// the error returned by the callback ends the stream
+	require.Equal(t, io.EOF, <-errCh)
+}
+
+func TestStreamAppStatusResumes(t *testing.T) {
+	server := clienttest.NewServer(clienttest.State{
+		AppStatus: appstatetypes.AppStatus{State: appstatetypes.StateReady},
+	})
+	defer server.Close()
+
+	server.Fail(http.MethodGet, "/api/v1/app/status/stream", clienttest.Failure{
+		StatusCode: http.StatusServiceUnavailable,
+		Code:       types.ErrorCodeNotReady,
+		Retryable:  true,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	// the status has not changed since the last event, so there is nothing to receive until the stream is cancelled
+	err := newTestClient(t, server).StreamAppStatus(ctx, "1", func(event AppStatusEvent) error {
+		return nil
+	})
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	requests := server.Requests()
+	require.Len(t, requests, 2)
+	require.Equal(t, "1", requests[1].Header.Get("Last-Event-ID"))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("replicated:3000")
+	require.Error(t, err)
+
+	c, err := New("http://replicated:3000/")
+	require.NoError(t, err)
+
+	req, err := c.newRequest(context.Background(), http.MethodGet, "/api/v1/license/fields/a%2Fb", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "http://replicated:3000/api/v1/license/fields/a%2Fb", req.URL.String())
+}
+
+func Test_newError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   *Error
+	}{
+		{
+			name:   "error response",
+			status: http.StatusBadRequest,
+			body:   `{"code":"invalid_request","message":"data: is required","retryable":false,"error":"data: is required","fields":[{"field":"data","message":"is required"}]}`,
+			want: &Error{
+				StatusCode: http.StatusBadRequest,
+				Code:       types.ErrorCodeInvalidRequest,
+				Message:    "data: is required",
+				Fields:     []openapi.FieldError{{Field: "data", Message: "is required"}},
+			},
+		},
+		{
+			name:   "legacy error response",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"error":"instance tags are unavailable in read-only mode","readOnlyMode":true}`,
+			want: &Error{
+				StatusCode: http.StatusUnprocessableEntity,
+				Message:    "instance tags are unavailable in read-only mode",
+			},
+		},
+		{
+			name:   "plain text",
+			status: http.StatusBadGateway,
+			body:   "bad gateway\n",
+			want: &Error{
+				StatusCode: http.StatusBadGateway,
+				Message:    "bad gateway",
+			},
+		},
+		{
+			name:   "empty body",
+			status: http.StatusNotFound,
+			want: &Error{
+				StatusCode: http.StatusNotFound,
+				Message:    "Not Found",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := newResponse(&http.Response{
+				StatusCode: tt.status,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			})
+			require.Equal(t, tt.want, newError(resp))
+		})
+	}
+}
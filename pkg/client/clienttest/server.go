@@ -0,0 +1,743 @@
+// Package clienttest provides an in-memory fake of the SDK API for testing code that uses the client package.
+package clienttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	diagnosticstypes "github.com/replicatedhq/replicated-sdk/pkg/diagnostics/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	metatypes "github.com/replicatedhq/replicated-sdk/pkg/meta/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/openapi"
+	"github.com/replicatedhq/replicated-sdk/pkg/readiness"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+	webhooktypes "github.com/replicatedhq/replicated-sdk/pkg/webhook/types"
+)
+
+// State is the data that the fake serves, and the mode it runs in
+type State struct {
+	// Token is required as a bearer token on every request if it is not empty
+	Token string
+	// ReadOnlyMode rejects requests that change data, like the SDK does in read-only mode
+	ReadOnlyMode bool
+	// IntegrationMode enables the mock data routes, and marks app responses as mock data
+	IntegrationMode bool
+	// ServedFromCache marks license and update responses as served from the cache
+	ServedFromCache bool
+	// UpdatesLastCheckedAt is returned as when updates were last checked, and is set to the current time when updates are refreshed
+	UpdatesLastCheckedAt *time.Time
+	// UpdatesStale marks update responses as stale, and is cleared when updates are refreshed
+	UpdatesStale bool
+
+	Version       string
+	Readiness     readiness.Status
+	Metrics       string
+	Diagnostics   diagnosticstypes.Report
+	AppInfo       types.GetCurrentAppInfoResponse
+	AppStatus     appstatetypes.AppStatus
+	Updates       []upstreamtypes.ChannelRelease
+	History       []types.AppRelease
+	LicenseInfo   types.LicenseInfo
+	LicenseFields sdklicensetypes.LicenseFields
+	// UploadedLicense is the license YAML of the last license upload
+	UploadedLicense []byte
+	CustomMetrics   types.CustomAppMetricsData
+	InstanceTags    metatypes.InstanceTagData
+	// SupportBundles holds the contents of the uploaded support bundles
+	SupportBundles        [][]byte
+	SupportBundleMetadata map[string]string
+	WebhookResults        []webhooktypes.DeliveryResult
+	DeadLetters           []metatypes.WebhookDeadLetter
+	MockData              json.RawMessage
+
+	// ImportedReleaseIndex is the signed release index of the last release index import. The fake doesn't verify
+	// or apply it, and serves Updates as the releases in the index.
+	ImportedReleaseIndex []byte
+	LicenseChannels      types.GetLicenseChannelsResponse
+	// ChannelUpdates are the releases on other channels than the one updates are checked on, by channel ID.
+	// Update requests for a channel that is not in it are rejected like for a channel the license is not entitled to.
+	ChannelUpdates map[string][]upstreamtypes.ChannelRelease
+}
+
+// Failure is an error response that the fake returns instead of handling a request
+type Failure struct {
+	StatusCode int
+	Code       types.ErrorCode
+	Message    string
+	Retryable  bool
+	// RetryAfter is sent in the Retry-After header if it is not empty
+	RetryAfter string
+}
+
+// Request is a request that the fake received
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+// Server is a fake of the SDK API. Its zero state is a ready SDK with no data.
+type Server struct {
+	*httptest.Server
+
+	mtx            sync.Mutex
+	state          State
+	failures       map[string][]Failure
+	requests       []Request
+	statusEventID  int64
+	statusWatchers map[chan struct{}]struct{}
+	done           chan struct{}
+	closeOnce      sync.Once
+}
+
+// NewServer starts a fake with the given state. It must be closed when the test is done.
+func NewServer(state State) *Server {
+	if state.Readiness.Phases == nil {
+		state.Readiness = readiness.Status{Ready: true, Phases: []readiness.PhaseStatus{}}
+	}
+
+	s := &Server{
+		state:          state,
+		failures:       map[string][]Failure{},
+		statusEventID:  1,
+		statusWatchers: map[chan struct{}]struct{}{},
+		done:           make(chan struct{}),
+	}
+	s.Server = httptest.NewServer(s.router())
+	return s
+}
+
+// Close ends open app status streams and shuts down the server
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	s.Server.Close()
+}
+
+// State returns a copy of the current state, including the changes made by requests
+func (s *Server) State() State {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.state
+}
+
+// Update changes the state. App status changes are sent to open app status streams.
+func (s *Server) Update(fn func(state *State)) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	previous := s.state.AppStatus
+	fn(&s.state)
+
+	if !reflect.DeepEqual(previous, s.state.AppStatus) {
+		s.statusEventID++
+		for watcher := range s.statusWatchers {
+			select {
+			case watcher <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Fail makes the next requests to the method and path fail with the given responses, one per request
+func (s *Server) Fail(method string, path string, failures ...Failure) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	key := method + " " + path
+	s.failures[key] = append(s.failures[key], failures...)
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+func (s *Server) router() http.Handler {
+	r := mux.NewRouter()
+	r.Use(s.middleware)
+
+	r.HandleFunc("/healthz", s.healthz)
+	r.HandleFunc("/readyz", s.readyz)
+	r.HandleFunc("/metrics", s.metrics).Methods("GET")
+	r.HandleFunc("/api/v1/openapi.json", s.openAPIDocument).Methods("GET")
+	r.HandleFunc("/api/v1/diagnostics", s.diagnostics).Methods("GET")
+
+	r.HandleFunc("/api/v1/license/info", s.licenseInfo).Methods("GET")
+	r.HandleFunc("/api/v1/license/channels", s.licenseChannels).Methods("GET")
+	r.HandleFunc("/api/v1/license/fields", s.licenseFields).Methods("GET")
+	r.HandleFunc("/api/v1/license/fields/{fieldName}", s.licenseField).Methods("GET")
+	r.HandleFunc("/api/v1/license/entitlements/evaluate", s.evaluateEntitlements).Methods("POST")
+	r.HandleFunc("/api/v1/license", s.uploadLicense).Methods("PUT")
+
+	r.HandleFunc("/api/v1/app/info", s.appInfo).Methods("GET")
+	r.HandleFunc("/api/v1/app/status", s.appStatus).Methods("GET")
+	r.HandleFunc("/api/v1/app/status/stream", s.streamAppStatus).Methods("GET")
+	r.HandleFunc("/api/v1/app/updates", s.appUpdates).Methods("GET")
+	r.HandleFunc("/api/v1/app/updates/index", s.importReleaseIndex).Methods("PUT")
+	r.HandleFunc("/api/v1/app/history", s.appHistory).Methods("GET")
+	r.HandleFunc("/api/v1/app/custom-metrics", s.customMetrics).Methods("POST", "PATCH")
+	r.HandleFunc("/api/v1/app/custom-metrics/{key}", s.deleteCustomMetric).Methods("DELETE")
+	r.HandleFunc("/api/v1/app/instance-tags", s.instanceTags).Methods("POST")
+
+	r.HandleFunc("/api/v1/webhooks/test", s.testWebhooks).Methods("POST")
+	r.HandleFunc("/api/v1/webhooks/dead-letters", s.webhookDeadLetters).Methods("GET")
+
+	r.HandleFunc("/api/v1/supportbundle", s.uploadSupportBundle).Methods("POST")
+	r.HandleFunc("/api/v1/supportbundle/metadata", s.supportBundleMetadata).Methods("POST", "PATCH")
+
+	r.HandleFunc("/api/v1/integration/mock-data", s.setMockData).Methods("POST")
+	r.HandleFunc("/api/v1/integration/mock-data", s.getMockData).Methods("GET")
+	r.HandleFunc("/api/v1/integration/status", s.integrationStatus).Methods("GET")
+
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, types.ErrorCodeNotFound, "not found", false)
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusMethodNotAllowed, types.ErrorCodeMethodNotAllowed, "method not allowed", false)
+	})
+
+	return r
+}
+
+// middleware records the request, and then applies injected failures and authentication
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, types.ErrorCodeInvalidRequest, "failed to read request body", false)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		s.mtx.Lock()
+		w.Header().Set("X-Request-ID", fmt.Sprintf("fake-%d", len(s.requests)+1))
+		s.requests = append(s.requests, Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.RawQuery,
+			Header: r.Header.Clone(),
+			Body:   body,
+		})
+		key := r.Method + " " + r.URL.Path
+		var failure *Failure
+		if failures := s.failures[key]; len(failures) > 0 {
+			failure = &failures[0]
+			s.failures[key] = failures[1:]
+		}
+		token := s.state.Token
+		s.mtx.Unlock()
+
+		if failure != nil {
+			if failure.RetryAfter != "" {
+				w.Header().Set("Retry-After", failure.RetryAfter)
+			}
+			writeError(w, failure.StatusCode, failure.Code, failure.Message, failure.Retryable)
+			return
+		}
+
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			writeError(w, http.StatusUnauthorized, types.ErrorCodeUnauthorized, "a valid API token is required", false)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	state := s.State()
+	writeJSON(w, http.StatusOK, types.HealthzResponse{Version: state.Version})
+}
+
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	state := s.State()
+	if !state.Readiness.Ready {
+		writeJSON(w, http.StatusServiceUnavailable, state.Readiness)
+		return
+	}
+	writeJSON(w, http.StatusOK, state.Readiness)
+}
+
+func (s *Server) metrics(w http.ResponseWriter, r *http.Request) {
+	state := s.State()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	io.WriteString(w, state.Metrics)
+}
+
+func (s *Server) openAPIDocument(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, openapi.NewDocument(openapi.Info{
+		Title:   "Replicated SDK API",
+		Version: s.State().Version,
+	}))
+}
+
+func (s *Server) diagnostics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.State().Diagnostics)
+}
+
+func (s *Server) licenseInfo(w http.ResponseWriter, r *http.Request) {
+	state := s.State()
+	setCacheHeader(w, state)
+	writeJSON(w, http.StatusOK, state.LicenseInfo)
+}
+
+func (s *Server) licenseChannels(w http.ResponseWriter, r *http.Request) {
+	channels := s.State().LicenseChannels
+	if channels.Channels == nil {
+		channels.Channels = []sdklicensetypes.LicenseChannel{}
+	}
+	writeJSON(w, http.StatusOK, channels)
+}
+
+func (s *Server) licenseFields(w http.ResponseWriter, r *http.Request) {
+	state := s.State()
+	setCacheHeader(w, state)
+	fields := state.LicenseFields
+	if fields == nil {
+		fields = sdklicensetypes.LicenseFields{}
+	}
+	writeJSON(w, http.StatusOK, fields)
+}
+
+func (s *Server) licenseField(w http.ResponseWriter, r *http.Request) {
+	fieldName := mux.Vars(r)["fieldName"]
+
+	state := s.State()
+	field, ok := state.LicenseFields[fieldName]
+	if !ok {
+		writeError(w, http.StatusNotFound, types.ErrorCodeNotFound, fmt.Sprintf("license field %q not found", fieldName), false)
+		return
+	}
+	setCacheHeader(w, state)
+	writeJSON(w, http.StatusOK, field)
+}
+
+// evaluateEntitlements evaluates the checks against the license fields in the state. There is no signed license,
+// so fields are reported as verified if they exist.
+func (s *Server) evaluateEntitlements(w http.ResponseWriter, r *http.Request) {
+	request := types.EvaluateEntitlementsRequest{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("invalid request body: %v", err), false)
+		return
+	}
+	if len(request.Checks) == 0 {
+		writeError(w, http.StatusBadRequest, types.ErrorCodeInvalidRequest, "at least one check is required", false)
+		return
+	}
+	for i, check := range request.Checks {
+		if err := check.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("checks[%d]: %v", i, err), false)
+			return
+		}
+	}
+
+	state := s.State()
+	response := types.EvaluateEntitlementsResponse{Passed: true}
+	for _, check := range request.Checks {
+		result := sdklicensetypes.EntitlementCheckResult{
+			Field:     check.Field,
+			Op:        check.Op,
+			Value:     check.Value,
+			Signature: sdklicensetypes.SignatureStatusUnverified,
+		}
+		if field, ok := state.LicenseFields[check.Field]; ok {
+			result.ValueType = field.ValueType
+			result.Signature = sdklicensetypes.SignatureStatusVerified
+			passed, actual, err := field.Evaluate(check)
+			result.Passed = passed
+			result.ActualValue = actual
+			if err != nil {
+				result.Error = err.Error()
+			}
+		} else {
+			result.Error = fmt.Sprintf("license field %q not found", check.Field)
+		}
+		if !result.Passed {
+			response.Passed = false
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	setCacheHeader(w, state)
+	writeJSON(w, http.StatusOK, response)
+}
+
+// uploadLicense records the uploaded license and responds with the license info in the state, since there is no
+// signed license to validate it against
+func (s *Server) uploadLicense(w http.ResponseWriter, r *http.Request) {
+	if s.State().ReadOnlyMode {
+		writeReadOnlyModeError(w)
+		return
+	}
+
+	license, err := io.ReadAll(r.Body)
+	if err != nil || len(bytes.TrimSpace(license)) == 0 {
+		writeError(w, http.StatusBadRequest, types.ErrorCodeInvalidRequest, "license is required", false)
+		return
+	}
+
+	s.Update(func(state *State) {
+		state.UploadedLicense = license
+	})
+	writeJSON(w, http.StatusOK, s.State().LicenseInfo)
+}
+
+func (s *Server) appInfo(w http.ResponseWriter, r *http.Request) {
+	state := s.State()
+	setMockDataHeader(w, state)
+	writeJSON(w, http.StatusOK, state.AppInfo)
+}
+
+func (s *Server) appStatus(w http.ResponseWriter, r *http.Request) {
+	state := s.State()
+	setMockDataHeader(w, state)
+	writeJSON(w, http.StatusOK, types.GetCurrentAppStatusResponse{AppStatus: state.AppStatus})
+}
+
+// streamAppStatus sends the current app status, and then the status after every Update that changes it
+func (s *Server) streamAppStatus(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, types.ErrorCodeInternal, "streaming is not supported", false)
+		return
+	}
+
+	changed := make(chan struct{}, 1)
+	s.mtx.Lock()
+	s.statusWatchers[changed] = struct{}{}
+	state := s.state
+	s.mtx.Unlock()
+
+	defer func() {
+		s.mtx.Lock()
+		delete(s.statusWatchers, changed)
+		s.mtx.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	setMockDataHeader(w, state)
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, "retry: 100\n\n")
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	for {
+		s.mtx.Lock()
+		eventID := strconv.FormatInt(s.statusEventID, 10)
+		appStatus := s.state.AppStatus
+		s.mtx.Unlock()
+
+		if eventID != lastEventID {
+			data, _ := json.Marshal(types.GetCurrentAppStatusResponse{AppStatus: appStatus})
+			fmt.Fprintf(w, "id: %s\nevent: appStatus\ndata: %s\n\n", eventID, data)
+			lastEventID = eventID
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case <-changed:
+		}
+	}
+}
+
+func (s *Server) appUpdates(w http.ResponseWriter, r *http.Request) {
+	if channel := r.URL.Query().Get("channel"); channel != "" && channel != s.State().LicenseChannels.UpdatesChannelID {
+		s.otherChannelUpdates(w, r, channel)
+		return
+	}
+
+	if r.URL.Query().Get("refresh") == "true" {
+		s.Update(func(state *State) {
+			now := time.Now()
+			state.UpdatesLastCheckedAt = &now
+			state.UpdatesStale = false
+		})
+	}
+
+	state := s.State()
+	if state.UpdatesLastCheckedAt != nil {
+		w.Header().Set("X-Replicated-Updates-Last-Checked-At", state.UpdatesLastCheckedAt.UTC().Format(time.RFC3339))
+	}
+	w.Header().Set("X-Replicated-Updates-Stale", strconv.FormatBool(state.UpdatesStale))
+	setCacheHeader(w, state)
+	setMockDataHeader(w, state)
+	opts := upstreamtypes.UpdatesOptions{
+		MaxBump:           upstreamtypes.SemverBump(r.URL.Query().Get("maxBump")),
+		IncludePrerelease: r.URL.Query().Get("includePrerelease") != "false",
+		SemverRequired:    state.LicenseInfo.IsSemverRequired,
+	}
+	writeJSON(w, http.StatusOK, upstreamtypes.SelectUpdates(state.Updates, state.AppInfo.CurrentRelease.VersionLabel, opts))
+}
+
+func (s *Server) otherChannelUpdates(w http.ResponseWriter, r *http.Request, channel string) {
+	state := s.State()
+	releases, ok := state.ChannelUpdates[channel]
+	if !ok {
+		writeError(w, http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("license is not entitled to channel %q", channel), false)
+		return
+	}
+
+	opts := upstreamtypes.UpdatesOptions{
+		MaxBump:           upstreamtypes.SemverBump(r.URL.Query().Get("maxBump")),
+		IncludePrerelease: r.URL.Query().Get("includePrerelease") != "false",
+	}
+	for _, licenseChannel := range state.LicenseChannels.Channels {
+		if licenseChannel.ChannelID == channel {
+			opts.SemverRequired = licenseChannel.IsSemverRequired
+		}
+	}
+	writeJSON(w, http.StatusOK, upstreamtypes.SelectUpdates(releases, state.AppInfo.CurrentRelease.VersionLabel, opts))
+}
+
+func (s *Server) importReleaseIndex(w http.ResponseWriter, r *http.Request) {
+	signedIndex, err := io.ReadAll(r.Body)
+	if err != nil || !json.Valid(signedIndex) {
+		writeError(w, http.StatusBadRequest, types.ErrorCodeInvalidRequest, "invalid release index", false)
+		return
+	}
+
+	s.Update(func(state *State) {
+		state.ImportedReleaseIndex = signedIndex
+	})
+
+	state := s.State()
+	opts := upstreamtypes.UpdatesOptions{
+		IncludePrerelease: true,
+		SemverRequired:    state.LicenseInfo.IsSemverRequired,
+	}
+	writeJSON(w, http.StatusOK, upstreamtypes.SelectUpdates(state.Updates, state.AppInfo.CurrentRelease.VersionLabel, opts))
+}
+
+func (s *Server) appHistory(w http.ResponseWriter, r *http.Request) {
+	state := s.State()
+	setMockDataHeader(w, state)
+	releases := state.History
+	if releases == nil {
+		releases = []types.AppRelease{}
+	}
+	writeJSON(w, http.StatusOK, types.GetAppHistoryResponse{Releases: releases})
+}
+
+func (s *Server) customMetrics(w http.ResponseWriter, r *http.Request) {
+	request := types.SendCustomAppMetricsRequest{}
+	if !s.decodeChange(w, r, &request) {
+		return
+	}
+	if len(request.Data) == 0 {
+		writeError(w, http.StatusBadRequest, types.ErrorCodeInvalidRequest, "no data provided", false)
+		return
+	}
+
+	s.Update(func(state *State) {
+		if r.Method == http.MethodPost || state.CustomMetrics == nil {
+			state.CustomMetrics = types.CustomAppMetricsData{}
+		}
+		for key, value := range request.Data {
+			state.CustomMetrics[key] = value
+		}
+	})
+
+	writeJSON(w, http.StatusOK, "")
+}
+
+func (s *Server) deleteCustomMetric(w http.ResponseWriter, r *http.Request) {
+	if s.State().ReadOnlyMode {
+		writeReadOnlyModeError(w)
+		return
+	}
+
+	key := mux.Vars(r)["key"]
+	s.Update(func(state *State) {
+		delete(state.CustomMetrics, key)
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) instanceTags(w http.ResponseWriter, r *http.Request) {
+	request := types.SendAppInstanceTagsRequest{}
+	if !s.decodeChange(w, r, &request) {
+		return
+	}
+
+	s.Update(func(state *State) {
+		state.InstanceTags = request.Data
+	})
+
+	writeJSON(w, http.StatusOK, "")
+}
+
+func (s *Server) testWebhooks(w http.ResponseWriter, r *http.Request) {
+	state := s.State()
+	if len(state.WebhookResults) == 0 {
+		writeError(w, http.StatusBadRequest, types.ErrorCodeNoWebhooks, "no webhooks are configured", false)
+		return
+	}
+	writeJSON(w, http.StatusOK, types.TestWebhooksResponse{Results: state.WebhookResults})
+}
+
+func (s *Server) webhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	deadLetters := s.State().DeadLetters
+	if deadLetters == nil {
+		deadLetters = []metatypes.WebhookDeadLetter{}
+	}
+	writeJSON(w, http.StatusOK, types.GetWebhookDeadLettersResponse{DeadLetters: deadLetters})
+}
+
+func (s *Server) uploadSupportBundle(w http.ResponseWriter, r *http.Request) {
+	if r.ContentLength <= 0 {
+		writeError(w, http.StatusBadRequest, types.ErrorCodeInvalidRequest, "Content-Length header is required", false)
+		return
+	}
+
+	bundle, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, types.ErrorCodeInvalidRequest, "failed to read support bundle", false)
+		return
+	}
+
+	var bundleNumber int
+	s.Update(func(state *State) {
+		state.SupportBundles = append(state.SupportBundles, bundle)
+		bundleNumber = len(state.SupportBundles)
+	})
+
+	writeJSON(w, http.StatusCreated, types.UploadSupportBundleResponse{
+		BundleID: fmt.Sprintf("fake-bundle-%d", bundleNumber),
+		Slug:     fmt.Sprintf("fake-bundle-%d", bundleNumber),
+	})
+}
+
+func (s *Server) supportBundleMetadata(w http.ResponseWriter, r *http.Request) {
+	request := types.SupportBundleMetadataRequest{}
+	if !s.decodeChange(w, r, &request) {
+		return
+	}
+
+	s.Update(func(state *State) {
+		if r.Method == http.MethodPost || state.SupportBundleMetadata == nil {
+			state.SupportBundleMetadata = map[string]string{}
+		}
+		for key, value := range request.Data {
+			state.SupportBundleMetadata[key] = value
+		}
+	})
+
+	writeJSON(w, http.StatusOK, "")
+}
+
+func (s *Server) setMockData(w http.ResponseWriter, r *http.Request) {
+	if !s.State().IntegrationMode {
+		writeError(w, http.StatusForbidden, types.ErrorCodeIntegrationModeDisabled, "integration mode is not enabled", false)
+		return
+	}
+
+	mockData := json.RawMessage{}
+	if !s.decodeChange(w, r, &mockData) {
+		return
+	}
+
+	s.Update(func(state *State) {
+		state.MockData = mockData
+	})
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) getMockData(w http.ResponseWriter, r *http.Request) {
+	state := s.State()
+	if !state.IntegrationMode {
+		writeError(w, http.StatusForbidden, types.ErrorCodeIntegrationModeDisabled, "integration mode is not enabled", false)
+		return
+	}
+	if state.MockData == nil {
+		writeError(w, http.StatusNotFound, types.ErrorCodeNotFound, "mock data has not been set", false)
+		return
+	}
+	writeJSON(w, http.StatusOK, state.MockData)
+}
+
+func (s *Server) integrationStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, types.GetIntegrationStatusResponse{IsEnabled: s.State().IntegrationMode})
+}
+
+// decodeChange rejects the request in read-only mode, and otherwise decodes its body into v
+func (s *Server) decodeChange(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if s.State().ReadOnlyMode {
+		writeReadOnlyModeError(w)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("invalid request body: %v", err), false)
+		return false
+	}
+	return true
+}
+
+func setCacheHeader(w http.ResponseWriter, state State) {
+	if state.ServedFromCache {
+		w.Header().Set("X-Replicated-Served-From-Cache", "true")
+		w.Header().Set("X-Replicated-Upstream-Error", string(types.ErrorCodeUpstreamUnavailable))
+	}
+}
+
+func setMockDataHeader(w http.ResponseWriter, state State) {
+	if state.IntegrationMode {
+		w.Header().Set("X-Replicated-Mock-Data", "true")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(payload)
+}
+
+func writeError(w http.ResponseWriter, code int, errorCode types.ErrorCode, message string, retryable bool) {
+	if strings.TrimSpace(message) == "" {
+		message = http.StatusText(code)
+	}
+	writeJSON(w, code, types.ErrorResponse{
+		Code:      errorCode,
+		Message:   message,
+		RequestID: w.Header().Get("X-Request-ID"),
+		Retryable: retryable,
+		Error:     message,
+	})
+}
+
+func writeReadOnlyModeError(w http.ResponseWriter) {
+	message := "the SDK is in read-only mode"
+	writeJSON(w, http.StatusUnprocessableEntity, types.ErrorResponse{
+		Code:         types.ErrorCodeReadOnlyMode,
+		Message:      message,
+		RequestID:    w.Header().Get("X-Request-ID"),
+		Error:        message,
+		ReadOnlyMode: true,
+	})
+}
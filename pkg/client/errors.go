@@ -0,0 +1,97 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/openapi"
+	"github.com/replicatedhq/replicated-sdk/pkg/retry"
+)
+
+// maxErrorBodySize limits how much of an error response is read, in case the endpoint is not the SDK
+const maxErrorBodySize = 1 << 20
+
+// Error is returned for responses with an error status code. Code is empty if the response was not an SDK error response.
+type Error struct {
+	StatusCode int
+	Code       types.ErrorCode
+	Message    string
+	RequestID  string
+	Retryable  bool
+	Fields     []openapi.FieldError
+	// RetryAfter is how long the SDK asked to wait before retrying
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("sdk api returned %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("sdk api returned %d (%s): %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *Error) isRetryable() bool {
+	if e.Retryable {
+		return true
+	}
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// newError reads the error response from the body, which is then closed
+func newError(resp *Response) *Error {
+	defer resp.Body.Close()
+
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.RequestID,
+		RetryAfter: retry.ParseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+
+	errorResponse := types.ErrorResponse{}
+	if err := json.Unmarshal(body, &errorResponse); err == nil && (errorResponse.Code != "" || errorResponse.Error != "") {
+		e.Code = errorResponse.Code
+		e.Message = errorResponse.Message
+		if e.Message == "" {
+			e.Message = errorResponse.Error
+		}
+		if errorResponse.RequestID != "" {
+			e.RequestID = errorResponse.RequestID
+		}
+		e.Retryable = errorResponse.Retryable
+		e.Fields = errorResponse.Fields
+		return e
+	}
+
+	e.Message = strings.TrimSpace(string(body))
+	if e.Message == "" {
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+	return e
+}
+
+// ErrorCode returns the code of the SDK error response that caused err, or an empty code if there is none
+func ErrorCode(err error) types.ErrorCode {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// IsNotFound returns true if err was caused by a 404 response
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	diagnosticstypes "github.com/replicatedhq/replicated-sdk/pkg/diagnostics/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/openapi"
+	"github.com/replicatedhq/replicated-sdk/pkg/readiness"
+)
+
+func (c *Client) Healthz(ctx context.Context) (*types.HealthzResponse, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/healthz", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	healthz := &types.HealthzResponse{}
+	resp, err := c.do(req, healthz)
+	if err != nil {
+		return nil, resp, err
+	}
+	return healthz, resp, nil
+}
+
+// Readyz returns the bootstrap status of the SDK. It is not an error for the SDK to not be ready yet, so the request is not retried.
+func (c *Client) Readyz(ctx context.Context) (*readiness.Status, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/readyz", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to send GET request")
+	}
+	resp := newResponse(httpResp)
+
+	// the status is returned along with a 503 until the SDK is ready
+	if httpResp.StatusCode != http.StatusOK && httpResp.StatusCode != http.StatusServiceUnavailable {
+		return nil, resp, newError(resp)
+	}
+	defer httpResp.Body.Close()
+
+	status := &readiness.Status{}
+	if err := json.NewDecoder(httpResp.Body).Decode(status); err != nil {
+		return nil, resp, errors.Wrap(err, "failed to decode response body")
+	}
+	return status, resp, nil
+}
+
+// GetMetrics returns the SDK metrics in the Prometheus text format
+func (c *Client) GetMetrics(ctx context.Context) ([]byte, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/metrics", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "text/plain")
+
+	var metrics []byte
+	resp, err := c.do(req, &metrics)
+	if err != nil {
+		return nil, resp, err
+	}
+	return metrics, resp, nil
+}
+
+// GetOpenAPIDocument returns the OpenAPI document that describes the SDK API
+func (c *Client) GetOpenAPIDocument(ctx context.Context) (*openapi.Document, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/openapi.json", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc := &openapi.Document{}
+	resp, err := c.do(req, doc)
+	if err != nil {
+		return nil, resp, err
+	}
+	return doc, resp, nil
+}
+
+// GetDiagnostics returns the permissions and environment checks of the SDK, and the features that are disabled as a result
+func (c *Client) GetDiagnostics(ctx context.Context) (*diagnosticstypes.Report, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/diagnostics", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := &diagnosticstypes.Report{}
+	resp, err := c.do(req, report)
+	if err != nil {
+		return nil, resp, err
+	}
+	return report, resp, nil
+}
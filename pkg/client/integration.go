@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	integrationtypes "github.com/replicatedhq/replicated-sdk/pkg/integration/types"
+)
+
+// SetMockData replaces the data that is returned in integration mode
+func (c *Client) SetMockData(ctx context.Context, mockData integrationtypes.MockData) (*Response, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, "/api/v1/integration/mock-data", nil, mockData)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req, nil)
+}
+
+// GetMockData returns the mock data, which is either a *integrationtypes.MockDataV1 or a *integrationtypes.MockDataV2
+func (c *Client) GetMockData(ctx context.Context) (integrationtypes.MockData, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/integration/mock-data", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var b []byte
+	resp, err := c.do(req, &b)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	version := integrationtypes.MockDataVersion{}
+	if err := json.Unmarshal(b, &version); err != nil {
+		return nil, resp, errors.Wrap(err, "failed to unmarshal mock data version")
+	}
+
+	var mockData integrationtypes.MockData
+	switch version.Version {
+	case "v1", "":
+		mockData = &integrationtypes.MockDataV1{}
+	case "v2":
+		mockData = &integrationtypes.MockDataV2{}
+	default:
+		return nil, resp, errors.Errorf("unknown mock data version: %s", version.Version)
+	}
+
+	if err := json.Unmarshal(b, mockData); err != nil {
+		return nil, resp, errors.Wrapf(err, "failed to unmarshal mock data %s", version.Version)
+	}
+	return mockData, resp, nil
+}
+
+func (c *Client) GetIntegrationStatus(ctx context.Context) (*types.GetIntegrationStatusResponse, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/integration/status", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status := &types.GetIntegrationStatusResponse{}
+	resp, err := c.do(req, status)
+	if err != nil {
+		return nil, resp, err
+	}
+	return status, resp, nil
+}
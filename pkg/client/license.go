@@ -0,0 +1,109 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+)
+
+func (c *Client) GetLicenseInfo(ctx context.Context) (*types.LicenseInfo, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/license/info", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	licenseInfo := &types.LicenseInfo{}
+	resp, err := c.do(req, licenseInfo)
+	if err != nil {
+		return nil, resp, err
+	}
+	return licenseInfo, resp, nil
+}
+
+// GetLicenseChannels returns the channels that the license is entitled to, and the channels that the app is installed from and gets updates from
+func (c *Client) GetLicenseChannels(ctx context.Context) (*types.GetLicenseChannelsResponse, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/license/channels", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	channels := &types.GetLicenseChannelsResponse{}
+	resp, err := c.do(req, channels)
+	if err != nil {
+		return nil, resp, err
+	}
+	return channels, resp, nil
+}
+
+func (c *Client) GetLicenseFields(ctx context.Context) (sdklicensetypes.LicenseFields, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/license/fields", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	licenseFields := sdklicensetypes.LicenseFields{}
+	resp, err := c.do(req, &licenseFields)
+	if err != nil {
+		return nil, resp, err
+	}
+	return licenseFields, resp, nil
+}
+
+// GetLicenseField returns a single license field. Use IsNotFound to check if the license does not have the field.
+func (c *Client) GetLicenseField(ctx context.Context, fieldName string) (*sdklicensetypes.LicenseField, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/license/fields/"+url.PathEscape(fieldName), nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	licenseField := &sdklicensetypes.LicenseField{}
+	resp, err := c.do(req, licenseField)
+	if err != nil {
+		return nil, resp, err
+	}
+	return licenseField, resp, nil
+}
+
+// EvaluateEntitlements runs the checks against the license fields. A check that can't be evaluated, e.g. because the field
+// does not exist, fails with an error in its result rather than failing the request.
+func (c *Client) EvaluateEntitlements(ctx context.Context, checks []sdklicensetypes.EntitlementCheck) (*types.EvaluateEntitlementsResponse, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, "/api/v1/license/entitlements/evaluate", nil, types.EvaluateEntitlementsRequest{Checks: checks})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response := &types.EvaluateEntitlementsResponse{}
+	resp, err := c.do(req, response)
+	if err != nil {
+		return nil, resp, err
+	}
+	return response, resp, nil
+}
+
+// UploadLicense replaces the license with the given license YAML, e.g. with a renewed license in an airgap install.
+// It requires a token with the write-license scope. The license is rejected with a license_rejected error if it is not
+// the current license with the same or a later sequence, or if its signature is not valid.
+func (c *Client) UploadLicense(ctx context.Context, license []byte) (*types.LicenseInfo, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodPut, "/api/v1/license", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/yaml")
+	req.ContentLength = int64(len(license))
+	req.Body = io.NopCloser(bytes.NewReader(license))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(license)), nil
+	}
+
+	licenseInfo := &types.LicenseInfo{}
+	resp, err := c.do(req, licenseInfo)
+	if err != nil {
+		return nil, resp, err
+	}
+	return licenseInfo, resp, nil
+}
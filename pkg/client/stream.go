@@ -0,0 +1,152 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/retry"
+)
+
+const (
+	appStatusEventName = "appStatus"
+	// defaultStreamRetry is the reconnection delay until the SDK sends its own
+	defaultStreamRetry = 5 * time.Second
+)
+
+// AppStatusEvent is a change of the app status. ID can be passed to StreamAppStatus to resume the stream after it.
+type AppStatusEvent struct {
+	ID        string
+	AppStatus appstatetypes.AppStatus
+}
+
+// StreamAppStatus calls fn with the current app status and then with every change to it, until ctx is done or fn returns an error.
+// The stream is resumed after lastEventID, or starts with the current status if it is empty.
+// Dropped connections are reconnected after the delay requested by the SDK, without repeating events that were already received.
+func (c *Client) StreamAppStatus(ctx context.Context, lastEventID string, fn func(AppStatusEvent) error) error {
+	s := &appStatusStream{
+		client:      c,
+		lastEventID: lastEventID,
+		retry:       defaultStreamRetry,
+		fn:          fn,
+	}
+
+	for {
+		err := s.receive(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var callbackErr *streamCallbackError
+		if errors.As(err, &callbackErr) {
+			return callbackErr.err
+		}
+		var apiErr *Error
+		if errors.As(err, &apiErr) && !apiErr.isRetryable() {
+			return err
+		}
+
+		if err := retry.Wait(ctx, s.retry); err != nil {
+			return err
+		}
+	}
+}
+
+type appStatusStream struct {
+	client      *Client
+	lastEventID string
+	retry       time.Duration
+	fn          func(AppStatusEvent) error
+}
+
+// streamCallbackError wraps an error returned by the callback, so that it is not mistaken for a stream error
+type streamCallbackError struct {
+	err error
+}
+
+func (e *streamCallbackError) Error() string {
+	return e.err.Error()
+}
+
+// receive reads events from a single connection until it is closed
+func (s *appStatusStream) receive(ctx context.Context) error {
+	req, err := s.client.newRequest(ctx, http.MethodGet, "/api/v1/app/status/stream", nil, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if s.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", s.lastEventID)
+	}
+
+	resp, err := s.client.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var id, event string
+	var data []string
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if event == appStatusEventName && len(data) > 0 {
+				if err := s.dispatch(id, strings.Join(data, "\n")); err != nil {
+					return err
+				}
+			}
+			id, event, data = "", "", nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			// heartbeat
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			id = value
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed to read app status stream")
+	}
+	return nil
+}
+
+func (s *appStatusStream) dispatch(id string, data string) error {
+	appStatus := types.GetCurrentAppStatusResponse{}
+	if err := json.Unmarshal([]byte(data), &appStatus); err != nil {
+		return errors.Wrap(err, "failed to decode app status event")
+	}
+
+	if id != "" {
+		s.lastEventID = id
+	}
+
+	if err := s.fn(AppStatusEvent{ID: id, AppStatus: appStatus.AppStatus}); err != nil {
+		return &streamCallbackError{err: err}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+)
+
+// UploadSupportBundle streams size bytes of a support bundle archive from r to the SDK, which uploads it to the vendor portal.
+// The upload is not retried, since the bundle may have been uploaded to the vendor portal before the request failed.
+func (c *Client) UploadSupportBundle(ctx context.Context, r io.Reader, size int64) (*types.UploadSupportBundleResponse, *Response, error) {
+	if size <= 0 {
+		return nil, nil, errors.New("support bundle size must be greater than zero")
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/api/v1/supportbundle", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	req.ContentLength = size
+	req.Body = io.NopCloser(io.LimitReader(r, size))
+
+	uploaded := &types.UploadSupportBundleResponse{}
+	resp, err := c.do(req, uploaded)
+	if err != nil {
+		return nil, resp, err
+	}
+	return uploaded, resp, nil
+}
+
+// UploadSupportBundleFile uploads the support bundle archive at path
+func (c *Client) UploadSupportBundleFile(ctx context.Context, path string) (*types.UploadSupportBundleResponse, *Response, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to open support bundle")
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to stat support bundle")
+	}
+
+	return c.UploadSupportBundle(ctx, f, fi.Size())
+}
+
+// SetSupportBundleMetadata replaces the metadata that is included in support bundles with data
+func (c *Client) SetSupportBundleMetadata(ctx context.Context, data map[string]string) (*Response, error) {
+	return c.sendSupportBundleMetadata(ctx, http.MethodPost, data)
+}
+
+// UpdateSupportBundleMetadata sets the support bundle metadata keys in data, and keeps the others
+func (c *Client) UpdateSupportBundleMetadata(ctx context.Context, data map[string]string) (*Response, error) {
+	return c.sendSupportBundleMetadata(ctx, http.MethodPatch, data)
+}
+
+func (c *Client) sendSupportBundleMetadata(ctx context.Context, method string, data map[string]string) (*Response, error) {
+	req, err := c.newRequest(ctx, method, "/api/v1/supportbundle/metadata", nil, types.SupportBundleMetadataRequest{Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req, nil)
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+)
+
+// TestWebhooks sends a sample event to every configured webhook and returns the outcome of each delivery.
+// It requires a token with the webhooks scope.
+func (c *Client) TestWebhooks(ctx context.Context) (*types.TestWebhooksResponse, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, "/api/v1/webhooks/test", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := &types.TestWebhooksResponse{}
+	resp, err := c.do(req, results)
+	if err != nil {
+		return nil, resp, err
+	}
+	return results, resp, nil
+}
+
+// GetWebhookDeadLetters returns the most recent events that could not be delivered.
+// It requires a token with the webhooks scope.
+func (c *Client) GetWebhookDeadLetters(ctx context.Context) (*types.GetWebhookDeadLettersResponse, *Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/v1/webhooks/dead-letters", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deadLetters := &types.GetWebhookDeadLettersResponse{}
+	resp, err := c.do(req, deadLetters)
+	if err != nil {
+		return nil, resp, err
+	}
+	return deadLetters, resp, nil
+}
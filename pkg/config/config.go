@@ -4,28 +4,56 @@ import (
 	"github.com/pkg/errors"
 	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
 	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	webhooktypes "github.com/replicatedhq/replicated-sdk/pkg/webhook/types"
 	"gopkg.in/yaml.v2"
 )
 
 type ReplicatedConfig struct {
-	License               string                               `yaml:"license"`
-	LicenseFields         sdklicensetypes.LicenseFields        `yaml:"licenseFields"`
-	AppName               string                               `yaml:"appName"`
-	ChannelID             string                               `yaml:"channelID"`
-	ChannelName           string                               `yaml:"channelName"`
-	ChannelSequence       int64                                `yaml:"channelSequence"`
-	ReleaseSequence       int64                                `yaml:"releaseSequence"`
-	ReleaseCreatedAt      string                               `yaml:"releaseCreatedAt"`
-	ReleaseNotes          string                               `yaml:"releaseNotes"`
-	VersionLabel          string                               `yaml:"versionLabel"`
-	ReplicatedAppEndpoint string                               `yaml:"replicatedAppEndpoint"`
-	ReleaseImages         []string                             `yaml:"releaseImages"`
-	StatusInformers       []appstatetypes.StatusInformerString `yaml:"statusInformers"`
-	ReplicatedID          string                               `yaml:"replicatedID"`
-	AppID                 string                               `yaml:"appID"`
-	TlsCertSecretName     string                               `yaml:"tlsCertSecretName"`
-	ReportAllImages       bool                                 `yaml:"reportAllImages"`
-	ReadOnlyMode          bool                                 `yaml:"readOnlyMode"`
+	License                    string                               `yaml:"license"`
+	LicenseFields              sdklicensetypes.LicenseFields        `yaml:"licenseFields"`
+	AppName                    string                               `yaml:"appName"`
+	ChannelID                  string                               `yaml:"channelID"`
+	ChannelName                string                               `yaml:"channelName"`
+	ChannelSequence            int64                                `yaml:"channelSequence"`
+	ReleaseSequence            int64                                `yaml:"releaseSequence"`
+	ReleaseCreatedAt           string                               `yaml:"releaseCreatedAt"`
+	ReleaseNotes               string                               `yaml:"releaseNotes"`
+	VersionLabel               string                               `yaml:"versionLabel"`
+	ReplicatedAppEndpoint      string                               `yaml:"replicatedAppEndpoint"`
+	ReleaseImages              []string                             `yaml:"releaseImages"`
+	StatusInformers            []appstatetypes.StatusInformerString `yaml:"statusInformers"`
+	ReplicatedID               string                               `yaml:"replicatedID"`
+	AppID                      string                               `yaml:"appID"`
+	TlsCertSecretName          string                               `yaml:"tlsCertSecretName"`
+	TlsVerifyClientCerts       bool                                 `yaml:"tlsVerifyClientCerts"`
+	ReportAllImages            bool                                 `yaml:"reportAllImages"`
+	ReadOnlyMode               bool                                 `yaml:"readOnlyMode"`
+	APITokensSecretName        string                               `yaml:"apiTokensSecretName"`
+	AllowUnauthenticatedAPI    bool                                 `yaml:"allowUnauthenticatedAPI"`
+	Webhooks                   []webhooktypes.Target                `yaml:"webhooks"`
+	LicenseExpiringSoonDays    int                                  `yaml:"licenseExpiringSoonDays"`
+	LicenseGracePeriodDays     int                                  `yaml:"licenseGracePeriodDays"`
+	StrictLicenseFields        bool                                 `yaml:"strictLicenseFields"`
+	LicenseSource              *sdklicensetypes.LicenseSource       `yaml:"licenseSource"`
+	UpdateCheckIntervalMinutes int                                  `yaml:"updateCheckIntervalMinutes"`
+	Proxy                      *ProxyConfig                         `yaml:"proxy"`
+	PrivateCASecret            *PrivateCASecret                     `yaml:"privateCASecret"`
+}
+
+// ProxyConfig is the proxy that requests to the Replicated upstream are made through.
+// Settings that are not set fall back to the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
+type ProxyConfig struct {
+	HTTPSProxy string `yaml:"httpsProxy"`
+	HTTPProxy  string `yaml:"httpProxy"`
+	NoProxy    string `yaml:"noProxy"`
+}
+
+// PrivateCASecret is a secret in the namespace of the SDK with a CA bundle that is trusted for requests to the
+// Replicated upstream, in addition to the system certificate authorities
+type PrivateCASecret struct {
+	Name string `yaml:"name"`
+	// Key is the key of the CA bundle in the secret, "ca.crt" if not set
+	Key string `yaml:"key"`
 }
 
 func ParseReplicatedConfig(config []byte) (*ReplicatedConfig, error) {
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	licensewrappertypes "github.com/replicatedhq/kotskinds/pkg/licensewrapper/types"
+	sdklicense "github.com/replicatedhq/replicated-sdk/pkg/license"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var (
+	typeErrorRegexp    = regexp.MustCompile(`^line (\d+): (.*)$`)
+	unknownFieldRegexp = regexp.MustCompile(`^field (\S+) not found in type \S+$`)
+)
+
+// ValidationError is a problem found in the config file. Line and Column are 1-based, and 0 when unknown.
+type ValidationError struct {
+	Line    int
+	Column  int
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	var b strings.Builder
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "line %d", e.Line)
+		if e.Column > 0 {
+			fmt.Fprintf(&b, ", column %d", e.Column)
+		}
+		b.WriteString(": ")
+	}
+	if e.Field != "" {
+		fmt.Fprintf(&b, "%s: ", e.Field)
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := []string{}
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// ValidateReplicatedConfig validates the config file against the ReplicatedConfig schema, decoding it strictly with the
+// same yaml decoder that ParseReplicatedConfig uses. Unknown fields, values of the wrong type, malformed status informers,
+// an invalid or incorrectly signed license, invalid endpoint, webhook and proxy urls, an invalid license source, and a
+// private CA secret without a name are returned as a ValidationErrors error. If allowUnknownFields is set, unknown fields
+// are returned as warnings instead, since the config file is still parsed without them. Any other error means that the
+// config file could not be parsed as yaml.
+func ValidateReplicatedConfig(config []byte, allowUnknownFields bool) (ValidationErrors, error) {
+	warnings := ValidationErrors{}
+	errs := ValidationErrors{}
+
+	var rc ReplicatedConfig
+	if err := yaml.UnmarshalStrict(config, &rc); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, errors.Wrap(err, "failed to parse config file")
+		}
+		for _, msg := range typeErr.Errors {
+			validationErr := newTypeValidationError(msg)
+			if matches := unknownFieldRegexp.FindStringSubmatch(validationErr.Message); len(matches) == 2 {
+				validationErr.Message = fmt.Sprintf("unknown field %q", matches[1])
+				if allowUnknownFields {
+					warnings = append(warnings, validationErr)
+					continue
+				}
+			}
+			errs = append(errs, validationErr)
+		}
+	}
+
+	// the decoded values are validated below, the node tree is only used to report where they are in the file
+	var root yamlv3.Node
+	doc := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(config, &root); err == nil && len(root.Content) > 0 {
+		doc = root.Content[0]
+	}
+
+	if rc.License != "" {
+		if err := validateLicense(rc.License); err != nil {
+			errs = append(errs, newValidationError(mappingValue(doc, "license"), "license", err.Error()))
+		}
+	}
+
+	if rc.ReplicatedAppEndpoint != "" {
+		if err := validateEndpoint(rc.ReplicatedAppEndpoint); err != nil {
+			errs = append(errs, newValidationError(mappingValue(doc, "replicatedAppEndpoint"), "replicatedAppEndpoint", err.Error()))
+		}
+	}
+
+	for i, informer := range rc.StatusInformers {
+		if _, err := informer.Parse(); err != nil {
+			errs = append(errs, newValidationError(sequenceItem(mappingValue(doc, "statusInformers"), i), fmt.Sprintf("statusInformers[%d]", i), fmt.Sprintf("invalid status informer %q, expected [namespace/]kind/name", informer)))
+		}
+	}
+
+	for i, target := range rc.Webhooks {
+		if err := target.Validate(); err != nil {
+			errs = append(errs, newValidationError(sequenceItem(mappingValue(doc, "webhooks"), i), fmt.Sprintf("webhooks[%d]", i), err.Error()))
+		}
+	}
+
+	if rc.LicenseSource != nil {
+		if err := rc.LicenseSource.Validate(); err != nil {
+			errs = append(errs, newValidationError(mappingValue(doc, "licenseSource"), "licenseSource", err.Error()))
+		}
+	}
+
+	if rc.Proxy != nil {
+		proxyNode := mappingValue(doc, "proxy")
+		for _, field := range []struct {
+			name  string
+			value string
+		}{
+			{name: "httpsProxy", value: rc.Proxy.HTTPSProxy},
+			{name: "httpProxy", value: rc.Proxy.HTTPProxy},
+		} {
+			if field.value == "" {
+				continue
+			}
+			if err := validateProxyURL(field.value); err != nil {
+				errs = append(errs, newValidationError(mappingValue(proxyNode, field.name), "proxy."+field.name, err.Error()))
+			}
+		}
+	}
+
+	if rc.PrivateCASecret != nil && rc.PrivateCASecret.Name == "" {
+		errs = append(errs, newValidationError(mappingValue(doc, "privateCASecret"), "privateCASecret", "name is required"))
+	}
+
+	for _, field := range []struct {
+		name  string
+		value int
+	}{
+		{name: "licenseExpiringSoonDays", value: rc.LicenseExpiringSoonDays},
+		{name: "licenseGracePeriodDays", value: rc.LicenseGracePeriodDays},
+		{name: "updateCheckIntervalMinutes", value: rc.UpdateCheckIntervalMinutes},
+	} {
+		if field.value < 0 {
+			errs = append(errs, newValidationError(mappingValue(doc, field.name), field.name, "must not be negative"))
+		}
+	}
+
+	if len(errs) > 0 {
+		return warnings, errs
+	}
+	return warnings, nil
+}
+
+func validateLicense(license string) error {
+	wrapper, err := sdklicense.LoadLicenseFromBytes([]byte(license))
+	if err != nil {
+		return errors.Wrap(err, "failed to parse license")
+	}
+	if err := wrapper.VerifySignature(); err != nil && !licensewrappertypes.IsLicenseDataValidationError(err) {
+		// data validation errors are not fatal at startup, the data inside the signature is used instead
+		return errors.Wrap(err, "failed to verify license signature")
+	}
+	return nil
+}
+
+func validateEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return errors.Wrap(err, "invalid url")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("url must be http or https")
+	}
+	if u.Host == "" {
+		return errors.New("url must have a host")
+	}
+	return nil
+}
+
+func validateProxyURL(proxyURL string) error {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return errors.Wrap(err, "invalid url")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+		return errors.New("url must be http, https or socks5")
+	}
+	if u.Host == "" {
+		return errors.New("url must have a host")
+	}
+	return nil
+}
+
+func mappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	if node == nil || node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func sequenceItem(node *yamlv3.Node, i int) *yamlv3.Node {
+	if node == nil || node.Kind != yamlv3.SequenceNode || i >= len(node.Content) {
+		return nil
+	}
+	return node.Content[i]
+}
+
+// newValidationError returns a ValidationError at the position of the node, which is unknown if the node is nil
+func newValidationError(node *yamlv3.Node, field string, message string) ValidationError {
+	if node == nil {
+		return ValidationError{Field: field, Message: message}
+	}
+	return ValidationError{
+		Line:    node.Line,
+		Column:  node.Column,
+		Field:   field,
+		Message: message,
+	}
+}
+
+// newTypeValidationError converts a yaml type error message, which only has a line number, to a ValidationError
+func newTypeValidationError(msg string) ValidationError {
+	matches := typeErrorRegexp.FindStringSubmatch(msg)
+	if len(matches) != 3 {
+		return ValidationError{Message: msg}
+	}
+	line, _ := strconv.Atoi(matches[1])
+	return ValidationError{
+		Line:    line,
+		Message: matches[2],
+	}
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateReplicatedConfig(t *testing.T) {
+	tests := []struct {
+		name               string
+		config             string
+		allowUnknownFields bool
+		wantWarnings       string
+		wantErr            string
+	}{
+		{
+			name: "valid config",
+			config: `appName: test-app
+replicatedAppEndpoint: https://replicated.app
+statusInformers:
+- deployment/web
+- my-namespace/statefulset/db
+licenseFields:
+  seats:
+    name: seats
+    value: 10
+    signature:
+      v1: abc
+webhooks:
+- name: ops
+  url: https://example.com/hook
+  secret: s3cr3t
+  events:
+  - app.stateChanged
+licenseSource:
+  secretName: my-license
+proxy:
+  httpsProxy: http://proxy.example.com:3128
+  noProxy: localhost,.svc
+privateCASecret:
+  name: my-ca
+`,
+		},
+		{
+			name:   "empty config",
+			config: "",
+		},
+		{
+			name: "unknown fields",
+			config: `appName: test-app
+statusInfomers:
+- deployment/web
+licenseFields:
+  seats:
+    valu: 10
+`,
+			wantErr: `line 2: unknown field "statusInfomers"
+line 6: unknown field "valu"`,
+		},
+		{
+			name: "allowed unknown fields",
+			config: `appName: test-app
+statusInfomers:
+- deployment/web
+licenseFields:
+  seats:
+    valu: 10
+`,
+			allowUnknownFields: true,
+			wantWarnings: `line 2: unknown field "statusInfomers"
+line 6: unknown field "valu"`,
+		},
+		{
+			name: "allowed unknown fields and wrong types",
+			config: `appName: test-app
+channelSequence: abc
+statusInfomers:
+- deployment/web
+`,
+			allowUnknownFields: true,
+			wantWarnings:       `line 3: unknown field "statusInfomers"`,
+			wantErr:            "line 2: cannot unmarshal !!str `abc` into int64",
+		},
+		{
+			name: "wrong types",
+			config: `appName: test-app
+channelSequence: abc
+readOnlyMode: maybe
+`,
+			wantErr: "line 2: cannot unmarshal !!str `abc` into int64\nline 3: cannot unmarshal !!str `maybe` into bool",
+		},
+		{
+			name: "invalid status informers",
+			config: `statusInformers:
+- deployment/web
+- web
+- a/b/c/d
+`,
+			wantErr: `line 3, column 3: statusInformers[1]: invalid status informer "web", expected [namespace/]kind/name
+line 4, column 3: statusInformers[2]: invalid status informer "a/b/c/d", expected [namespace/]kind/name`,
+		},
+		{
+			name:    "invalid endpoint",
+			config:  `replicatedAppEndpoint: replicated.app`,
+			wantErr: `line 1, column 24: replicatedAppEndpoint: url must be http or https`,
+		},
+		{
+			name: "invalid webhook",
+			config: `webhooks:
+- name: ops
+  url: ftp://example.com
+  secret: s3cr3t
+`,
+			wantErr: `line 2, column 3: webhooks[0]: webhook ops url must be http or https`,
+		},
+		{
+			name: "license source with both a path and a secret",
+			config: `licenseSource:
+  path: /etc/replicated/license.yaml
+  secretName: my-license
+`,
+			wantErr: `line 2, column 3: licenseSource: only one of path or secretName can be set`,
+		},
+		{
+			name: "invalid proxy",
+			config: `proxy:
+  httpsProxy: proxy.example.com:3128
+  httpProxy: ftp://proxy.example.com
+`,
+			wantErr: `line 2, column 15: proxy.httpsProxy: url must be http, https or socks5
+line 3, column 14: proxy.httpProxy: url must be http, https or socks5`,
+		},
+		{
+			name: "private ca secret without a name",
+			config: `privateCASecret:
+  key: ca.pem
+`,
+			wantErr: `line 2, column 3: privateCASecret: name is required`,
+		},
+		{
+			name: "negative license expiration days",
+			config: `licenseExpiringSoonDays: 14
+licenseGracePeriodDays: -1
+`,
+			wantErr: `line 2, column 25: licenseGracePeriodDays: must not be negative`,
+		},
+		{
+			name:    "negative update check interval",
+			config:  "updateCheckIntervalMinutes: -5\n",
+			wantErr: `line 1, column 29: updateCheckIntervalMinutes: must not be negative`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+			warnings, err := ValidateReplicatedConfig([]byte(tt.config), tt.allowUnknownFields)
+			if tt.wantWarnings == "" {
+				req.Empty(warnings)
+			} else {
+				req.EqualError(warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				req.NoError(err)
+				return
+			}
+			req.EqualError(err, tt.wantErr)
+			req.IsType(ValidationErrors{}, err)
+		})
+	}
+}
+
+func TestValidateReplicatedConfig_InvalidLicense(t *testing.T) {
+	req := require.New(t)
+
+	_, err := ValidateReplicatedConfig([]byte(`appName: test-app
+license: "{not a license"
+`), false)
+	req.Error(err)
+
+	validationErrs, ok := err.(ValidationErrors)
+	req.True(ok)
+	req.Len(validationErrs, 1)
+	req.Equal(2, validationErrs[0].Line)
+	req.Equal(10, validationErrs[0].Column)
+	req.Equal("license", validationErrs[0].Field)
+	req.Contains(validationErrs[0].Message, "failed to parse license")
+}
+
+func TestValidateReplicatedConfig_SyntaxError(t *testing.T) {
+	_, err := ValidateReplicatedConfig([]byte("appName: [test-app"), false)
+	require.ErrorContains(t, err, "failed to parse config file")
+}
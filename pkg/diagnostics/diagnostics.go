@@ -0,0 +1,447 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/replicatedhq/replicated-sdk/pkg/appstate"
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/diagnostics/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/meta"
+	"github.com/replicatedhq/replicated-sdk/pkg/util"
+	authv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	featureIntegrationMode       = "integration mode"
+	featureInstanceReporting     = "instance reporting"
+	featureImageReporting        = "running image reporting"
+	featureAllNamespaceImages    = "running image reporting in all namespaces"
+	featureCustomMetrics         = "custom metrics"
+	featureInstanceTags          = "instance tags"
+	featureAirgapReports         = "airgap reports"
+	featureSupportBundleMetadata = "support bundle metadata"
+	featureDistribution          = "kubernetes distribution detection"
+	featureTLSReload             = "tls certificate reload"
+	featureAPITokens             = "api token authentication"
+	featureAppHistory            = "app history"
+	featureHelmInformers         = "status informers from the helm release"
+	featureHelmChartURL          = "helm chart url in app info"
+	featureAppStatus             = "app status"
+	featureLicenseReload         = "license reload"
+	featureLicenseUpload         = "license upload"
+	featureReleaseIndexImport    = "release index import"
+	featurePrivateCA             = "private certificate authorities"
+)
+
+// Options describe how the SDK is configured, which determines the permissions and environment it needs
+type Options struct {
+	Namespace           string
+	StatusInformers     []appstatetypes.StatusInformerString
+	ReadOnlyMode        bool
+	ReportAllImages     bool
+	TlsCertSecretName   string
+	APITokensSecretName string
+	// LicenseSourceSecretName is the secret that the license is reloaded from, if the license source is a secret
+	LicenseSourceSecretName string
+	// PrivateCASecretName is the secret with the certificate authorities that are trusted for upstream requests
+	PrivateCASecretName string
+	// Getenv reads the environment variables of the SDK, and defaults to os.Getenv
+	Getenv func(key string) string
+}
+
+type resourceAccess struct {
+	group     string
+	resource  string
+	name      string
+	namespace string
+	verbs     []string
+}
+
+// permission is an access that the SDK needs, and what happens when it doesn't have it
+type permission struct {
+	access   []resourceAccess
+	severity types.Status
+	features []string
+}
+
+// informerResources are the resources that the informer of each kind reads, in addition to the resource itself
+var informerResources = map[string][]resourceAccess{
+	appstate.DeploymentResourceKind:            {{group: "apps", resource: "deployments", verbs: []string{"list", "watch"}}},
+	appstate.StatefulSetResourceKind:           {{group: "apps", resource: "statefulsets", verbs: []string{"list", "watch"}}, {resource: "pods", verbs: []string{"list"}}},
+	appstate.DaemonSetResourceKind:             {{group: "apps", resource: "daemonsets", verbs: []string{"list", "watch"}}, {resource: "pods", verbs: []string{"list"}}},
+	appstate.ServiceResourceKind:               {{resource: "services", verbs: []string{"list", "watch"}}, {group: "discovery.k8s.io", resource: "endpointslices", verbs: []string{"list"}}},
+	appstate.IngressResourceKind:               {{group: "networking.k8s.io", resource: "ingresses", verbs: []string{"list", "watch"}}, {resource: "services", verbs: []string{"get"}}, {group: "discovery.k8s.io", resource: "endpointslices", verbs: []string{"list"}}},
+	appstate.PersistentVolumeClaimResourceKind: {{resource: "persistentvolumeclaims", verbs: []string{"list", "watch"}}},
+}
+
+// Run checks the permissions and environment that the SDK needs, and reports which features are disabled as a result
+func Run(ctx context.Context, clientset kubernetes.Interface, opts Options) types.Report {
+	if opts.Getenv == nil {
+		opts.Getenv = os.Getenv
+	}
+
+	checks := environmentChecks(opts)
+	checks = append(checks, informerChecks(opts)...)
+	for _, p := range permissions(opts) {
+		checks = append(checks, checkPermission(ctx, clientset, p))
+	}
+
+	return newReport(checks)
+}
+
+func newReport(checks []types.Check) types.Report {
+	report := types.Report{
+		Status:           types.StatusPass,
+		Checks:           checks,
+		DisabledFeatures: []string{},
+	}
+
+	disabled := map[string]struct{}{}
+	for _, check := range checks {
+		report.Status = types.Worse(report.Status, check.Status)
+		if check.Status == types.StatusPass {
+			continue
+		}
+		for _, feature := range check.DisabledFeatures {
+			disabled[feature] = struct{}{}
+		}
+	}
+	for feature := range disabled {
+		report.DisabledFeatures = append(report.DisabledFeatures, feature)
+	}
+	sort.Strings(report.DisabledFeatures)
+
+	return report
+}
+
+func environmentChecks(opts Options) []types.Check {
+	checks := []types.Check{}
+
+	add := func(name string, status types.Status, message string, features ...string) {
+		check := types.Check{
+			Category: types.CategoryEnvironment,
+			Name:     name,
+			Status:   status,
+			Message:  message,
+		}
+		if status != types.StatusPass {
+			check.DisabledFeatures = features
+		}
+		checks = append(checks, check)
+	}
+
+	if opts.Namespace == "" {
+		add("REPLICATED_NAMESPACE", types.StatusFail, "the namespace of the SDK is not set", featureInstanceReporting, featureAppStatus, featureCustomMetrics, featureInstanceTags)
+	} else {
+		add("REPLICATED_NAMESPACE", types.StatusPass, fmt.Sprintf("using namespace %s", opts.Namespace))
+	}
+
+	if podName := opts.Getenv("REPLICATED_POD_NAME"); podName == "" {
+		add("REPLICATED_POD_NAME", types.StatusWarn, "not set, so the SDK can't find its own pod", featureInstanceReporting)
+	} else {
+		add("REPLICATED_POD_NAME", types.StatusPass, fmt.Sprintf("using pod %s", podName))
+	}
+
+	add("REPLICATED_SECRET_NAME", types.StatusPass, fmt.Sprintf("using secret %s", util.GetReplicatedSecretName()))
+	add("REPLICATED_DEPLOYMENT_NAME", types.StatusPass, fmt.Sprintf("using deployment %s", util.GetReplicatedDeploymentName()))
+
+	if opts.Getenv("DISABLE_OUTBOUND_CONNECTIONS") == "true" {
+		add("DISABLE_OUTBOUND_CONNECTIONS", types.StatusPass, "airgap mode, reports are stored in secrets")
+	} else {
+		add("DISABLE_OUTBOUND_CONNECTIONS", types.StatusPass, "online mode, reports are sent to the Replicated API")
+	}
+
+	if opts.Getenv("IS_HELM_MANAGED") != "true" {
+		features := []string{featureAppHistory, featureHelmChartURL}
+		if len(opts.StatusInformers) == 0 {
+			features = append(features, featureHelmInformers)
+		}
+		add("IS_HELM_MANAGED", types.StatusWarn, "the SDK is not managed by helm", features...)
+		return checks
+	}
+	add("IS_HELM_MANAGED", types.StatusPass, "the SDK is managed by helm")
+
+	for _, key := range []string{"HELM_RELEASE_NAME", "HELM_RELEASE_NAMESPACE"} {
+		if value := opts.Getenv(key); value == "" {
+			add(key, types.StatusFail, "required when the SDK is managed by helm", featureAppHistory, featureHelmInformers)
+		} else {
+			add(key, types.StatusPass, fmt.Sprintf("using %s", value))
+		}
+	}
+
+	if opts.Getenv("HELM_PARENT_CHART_URL") == "" {
+		add("HELM_PARENT_CHART_URL", types.StatusWarn, "not set", featureHelmChartURL)
+	} else {
+		add("HELM_PARENT_CHART_URL", types.StatusPass, "")
+	}
+
+	add("HELM_DRIVER", types.StatusPass, fmt.Sprintf("using the %s storage driver", helmDriver(opts)))
+
+	return checks
+}
+
+// informerChecks reports the status informers that can't be parsed, or that are of a kind that is not supported
+func informerChecks(opts Options) []types.Check {
+	checks := []types.Check{}
+	for _, informerString := range opts.StatusInformers {
+		informer, err := informerString.Parse()
+		if err == nil {
+			informer = appstate.NormalizeStatusInformers([]appstatetypes.StatusInformer{informer}, opts.Namespace)[0]
+			if _, ok := informerResources[informer.Kind]; ok {
+				continue
+			}
+			err = fmt.Errorf("resource kind %s is not supported", informer.Kind)
+		}
+		checks = append(checks, types.Check{
+			Category:         types.CategoryEnvironment,
+			Name:             fmt.Sprintf("status informer %s", informerString),
+			Status:           types.StatusWarn,
+			Message:          err.Error(),
+			DisabledFeatures: []string{fmt.Sprintf("status of %s", informerString)},
+		})
+	}
+	return checks
+}
+
+func permissions(opts Options) []permission {
+	ns := opts.Namespace
+
+	perms := []permission{
+		{
+			access:   []resourceAccess{{resource: "secrets", name: util.GetReplicatedSecretName(), namespace: ns, verbs: []string{"get"}}},
+			severity: types.StatusFail,
+			features: []string{featureIntegrationMode},
+		},
+		{
+			access:   []resourceAccess{{group: "apps", resource: "deployments", name: util.GetReplicatedDeploymentName(), namespace: ns, verbs: []string{"get"}}},
+			severity: types.StatusFail,
+			features: []string{featureInstanceReporting, featureAirgapReports},
+		},
+		{
+			access:   []resourceAccess{{group: "apps", resource: "replicasets", namespace: ns, verbs: []string{"get"}}},
+			severity: types.StatusWarn,
+			features: []string{featureInstanceReporting},
+		},
+		{
+			access:   []resourceAccess{{resource: "pods", namespace: ns, verbs: []string{"get", "list", "watch"}}},
+			severity: types.StatusWarn,
+			features: []string{featureInstanceReporting, featureImageReporting},
+		},
+		{
+			access:   []resourceAccess{{resource: "secrets", name: meta.ReplicatedMetadataSecretName, namespace: ns, verbs: []string{"get"}}},
+			severity: types.StatusWarn,
+			features: []string{featureCustomMetrics, featureInstanceTags},
+		},
+		{
+			access:   []resourceAccess{{resource: "secrets", name: "replicated-support-metadata", namespace: ns, verbs: []string{"get"}}},
+			severity: types.StatusWarn,
+			features: []string{featureSupportBundleMetadata},
+		},
+		{
+			access:   []resourceAccess{{resource: "nodes", verbs: []string{"list"}}},
+			severity: types.StatusWarn,
+			features: []string{featureDistribution},
+		},
+	}
+
+	if !opts.ReadOnlyMode {
+		perms = append(perms,
+			permission{
+				access:   []resourceAccess{{resource: "secrets", namespace: ns, verbs: []string{"create"}}},
+				severity: types.StatusWarn,
+				features: []string{featureCustomMetrics, featureInstanceTags, featureAirgapReports, featureSupportBundleMetadata},
+			},
+			permission{
+				access: []resourceAccess{
+					{resource: "secrets", name: "replicated-instance-report", namespace: ns, verbs: []string{"update"}},
+					{resource: "secrets", name: "replicated-custom-app-metrics-report", namespace: ns, verbs: []string{"update"}},
+				},
+				severity: types.StatusWarn,
+				features: []string{featureAirgapReports},
+			},
+			permission{
+				access:   []resourceAccess{{resource: "secrets", name: util.GetReplicatedSecretName(), namespace: ns, verbs: []string{"update"}}},
+				severity: types.StatusWarn,
+				features: []string{featureLicenseUpload, featureReleaseIndexImport},
+			},
+			permission{
+				access:   []resourceAccess{{resource: "secrets", name: meta.ReplicatedMetadataSecretName, namespace: ns, verbs: []string{"update"}}},
+				severity: types.StatusWarn,
+				features: []string{featureCustomMetrics, featureInstanceTags},
+			},
+			permission{
+				access:   []resourceAccess{{resource: "secrets", name: "replicated-support-metadata", namespace: ns, verbs: []string{"update"}}},
+				severity: types.StatusWarn,
+				features: []string{featureSupportBundleMetadata},
+			},
+		)
+	}
+
+	if opts.ReportAllImages {
+		perms = append(perms, permission{
+			access:   []resourceAccess{{resource: "namespaces", verbs: []string{"list"}}},
+			severity: types.StatusWarn,
+			features: []string{featureAllNamespaceImages},
+		})
+	}
+
+	if opts.TlsCertSecretName != "" {
+		perms = append(perms, permission{
+			access:   []resourceAccess{{resource: "secrets", name: opts.TlsCertSecretName, namespace: ns, verbs: []string{"get", "list", "watch"}}},
+			severity: types.StatusFail,
+			features: []string{featureTLSReload},
+		})
+	}
+
+	if opts.APITokensSecretName != "" {
+		perms = append(perms, permission{
+			access:   []resourceAccess{{resource: "secrets", name: opts.APITokensSecretName, namespace: ns, verbs: []string{"get"}}},
+			severity: types.StatusFail,
+			features: []string{featureAPITokens},
+		})
+	}
+
+	if opts.LicenseSourceSecretName != "" {
+		perms = append(perms, permission{
+			access:   []resourceAccess{{resource: "secrets", name: opts.LicenseSourceSecretName, namespace: ns, verbs: []string{"get", "list", "watch"}}},
+			severity: types.StatusWarn,
+			features: []string{featureLicenseReload},
+		})
+	}
+
+	// the sdk doesn't start without the private certificate authorities
+	if opts.PrivateCASecretName != "" {
+		perms = append(perms, permission{
+			access:   []resourceAccess{{resource: "secrets", name: opts.PrivateCASecretName, namespace: ns, verbs: []string{"get"}}},
+			severity: types.StatusFail,
+			features: []string{featurePrivateCA},
+		})
+	}
+
+	// helm stores its releases as secrets or configmaps in the release namespace
+	if opts.Getenv("IS_HELM_MANAGED") == "true" {
+		resource := "secrets"
+		if driver := helmDriver(opts); driver == "configmap" || driver == "configmaps" {
+			resource = "configmaps"
+		}
+		features := []string{featureAppHistory}
+		if len(opts.StatusInformers) == 0 {
+			features = append(features, featureHelmInformers)
+		}
+		perms = append(perms, permission{
+			access:   []resourceAccess{{resource: resource, namespace: opts.Getenv("HELM_RELEASE_NAMESPACE"), verbs: []string{"get", "list"}}},
+			severity: types.StatusFail,
+			features: features,
+		})
+	}
+
+	// one check per namespace and kind, since the informers of a kind in a namespace share a watch
+	seen := map[string]struct{}{}
+	for _, informerString := range opts.StatusInformers {
+		informer, err := informerString.Parse()
+		if err != nil {
+			continue
+		}
+		informer = appstate.NormalizeStatusInformers([]appstatetypes.StatusInformer{informer}, ns)[0]
+
+		resources, ok := informerResources[informer.Kind]
+		if !ok {
+			continue
+		}
+		key := informer.Namespace + "/" + informer.Kind
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		access := []resourceAccess{}
+		for _, r := range resources {
+			r.namespace = informer.Namespace
+			access = append(access, r)
+		}
+		perms = append(perms, permission{
+			access:   access,
+			severity: types.StatusFail,
+			features: []string{fmt.Sprintf("%s status in namespace %s", informer.Kind, informer.Namespace), featureAppStatus},
+		})
+	}
+
+	return perms
+}
+
+func checkPermission(ctx context.Context, clientset kubernetes.Interface, p permission) types.Check {
+	names := []string{}
+	for _, access := range p.access {
+		names = append(names, access.String())
+	}
+
+	check := types.Check{
+		Category: types.CategoryPermission,
+		Name:     strings.Join(names, ", "),
+		Status:   types.StatusPass,
+	}
+
+	for _, access := range p.access {
+		for _, verb := range access.verbs {
+			sar := &authv1.SelfSubjectAccessReview{
+				Spec: authv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authv1.ResourceAttributes{
+						Namespace: access.namespace,
+						Verb:      verb,
+						Group:     access.group,
+						Resource:  access.resource,
+						Name:      access.name,
+					},
+				},
+			}
+
+			result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, sar, metav1.CreateOptions{})
+			if err != nil {
+				check.Status = p.severity
+				check.Message = fmt.Sprintf("failed to check access: %v", err)
+				check.DisabledFeatures = p.features
+				return check
+			}
+			if !result.Status.Allowed {
+				check.Status = p.severity
+				check.Message = fmt.Sprintf("missing permission to %s %s", verb, access.describe())
+				check.DisabledFeatures = p.features
+				return check
+			}
+		}
+	}
+
+	return check
+}
+
+// String formats the access like "get,list secrets/name in namespace default"
+func (a resourceAccess) String() string {
+	return fmt.Sprintf("%s %s", strings.Join(a.verbs, ","), a.describe())
+}
+
+func (a resourceAccess) describe() string {
+	s := a.resource
+	if a.group != "" {
+		s += "." + a.group
+	}
+	if a.name != "" {
+		s += "/" + a.name
+	}
+	if a.namespace != "" {
+		s += fmt.Sprintf(" in namespace %s", a.namespace)
+	}
+	return s
+}
+
+func helmDriver(opts Options) string {
+	if driver := opts.Getenv("HELM_DRIVER"); driver != "" {
+		return driver
+	}
+	return "secret"
+}
@@ -0,0 +1,207 @@
+package diagnostics
+
+import (
+	"context"
+	"testing"
+
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/diagnostics/types"
+	"github.com/stretchr/testify/require"
+	authv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newTestClientset returns a clientset that denies the given accesses, formatted as "verb resource namespace", and allows everything else
+func newTestClientset(denied ...string) *fake.Clientset {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		sar := action.(k8stesting.CreateAction).GetObject().(*authv1.SelfSubjectAccessReview)
+		attrs := sar.Spec.ResourceAttributes
+		key := attrs.Verb + " " + attrs.Resource + " " + attrs.Namespace
+		// access can also be denied to a single named resource, e.g. "get secrets/my-ca default"
+		namedKey := attrs.Verb + " " + attrs.Resource + "/" + attrs.Name + " " + attrs.Namespace
+
+		sar.Status.Allowed = true
+		for _, d := range denied {
+			if d == key || d == namedKey {
+				sar.Status.Allowed = false
+			}
+		}
+		return true, sar, nil
+	})
+	return clientset
+}
+
+func newTestEnv(env map[string]string) func(string) string {
+	return func(key string) string {
+		return env[key]
+	}
+}
+
+func findCheck(t *testing.T, report types.Report, name string) types.Check {
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	require.Failf(t, "check not found", "no check named %q", name)
+	return types.Check{}
+}
+
+func TestRun(t *testing.T) {
+	helmEnv := map[string]string{
+		"REPLICATED_POD_NAME":    "replicated-abc",
+		"IS_HELM_MANAGED":        "true",
+		"HELM_RELEASE_NAME":      "my-app",
+		"HELM_RELEASE_NAMESPACE": "default",
+		"HELM_PARENT_CHART_URL":  "oci://registry.replicated.com/my-app",
+	}
+
+	tests := []struct {
+		name                 string
+		clientset            *fake.Clientset
+		opts                 Options
+		wantStatus           types.Status
+		wantDisabledFeatures []string
+		wantChecks           map[string]types.Status
+	}{
+		{
+			name:      "everything allowed",
+			clientset: newTestClientset(),
+			opts: Options{
+				Namespace:       "default",
+				StatusInformers: []appstatetypes.StatusInformerString{"deployment/web", "deploy/api", "other/service/db"},
+				Getenv:          newTestEnv(helmEnv),
+			},
+			wantStatus:           types.StatusPass,
+			wantDisabledFeatures: []string{},
+			wantChecks: map[string]types.Status{
+				"list,watch deployments.apps in namespace default":                                                types.StatusPass,
+				"list,watch services in namespace other, list endpointslices.discovery.k8s.io in namespace other": types.StatusPass,
+				"get,list secrets in namespace default":                                                           types.StatusPass,
+			},
+		},
+		{
+			name:      "missing permissions",
+			clientset: newTestClientset("list nodes ", "watch deployments default", "update secrets default"),
+			opts: Options{
+				Namespace:       "default",
+				StatusInformers: []appstatetypes.StatusInformerString{"deployment/web"},
+				Getenv:          newTestEnv(helmEnv),
+			},
+			wantStatus: types.StatusFail,
+			wantDisabledFeatures: []string{
+				"airgap reports",
+				"app status",
+				"custom metrics",
+				"deployment status in namespace default",
+				"instance tags",
+				"kubernetes distribution detection",
+				"license upload",
+				"release index import",
+				"support bundle metadata",
+			},
+			wantChecks: map[string]types.Status{
+				"list nodes": types.StatusWarn,
+				"list,watch deployments.apps in namespace default":         types.StatusFail,
+				"update secrets/replicated-meta-data in namespace default": types.StatusWarn,
+			},
+		},
+		{
+			name:      "read only mode does not need write permissions",
+			clientset: newTestClientset("create secrets default", "update secrets default"),
+			opts: Options{
+				Namespace:    "default",
+				ReadOnlyMode: true,
+				Getenv:       newTestEnv(helmEnv),
+			},
+			wantStatus:           types.StatusPass,
+			wantDisabledFeatures: []string{},
+		},
+		{
+			name:      "license source secret can't be watched",
+			clientset: newTestClientset("watch secrets default"),
+			opts: Options{
+				Namespace:               "default",
+				ReadOnlyMode:            true,
+				LicenseSourceSecretName: "my-license",
+				Getenv:                  newTestEnv(helmEnv),
+			},
+			wantStatus:           types.StatusWarn,
+			wantDisabledFeatures: []string{"license reload"},
+			wantChecks: map[string]types.Status{
+				"get,list,watch secrets/my-license in namespace default": types.StatusWarn,
+			},
+		},
+		{
+			name:      "private ca secret can't be read",
+			clientset: newTestClientset("get secrets/my-ca default"),
+			opts: Options{
+				Namespace:           "default",
+				ReadOnlyMode:        true,
+				PrivateCASecretName: "my-ca",
+				Getenv:              newTestEnv(helmEnv),
+			},
+			wantStatus:           types.StatusFail,
+			wantDisabledFeatures: []string{"private certificate authorities"},
+			wantChecks: map[string]types.Status{
+				"get secrets/my-ca in namespace default": types.StatusFail,
+			},
+		},
+		{
+			name:      "not helm managed and missing environment",
+			clientset: newTestClientset(),
+			opts: Options{
+				StatusInformers: []appstatetypes.StatusInformerString{"web", "cronjob/backup"},
+				Getenv:          newTestEnv(map[string]string{}),
+			},
+			wantStatus: types.StatusFail,
+			wantDisabledFeatures: []string{
+				"app history",
+				"app status",
+				"custom metrics",
+				"helm chart url in app info",
+				"instance reporting",
+				"instance tags",
+				"status of cronjob/backup",
+				"status of web",
+			},
+			wantChecks: map[string]types.Status{
+				"REPLICATED_NAMESPACE":           types.StatusFail,
+				"REPLICATED_POD_NAME":            types.StatusWarn,
+				"IS_HELM_MANAGED":                types.StatusWarn,
+				"status informer web":            types.StatusWarn,
+				"status informer cronjob/backup": types.StatusWarn,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Run(context.Background(), tt.clientset, tt.opts)
+			require.Equal(t, tt.wantStatus, report.Status)
+			require.Equal(t, tt.wantDisabledFeatures, report.DisabledFeatures)
+			for name, status := range tt.wantChecks {
+				require.Equal(t, status, findCheck(t, report, name).Status, name)
+			}
+		})
+	}
+}
+
+func TestCheckPermissionMessage(t *testing.T) {
+	clientset := newTestClientset("get secrets default")
+
+	check := checkPermission(context.Background(), clientset, permission{
+		access:   []resourceAccess{{resource: "secrets", name: "replicated", namespace: "default", verbs: []string{"get"}}},
+		severity: types.StatusFail,
+		features: []string{"integration mode"},
+	})
+	require.Equal(t, types.Check{
+		Category:         types.CategoryPermission,
+		Name:             "get secrets/replicated in namespace default",
+		Status:           types.StatusFail,
+		Message:          "missing permission to get secrets/replicated in namespace default",
+		DisabledFeatures: []string{"integration mode"},
+	}, check)
+}
@@ -0,0 +1,44 @@
+package types
+
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+type Category string
+
+const (
+	CategoryPermission  Category = "permission"
+	CategoryEnvironment Category = "environment"
+)
+
+// Report is the result of the diagnostic checks. Its status is the worst status of its checks.
+type Report struct {
+	Status Status  `json:"status"`
+	Checks []Check `json:"checks"`
+	// DisabledFeatures are the features that don't work because of the checks that did not pass
+	DisabledFeatures []string `json:"disabledFeatures"`
+}
+
+type Check struct {
+	Category Category `json:"category"`
+	Name     string   `json:"name"`
+	Status   Status   `json:"status"`
+	Message  string   `json:"message,omitempty"`
+	// DisabledFeatures are the features that don't work because this check did not pass
+	DisabledFeatures []string `json:"disabledFeatures,omitempty"`
+}
+
+// Worse returns the more severe of the two statuses
+func Worse(a Status, b Status) Status {
+	if a == StatusFail || b == StatusFail {
+		return StatusFail
+	}
+	if a == StatusWarn || b == StatusWarn {
+		return StatusWarn
+	}
+	return StatusPass
+}
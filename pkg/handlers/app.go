@@ -6,24 +6,26 @@ import (
 	"net/http"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
-	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/config"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/helm"
 	"github.com/replicatedhq/replicated-sdk/pkg/integration"
 	integrationtypes "github.com/replicatedhq/replicated-sdk/pkg/integration/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
 	sdklicense "github.com/replicatedhq/replicated-sdk/pkg/license"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/logger"
 	"github.com/replicatedhq/replicated-sdk/pkg/meta"
-	"github.com/replicatedhq/replicated-sdk/pkg/meta/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/report"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
-	"github.com/replicatedhq/replicated-sdk/pkg/upstream"
+	"github.com/replicatedhq/replicated-sdk/pkg/updates"
 	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/util"
 	helmrelease "helm.sh/helm/v3/pkg/release"
@@ -32,71 +34,27 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
-type GetCurrentAppInfoResponse struct {
-	InstanceID      string              `json:"instanceID"`
-	AppSlug         string              `json:"appSlug"`
-	AppName         string              `json:"appName"`
-	AppStatus       appstatetypes.State `json:"appStatus"`
-	HelmChartURL    string              `json:"helmChartURL,omitempty"`
-	CurrentRelease  AppRelease          `json:"currentRelease"`
-	ChannelID       string              `json:"channelID"`
-	ChannelName     string              `json:"channelName"`
-	ChannelSequence int64               `json:"channelSequence"`
-	ReleaseSequence int64               `json:"releaseSequence"`
-}
-
-type GetCurrentAppStatusResponse struct {
-	AppStatus appstatetypes.AppStatus `json:"appStatus"`
-}
-
-type GetAppHistoryResponse struct {
-	Releases []AppRelease `json:"releases"`
-}
-
-type AppRelease struct {
-	VersionLabel         string `json:"versionLabel"`
-	ReleaseNotes         string `json:"releaseNotes"`
-	CreatedAt            string `json:"createdAt"`
-	DeployedAt           string `json:"deployedAt"`
-	HelmReleaseName      string `json:"helmReleaseName,omitempty"`
-	HelmReleaseRevision  int    `json:"helmReleaseRevision,omitempty"`
-	HelmReleaseNamespace string `json:"helmReleaseNamespace,omitempty"`
-}
-
-type SendCustomAppMetricsRequest struct {
-	Data CustomAppMetricsData `json:"data"`
-}
-
-type CustomAppMetricsData map[string]interface{}
-
-type SendAppInstanceTagsRequest struct {
-	Data types.InstanceTagData `json:"data"`
-}
-
 func GetCurrentAppInfo(w http.ResponseWriter, r *http.Request) {
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to get clientset"))
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
 		return
 	}
 
 	isIntegrationModeEnabled, err := integration.IsEnabled(r.Context(), clientset, store.GetStore().GetNamespace(), store.GetStore().GetLicense())
 	if err != nil {
-		logger.Errorf("failed to check if integration mode is enabled: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to check if integration mode is enabled"))
 		return
 	}
 
 	if isIntegrationModeEnabled {
 		mockData, err := integration.GetMockData(r.Context(), clientset, store.GetStore().GetNamespace())
 		if err != nil {
-			logger.Errorf("failed to get mock data: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			JSONError(w, r, errors.Wrap(err, "failed to get mock data"))
 			return
 		}
 
-		response := GetCurrentAppInfoResponse{
+		response := types.GetCurrentAppInfoResponse{
 			InstanceID: store.GetStore().GetAppID(),
 			AppSlug:    store.GetStore().GetAppSlug(),
 			AppName:    store.GetStore().GetAppName(),
@@ -134,13 +92,13 @@ func GetCurrentAppInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := GetCurrentAppInfoResponse{
+	response := types.GetCurrentAppInfoResponse{
 		InstanceID:   store.GetStore().GetAppID(),
 		AppSlug:      store.GetStore().GetAppSlug(),
 		AppName:      store.GetStore().GetAppName(),
 		AppStatus:    store.GetStore().GetAppStatus().State,
 		HelmChartURL: helm.GetParentChartURL(),
-		CurrentRelease: AppRelease{
+		CurrentRelease: types.AppRelease{
 			VersionLabel: store.GetStore().GetVersionLabel(),
 			CreatedAt:    store.GetStore().GetReleaseCreatedAt(),
 			ReleaseNotes: store.GetStore().GetReleaseNotes(),
@@ -154,8 +112,7 @@ func GetCurrentAppInfo(w http.ResponseWriter, r *http.Request) {
 	if helm.IsHelmManaged() {
 		helmRelease, err := helm.GetRelease(helm.GetReleaseName())
 		if err != nil {
-			logger.Error(errors.Wrap(err, "failed to get helm release"))
-			w.WriteHeader(http.StatusInternalServerError)
+			JSONError(w, r, errors.Wrap(err, "failed to get helm release"))
 			return
 		}
 
@@ -173,32 +130,28 @@ func GetCurrentAppInfo(w http.ResponseWriter, r *http.Request) {
 func GetCurrentAppStatus(w http.ResponseWriter, r *http.Request) {
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to get clientset"))
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
 		return
 	}
 
 	isIntegrationModeEnabled, err := integration.IsEnabled(r.Context(), clientset, store.GetStore().GetNamespace(), store.GetStore().GetLicense())
 	if err != nil {
-		logger.Errorf("failed to check if integration mode is enabled: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to check if integration mode is enabled"))
 		return
 	}
 
 	if isIntegrationModeEnabled {
-		response := GetCurrentAppStatusResponse{}
+		response := types.GetCurrentAppStatusResponse{}
 
 		mockData, err := integration.GetMockData(r.Context(), clientset, store.GetStore().GetNamespace())
 		if err != nil {
-			logger.Errorf("failed to get mock data: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			JSONError(w, r, errors.Wrap(err, "failed to get mock data"))
 			return
 		}
 
 		switch mockData := mockData.(type) {
 		case *integrationtypes.MockDataV1:
-			logger.Errorf("app status is not supported in v1 mock data")
-			w.WriteHeader(http.StatusInternalServerError)
+			JSONError(w, r, errors.New("app status is not supported in v1 mock data"))
 			return
 		case *integrationtypes.MockDataV2:
 			response.AppStatus = mockData.AppStatus
@@ -212,7 +165,7 @@ func GetCurrentAppStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := GetCurrentAppStatusResponse{
+	response := types.GetCurrentAppStatusResponse{
 		AppStatus: store.GetStore().GetAppStatus(),
 	}
 
@@ -220,110 +173,257 @@ func GetCurrentAppStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetAppUpdates(w http.ResponseWriter, r *http.Request) {
+	opts, err := getUpdatesOptions(r)
+	if err != nil {
+		JSONError(w, r, err)
+		return
+	}
+
+	otherChannel, err := getOtherUpdatesChannel(r)
+	if err != nil {
+		JSONError(w, r, err)
+		return
+	}
+
 	if util.IsAirgap() {
-		JSON(w, http.StatusOK, []upstreamtypes.ChannelRelease{})
+		if otherChannel != nil {
+			getOtherChannelUpdates(w, r, *otherChannel, opts)
+			return
+		}
+
+		// the updates are imported from the release index that is shipped with airgap bundles, and are empty until one is imported
+		JSON(w, http.StatusOK, upstreamtypes.SelectUpdates(store.GetStore().GetUpdates(), store.GetStore().GetVersionLabel(), opts))
 		return
 	}
 
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to get clientset"))
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
 		return
 	}
 
 	isIntegrationModeEnabled, err := integration.IsEnabled(r.Context(), clientset, store.GetStore().GetNamespace(), store.GetStore().GetLicense())
 	if err != nil {
-		logger.Errorf("failed to check if integration mode is enabled: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to check if integration mode is enabled"))
 		return
 	}
 
 	if isIntegrationModeEnabled {
 		mockData, err := integration.GetMockData(r.Context(), clientset, store.GetStore().GetNamespace())
 		if err != nil {
-			logger.Errorf("failed to get mock data: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			JSONError(w, r, errors.Wrap(err, "failed to get mock data"))
 			return
 		}
 
 		response := []upstreamtypes.ChannelRelease{}
 		var avalableReleases []integrationtypes.MockRelease
+		currentVersionLabel := store.GetStore().GetVersionLabel()
 
 		switch mockData := mockData.(type) {
 		case *integrationtypes.MockDataV1:
 			avalableReleases = mockData.AvailableReleases
+			if mockData.CurrentRelease != nil {
+				currentVersionLabel = mockData.CurrentRelease.VersionLabel
+			}
 		case *integrationtypes.MockDataV2:
 			avalableReleases = mockData.AvailableReleases
+			if mockData.CurrentRelease != nil {
+				currentVersionLabel = mockData.CurrentRelease.VersionLabel
+			}
 		default:
 			logger.Errorf("unknown mock data type: %T", mockData)
 		}
 
 		for _, mockRelease := range avalableReleases {
 			response = append(response, upstreamtypes.ChannelRelease{
-				VersionLabel: mockRelease.VersionLabel,
-				CreatedAt:    mockRelease.CreatedAt,
-				ReleaseNotes: mockRelease.ReleaseNotes,
+				ChannelSequence: mockRelease.ChannelSequence,
+				VersionLabel:    mockRelease.VersionLabel,
+				CreatedAt:       mockRelease.CreatedAt,
+				ReleaseNotes:    mockRelease.ReleaseNotes,
+				IsRequired:      mockRelease.IsRequired,
 			})
 		}
 
 		w.Header().Set(MockDataHeader, "true")
 
-		JSON(w, http.StatusOK, response)
+		JSON(w, http.StatusOK, upstreamtypes.SelectUpdates(response, currentVersionLabel, opts))
 		return
 	}
 
-	license := store.GetStore().GetLicense()
-	updates := store.GetStore().GetUpdates()
+	if otherChannel != nil {
+		getOtherChannelUpdates(w, r, *otherChannel, opts)
+		return
+	}
+
+	// updates are checked in the background, refresh=true checks now instead of serving the last check
+	if r.URL.Query().Get("refresh") == "true" {
+		if err := updates.Refresh(r.Context()); err != nil {
+			logger.Error(errors.Wrap(err, "failed to refresh updates"))
+		}
+	}
+
+	status := updates.GetStatus(time.Now())
+	if status.LastCheckedAt != nil {
+		w.Header().Set(UpdatesLastCheckedAtHeader, status.LastCheckedAt.UTC().Format(time.RFC3339))
+	}
+	w.Header().Set(UpdatesStaleHeader, strconv.FormatBool(status.Stale))
+
+	response := upstreamtypes.SelectUpdates(store.GetStore().GetUpdates(), store.GetStore().GetVersionLabel(), opts)
 
-	licenseData, err := sdklicense.GetLatestLicense(license, store.GetStore().GetReplicatedAppEndpoint())
+	if status.Err != nil {
+		SetUpstreamErrorHeader(w, status.Err)
+		JSONCached(w, http.StatusOK, response)
+		return
+	}
+
+	JSON(w, http.StatusOK, response)
+}
+
+// getOtherChannelUpdates serves the releases on another channel than the one updates are checked on. They are not checked
+// in the background, and are always requested from the Replicated API, or read from the release index in airgap mode.
+func getOtherChannelUpdates(w http.ResponseWriter, r *http.Request, channel sdklicensetypes.LicenseChannel, opts upstreamtypes.UpdatesOptions) {
+	releases, err := updates.GetChannelUpdates(channel.ChannelID)
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to get latest license"))
-		JSONCached(w, http.StatusOK, updates)
+		JSONError(w, r, errors.Wrapf(err, "failed to get updates on channel %s", channel.ChannelID))
 		return
 	}
 
-	license = licenseData.License
-	store.GetStore().SetLicense(license)
+	opts.SemverRequired = channel.IsSemverRequired
+	JSON(w, http.StatusOK, upstreamtypes.SelectUpdates(releases, store.GetStore().GetVersionLabel(), opts))
+}
 
-	currentCursor := upstreamtypes.ReplicatedCursor{
-		ChannelID:       store.GetStore().GetChannelID(),
-		ChannelName:     store.GetStore().GetChannelName(),
-		ChannelSequence: store.GetStore().GetChannelSequence(),
+// maxReleaseIndexBodySize is the largest release index file that can be imported
+const maxReleaseIndexBodySize = 10 << 20
+
+// importReleaseIndexMtx serializes release index imports, so that the persisted index is the one whose updates are served
+var importReleaseIndexMtx sync.Mutex
+
+// ImportReleaseIndex imports the signed release index that is shipped with airgap bundles, so that updates can be discovered in airgap mode.
+// It returns the releases in the index that are available to update to. An index that is older than the imported one is rejected.
+// Like license uploads, it is unavailable in read-only mode, since the index is persisted in the replicated secret.
+func ImportReleaseIndex(w http.ResponseWriter, r *http.Request) {
+	if !util.IsAirgap() {
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeNotAirgap, "release indexes can only be imported in airgap mode, updates are checked with the Replicated API otherwise"))
+		return
 	}
-	us, err := upstream.GetUpdates(store.GetStore(), license, currentCursor)
+
+	if store.GetStore().GetReadOnlyMode() {
+		ReadOnlyModeError(w, r, "release index imports are unavailable in read-only mode")
+		return
+	}
+
+	signedIndexBytes, err := readRequestBody(w, r, maxReleaseIndexBodySize)
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to get updates"))
-		JSONCached(w, http.StatusOK, updates)
+		JSONError(w, r, err)
 		return
 	}
 
-	updates = us
-	store.GetStore().SetUpdates(updates)
+	signedIndex, err := updates.ParseSignedReleaseIndex(signedIndexBytes)
+	if err != nil {
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("invalid release index: %v", err)))
+		return
+	}
+
+	importReleaseIndexMtx.Lock()
+	defer importReleaseIndexMtx.Unlock()
+
+	index, err := updates.VerifyReleaseIndex(store.GetStore().GetLicense(), signedIndex)
+	if err != nil {
+		JSONError(w, r, NewAPIError(http.StatusUnprocessableEntity, types.ErrorCodeReleaseIndexRejected, err.Error()))
+		return
+	}
+
+	if err := updates.ValidateReleaseIndexUpdate(index); err != nil {
+		JSONError(w, r, NewAPIError(http.StatusUnprocessableEntity, types.ErrorCodeReleaseIndexRejected, err.Error()))
+		return
+	}
+
+	var clientset kubernetes.Interface
+	if testClientSet != nil {
+		clientset = testClientSet
+	} else {
+		clientset, err = k8sutil.GetClientset()
+		if err != nil {
+			JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
+			return
+		}
+	}
+
+	if err := updates.SaveReleaseIndex(r.Context(), clientset, store.GetStore().GetNamespace(), signedIndexBytes); err != nil {
+		JSONError(w, r, errors.Wrap(err, "failed to save release index"))
+		return
+	}
+
+	releases := updates.ImportReleaseIndex(index)
+	logger.Infof("Imported %d available updates from the release index created at %s", len(releases), index.CreatedAt)
+
+	JSON(w, http.StatusOK, upstreamtypes.SelectUpdates(releases, store.GetStore().GetVersionLabel(), upstreamtypes.UpdatesOptions{
+		IncludePrerelease: true,
+		SemverRequired:    store.GetStore().GetLicense().IsSemverRequired(),
+	}))
+}
+
+// getOtherUpdatesChannel returns the channel from the query of an updates request if it is not the channel that updates are checked on.
+// The license must be entitled to the channel.
+func getOtherUpdatesChannel(r *http.Request) (*sdklicensetypes.LicenseChannel, error) {
+	channelID := r.URL.Query().Get("channel")
+	if channelID == "" {
+		return nil, nil
+	}
+
+	license := store.GetStore().GetLicense()
+	channel, ok := sdklicense.GetLicenseChannel(license, channelID)
+	if !ok {
+		return nil, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("license is not entitled to channel %q", channelID))
+	}
+
+	if channelID == sdklicense.GetUpdatesChannelID(license, store.GetStore().GetChannelID()) {
+		return nil, nil
+	}
+	return &channel, nil
+}
+
+// getUpdatesOptions returns the filters from the query of an updates request. Prereleases are included unless includePrerelease is false,
+// and releases are ordered by semver if the license requires it.
+func getUpdatesOptions(r *http.Request) (upstreamtypes.UpdatesOptions, error) {
+	opts := upstreamtypes.UpdatesOptions{
+		MaxBump:           upstreamtypes.SemverBump(r.URL.Query().Get("maxBump")),
+		IncludePrerelease: true,
+		SemverRequired:    store.GetStore().GetLicense().IsSemverRequired(),
+	}
+
+	if opts.MaxBump != "" && opts.MaxBump.Rank() == 0 {
+		return opts, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("invalid maxBump %q", opts.MaxBump))
+	}
+
+	if value := r.URL.Query().Get("includePrerelease"); value != "" {
+		includePrerelease, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("invalid includePrerelease %q", value))
+		}
+		opts.IncludePrerelease = includePrerelease
+	}
 
-	JSON(w, http.StatusOK, updates)
+	return opts, nil
 }
 
 func GetAppHistory(w http.ResponseWriter, r *http.Request) {
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to get clientset"))
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
 		return
 	}
 
 	isIntegrationModeEnabled, err := integration.IsEnabled(r.Context(), clientset, store.GetStore().GetNamespace(), store.GetStore().GetLicense())
 	if err != nil {
-		logger.Errorf("failed to check if integration mode is enabled: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to check if integration mode is enabled"))
 		return
 	}
 
 	if isIntegrationModeEnabled {
 		mockData, err := integration.GetMockData(r.Context(), clientset, store.GetStore().GetNamespace())
 		if err != nil {
-			logger.Errorf("failed to get mock data: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			JSONError(w, r, errors.Wrap(err, "failed to get mock data"))
 			return
 		}
 
@@ -338,8 +438,8 @@ func GetAppHistory(w http.ResponseWriter, r *http.Request) {
 			logger.Errorf("unknown mock data type: %T", mockData)
 		}
 
-		response := GetAppHistoryResponse{
-			Releases: []AppRelease{},
+		response := types.GetAppHistoryResponse{
+			Releases: []types.AppRelease{},
 		}
 		for _, mockRelease := range deployedReleases {
 			response.Releases = append(response.Releases, mockReleaseToAppRelease(mockRelease))
@@ -352,16 +452,13 @@ func GetAppHistory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !helm.IsHelmManaged() {
-		err := errors.New("app history is only available in Helm mode")
-		logger.Error(err)
-		JSON(w, http.StatusBadRequest, err)
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeNotHelmManaged, "app history is only available in Helm mode"))
 		return
 	}
 
 	helmHistory, err := helm.GetReleaseHistory()
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to list helm releases"))
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to list helm releases"))
 		return
 	}
 
@@ -370,8 +467,8 @@ func GetAppHistory(w http.ResponseWriter, r *http.Request) {
 		return helmHistory[i].Version > helmHistory[j].Version
 	})
 
-	response := GetAppHistoryResponse{
-		Releases: []AppRelease{},
+	response := types.GetAppHistoryResponse{
+		Releases: []types.AppRelease{},
 	}
 	for _, helmRelease := range helmHistory {
 		appRelease := helmReleaseToAppRelease(helmRelease)
@@ -383,7 +480,7 @@ func GetAppHistory(w http.ResponseWriter, r *http.Request) {
 	JSON(w, http.StatusOK, response)
 }
 
-func helmReleaseToAppRelease(helmRelease *helmrelease.Release) *AppRelease {
+func helmReleaseToAppRelease(helmRelease *helmrelease.Release) *types.AppRelease {
 	// find the replicated secret in the helm release and get the info from it
 	for _, doc := range strings.Split(helmRelease.Manifest, "\n---\n") {
 		if doc == "" {
@@ -409,7 +506,7 @@ func helmReleaseToAppRelease(helmRelease *helmrelease.Release) *AppRelease {
 			return nil
 		}
 
-		appRelease := &AppRelease{
+		appRelease := &types.AppRelease{
 			DeployedAt:           helmRelease.Info.LastDeployed.Format(time.RFC3339),
 			HelmReleaseName:      helmRelease.Name,
 			HelmReleaseRevision:  helmRelease.Version,
@@ -436,8 +533,8 @@ func helmReleaseToAppRelease(helmRelease *helmrelease.Release) *AppRelease {
 	return nil
 }
 
-func mockReleaseToAppRelease(mockRelease integrationtypes.MockRelease) AppRelease {
-	appRelease := AppRelease{
+func mockReleaseToAppRelease(mockRelease integrationtypes.MockRelease) types.AppRelease {
+	appRelease := types.AppRelease{
 		VersionLabel:         mockRelease.VersionLabel,
 		ReleaseNotes:         mockRelease.ReleaseNotes,
 		CreatedAt:            mockRelease.CreatedAt,
@@ -457,16 +554,14 @@ func SetTestClientSet(clientset kubernetes.Interface) {
 }
 
 func SendCustomAppMetrics(w http.ResponseWriter, r *http.Request) {
-	request := SendCustomAppMetricsRequest{}
+	request := types.SendCustomAppMetricsRequest{}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		logger.Error(errors.Wrap(err, "decode request"))
-		w.WriteHeader(http.StatusBadRequest)
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("invalid request body: %v", err)))
 		return
 	}
 
 	if err := validateCustomAppMetricsData(request.Data); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(err.Error()))
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, err.Error()))
 		return
 	}
 
@@ -477,8 +572,7 @@ func SendCustomAppMetrics(w http.ResponseWriter, r *http.Request) {
 		var err error
 		clientset, err = k8sutil.GetClientset()
 		if err != nil {
-			logger.Error(errors.Wrap(err, "failed to get clientset"))
-			w.WriteHeader(http.StatusInternalServerError)
+			JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
 			return
 		}
 	}
@@ -489,8 +583,7 @@ func SendCustomAppMetrics(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := report.SendCustomAppMetrics(clientset, store.GetStore(), request.Data, overwrite); err != nil {
-		logger.Error(errors.Wrap(err, "set application data"))
-		w.WriteHeader(http.StatusBadRequest)
+		JSONError(w, r, errors.Wrap(err, "failed to send custom app metrics"))
 		return
 	}
 
@@ -499,41 +592,34 @@ func SendCustomAppMetrics(w http.ResponseWriter, r *http.Request) {
 
 func DeleteCustomAppMetricsKey(w http.ResponseWriter, r *http.Request) {
 	if store.GetStore().GetReadOnlyMode() {
-		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
-			"error":        "custom metrics delete is unavailable in read-only mode",
-			"readOnlyMode": true,
-		})
+		ReadOnlyModeError(w, r, "custom metrics delete is unavailable in read-only mode")
 		return
 	}
 
 	key, ok := mux.Vars(r)["key"]
 
 	if !ok || key == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "key cannot be empty")
-		logger.Errorf("cannot delete custom metrics key - key cannot be empty")
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, "key cannot be empty"))
 		return
 	}
 
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to get clientset"))
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
 		return
 	}
 
 	data := map[string]interface{}{key: nil}
 
 	if err := report.SendCustomAppMetrics(clientset, store.GetStore(), data, false); err != nil {
-		logger.Error(errors.Wrapf(err, "failed to delete custom merics key: %s", key))
-		w.WriteHeader(http.StatusBadRequest)
+		JSONError(w, r, errors.Wrapf(err, "failed to delete custom metrics key: %s", key))
 		return
 	}
 
 	JSON(w, http.StatusNoContent, "")
 }
 
-func validateCustomAppMetricsData(data CustomAppMetricsData) error {
+func validateCustomAppMetricsData(data types.CustomAppMetricsData) error {
 	if len(data) == 0 {
 		return errors.New("no data provided")
 	}
@@ -559,44 +645,35 @@ func validateCustomAppMetricsData(data CustomAppMetricsData) error {
 
 func SendAppInstanceTags(w http.ResponseWriter, r *http.Request) {
 	if store.GetStore().GetReadOnlyMode() {
-		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
-			"error":        "instance tags are unavailable in read-only mode",
-			"readOnlyMode": true,
-		})
+		ReadOnlyModeError(w, r, "instance tags are unavailable in read-only mode")
 		return
 	}
 
-	request := SendAppInstanceTagsRequest{}
+	request := types.SendAppInstanceTagsRequest{}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		t, ok := err.(*json.UnmarshalTypeError)
 		if ok {
-			logger.Errorf("failed to decode instance-tag request: %s value is not a string", t.Field)
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(w, "%v not supported, only string values are allowed on instance-tags", t.Value)
+			JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("%v not supported, only string values are allowed on instance-tags", t.Value)))
 			return
 		}
 
-		logger.Error(errors.Wrap(err, "decode request"))
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("invalid request body: %v", err)))
 		return
 	}
 
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to get clientset"))
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
 		return
 	}
 
 	if err := meta.SaveInstanceTag(r.Context(), clientset, store.GetStore().GetNamespace(), request.Data); err != nil {
-		logger.Errorf("failed to save instance tags: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to save instance tags"))
 		return
 	}
 
 	if err := report.SendInstanceData(clientset, store.GetStore()); err != nil {
-		logger.Errorf("failed to send instance data: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to send instance data"))
 		return
 	}
 
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/hashstructure"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/appstate"
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/integration"
+	integrationtypes "github.com/replicatedhq/replicated-sdk/pkg/integration/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	appStatusEventName = "appStatus"
+	// streamRetryMilliseconds is the reconnection delay suggested to clients
+	streamRetryMilliseconds = 5000
+)
+
+var (
+	// streamHeartbeatInterval is how often a comment is sent to keep idle connections (and proxies) from timing out
+	streamHeartbeatInterval = 15 * time.Second
+	// mockAppStatusPollInterval is how often the mock data is checked for app status changes in integration mode
+	mockAppStatusPollInterval = 5 * time.Second
+
+	streamsDone      = make(chan struct{})
+	closeStreamsOnce sync.Once
+)
+
+// CloseStreams ends all open event streams. It is called when the server shuts down, since streams would otherwise
+// keep the server from draining until the shutdown timeout.
+func CloseStreams() {
+	closeStreamsOnce.Do(func() {
+		close(streamsDone)
+	})
+}
+
+// StreamAppStatus streams the app status as server-sent events. The current status is sent on connect and then
+// every change to it. Clients that reconnect with a Last-Event-ID header only receive the changes they missed.
+func StreamAppStatus(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		JSONError(w, r, errors.New("streaming is not supported by the response writer"))
+		return
+	}
+
+	var clientset kubernetes.Interface
+	if testClientSet != nil {
+		clientset = testClientSet
+	} else {
+		var err error
+		clientset, err = k8sutil.GetClientset()
+		if err != nil {
+			JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
+			return
+		}
+	}
+
+	isIntegrationModeEnabled, err := integration.IsEnabled(r.Context(), clientset, store.GetStore().GetNamespace(), store.GetStore().GetLicense())
+	if err != nil {
+		JSONError(w, r, errors.Wrap(err, "failed to check if integration mode is enabled"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// disable response buffering in nginx based proxies
+	w.Header().Set("X-Accel-Buffering", "no")
+	if isIntegrationModeEnabled {
+		w.Header().Set(MockDataHeader, "true")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetryMilliseconds)
+	flusher.Flush()
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+
+	if isIntegrationModeEnabled {
+		err = streamMockAppStatus(r.Context(), w, flusher, clientset, lastEventID)
+	} else {
+		err = streamAppStatus(r.Context(), w, flusher, lastEventID)
+	}
+	if err != nil && r.Context().Err() == nil {
+		logger.Error(errors.Wrap(err, "failed to stream app status"))
+	}
+}
+
+func streamAppStatus(ctx context.Context, w io.Writer, flusher http.Flusher, lastEventID string) error {
+	// IDs from a previous process or another replica have a different boot id and are not found in the history,
+	// and neither are malformed IDs, so the client gets the current status
+	replay, events, unsubscribe := appstate.SubscribeAppStatus(lastEventID)
+	defer unsubscribe()
+
+	for _, event := range replay {
+		if err := writeAppStatusEvent(w, event.ID, event.AppStatus); err != nil {
+			return err
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-streamsDone:
+			return nil
+		case <-heartbeat.C:
+			if err := writeHeartbeat(w); err != nil {
+				return err
+			}
+		case event, ok := <-events:
+			if !ok {
+				// the client fell behind, it will reconnect and catch up using the last event id
+				return nil
+			}
+			if err := writeAppStatusEvent(w, event.ID, event.AppStatus); err != nil {
+				return err
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// streamMockAppStatus streams the app status from the integration mock data. Event IDs are derived from the status itself,
+// so a client that reconnects with the ID of the current status does not receive it again.
+func streamMockAppStatus(ctx context.Context, w io.Writer, flusher http.Flusher, clientset kubernetes.Interface, lastEventID string) error {
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	poll := time.NewTicker(mockAppStatusPollInterval)
+	defer poll.Stop()
+
+	for {
+		appStatus, err := getMockAppStatus(ctx, clientset)
+		if err != nil {
+			return errors.Wrap(err, "failed to get mock app status")
+		}
+
+		hash, err := hashstructure.Hash(appStatus, nil)
+		if err != nil {
+			return errors.Wrap(err, "failed to hash mock app status")
+		}
+
+		eventID := strconv.FormatUint(hash, 10)
+		if eventID != lastEventID {
+			if err := writeAppStatusEvent(w, eventID, appStatus); err != nil {
+				return err
+			}
+			lastEventID = eventID
+			flusher.Flush()
+		}
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-streamsDone:
+				return nil
+			case <-heartbeat.C:
+				if err := writeHeartbeat(w); err != nil {
+					return err
+				}
+				flusher.Flush()
+			case <-poll.C:
+				break wait
+			}
+		}
+	}
+}
+
+func getMockAppStatus(ctx context.Context, clientset kubernetes.Interface) (appstatetypes.AppStatus, error) {
+	mockData, err := integration.GetMockData(ctx, clientset, store.GetStore().GetNamespace())
+	if err != nil {
+		return appstatetypes.AppStatus{}, errors.Wrap(err, "failed to get mock data")
+	}
+
+	switch mockData := mockData.(type) {
+	case *integrationtypes.MockDataV1:
+		return appstatetypes.AppStatus{}, errors.New("app status is not supported in v1 mock data")
+	case *integrationtypes.MockDataV2:
+		return mockData.AppStatus, nil
+	default:
+		return appstatetypes.AppStatus{}, errors.Errorf("unknown mock data type: %T", mockData)
+	}
+}
+
+func writeAppStatusEvent(w io.Writer, id string, appStatus appstatetypes.AppStatus) error {
+	data, err := json.Marshal(types.GetCurrentAppStatusResponse{AppStatus: appStatus})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal app status")
+	}
+
+	if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, appStatusEventName, data); err != nil {
+		return errors.Wrap(err, "failed to write event")
+	}
+
+	return nil
+}
+
+func writeHeartbeat(w io.Writer) error {
+	if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+		return errors.Wrap(err, "failed to write heartbeat")
+	}
+	return nil
+}
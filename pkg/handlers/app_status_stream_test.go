@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/replicated-sdk/pkg/appstate"
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type testEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+func readTestEvent(t *testing.T, scanner *bufio.Scanner) testEvent {
+	event := testEvent{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if event.event != "" {
+				return event
+			}
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	require.NoError(t, scanner.Err())
+	t.Fatal("stream ended before an event was received")
+	return event
+}
+
+func Test_StreamAppStatus(t *testing.T) {
+	req := require.New(t)
+
+	SetTestClientSet(fake.NewSimpleClientset())
+	defer SetTestClientSet(nil)
+
+	store.InitInMemory(store.InitInMemoryStoreOptions{Namespace: "default"})
+	defer store.SetStore(nil)
+
+	initial := appstate.PublishAppStatus(appstatetypes.AppStatus{AppSlug: "my-app", State: appstatetypes.StateMissing})
+
+	server := httptest.NewServer(http.HandlerFunc(StreamAppStatus))
+	defer server.Close()
+
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	resp, err := client.Get(server.URL)
+	req.NoError(err)
+	defer resp.Body.Close()
+
+	req.Equal(http.StatusOK, resp.StatusCode)
+	req.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+	scanner := bufio.NewScanner(resp.Body)
+
+	/* the current status is sent on connect */
+	event := readTestEvent(t, scanner)
+	req.Equal(appStatusEventName, event.event)
+	req.Contains(event.data, `"state":"missing"`)
+
+	/* changes are sent as they are published */
+	ready := appstate.PublishAppStatus(appstatetypes.AppStatus{AppSlug: "my-app", State: appstatetypes.StateReady})
+
+	event = readTestEvent(t, scanner)
+	req.Equal(ready.ID, event.id)
+	req.Contains(event.data, `"state":"ready"`)
+
+	/* a client resuming from a previous event only receives the changes it missed */
+	resumeReq, err := http.NewRequest("GET", server.URL, nil)
+	req.NoError(err)
+	resumeReq.Header.Set("Last-Event-ID", event.id)
+
+	appstate.PublishAppStatus(appstatetypes.AppStatus{AppSlug: "my-app", State: appstatetypes.StateDegraded})
+
+	resumeResp, err := client.Do(resumeReq)
+	req.NoError(err)
+	defer resumeResp.Body.Close()
+
+	resumeScanner := bufio.NewScanner(resumeResp.Body)
+	event = readTestEvent(t, resumeScanner)
+	req.Contains(event.data, `"state":"degraded"`)
+	req.NotEqual(initial.ID, event.id)
+
+	/* a client reconnecting with an ID from a previous process receives the current status */
+	staleReq, err := http.NewRequest("GET", server.URL, nil)
+	req.NoError(err)
+	staleReq.Header.Set("Last-Event-ID", "0123456789abcdef-1")
+
+	staleResp, err := client.Do(staleReq)
+	req.NoError(err)
+	defer staleResp.Body.Close()
+
+	staleScanner := bufio.NewScanner(staleResp.Body)
+	event = readTestEvent(t, staleScanner)
+	req.Contains(event.data, `"state":"degraded"`)
+}
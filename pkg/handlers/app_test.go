@@ -1,20 +1,38 @@
 package handlers
 
 import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/licensetest"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func Test_validateCustomAppMetricsData(t *testing.T) {
 	tests := []struct {
 		name    string
-		data    CustomAppMetricsData
+		data    types.CustomAppMetricsData
 		wantErr bool
 	}{
 		{
 			name: "all values are valid",
-			data: CustomAppMetricsData{
+			data: types.CustomAppMetricsData{
 				"key1": "val1",
 				"key2": 6,
 				"key3": 6.6,
@@ -24,12 +42,12 @@ func Test_validateCustomAppMetricsData(t *testing.T) {
 		},
 		{
 			name:    "no data",
-			data:    CustomAppMetricsData{},
+			data:    types.CustomAppMetricsData{},
 			wantErr: true,
 		},
 		{
 			name: "array value",
-			data: CustomAppMetricsData{
+			data: types.CustomAppMetricsData{
 				"key1": 10,
 				"key2": []string{"val1", "val2"},
 			},
@@ -37,7 +55,7 @@ func Test_validateCustomAppMetricsData(t *testing.T) {
 		},
 		{
 			name: "map value",
-			data: CustomAppMetricsData{
+			data: types.CustomAppMetricsData{
 				"key1": 10,
 				"key2": map[string]string{"key1": "val1"},
 			},
@@ -45,7 +63,7 @@ func Test_validateCustomAppMetricsData(t *testing.T) {
 		},
 		{
 			name: "nil value",
-			data: CustomAppMetricsData{
+			data: types.CustomAppMetricsData{
 				"key1": nil,
 				"key2": 4,
 			},
@@ -64,3 +82,243 @@ func Test_validateCustomAppMetricsData(t *testing.T) {
 		})
 	}
 }
+
+func Test_getUpdatesOptions(t *testing.T) {
+	store.InitInMemory(store.InitInMemoryStoreOptions{
+		License: licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+			Spec: v1beta1.LicenseSpec{IsSemverRequired: true},
+		}},
+	})
+	defer store.SetStore(nil)
+
+	tests := []struct {
+		name    string
+		query   string
+		want    upstreamtypes.UpdatesOptions
+		wantErr string
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  upstreamtypes.UpdatesOptions{IncludePrerelease: true, SemverRequired: true},
+		},
+		{
+			name:  "filters",
+			query: "?maxBump=minor&includePrerelease=false",
+			want:  upstreamtypes.UpdatesOptions{MaxBump: upstreamtypes.SemverBumpMinor, SemverRequired: true},
+		},
+		{
+			name:    "invalid max bump",
+			query:   "?maxBump=huge",
+			wantErr: `invalid maxBump "huge"`,
+		},
+		{
+			name:    "invalid include prerelease",
+			query:   "?includePrerelease=maybe",
+			wantErr: `invalid includePrerelease "maybe"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newTestRequest("GET", "/api/v1/app/updates"+tt.query, nil)
+			opts, err := getUpdatesOptions(r)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, opts)
+		})
+	}
+}
+
+func Test_getOtherUpdatesChannel(t *testing.T) {
+	store.InitInMemory(store.InitInMemoryStoreOptions{
+		License: licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+			Spec: v1beta1.LicenseSpec{
+				ChannelID: "stable-id",
+				Channels: []v1beta1.Channel{
+					{ChannelID: "stable-id", IsDefault: true},
+					{ChannelID: "beta-id", IsSemverRequired: true},
+				},
+			},
+		}},
+		ChannelID: "stable-id",
+	})
+	defer store.SetStore(nil)
+
+	tests := []struct {
+		name    string
+		query   string
+		want    *sdklicensetypes.LicenseChannel
+		wantErr string
+	}{
+		{
+			name:  "no channel",
+			query: "",
+		},
+		{
+			name:  "installed channel",
+			query: "?channel=stable-id",
+		},
+		{
+			name:  "another channel",
+			query: "?channel=beta-id",
+			want:  &sdklicensetypes.LicenseChannel{ChannelID: "beta-id", IsSemverRequired: true},
+		},
+		{
+			name:    "channel the license is not entitled to",
+			query:   "?channel=unstable-id",
+			wantErr: `license is not entitled to channel "unstable-id"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newTestRequest("GET", "/api/v1/app/updates"+tt.query, nil)
+			channel, err := getOtherUpdatesChannel(r)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, channel)
+		})
+	}
+}
+
+func Test_ImportReleaseIndex(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	signature, err := licensetest.AppKeySignature(&key.PublicKey)
+	require.NoError(t, err)
+
+	license := licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+		Spec: v1beta1.LicenseSpec{
+			LicenseID: "license-id",
+			AppSlug:   "my-app",
+			ChannelID: "channel-id",
+			Signature: signature,
+		},
+	}}
+
+	indexBytes, err := json.Marshal(upstreamtypes.ReleaseIndex{
+		AppSlug: "my-app",
+		Releases: []upstreamtypes.ReleaseIndexEntry{
+			{ChannelRelease: upstreamtypes.ChannelRelease{ChannelSequence: 1, VersionLabel: "1.0.0"}, ChannelID: "channel-id"},
+			{ChannelRelease: upstreamtypes.ChannelRelease{ChannelSequence: 2, VersionLabel: "1.1.0"}, ChannelID: "channel-id"},
+			{ChannelRelease: upstreamtypes.ChannelRelease{ChannelSequence: 3, VersionLabel: "2.0.0"}, ChannelID: "other-channel-id"},
+		},
+	})
+	require.NoError(t, err)
+
+	signIndex := func(key *rsa.PrivateKey) string {
+		hashed := sha256.Sum256(indexBytes)
+		signature, err := rsa.SignPSS(rand.Reader, key, crypto.SHA256, hashed[:], nil)
+		require.NoError(t, err)
+
+		signedIndexBytes, err := json.Marshal(upstreamtypes.SignedReleaseIndex{Index: indexBytes, Signature: signature})
+		require.NoError(t, err)
+		return string(signedIndexBytes)
+	}
+
+	tests := []struct {
+		name         string
+		airgap       bool
+		readOnlyMode bool
+		body         string
+		wantCode     int
+		wantErrCode  types.ErrorCode
+	}{
+		{
+			name:     "signed with the app key",
+			airgap:   true,
+			body:     signIndex(key),
+			wantCode: http.StatusOK,
+		},
+		{
+			name:         "read-only mode",
+			airgap:       true,
+			readOnlyMode: true,
+			body:         signIndex(key),
+			wantCode:     http.StatusUnprocessableEntity,
+			wantErrCode:  types.ErrorCodeReadOnlyMode,
+		},
+		{
+			name:        "larger than the limit",
+			airgap:      true,
+			body:        `{"index": "` + strings.Repeat("a", maxReleaseIndexBodySize) + `"}`,
+			wantCode:    http.StatusRequestEntityTooLarge,
+			wantErrCode: types.ErrorCodeInvalidRequest,
+		},
+		{
+			name:        "signed with another key",
+			airgap:      true,
+			body:        signIndex(otherKey),
+			wantCode:    http.StatusUnprocessableEntity,
+			wantErrCode: types.ErrorCodeReleaseIndexRejected,
+		},
+		{
+			name:        "not a release index",
+			airgap:      true,
+			body:        `{"releases": []}`,
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: types.ErrorCodeInvalidRequest,
+		},
+		{
+			name:        "not airgap",
+			body:        signIndex(key),
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: types.ErrorCodeNotAirgap,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			if tt.airgap {
+				t.Setenv("DISABLE_OUTBOUND_CONNECTIONS", "true")
+			}
+
+			store.InitInMemory(store.InitInMemoryStoreOptions{
+				License:         license,
+				ChannelID:       "channel-id",
+				ChannelSequence: 1,
+				VersionLabel:    "1.0.0",
+				Namespace:       "default",
+				ReadOnlyMode:    tt.readOnlyMode,
+			})
+			defer store.SetStore(nil)
+
+			clientset := fake.NewSimpleClientset(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "replicated", Namespace: "default"},
+			})
+			SetTestClientSet(clientset)
+			defer SetTestClientSet(nil)
+
+			r, recorder := newTestRequest("PUT", "/api/v1/app/updates/index", []byte(tt.body))
+			ImportReleaseIndex(recorder, r)
+			req.Equal(tt.wantCode, recorder.Code)
+
+			secret, err := clientset.CoreV1().Secrets("default").Get(context.Background(), "replicated", metav1.GetOptions{})
+			req.NoError(err)
+
+			if tt.wantErrCode != "" {
+				var response types.ErrorResponse
+				req.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+				req.Equal(tt.wantErrCode, response.Code)
+				req.Empty(store.GetStore().GetUpdates())
+				req.Empty(secret.Data)
+				return
+			}
+
+			var response []upstreamtypes.ChannelRelease
+			req.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+			req.Equal([]upstreamtypes.ChannelRelease{{ChannelSequence: 2, VersionLabel: "1.1.0", Bump: upstreamtypes.SemverBumpMinor}}, response)
+			req.Equal([]upstreamtypes.ChannelRelease{{ChannelSequence: 2, VersionLabel: "1.1.0"}}, store.GetStore().GetUpdates())
+			req.Equal(tt.body, string(secret.Data["release-index"]))
+		})
+	}
+}
@@ -8,7 +8,7 @@ func CORS(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, DELETE, PUT")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Headers", "content-type, origin, accept, authorization")
-	w.Header().Set("Access-Control-Expose-Headers", "content-disposition")
+	w.Header().Set("Access-Control-Expose-Headers", "content-disposition, x-request-id, x-replicated-upstream-error")
 }
 
 func handleOptionsRequest(w http.ResponseWriter, r *http.Request) (isOptionsRequest bool) {
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/diagnostics"
+	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"k8s.io/client-go/kubernetes"
+)
+
+// GetDiagnostics returns a handler that checks the permissions and environment of the SDK.
+// It is served during bootstrap as well, since missing permissions are a common reason for bootstrap to fail.
+func GetDiagnostics(opts diagnostics.Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var clientset kubernetes.Interface
+		if testClientSet != nil {
+			clientset = testClientSet
+		} else {
+			var err error
+			clientset, err = k8sutil.GetClientset()
+			if err != nil {
+				JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
+				return
+			}
+		}
+
+		requestOpts := opts
+		// without configured status informers, the informers are generated from the helm release during bootstrap
+		if len(requestOpts.StatusInformers) == 0 && store.IsInitialized() {
+			for _, resourceState := range store.GetStore().GetAppStatus().ResourceStates {
+				informer := fmt.Sprintf("%s/%s/%s", resourceState.Namespace, resourceState.Kind, resourceState.Name)
+				requestOpts.StatusInformers = append(requestOpts.StatusInformers, appstatetypes.StatusInformerString(informer))
+			}
+		}
+
+		JSON(w, http.StatusOK, diagnostics.Run(r.Context(), clientset, requestOpts))
+	}
+}
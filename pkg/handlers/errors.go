@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"github.com/replicatedhq/replicated-sdk/pkg/util"
+)
+
+// UpstreamErrorHeader is set on responses that are served from the last known data because the Replicated API could not be reached
+const UpstreamErrorHeader = "X-Replicated-Upstream-Error"
+
+// APIError is an error that is returned to the client as is
+type APIError struct {
+	StatusCode int
+	Code       types.ErrorCode
+	Message    string
+	Retryable  bool
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func NewAPIError(statusCode int, code types.ErrorCode, message string) *APIError {
+	return &APIError{
+		StatusCode: statusCode,
+		Code:       code,
+		Message:    message,
+	}
+}
+
+// JSONError writes the error response for err.
+// An *APIError anywhere in the chain is returned as is. Errors returned by the Replicated API are passed on with their message,
+// and failures to reach it are reported as retryable. Anything else is an internal error.
+// Server errors are logged with the request ID, and only a generic message is returned for them, since the error chain
+// may include internal details such as upstream responses or kubernetes errors.
+func JSONError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := toAPIError(err)
+
+	requestID := GetRequestID(r)
+	if apiErr.StatusCode >= http.StatusInternalServerError {
+		logger.Errorf("request %s failed: %v", requestID, err)
+	}
+
+	JSON(w, apiErr.StatusCode, types.ErrorResponse{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		RequestID: requestID,
+		Retryable: apiErr.Retryable,
+		Error:     apiErr.Message,
+	})
+}
+
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var actionableErr util.ActionableError
+	if errors.As(err, &actionableErr) {
+		return &APIError{
+			StatusCode: http.StatusBadGateway,
+			Code:       types.ErrorCodeUpstreamRejected,
+			Message:    actionableErr.Message,
+			Retryable:  actionableErr.StatusCode == http.StatusTooManyRequests || actionableErr.StatusCode >= http.StatusInternalServerError,
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return &APIError{
+			StatusCode: http.StatusBadGateway,
+			Code:       types.ErrorCodeUpstreamUnavailable,
+			Message:    "failed to reach the Replicated API",
+			Retryable:  true,
+		}
+	}
+
+	return &APIError{
+		StatusCode: http.StatusInternalServerError,
+		Code:       types.ErrorCodeInternal,
+		Message:    "internal error",
+	}
+}
+
+// ReadOnlyModeError writes the error response for a request that modifies state while in read-only mode
+func ReadOnlyModeError(w http.ResponseWriter, r *http.Request, message string) {
+	JSON(w, http.StatusUnprocessableEntity, types.ErrorResponse{
+		Code:         types.ErrorCodeReadOnlyMode,
+		Message:      message,
+		RequestID:    GetRequestID(r),
+		Error:        message,
+		ReadOnlyMode: true,
+	})
+}
+
+// NotFound is the handler for requests that don't match any route
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	JSONError(w, r, NewAPIError(http.StatusNotFound, types.ErrorCodeNotFound, "route not found"))
+}
+
+// MethodNotAllowed is the handler for requests that match a route with a different method
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	JSONError(w, r, NewAPIError(http.StatusMethodNotAllowed, types.ErrorCodeMethodNotAllowed, "method not allowed"))
+}
+
+// SetUpstreamErrorHeader reports the code of the error that kept a response from being refreshed from the Replicated API,
+// for responses that are served from the last known data instead of failing
+func SetUpstreamErrorHeader(w http.ResponseWriter, err error) {
+	w.Header().Set(UpstreamErrorHeader, string(toAPIError(err).Code))
+}
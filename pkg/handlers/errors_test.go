@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantStatusCode int
+		wantResponse   types.ErrorResponse
+	}{
+		{
+			name:           "api error",
+			err:            NewAPIError(http.StatusNotFound, types.ErrorCodeNotFound, `license field "foo" not found`),
+			wantStatusCode: http.StatusNotFound,
+			wantResponse: types.ErrorResponse{
+				Code:    types.ErrorCodeNotFound,
+				Message: `license field "foo" not found`,
+				Error:   `license field "foo" not found`,
+			},
+		},
+		{
+			name:           "wrapped api error",
+			err:            errors.Wrap(NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, "key cannot be empty"), "failed to delete"),
+			wantStatusCode: http.StatusBadRequest,
+			wantResponse: types.ErrorResponse{
+				Code:    types.ErrorCodeInvalidRequest,
+				Message: "key cannot be empty",
+				Error:   "key cannot be empty",
+			},
+		},
+		{
+			name:           "upstream rejected the request",
+			err:            errors.Wrap(util.ActionableError{Message: "license is expired", StatusCode: http.StatusForbidden}, "failed to get upload url"),
+			wantStatusCode: http.StatusBadGateway,
+			wantResponse: types.ErrorResponse{
+				Code:    types.ErrorCodeUpstreamRejected,
+				Message: "license is expired",
+				Error:   "license is expired",
+			},
+		},
+		{
+			name:           "upstream server error",
+			err:            errors.Wrap(util.ActionableError{Message: "service unavailable", StatusCode: http.StatusServiceUnavailable}, "failed to send custom metrics"),
+			wantStatusCode: http.StatusBadGateway,
+			wantResponse: types.ErrorResponse{
+				Code:      types.ErrorCodeUpstreamRejected,
+				Message:   "service unavailable",
+				Retryable: true,
+				Error:     "service unavailable",
+			},
+		},
+		{
+			name:           "upstream unreachable",
+			err:            errors.Wrap(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, "failed to execute request"),
+			wantStatusCode: http.StatusBadGateway,
+			wantResponse: types.ErrorResponse{
+				Code:      types.ErrorCodeUpstreamUnavailable,
+				Message:   "failed to reach the Replicated API",
+				Retryable: true,
+				Error:     "failed to reach the Replicated API",
+			},
+		},
+		{
+			name:           "internal error",
+			err:            errors.Wrap(errors.New("secrets is forbidden"), "failed to get mock data"),
+			wantStatusCode: http.StatusInternalServerError,
+			wantResponse: types.ErrorResponse{
+				Code:    types.ErrorCodeInternal,
+				Message: "internal error",
+				Error:   "internal error",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				JSONError(w, r, tt.err)
+			}))
+
+			r := httptest.NewRequest("GET", "/api/v1/app/info", nil)
+			r.Header.Set(RequestIDHeader, "request-1")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			req.Equal(tt.wantStatusCode, w.Code)
+			req.Equal("request-1", w.Header().Get(RequestIDHeader))
+
+			var response types.ErrorResponse
+			req.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+
+			tt.wantResponse.RequestID = "request-1"
+			req.Equal(tt.wantResponse, response)
+		})
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		header        string
+		wantGenerated bool
+	}{
+		{
+			name:          "no request id",
+			wantGenerated: true,
+		},
+		{
+			name:   "valid request id",
+			header: "5d8f1e2a-1c1b-4c59-9a57-2f4b1d9e8c01",
+		},
+		{
+			name:          "invalid request id",
+			header:        "bad id\r\nX-Injected: true",
+			wantGenerated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			var requestID string
+			handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestID = GetRequestID(r)
+			}))
+
+			r := httptest.NewRequest("GET", "/healthz", nil)
+			if tt.header != "" {
+				r.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			req.NotEmpty(requestID)
+			req.Equal(requestID, w.Header().Get(RequestIDHeader))
+			if tt.wantGenerated {
+				req.Len(requestID, 32)
+			} else {
+				req.Equal(tt.header, requestID)
+			}
+		})
+	}
+}
@@ -2,15 +2,26 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/logger"
 )
 
 const (
 	MockDataHeader = "X-Replicated-Mock-Data"
+	// UpdatesLastCheckedAtHeader is when the available updates were last checked successfully
+	UpdatesLastCheckedAtHeader = "X-Replicated-Updates-Last-Checked-At"
+	// UpdatesStaleHeader is true if the last check for updates failed, or if updates were not checked recently
+	UpdatesStaleHeader = "X-Replicated-Updates-Stale"
 )
 
+// maxJSONBodySize is the largest JSON request body that is read
+const maxJSONBodySize = 1 << 20
+
 func JSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, err := json.Marshal(payload)
 	if err != nil {
@@ -37,3 +48,16 @@ func JSONCached(w http.ResponseWriter, code int, payload interface{}) {
 	w.WriteHeader(code)
 	w.Write(response)
 }
+
+// readRequestBody reads the request body, and rejects bodies larger than limit bytes with a 413
+func readRequestBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, NewAPIError(http.StatusRequestEntityTooLarge, types.ErrorCodeInvalidRequest, fmt.Sprintf("request body is larger than %d bytes", limit))
+		}
+		return nil, errors.Wrap(err, "failed to read request body")
+	}
+	return body, nil
+}
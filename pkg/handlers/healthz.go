@@ -4,16 +4,25 @@ import (
 	"net/http"
 
 	"github.com/replicatedhq/replicated-sdk/pkg/buildversion"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/readiness"
 )
 
-type HealthzResponse struct {
-	Version string `json:"version"`
-}
-
 func Healthz(w http.ResponseWriter, r *http.Request) {
-	healthzResponse := HealthzResponse{
+	healthzResponse := types.HealthzResponse{
 		Version: buildversion.Version(),
 	}
 
 	JSON(w, http.StatusOK, healthzResponse)
 }
+
+// Readyz reports the state of each bootstrap phase and returns 503 until bootstrap has completed
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	status := readiness.GetStatus()
+	if !status.Ready {
+		JSON(w, http.StatusServiceUnavailable, status)
+		return
+	}
+
+	JSON(w, http.StatusOK, status)
+}
@@ -1,76 +1,234 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
 	kotsv1beta2 "github.com/replicatedhq/kotskinds/apis/kots/v1beta2"
 	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
 	sdklicense "github.com/replicatedhq/replicated-sdk/pkg/license"
 	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/logger"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"github.com/replicatedhq/replicated-sdk/pkg/updates"
 	"github.com/replicatedhq/replicated-sdk/pkg/util"
+	"github.com/replicatedhq/replicated-sdk/pkg/webhook"
+	"k8s.io/client-go/kubernetes"
 )
 
-type LicenseInfo struct {
-	LicenseID                      string      `json:"licenseID"`
-	AppSlug                        string      `json:"appSlug"`
-	ChannelName                    string      `json:"channelName"`
-	CustomerID                     string      `json:"customerID"`
-	CustomerName                   string      `json:"customerName"`
-	CustomerEmail                  string      `json:"customerEmail"`
-	LicenseType                    string      `json:"licenseType"`
-	ChannelID                      string      `json:"channelID"`
-	LicenseSequence                int64       `json:"licenseSequence"`
-	IsAirgapSupported              bool        `json:"isAirgapSupported"`
-	IsGitOpsSupported              bool        `json:"isGitOpsSupported"`
-	IsIdentityServiceSupported     bool        `json:"isIdentityServiceSupported"`
-	IsGeoaxisSupported             bool        `json:"isGeoaxisSupported"`
-	IsSnapshotSupported            bool        `json:"isSnapshotSupported"`
-	IsSupportBundleUploadSupported bool        `json:"isSupportBundleUploadSupported"`
-	IsSemverRequired               bool        `json:"isSemverRequired"`
-	Endpoint                       string      `json:"endpoint"`
-	Entitlements                   interface{} `json:"entitlements,omitempty"`
-}
-
 func GetLicenseInfo(w http.ResponseWriter, r *http.Request) {
 	wrapper := store.GetStore().GetLicense()
 
 	if !util.IsAirgap() {
-		l, err := sdklicense.GetLatestLicense(wrapper, store.GetStore().GetReplicatedAppEndpoint())
+		l, err := sdklicense.GetLatestLicenseOnRequest(wrapper, store.GetStore().GetReplicatedAppEndpoint())
 		if err != nil {
 			logger.Error(errors.Wrap(err, "failed to get latest license"))
-			JSONCached(w, http.StatusOK, licenseInfoFromWrapper(wrapper))
+			SetUpstreamErrorHeader(w, err)
+			JSONCached(w, http.StatusOK, getLicenseInfo(wrapper))
 			return
 		}
 
+		webhook.NotifyLicenseChanged(wrapper, l.License)
 		wrapper = l.License
 		store.GetStore().SetLicense(wrapper)
+		sdklicense.RecordLicenseSync(l)
+	}
+
+	JSON(w, http.StatusOK, getLicenseInfo(wrapper))
+}
+
+// getLicenseInfo returns the license info with the expiration state re-evaluated, so that
+// a license that expired since the last sync is reported as such even if the sync failed.
+// The state in the store is only updated by the license syncs, not on the request path.
+func getLicenseInfo(wrapper licensewrapper.LicenseWrapper) types.LicenseInfo {
+	expiration, err := sdklicense.GetLicenseExpirationStatus(wrapper, sdklicense.GetExpirationOptions(), time.Now())
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to evaluate license expiration"))
+		expiration = store.GetStore().GetLicenseExpiration()
 	}
+	syncStatus := sdklicense.GetLicenseSyncStatus(time.Now())
 
-	JSON(w, http.StatusOK, licenseInfoFromWrapper(wrapper))
+	licenseInfo := licenseInfoFromWrapper(wrapper)
+	licenseInfo.Expiration = &expiration
+	licenseInfo.Sync = &syncStatus
+	return licenseInfo
 }
 
 func GetLicenseFields(w http.ResponseWriter, r *http.Request) {
+	licenseFields, cached := getLatestLicenseFields(w)
+	licenseFields = servableLicenseFields(licenseFields)
+	if cached {
+		JSONCached(w, http.StatusOK, licenseFields)
+		return
+	}
+
+	JSON(w, http.StatusOK, licenseFields)
+}
+
+// getLatestLicenseFields syncs the license fields, and falls back to the last known fields if the upstream is unavailable,
+// in which case it returns true and sets the upstream error header
+func getLatestLicenseFields(w http.ResponseWriter) (sdklicensetypes.LicenseFields, bool) {
 	licenseFields := store.GetStore().GetLicenseFields()
 
 	if !util.IsAirgap() {
 		fields, err := sdklicense.GetLatestLicenseFields(store.GetStore().GetLicense(), store.GetStore().GetReplicatedAppEndpoint())
 		if err != nil {
 			logger.Error(errors.Wrap(err, "failed to get latest license fields"))
-			JSONCached(w, http.StatusOK, licenseFields)
-			return
+			SetUpstreamErrorHeader(w, err)
+			return licenseFields, true
 		}
 
-		licenseFields = fields
+		licenseFields = sdklicense.VerifyLicenseFields(store.GetStore().GetLicense(), fields)
 		store.GetStore().SetLicenseFields(licenseFields)
+		sdklicense.RecordLicenseFieldsSync(licenseFields)
 	}
 
-	JSON(w, http.StatusOK, licenseFields)
+	return licenseFields, false
+}
+
+// servableLicenseFields leaves out the fields that failed signature verification in strict mode
+func servableLicenseFields(licenseFields sdklicensetypes.LicenseFields) sdklicensetypes.LicenseFields {
+	if !store.GetStore().GetStrictLicenseFields() {
+		return licenseFields
+	}
+
+	servable := sdklicensetypes.LicenseFields{}
+	for name, field := range licenseFields {
+		if field.Verified {
+			servable[name] = field
+		}
+	}
+	return servable
+}
+
+func EvaluateEntitlements(w http.ResponseWriter, r *http.Request) {
+	request := types.EvaluateEntitlementsRequest{}
+	decoder := json.NewDecoder(r.Body)
+	// keep integers exact rather than decoding them as float64
+	decoder.UseNumber()
+	if err := decoder.Decode(&request); err != nil {
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("invalid request body: %v", err)))
+		return
+	}
+
+	if len(request.Checks) == 0 {
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, "at least one check is required"))
+		return
+	}
+	for i, check := range request.Checks {
+		if err := check.Validate(); err != nil {
+			JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("checks[%d]: %v", i, err)))
+			return
+		}
+	}
+
+	licenseFields, cached := getLatestLicenseFields(w)
+	licenseFields = servableLicenseFields(licenseFields)
+
+	response := types.EvaluateEntitlementsResponse{
+		Passed:  true,
+		Results: sdklicense.EvaluateEntitlements(store.GetStore().GetLicense(), licenseFields, request.Checks),
+	}
+	for _, result := range response.Results {
+		if !result.Passed {
+			response.Passed = false
+		}
+	}
+
+	if cached {
+		JSONCached(w, http.StatusOK, response)
+		return
+	}
+
+	JSON(w, http.StatusOK, response)
+}
+
+// GetLicenseChannels returns the channels that the license is entitled to, and the channels the app is installed from and gets updates from
+func GetLicenseChannels(w http.ResponseWriter, r *http.Request) {
+	wrapper := store.GetStore().GetLicense()
+	installedChannelID := store.GetStore().GetChannelID()
+
+	JSON(w, http.StatusOK, types.GetLicenseChannelsResponse{
+		Channels:           sdklicense.GetLicenseChannels(wrapper),
+		InstalledChannelID: installedChannelID,
+		UpdatesChannelID:   sdklicense.GetUpdatesChannelID(wrapper, installedChannelID),
+	})
+}
+
+// maxLicenseBodySize is the largest license that can be uploaded
+const maxLicenseBodySize = 1 << 20
+
+// uploadLicenseMtx serializes license uploads, so that each upload is validated against the license it replaces
+var uploadLicenseMtx sync.Mutex
+
+// UploadLicense replaces the license with the license in the request body, e.g. with a renewed license in an airgap install.
+// The license must have a valid signature, and must be the current license with the same or a later sequence.
+// It is persisted in the replicated secret, so that it is still served after a restart.
+func UploadLicense(w http.ResponseWriter, r *http.Request) {
+	if store.GetStore().GetReadOnlyMode() {
+		ReadOnlyModeError(w, r, "license uploads are unavailable in read-only mode")
+		return
+	}
+
+	licenseBytes, err := readRequestBody(w, r, maxLicenseBodySize)
+	if err != nil {
+		JSONError(w, r, err)
+		return
+	}
+	if len(bytes.TrimSpace(licenseBytes)) == 0 {
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, "license is required"))
+		return
+	}
+
+	updated, err := sdklicense.LoadLicenseFromBytes(licenseBytes)
+	if err != nil {
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("invalid license: %v", err)))
+		return
+	}
+
+	uploadLicenseMtx.Lock()
+	defer uploadLicenseMtx.Unlock()
+
+	previous := store.GetStore().GetLicense()
+	if err := sdklicense.ValidateLicenseReplacement(previous, updated); err != nil {
+		JSONError(w, r, NewAPIError(http.StatusUnprocessableEntity, types.ErrorCodeLicenseRejected, err.Error()))
+		return
+	}
+
+	var clientset kubernetes.Interface
+	if testClientSet != nil {
+		clientset = testClientSet
+	} else {
+		clientset, err = k8sutil.GetClientset()
+		if err != nil {
+			JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
+			return
+		}
+	}
+
+	if err := sdklicense.SaveUploadedLicense(r.Context(), clientset, store.GetStore().GetNamespace(), licenseBytes); err != nil {
+		JSONError(w, r, errors.Wrap(err, "failed to save license"))
+		return
+	}
+
+	sdklicense.SetLicense(store.GetStore(), updated)
+	if previous.GetLicenseSequence() != updated.GetLicenseSequence() {
+		// the releases in an imported release index that are available depend on the license sequence
+		updates.RefilterReleaseIndexUpdates()
+	}
+	logger.Infof("Replaced license with an uploaded license, sequence %d -> %d", previous.GetLicenseSequence(), updated.GetLicenseSequence())
+	webhook.NotifyLicenseChanged(previous, updated)
+
+	JSON(w, http.StatusOK, getLicenseInfo(updated))
 }
 
 func GetLicenseField(w http.ResponseWriter, r *http.Request) {
@@ -85,8 +243,11 @@ func GetLicenseField(w http.ResponseWriter, r *http.Request) {
 		field, err := sdklicense.GetLatestLicenseField(store.GetStore().GetLicense(), store.GetStore().GetReplicatedAppEndpoint(), fieldName)
 		if err != nil {
 			logger.Error(errors.Wrap(err, "failed to get latest license field"))
+			SetUpstreamErrorHeader(w, err)
 			if lf, ok := licenseFields[fieldName]; !ok {
-				JSONCached(w, http.StatusNotFound, fmt.Sprintf("license field %q not found", fieldName))
+				JSONError(w, r, NewAPIError(http.StatusNotFound, types.ErrorCodeNotFound, fmt.Sprintf("license field %q not found", fieldName)))
+			} else if apiErr := checkLicenseFieldServable(fieldName, lf); apiErr != nil {
+				JSONError(w, r, apiErr)
 			} else {
 				JSONCached(w, http.StatusOK, lf)
 			}
@@ -97,20 +258,35 @@ func GetLicenseField(w http.ResponseWriter, r *http.Request) {
 			// field might not exist or has been removed
 			delete(licenseFields, fieldName)
 		} else {
-			licenseFields[fieldName] = *field
+			verified := sdklicense.VerifyLicenseFields(store.GetStore().GetLicense(), sdklicensetypes.LicenseFields{fieldName: *field})
+			licenseFields[fieldName] = verified[fieldName]
 		}
 		store.GetStore().SetLicenseFields(licenseFields)
+		sdklicense.RecordLicenseFieldsSync(licenseFields)
 	}
 
-	if _, ok := licenseFields[fieldName]; !ok {
-		JSON(w, http.StatusNotFound, fmt.Sprintf("license field %q not found", fieldName))
+	lf, ok := licenseFields[fieldName]
+	if !ok {
+		JSONError(w, r, NewAPIError(http.StatusNotFound, types.ErrorCodeNotFound, fmt.Sprintf("license field %q not found", fieldName)))
 		return
 	}
+	if apiErr := checkLicenseFieldServable(fieldName, lf); apiErr != nil {
+		JSONError(w, r, apiErr)
+		return
+	}
+
+	JSON(w, http.StatusOK, lf)
+}
 
-	JSON(w, http.StatusOK, licenseFields[fieldName])
+// checkLicenseFieldServable refuses to serve a field that failed signature verification in strict mode
+func checkLicenseFieldServable(fieldName string, field sdklicensetypes.LicenseField) *APIError {
+	if !store.GetStore().GetStrictLicenseFields() || field.Verified {
+		return nil
+	}
+	return NewAPIError(http.StatusForbidden, types.ErrorCodeLicenseFieldUnverified, fmt.Sprintf("license field %q failed signature verification", fieldName))
 }
 
-func licenseInfoFromWrapper(wrapper licensewrapper.LicenseWrapper) LicenseInfo {
+func licenseInfoFromWrapper(wrapper licensewrapper.LicenseWrapper) types.LicenseInfo {
 	// Convert EntitlementFieldWrapper map to version-specific EntitlementField map
 	// Return v1 format for v1 licenses, v2 format for v2 licenses
 	var entitlements interface{}
@@ -135,7 +311,7 @@ func licenseInfoFromWrapper(wrapper licensewrapper.LicenseWrapper) LicenseInfo {
 		}
 	}
 
-	return LicenseInfo{
+	return types.LicenseInfo{
 		LicenseID:                      wrapper.GetLicenseID(),
 		AppSlug:                        wrapper.GetAppSlug(),
 		ChannelName:                    wrapper.GetChannelName(),
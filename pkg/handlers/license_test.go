@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	sdklicense "github.com/replicatedhq/replicated-sdk/pkg/license"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/licensetest"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_EvaluateEntitlements(t *testing.T) {
+	t.Setenv("DISABLE_OUTBOUND_CONNECTIONS", "true")
+
+	store.InitInMemory(store.InitInMemoryStoreOptions{
+		License: licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+			Spec: v1beta1.LicenseSpec{
+				LicenseID: "license-id",
+				Entitlements: map[string]v1beta1.EntitlementField{
+					"seat_count": {ValueType: "Integer", Value: v1beta1.EntitlementValue{Type: v1beta1.Int, IntVal: 50}},
+					"feature_x":  {ValueType: "Boolean", Value: v1beta1.EntitlementValue{Type: v1beta1.Bool, BoolVal: true}},
+				},
+			},
+		}},
+		LicenseFields: sdklicensetypes.LicenseFields{
+			"seat_count": {Name: "seat_count", ValueType: "Integer", Value: float64(50)},
+			"feature_x":  {Name: "feature_x", ValueType: "Boolean", Value: "false"},
+			"tier":       {Name: "tier", ValueType: "String", Value: "gold"},
+		},
+	})
+	defer store.SetStore(nil)
+
+	tests := []struct {
+		name         string
+		body         string
+		wantCode     int
+		wantResponse types.EvaluateEntitlementsResponse
+		wantError    string
+	}{
+		{
+			name:     "checks pass",
+			body:     `{"checks": [{"field": "seat_count", "op": ">=", "value": 25}, {"field": "tier", "op": "==", "value": "gold"}]}`,
+			wantCode: http.StatusOK,
+			wantResponse: types.EvaluateEntitlementsResponse{
+				Passed: true,
+				Results: []sdklicensetypes.EntitlementCheckResult{
+					{Field: "seat_count", Op: ">=", Value: float64(25), ActualValue: float64(50), ValueType: "Integer", Passed: true, Signature: sdklicensetypes.SignatureStatusVerified},
+					{Field: "tier", Op: "==", Value: "gold", ActualValue: "gold", ValueType: "String", Passed: true, Signature: sdklicensetypes.SignatureStatusUnverified},
+				},
+			},
+		},
+		{
+			name:     "checks fail",
+			body:     `{"checks": [{"field": "feature_x", "op": "enabled"}, {"field": "tier", "op": ">", "value": "silver"}, {"field": "missing", "op": "enabled"}]}`,
+			wantCode: http.StatusOK,
+			wantResponse: types.EvaluateEntitlementsResponse{
+				Passed: false,
+				Results: []sdklicensetypes.EntitlementCheckResult{
+					{Field: "feature_x", Op: "enabled", ActualValue: false, ValueType: "Boolean", Passed: false, Signature: sdklicensetypes.SignatureStatusInvalid},
+					{Field: "tier", Op: ">", Value: "silver", ActualValue: "gold", ValueType: "String", Passed: false, Signature: sdklicensetypes.SignatureStatusUnverified, Error: `operator ">" is only supported for Integer fields`},
+					{Field: "missing", Op: "enabled", Passed: false, Signature: sdklicensetypes.SignatureStatusUnverified, Error: `license field "missing" not found`},
+				},
+			},
+		},
+		{
+			name:      "no checks",
+			body:      `{"checks": []}`,
+			wantCode:  http.StatusBadRequest,
+			wantError: "at least one check is required",
+		},
+		{
+			name:      "missing value",
+			body:      `{"checks": [{"field": "seat_count", "op": ">="}]}`,
+			wantCode:  http.StatusBadRequest,
+			wantError: `checks[0]: operator ">=" requires a value`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			r, recorder := newTestRequest("POST", "/api/v1/license/entitlements/evaluate", []byte(tt.body))
+			EvaluateEntitlements(recorder, r)
+			req.Equal(tt.wantCode, recorder.Code)
+
+			if tt.wantError != "" {
+				var response types.ErrorResponse
+				req.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+				req.Equal(tt.wantError, response.Message)
+				return
+			}
+
+			var response types.EvaluateEntitlementsResponse
+			req.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+			req.Equal(tt.wantResponse, response)
+		})
+	}
+}
+
+func Test_GetLicenseFieldsStrict(t *testing.T) {
+	t.Setenv("DISABLE_OUTBOUND_CONNECTIONS", "true")
+
+	licenseFields := sdklicensetypes.LicenseFields{
+		"seat_count": {Name: "seat_count", ValueType: "Integer", Value: float64(50), Verified: true},
+		"tier":       {Name: "tier", ValueType: "String", Value: "gold"},
+	}
+
+	tests := []struct {
+		name                string
+		strictLicenseFields bool
+		wantFields          []string
+		wantTierCode        int
+	}{
+		{
+			name:         "not strict",
+			wantFields:   []string{"seat_count", "tier"},
+			wantTierCode: http.StatusOK,
+		},
+		{
+			name:                "strict",
+			strictLicenseFields: true,
+			wantFields:          []string{"seat_count"},
+			wantTierCode:        http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			store.InitInMemory(store.InitInMemoryStoreOptions{
+				License:             licensewrapper.LicenseWrapper{V1: &v1beta1.License{}},
+				LicenseFields:       licenseFields,
+				StrictLicenseFields: tt.strictLicenseFields,
+			})
+			defer store.SetStore(nil)
+
+			r, recorder := newTestRequest("GET", "/api/v1/license/fields", nil)
+			GetLicenseFields(recorder, r)
+			req.Equal(http.StatusOK, recorder.Code)
+
+			var fields sdklicensetypes.LicenseFields
+			req.NoError(json.Unmarshal(recorder.Body.Bytes(), &fields))
+			var names []string
+			for name := range fields {
+				names = append(names, name)
+			}
+			req.ElementsMatch(tt.wantFields, names)
+
+			r, recorder = newTestRequest("GET", "/api/v1/license/fields/tier", nil)
+			r = mux.SetURLVars(r, map[string]string{"fieldName": "tier"})
+			GetLicenseField(recorder, r)
+			req.Equal(tt.wantTierCode, recorder.Code)
+
+			if tt.wantTierCode != http.StatusOK {
+				var response types.ErrorResponse
+				req.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+				req.Equal(types.ErrorCodeLicenseFieldUnverified, response.Code)
+			}
+		})
+	}
+}
+
+func Test_UploadLicense(t *testing.T) {
+	t.Setenv("DISABLE_OUTBOUND_CONNECTIONS", "true")
+
+	uploaded, err := sdklicense.LoadLicenseFromBytes([]byte(licensetest.ValidSignedLicense))
+	require.NoError(t, err)
+
+	currentLicense := func(appSlug string, sequence int64) licensewrapper.LicenseWrapper {
+		return licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+			Spec: v1beta1.LicenseSpec{LicenseID: uploaded.GetLicenseID(), AppSlug: appSlug, LicenseSequence: sequence},
+		}}
+	}
+
+	tests := []struct {
+		name         string
+		current      licensewrapper.LicenseWrapper
+		readOnlyMode bool
+		body         string
+		wantCode     int
+		wantErrCode  types.ErrorCode
+	}{
+		{
+			name:     "renewed license",
+			current:  currentLicense("my-app", 6),
+			body:     licensetest.ValidSignedLicense,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:        "older license",
+			current:     currentLicense("my-app", 8),
+			body:        licensetest.ValidSignedLicense,
+			wantCode:    http.StatusUnprocessableEntity,
+			wantErrCode: types.ErrorCodeLicenseRejected,
+		},
+		{
+			name:        "license for a different app",
+			current:     currentLicense("other-app", 6),
+			body:        licensetest.ValidSignedLicense,
+			wantCode:    http.StatusUnprocessableEntity,
+			wantErrCode: types.ErrorCodeLicenseRejected,
+		},
+		{
+			name:        "not a license",
+			current:     currentLicense("my-app", 6),
+			body:        "not a license",
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: types.ErrorCodeInvalidRequest,
+		},
+		{
+			name:        "license larger than the limit",
+			current:     currentLicense("my-app", 6),
+			body:        licensetest.ValidSignedLicense + "#" + strings.Repeat("a", maxLicenseBodySize),
+			wantCode:    http.StatusRequestEntityTooLarge,
+			wantErrCode: types.ErrorCodeInvalidRequest,
+		},
+		{
+			name:         "read-only mode",
+			current:      currentLicense("my-app", 6),
+			readOnlyMode: true,
+			body:         licensetest.ValidSignedLicense,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantErrCode:  types.ErrorCodeReadOnlyMode,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			store.InitInMemory(store.InitInMemoryStoreOptions{
+				License:       tt.current,
+				LicenseFields: sdklicensetypes.LicenseFields{"removed_field": {Name: "removed_field", Value: "old"}},
+				Namespace:     "default",
+				ReadOnlyMode:  tt.readOnlyMode,
+			})
+			defer store.SetStore(nil)
+
+			clientset := fake.NewSimpleClientset(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "replicated", Namespace: "default"},
+			})
+			SetTestClientSet(clientset)
+			defer SetTestClientSet(nil)
+
+			r, recorder := newTestRequest("PUT", "/api/v1/license", []byte(tt.body))
+			UploadLicense(recorder, r)
+			req.Equal(tt.wantCode, recorder.Code)
+
+			secret, err := clientset.CoreV1().Secrets("default").Get(context.Background(), "replicated", metav1.GetOptions{})
+			req.NoError(err)
+
+			if tt.wantErrCode != "" {
+				var response types.ErrorResponse
+				req.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+				req.Equal(tt.wantErrCode, response.Code)
+				req.Equal(tt.current, store.GetStore().GetLicense())
+				req.Empty(secret.Data)
+				return
+			}
+
+			var response types.LicenseInfo
+			req.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+			req.Equal(int64(7), response.LicenseSequence)
+			req.Equal(int64(7), store.GetStore().GetLicense().GetLicenseSequence())
+			req.Equal(licensetest.ValidSignedLicense, string(secret.Data["uploaded-license"]))
+
+			// the fields are rebuilt from the entitlements of the uploaded license
+			fields := store.GetStore().GetLicenseFields()
+			req.NotContains(fields, "removed_field")
+			req.Equal("Int Field", fields["int_field"].Title)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
+	"github.com/replicatedhq/replicated-sdk/pkg/license"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"github.com/replicatedhq/replicated-sdk/pkg/meta"
+	"github.com/replicatedhq/replicated-sdk/pkg/metrics"
+	"github.com/replicatedhq/replicated-sdk/pkg/readiness"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"k8s.io/client-go/kubernetes"
+)
+
+var appStates = []appstatetypes.State{
+	appstatetypes.StateReady,
+	appstatetypes.StateUpdating,
+	appstatetypes.StateDegraded,
+	appstatetypes.StateUnavailable,
+	appstatetypes.StateMissing,
+}
+
+// Metrics serves the SDK and application state in the Prometheus text exposition format
+func Metrics(w http.ResponseWriter, r *http.Request) {
+	families := []metrics.Family{readyFamily()}
+
+	if store.IsInitialized() {
+		sdkStore := store.GetStore()
+
+		families = append(families, appStateFamilies(sdkStore.GetAppStatus())...)
+		families = append(families, licenseFamilies(sdkStore)...)
+		families = append(families, metrics.Family{
+			Name:    "replicated_app_updates_available",
+			Help:    "Number of releases available to upgrade to.",
+			Type:    metrics.MetricTypeGauge,
+			Samples: []metrics.Sample{{Value: float64(len(sdkStore.GetUpdates()))}},
+		})
+
+		customMetrics, err := getLatestCustomAppMetrics(r, sdkStore.GetNamespace())
+		if err != nil {
+			logger.Error(errors.Wrap(err, "failed to get custom app metrics"))
+		} else {
+			families = append(families, customMetricsFamily(customMetrics))
+		}
+	}
+
+	families = append(families, metrics.UpstreamFamilies()...)
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	w.WriteHeader(http.StatusOK)
+	if err := metrics.Write(w, families); err != nil {
+		logger.Error(errors.Wrap(err, "failed to write metrics"))
+	}
+}
+
+func readyFamily() metrics.Family {
+	value := 0.0
+	if readiness.IsReady() {
+		value = 1
+	}
+	return metrics.Family{
+		Name:    "replicated_ready",
+		Help:    "Whether the SDK has completed bootstrap.",
+		Type:    metrics.MetricTypeGauge,
+		Samples: []metrics.Sample{{Value: value}},
+	}
+}
+
+func appStateFamilies(appStatus appstatetypes.AppStatus) []metrics.Family {
+	app := metrics.Family{
+		Name: "replicated_app_state",
+		Help: "Current state of the application, one series per state with a value of 1 for the current state.",
+		Type: metrics.MetricTypeGauge,
+	}
+	if appStatus.State != "" {
+		for _, state := range appStates {
+			app.Samples = append(app.Samples, metrics.Sample{
+				Labels: []metrics.Label{{Name: "state", Value: string(state)}},
+				Value:  boolValue(appStatus.State == state),
+			})
+		}
+	}
+
+	resources := metrics.Family{
+		Name: "replicated_resource_state",
+		Help: "Current state of each application resource, one series per state with a value of 1 for the current state.",
+		Type: metrics.MetricTypeGauge,
+	}
+	for _, resourceState := range appStatus.ResourceStates {
+		for _, state := range appStates {
+			resources.Samples = append(resources.Samples, metrics.Sample{
+				Labels: []metrics.Label{
+					{Name: "kind", Value: resourceState.Kind},
+					{Name: "namespace", Value: resourceState.Namespace},
+					{Name: "name", Value: resourceState.Name},
+					{Name: "state", Value: string(state)},
+				},
+				Value: boolValue(resourceState.State == state),
+			})
+		}
+	}
+	metrics.SortSamples(resources.Samples)
+
+	return []metrics.Family{app, resources}
+}
+
+func licenseFamilies(sdkStore store.Store) []metrics.Family {
+	expiry := metrics.Family{
+		Name: "replicated_license_expiry_days",
+		Help: "Days until the license expires. Negative once the license has expired. Not reported for licenses that do not expire.",
+		Type: metrics.MetricTypeGauge,
+	}
+
+	expiresAt, err := license.GetLicenseExpiration(sdkStore.GetLicense())
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to get license expiration"))
+	} else if expiresAt != nil {
+		expiry.Samples = append(expiry.Samples, metrics.Sample{
+			Value: time.Until(*expiresAt).Hours() / 24,
+		})
+	}
+
+	return []metrics.Family{expiry}
+}
+
+// customMetricsFamily reports numeric and boolean custom metrics. Other value types can't be represented as a sample and are skipped.
+func customMetricsFamily(customMetrics map[string]interface{}) metrics.Family {
+	family := metrics.Family{
+		Name: "replicated_custom_metric",
+		Help: "Latest value of each numeric custom application metric.",
+		Type: metrics.MetricTypeGauge,
+	}
+
+	for name, value := range customMetrics {
+		var v float64
+		switch value := value.(type) {
+		case float64:
+			v = value
+		case int64:
+			v = float64(value)
+		case int:
+			v = float64(value)
+		case bool:
+			v = boolValue(value)
+		default:
+			continue
+		}
+		family.Samples = append(family.Samples, metrics.Sample{
+			Labels: []metrics.Label{{Name: "name", Value: name}},
+			Value:  v,
+		})
+	}
+	metrics.SortSamples(family.Samples)
+
+	return family
+}
+
+func getLatestCustomAppMetrics(r *http.Request, namespace string) (map[string]interface{}, error) {
+	var clientset kubernetes.Interface
+	if testClientSet != nil {
+		clientset = testClientSet
+	} else {
+		var err error
+		clientset, err = k8sutil.GetClientset()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get clientset")
+		}
+	}
+
+	return meta.GetLatestCustomAppMetrics(r.Context(), clientset, namespace)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
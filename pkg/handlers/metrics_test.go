@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/meta"
+	"github.com/replicatedhq/replicated-sdk/pkg/metrics"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_Metrics(t *testing.T) {
+	req := require.New(t)
+
+	metrics.ResetUpstream()
+	defer metrics.ResetUpstream()
+	meta.ResetLatestCustomAppMetrics()
+	defer meta.ResetLatestCustomAppMetrics()
+
+	SetTestClientSet(fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      meta.ReplicatedMetadataSecretName,
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"latest-custom-metrics": []byte(`{"numProjects": 20, "isTrial": true, "plan": "enterprise"}`),
+		},
+	}))
+	defer SetTestClientSet(nil)
+
+	store.SetStore(nil)
+	defer store.SetStore(nil)
+
+	/* Only SDK metrics should be reported until the store is initialized */
+	r, recorder := newTestRequest("GET", "/metrics", nil)
+	Metrics(recorder, r)
+
+	req.Equal(http.StatusOK, recorder.Code)
+	req.Equal(metrics.ContentType, recorder.Header().Get("Content-Type"))
+	req.Contains(recorder.Body.String(), "replicated_ready 0\n")
+	req.NotContains(recorder.Body.String(), "replicated_app_state")
+
+	store.InitInMemory(store.InitInMemoryStoreOptions{Namespace: "default"})
+	store.GetStore().SetAppStatus(appstatetypes.AppStatus{
+		State: appstatetypes.StateDegraded,
+		ResourceStates: appstatetypes.ResourceStates{
+			{Kind: "deployment", Namespace: "default", Name: "api", State: appstatetypes.StateDegraded},
+		},
+	})
+	store.GetStore().SetUpdates([]upstreamtypes.ChannelRelease{{VersionLabel: "1.0.1"}, {VersionLabel: "1.0.2"}})
+	metrics.ObserveUpstreamRequest("instance_data", &http.Response{StatusCode: http.StatusOK}, nil)
+
+	r, recorder = newTestRequest("GET", "/metrics", nil)
+	Metrics(recorder, r)
+
+	body := recorder.Body.String()
+	req.Equal(http.StatusOK, recorder.Code)
+	req.Contains(body, `replicated_app_state{state="ready"} 0`+"\n")
+	req.Contains(body, `replicated_app_state{state="degraded"} 1`+"\n")
+	req.Contains(body, `replicated_resource_state{kind="deployment",namespace="default",name="api",state="degraded"} 1`+"\n")
+	req.Contains(body, `replicated_resource_state{kind="deployment",namespace="default",name="api",state="ready"} 0`+"\n")
+	req.Contains(body, "replicated_app_updates_available 2\n")
+	req.Contains(body, `replicated_custom_metric{name="isTrial"} 1`+"\n")
+	req.Contains(body, `replicated_custom_metric{name="numProjects"} 20`+"\n")
+	req.NotContains(body, `name="plan"`)
+	req.Contains(body, `replicated_upstream_requests_total{operation="instance_data",outcome="success"} 1`+"\n")
+	req.NotContains(body, "replicated_license_expiry_days")
+}
@@ -13,8 +13,11 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/auth"
 	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"github.com/replicatedhq/replicated-sdk/pkg/openapi"
+	"github.com/replicatedhq/replicated-sdk/pkg/readiness"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
 )
 
@@ -30,32 +33,142 @@ func CorsMiddleware(next http.Handler) http.Handler {
 func EnforceMockAccess(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if !store.GetStore().IsDevLicense() {
-			w.WriteHeader(http.StatusForbidden)
+			JSONError(w, r, NewAPIError(http.StatusForbidden, types.ErrorCodeDevLicenseRequired, "mock data is only available with a development license"))
 			return
 		}
 		next.ServeHTTP(w, r)
 	}
 }
 
-func RequireValidLicenseIDMiddleware(next http.Handler) http.Handler {
+// RequireStoreInitializedMiddleware responds with a 503 until bootstrap has initialized the store,
+// so that clients can tell an SDK that is still starting apart from one that is returning empty data.
+func RequireStoreInitializedMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		licenseID := r.Header.Get("authorization")
-		if licenseID == "" {
-			response := types.ErrorResponse{Error: "missing authorization header"}
-			JSON(w, http.StatusUnauthorized, response)
+		if !store.IsInitialized() {
+			message := "replicated is still starting"
+			w.Header().Set("Retry-After", "10")
+			JSON(w, http.StatusServiceUnavailable, types.NotInitializedResponse{
+				ErrorResponse: types.ErrorResponse{
+					Code:      types.ErrorCodeNotReady,
+					Message:   message,
+					RequestID: GetRequestID(r),
+					Retryable: true,
+					Error:     message,
+				},
+				Readiness: readiness.GetStatus(),
+			})
 			return
 		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		if store.GetStore().GetLicense().GetLicenseID() != licenseID {
-			response := types.ErrorResponse{Error: "license ID is not valid"}
-			JSON(w, http.StatusUnauthorized, response)
+// RequireClientCertMiddleware rejects requests that were not made with a client certificate signed by the configured CA.
+// Verification of presented certificates happens during the TLS handshake.
+func RequireClientCertMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			JSONError(w, r, NewAPIError(http.StatusUnauthorized, types.ErrorCodeUnauthorized, "a valid client certificate is required"))
 			return
 		}
-
 		next.ServeHTTP(w, r)
 	})
 }
 
+// RequireScopeMiddleware rejects requests that do not present an API token with the given scope.
+// An empty scope only requires a valid token. Requests are let through as-is when token authentication is not enabled.
+func RequireScopeMiddleware(scope auth.Scope) mux.MiddlewareFunc {
+	return requireScopeMiddleware(scope, false)
+}
+
+// RequireTokenMiddleware is RequireScopeMiddleware for routes that change what the SDK serves, which are never let through
+// without an API token, even if token authentication is not enabled or unauthenticated requests are allowed
+func RequireTokenMiddleware(scope auth.Scope) mux.MiddlewareFunc {
+	return requireScopeMiddleware(scope, true)
+}
+
+func requireScopeMiddleware(scope auth.Scope, requireToken bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := auth.Authorize(r.Context(), r.Header.Get("Authorization"), scope)
+			if err == nil && token == nil && requireToken {
+				if auth.IsEnabled() {
+					err = auth.ErrMissingToken
+				} else {
+					JSONError(w, r, NewAPIError(http.StatusForbidden, types.ErrorCodeForbidden, "this endpoint requires API token authentication to be configured"))
+					return
+				}
+			}
+			if err != nil {
+				switch errors.Cause(err) {
+				case auth.ErrMissingToken, auth.ErrInvalidToken:
+					w.Header().Set("WWW-Authenticate", `Bearer realm="replicated"`)
+					JSONError(w, r, NewAPIError(http.StatusUnauthorized, types.ErrorCodeUnauthorized, err.Error()))
+				case auth.ErrInsufficientScope:
+					JSONError(w, r, NewAPIError(http.StatusForbidden, types.ErrorCodeForbidden, fmt.Sprintf("token does not have the %q scope", scope)))
+				default:
+					JSONError(w, r, errors.Wrap(err, "failed to authorize request"))
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ValidateRequestMiddleware rejects requests whose parameters or JSON body do not match the route's operation
+// in the API document, listing every invalid field. Routes that are not in the document are let through.
+func ValidateRequestMiddleware(doc *openapi.Document) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			path, err := route.GetPathTemplate()
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			op := doc.Operation(r.Method, path)
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			fieldErrors := doc.ValidateParameters(op, r.URL.Query(), mux.Vars(r))
+
+			if op.RequestBody != nil {
+				if _, ok := op.RequestBody.Content[openapi.ContentTypeJSON]; ok {
+					body, err := readRequestBody(w, r, maxJSONBodySize)
+					if err != nil {
+						JSONError(w, r, err)
+						return
+					}
+					r.Body = io.NopCloser(bytes.NewBuffer(body))
+
+					fieldErrors = append(fieldErrors, doc.ValidateJSONBody(op.RequestBody, body)...)
+				}
+			}
+
+			if len(fieldErrors) > 0 {
+				message := "invalid request"
+				JSON(w, http.StatusBadRequest, types.ErrorResponse{
+					Code:      types.ErrorCodeInvalidRequest,
+					Message:   message,
+					RequestID: GetRequestID(r),
+					Error:     message,
+					Fields:    fieldErrors,
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // Code for the cache middleware
 type CacheEntry struct {
 	RequestBody  []byte
@@ -129,8 +242,7 @@ func cacheMiddleware(next http.Handler, cache *cache, duration time.Duration) ht
 	return func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			logger.Error(errors.Wrap(err, "cache middleware - failed to read request body"))
-			http.Error(w, "cache middleware: unable to read request body", http.StatusInternalServerError)
+			JSONError(w, r, errors.Wrap(err, "cache middleware: failed to read request body"))
 			return
 		}
 		r.Body = io.NopCloser(bytes.NewBuffer(body))
@@ -2,12 +2,24 @@ package handlers
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/replicatedhq/replicated-sdk/pkg/auth"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/openapi"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func Test_IsSamePayload(t *testing.T) {
@@ -266,3 +278,229 @@ func Test_CacheMiddleware_DoNotCacheErroredPayload(t *testing.T) {
 	require.Equal(t, "", recorder.Header().Get("X-Replicated-Rate-Limited"))      // Header should NOT exist because the response is NOT rate limited
 
 }
+
+func Test_RequireStoreInitializedMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, map[string]interface{}{"message": "Hello, World!"})
+	})
+	wrapped := RequireStoreInitializedMiddleware(handler)
+
+	store.SetStore(nil)
+	defer store.SetStore(nil)
+
+	/* Requests should be rejected until the store is initialized */
+	req, recorder := newTestRequest("GET", "/api/v1/app/info", nil)
+	wrapped.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `"code":"not_ready","message":"replicated is still starting"`)
+	require.Contains(t, recorder.Body.String(), `"retryable":true`)
+	require.Contains(t, recorder.Body.String(), `"ready":false`)
+	require.Equal(t, "10", recorder.Header().Get("Retry-After"))
+
+	/* Requests should be served once the store is initialized */
+	store.SetStore(&store.InMemoryStore{})
+
+	req, recorder = newTestRequest("GET", "/api/v1/app/info", nil)
+	wrapped.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, `{"message":"Hello, World!"}`, recorder.Body.String())
+}
+
+func Test_RequireScopeMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, map[string]interface{}{"message": "Hello, World!"})
+	})
+	wrapped := RequireScopeMiddleware(auth.ScopeReadLicense)(handler)
+
+	/* Requests should be served when token authentication is not enabled */
+	req, recorder := newTestRequest("GET", "/api/v1/license/info", nil)
+	wrapped.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "replicated-api-tokens",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			auth.TokensSecretKey: []byte(`
+- name: app
+  token: license-token
+  scopes: ["read-license"]
+- name: metrics
+  token: metrics-token
+  scopes: ["write-metrics"]
+`),
+		},
+	})
+	auth.Init(auth.InitOptions{
+		Clientset:        clientset,
+		Namespace:        "default",
+		TokensSecretName: "replicated-api-tokens",
+	})
+	defer auth.Init(auth.InitOptions{})
+
+	/* Requests without a token should be rejected */
+	req, recorder = newTestRequest("GET", "/api/v1/license/info", nil)
+	wrapped.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.JSONEq(t, `{"code":"unauthorized","message":"missing bearer token","retryable":false,"error":"missing bearer token"}`, recorder.Body.String())
+	require.Equal(t, `Bearer realm="replicated"`, recorder.Header().Get("WWW-Authenticate"))
+
+	/* Requests with a token that does not have the scope should be forbidden */
+	req, recorder = newTestRequest("GET", "/api/v1/license/info", nil)
+	req.Header.Set("Authorization", "Bearer metrics-token")
+	wrapped.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	require.JSONEq(t, `{"code":"forbidden","message":"token does not have the \"read-license\" scope","retryable":false,"error":"token does not have the \"read-license\" scope"}`, recorder.Body.String())
+
+	/* Requests with a token that has the scope should be served */
+	req, recorder = newTestRequest("GET", "/api/v1/license/info", nil)
+	req.Header.Set("Authorization", "Bearer license-token")
+	wrapped.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, `{"message":"Hello, World!"}`, recorder.Body.String())
+}
+
+func Test_RequireTokenMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, map[string]interface{}{"message": "Hello, World!"})
+	})
+	wrapped := RequireTokenMiddleware(auth.ScopeWriteLicense)(handler)
+
+	/* Requests should be forbidden when token authentication is not enabled */
+	req, recorder := newTestRequest("PUT", "/api/v1/license", nil)
+	wrapped.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	require.JSONEq(t, `{"code":"forbidden","message":"this endpoint requires API token authentication to be configured","retryable":false,"error":"this endpoint requires API token authentication to be configured"}`, recorder.Body.String())
+
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "replicated-api-tokens",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			auth.TokensSecretKey: []byte(`
+- name: admin
+  token: admin-token
+  scopes: ["write-license"]
+`),
+		},
+	})
+	auth.Init(auth.InitOptions{
+		Clientset:            clientset,
+		Namespace:            "default",
+		TokensSecretName:     "replicated-api-tokens",
+		AllowUnauthenticated: true,
+	})
+	defer auth.Init(auth.InitOptions{})
+
+	/* Requests without a token should be rejected even if unauthenticated requests are allowed */
+	req, recorder = newTestRequest("PUT", "/api/v1/license", nil)
+	wrapped.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.JSONEq(t, `{"code":"unauthorized","message":"missing bearer token","retryable":false,"error":"missing bearer token"}`, recorder.Body.String())
+
+	/* Requests with a token that has the scope should be served */
+	req, recorder = newTestRequest("PUT", "/api/v1/license", nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	wrapped.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func Test_RequireClientCertMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, map[string]interface{}{"message": "Hello, World!"})
+	})
+	wrapped := RequireClientCertMiddleware(handler)
+
+	/* Requests without a verified client certificate should be rejected */
+	req, recorder := newTestRequest("GET", "/api/v1/app/info", nil)
+	req.TLS = &tls.ConnectionState{}
+	wrapped.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.JSONEq(t, `{"code":"unauthorized","message":"a valid client certificate is required","retryable":false,"error":"a valid client certificate is required"}`, recorder.Body.String())
+
+	/* Requests with a verified client certificate should be served */
+	req, recorder = newTestRequest("GET", "/api/v1/app/info", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+	wrapped.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func Test_ValidateRequestMiddleware(t *testing.T) {
+	g := openapi.NewGenerator()
+	doc := openapi.NewDocument(openapi.Info{Title: "test"})
+	doc.Add("POST", "/api/v1/app/instance-tags", &openapi.Operation{
+		OperationID: "sendInstanceTags",
+		RequestBody: openapi.JSONBody("The instance tags", g.SchemaOf(types.SendAppInstanceTagsRequest{})),
+	})
+	doc.Components.Schemas = g.Schemas()
+
+	var received []byte
+	r := mux.NewRouter()
+	r.Use(ValidateRequestMiddleware(doc))
+	r.HandleFunc("/api/v1/app/instance-tags", func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		JSON(w, http.StatusOK, "")
+	}).Methods("POST")
+	r.HandleFunc("/api/v1/app/custom-metrics", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, "")
+	}).Methods("POST")
+
+	/* Invalid requests should be rejected with an error for each field */
+	req, recorder := newTestRequest("POST", "/api/v1/app/instance-tags", []byte(`{"data": {"force": "true", "tags": {"env": 1}}}`))
+	r.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.JSONEq(t, `{
+		"code": "invalid_request",
+		"message": "invalid request",
+		"retryable": false,
+		"error": "invalid request",
+		"fields": [
+			{"field": "data.force", "message": "must be a boolean"},
+			{"field": "data.tags.env", "message": "must be a string"}
+		]
+	}`, recorder.Body.String())
+
+	/* Requests without the required fields should be rejected */
+	req, recorder = newTestRequest("POST", "/api/v1/app/instance-tags", []byte(`{}`))
+	r.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.JSONEq(t, `{"code": "invalid_request", "message": "invalid request", "retryable": false, "error": "invalid request", "fields": [{"field": "data", "message": "is required"}]}`, recorder.Body.String())
+
+	/* Valid requests should be passed to the handler with the body intact */
+	body := []byte(`{"data": {"tags": {"env": "prod"}}}`)
+	req, recorder = newTestRequest("POST", "/api/v1/app/instance-tags", body)
+	r.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, body, received)
+
+	/* Bodies larger than the limit should be rejected without being read completely */
+	largeBody := []byte(`{"data": {"tags": {"env": "` + strings.Repeat("a", maxJSONBodySize) + `"}}}`)
+	req, recorder = newTestRequest("POST", "/api/v1/app/instance-tags", largeBody)
+	r.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `"code":"invalid_request"`)
+
+	/* Routes that are not in the document should not be validated */
+	req, recorder = newTestRequest("POST", "/api/v1/app/custom-metrics", []byte(`not json`))
+	r.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
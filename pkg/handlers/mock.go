@@ -1,65 +1,54 @@
 package handlers
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/integration"
 	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
-	"github.com/replicatedhq/replicated-sdk/pkg/logger"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
 )
 
-type GetIntegrationStatusResponse struct {
-	IsEnabled bool `json:"isEnabled"`
-}
-
 func PostIntegrationMockData(w http.ResponseWriter, r *http.Request) {
 	if store.GetStore().GetReadOnlyMode() {
-		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
-			"error":        "mock data updates are unavailable in read-only mode",
-			"readOnlyMode": true,
-		})
+		ReadOnlyModeError(w, r, "mock data updates are unavailable in read-only mode")
 		return
 	}
 
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to get clientset"))
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
 		return
 	}
 
 	isIntegrationModeEnabled, err := integration.IsEnabled(r.Context(), clientset, store.GetStore().GetNamespace(), store.GetStore().GetLicense())
 	if err != nil {
-		logger.Errorf("failed to check if integration mode is enabled: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to check if integration mode is enabled"))
 		return
 	}
 
 	if !isIntegrationModeEnabled {
-		w.WriteHeader(http.StatusForbidden)
+		JSONError(w, r, NewAPIError(http.StatusForbidden, types.ErrorCodeIntegrationModeDisabled, "integration mode is not enabled"))
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		logger.Errorf("failed to read request body: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to read request body"))
 		return
 	}
 
 	mockDataRequest, err := integration.UnmarshalJSON(body)
 	if err != nil {
-		logger.Errorf("failed to read request body: %v", err)
-		w.WriteHeader(http.StatusBadRequest)
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("invalid mock data: %v", err)))
 		return
 	}
 
 	if err := integration.SetMockData(r.Context(), clientset, store.GetStore().GetNamespace(), mockDataRequest); err != nil {
-		logger.Errorf("failed to update mock data: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to update mock data"))
 		return
 	}
 
@@ -69,32 +58,29 @@ func PostIntegrationMockData(w http.ResponseWriter, r *http.Request) {
 func GetIntegrationMockData(w http.ResponseWriter, r *http.Request) {
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to get clientset"))
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
 		return
 	}
 
 	isIntegrationModeEnabled, err := integration.IsEnabled(r.Context(), clientset, store.GetStore().GetNamespace(), store.GetStore().GetLicense())
 	if err != nil {
-		logger.Errorf("failed to check if integration mode is enabled: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to check if integration mode is enabled"))
 		return
 	}
 
 	if !isIntegrationModeEnabled {
-		w.WriteHeader(http.StatusForbidden)
+		JSONError(w, r, NewAPIError(http.StatusForbidden, types.ErrorCodeIntegrationModeDisabled, "integration mode is not enabled"))
 		return
 	}
 
 	mockData, err := integration.GetMockData(r.Context(), clientset, store.GetStore().GetNamespace())
 	if err != nil {
-		logger.Errorf("failed to get mock data: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to get mock data"))
 		return
 	}
 
 	if mockData == nil {
-		w.WriteHeader(http.StatusNotFound)
+		JSONError(w, r, NewAPIError(http.StatusNotFound, types.ErrorCodeNotFound, "mock data has not been set"))
 		return
 	}
 
@@ -104,19 +90,17 @@ func GetIntegrationMockData(w http.ResponseWriter, r *http.Request) {
 func GetIntegrationStatus(w http.ResponseWriter, r *http.Request) {
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to get clientset"))
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
 		return
 	}
 
 	isIntegrationModeEnabled, err := integration.IsEnabled(r.Context(), clientset, store.GetStore().GetNamespace(), store.GetStore().GetLicense())
 	if err != nil {
-		logger.Errorf("failed to check if integration mode is enabled: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to check if integration mode is enabled"))
 		return
 	}
 
-	response := GetIntegrationStatusResponse{
+	response := types.GetIntegrationStatusResponse{
 		IsEnabled: isIntegrationModeEnabled,
 	}
 
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/replicatedhq/replicated-sdk/pkg/openapi"
+)
+
+// GetOpenAPIDocument returns a handler that serves the API document
+func GetOpenAPIDocument(doc *openapi.Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, http.StatusOK, doc)
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// requestIDRegexp limits the request IDs accepted from clients to ones that are safe to log and echo back
+var requestIDRegexp = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestIDMiddleware assigns every request an ID, which is returned in the X-Request-ID header and in error responses.
+// A valid X-Request-ID header from the client is used as is, so that requests can be traced across services.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !requestIDRegexp.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID)))
+	})
+}
+
+// GetRequestID returns the ID assigned to the request by RequestIDMiddleware
+func GetRequestID(r *http.Request) string {
+	requestID, _ := r.Context().Value(requestIDKey{}).(string)
+	return requestID
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -3,51 +3,42 @@ package handlers
 import (
 	"net/http"
 
-	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
 	"github.com/replicatedhq/replicated-sdk/pkg/upstream"
 	"github.com/replicatedhq/replicated-sdk/pkg/util"
 )
 
-type UploadSupportBundleResponse struct {
-	BundleID string `json:"bundleId"`
-	Slug     string `json:"slug"`
-}
-
 func UploadSupportBundle(w http.ResponseWriter, r *http.Request) {
 	if util.IsAirgap() {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("support bundle upload is not available in airgap mode"))
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeAirgap, "support bundle upload is not available in airgap mode"))
 		return
 	}
 
 	if r.ContentLength <= 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Content-Length header is required"))
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, "Content-Length header is required"))
 		return
 	}
 
 	uploadURLResp, err := upstream.GetSupportBundleUploadURL(store.GetStore())
 	if err != nil {
-		logger.Errorf("failed to get support bundle upload url: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to get support bundle upload url"))
 		return
 	}
 
 	if err := upstream.UploadToS3(uploadURLResp.UploadURL, r.Body, r.ContentLength); err != nil {
-		logger.Errorf("failed to upload support bundle to S3: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to upload support bundle to S3"))
 		return
 	}
 
 	slug, err := upstream.MarkSupportBundleUploaded(store.GetStore(), uploadURLResp.BundleID)
 	if err != nil {
-		logger.Errorf("failed to mark support bundle as uploaded: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to mark support bundle as uploaded"))
 		return
 	}
 
-	JSON(w, http.StatusCreated, UploadSupportBundleResponse{
+	JSON(w, http.StatusCreated, types.UploadSupportBundleResponse{
 		BundleID: uploadURLResp.BundleID,
 		Slug:     slug,
 	})
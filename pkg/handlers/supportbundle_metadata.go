@@ -2,54 +2,43 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
-	"github.com/replicatedhq/replicated-sdk/pkg/logger"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
 	"github.com/replicatedhq/replicated-sdk/pkg/supportbundle"
 )
 
-type SupportBundleMetadataRequest struct {
-	Data map[string]string `json:"data"`
-}
-
 func PostSupportBundleMetadata(w http.ResponseWriter, r *http.Request) {
 	if store.GetStore().GetReadOnlyMode() {
-		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
-			"error":        "support bundle metadata is unavailable in read-only mode",
-			"readOnlyMode": true,
-		})
+		ReadOnlyModeError(w, r, "support bundle metadata is unavailable in read-only mode")
 		return
 	}
 
-	request := SupportBundleMetadataRequest{}
+	request := types.SupportBundleMetadataRequest{}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		logger.Errorf("failed to decode support bundle metadata request: %v", err)
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("invalid request body"))
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("invalid request body: %v", err)))
 		return
 	}
 
 	if request.Data == nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("data is required"))
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, "data is required"))
 		return
 	}
 
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to get clientset"))
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
 		return
 	}
 
 	namespace := store.GetStore().GetNamespace()
 
 	if err := supportbundle.SaveMetadata(r.Context(), clientset, namespace, request.Data, true); err != nil {
-		logger.Errorf("failed to save support bundle metadata: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to save support bundle metadata"))
 		return
 	}
 
@@ -58,39 +47,31 @@ func PostSupportBundleMetadata(w http.ResponseWriter, r *http.Request) {
 
 func PatchSupportBundleMetadata(w http.ResponseWriter, r *http.Request) {
 	if store.GetStore().GetReadOnlyMode() {
-		JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
-			"error":        "support bundle metadata is unavailable in read-only mode",
-			"readOnlyMode": true,
-		})
+		ReadOnlyModeError(w, r, "support bundle metadata is unavailable in read-only mode")
 		return
 	}
 
-	request := SupportBundleMetadataRequest{}
+	request := types.SupportBundleMetadataRequest{}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		logger.Errorf("failed to decode support bundle metadata request: %v", err)
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("invalid request body"))
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, fmt.Sprintf("invalid request body: %v", err)))
 		return
 	}
 
 	if request.Data == nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("data is required"))
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeInvalidRequest, "data is required"))
 		return
 	}
 
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
-		logger.Error(errors.Wrap(err, "failed to get clientset"))
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
 		return
 	}
 
 	namespace := store.GetStore().GetNamespace()
 
 	if err := supportbundle.SaveMetadata(r.Context(), clientset, namespace, request.Data, false); err != nil {
-		logger.Errorf("failed to save support bundle metadata: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		JSONError(w, r, errors.Wrap(err, "failed to save support bundle metadata"))
 		return
 	}
 
@@ -18,4 +18,5 @@ func TestUploadSupportBundle_Airgap(t *testing.T) {
 	UploadSupportBundle(w, r)
 
 	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), `"code":"unavailable_in_airgap"`)
 }
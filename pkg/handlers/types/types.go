@@ -1,5 +1,166 @@
 package types
 
+import (
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	metatypes "github.com/replicatedhq/replicated-sdk/pkg/meta/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/openapi"
+	"github.com/replicatedhq/replicated-sdk/pkg/readiness"
+	webhooktypes "github.com/replicatedhq/replicated-sdk/pkg/webhook/types"
+)
+
+// ErrorCode identifies the kind of error in an error response. Codes are stable, so clients can branch on them.
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidRequest          ErrorCode = "invalid_request"
+	ErrorCodeUnauthorized            ErrorCode = "unauthorized"
+	ErrorCodeForbidden               ErrorCode = "forbidden"
+	ErrorCodeNotFound                ErrorCode = "not_found"
+	ErrorCodeMethodNotAllowed        ErrorCode = "method_not_allowed"
+	ErrorCodeNotReady                ErrorCode = "not_ready"
+	ErrorCodeReadOnlyMode            ErrorCode = "read_only_mode"
+	ErrorCodeAirgap                  ErrorCode = "unavailable_in_airgap"
+	ErrorCodeNotAirgap               ErrorCode = "only_available_in_airgap"
+	ErrorCodeNotHelmManaged          ErrorCode = "not_helm_managed"
+	ErrorCodeDevLicenseRequired      ErrorCode = "dev_license_required"
+	ErrorCodeIntegrationModeDisabled ErrorCode = "integration_mode_disabled"
+	ErrorCodeNoWebhooks              ErrorCode = "no_webhooks_configured"
+	ErrorCodeLicenseFieldUnverified  ErrorCode = "license_field_unverified"
+	ErrorCodeLicenseRejected         ErrorCode = "license_rejected"
+	ErrorCodeReleaseIndexRejected    ErrorCode = "release_index_rejected"
+	ErrorCodeUpstreamRejected        ErrorCode = "upstream_rejected"
+	ErrorCodeUpstreamUnavailable     ErrorCode = "upstream_unavailable"
+	ErrorCodeInternal                ErrorCode = "internal_error"
+)
+
+// ErrorResponse is the body of every error response
 type ErrorResponse struct {
-	Error string `json:"error,omitempty"`
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	// RequestID is also returned in the X-Request-ID header, and is logged with the error
+	RequestID string `json:"requestId,omitempty"`
+	// Retryable is true if the same request may succeed later
+	Retryable bool `json:"retryable"`
+	// Error is the same as Message, for clients that predate the other fields
+	Error string `json:"error"`
+	// Fields lists the values in the request that are invalid
+	Fields []openapi.FieldError `json:"fields,omitempty"`
+	// ReadOnlyMode is true if the request was rejected because the SDK is in read-only mode
+	ReadOnlyMode bool `json:"readOnlyMode,omitempty"`
+}
+
+type GetCurrentAppInfoResponse struct {
+	InstanceID      string              `json:"instanceID"`
+	AppSlug         string              `json:"appSlug"`
+	AppName         string              `json:"appName"`
+	AppStatus       appstatetypes.State `json:"appStatus"`
+	HelmChartURL    string              `json:"helmChartURL,omitempty"`
+	CurrentRelease  AppRelease          `json:"currentRelease"`
+	ChannelID       string              `json:"channelID"`
+	ChannelName     string              `json:"channelName"`
+	ChannelSequence int64               `json:"channelSequence"`
+	ReleaseSequence int64               `json:"releaseSequence"`
+}
+
+type GetCurrentAppStatusResponse struct {
+	AppStatus appstatetypes.AppStatus `json:"appStatus"`
+}
+
+type GetAppHistoryResponse struct {
+	Releases []AppRelease `json:"releases"`
+}
+
+type AppRelease struct {
+	VersionLabel         string `json:"versionLabel"`
+	ReleaseNotes         string `json:"releaseNotes"`
+	CreatedAt            string `json:"createdAt"`
+	DeployedAt           string `json:"deployedAt"`
+	HelmReleaseName      string `json:"helmReleaseName,omitempty"`
+	HelmReleaseRevision  int    `json:"helmReleaseRevision,omitempty"`
+	HelmReleaseNamespace string `json:"helmReleaseNamespace,omitempty"`
+}
+
+type SendCustomAppMetricsRequest struct {
+	Data CustomAppMetricsData `json:"data" validate:"required"`
+}
+
+type CustomAppMetricsData map[string]interface{}
+
+type SendAppInstanceTagsRequest struct {
+	Data metatypes.InstanceTagData `json:"data" validate:"required"`
+}
+
+type EvaluateEntitlementsRequest struct {
+	Checks []sdklicensetypes.EntitlementCheck `json:"checks" validate:"required"`
+}
+
+type EvaluateEntitlementsResponse struct {
+	// Passed is true if every check passed
+	Passed  bool                                     `json:"passed"`
+	Results []sdklicensetypes.EntitlementCheckResult `json:"results"`
+}
+
+type LicenseInfo struct {
+	LicenseID                      string      `json:"licenseID"`
+	AppSlug                        string      `json:"appSlug"`
+	ChannelName                    string      `json:"channelName"`
+	CustomerID                     string      `json:"customerID"`
+	CustomerName                   string      `json:"customerName"`
+	CustomerEmail                  string      `json:"customerEmail"`
+	LicenseType                    string      `json:"licenseType"`
+	ChannelID                      string      `json:"channelID"`
+	LicenseSequence                int64       `json:"licenseSequence"`
+	IsAirgapSupported              bool        `json:"isAirgapSupported"`
+	IsGitOpsSupported              bool        `json:"isGitOpsSupported"`
+	IsIdentityServiceSupported     bool        `json:"isIdentityServiceSupported"`
+	IsGeoaxisSupported             bool        `json:"isGeoaxisSupported"`
+	IsSnapshotSupported            bool        `json:"isSnapshotSupported"`
+	IsSupportBundleUploadSupported bool        `json:"isSupportBundleUploadSupported"`
+	IsSemverRequired               bool        `json:"isSemverRequired"`
+	Endpoint                       string      `json:"endpoint"`
+	Entitlements                   interface{} `json:"entitlements,omitempty"`
+	// Expiration is the expiration state of the license, re-evaluated every time the license is synced
+	Expiration *sdklicensetypes.LicenseExpiration `json:"expiration,omitempty"`
+	// Sync is when the license was last synced, and whether the last known good license is served because it could not be synced
+	Sync *sdklicensetypes.LicenseSyncStatus `json:"sync,omitempty"`
+}
+
+type GetLicenseChannelsResponse struct {
+	Channels []sdklicensetypes.LicenseChannel `json:"channels"`
+	// InstalledChannelID is the channel that the app was installed from
+	InstalledChannelID string `json:"installedChannelID"`
+	// UpdatesChannelID is the channel that updates are checked on: the installed channel if the license is entitled to it,
+	// or the default channel of the license otherwise
+	UpdatesChannelID string `json:"updatesChannelID"`
+}
+
+type GetIntegrationStatusResponse struct {
+	IsEnabled bool `json:"isEnabled"`
+}
+
+type TestWebhooksResponse struct {
+	Results []webhooktypes.DeliveryResult `json:"results"`
+}
+
+type GetWebhookDeadLettersResponse struct {
+	DeadLetters []metatypes.WebhookDeadLetter `json:"deadLetters"`
+}
+
+type SupportBundleMetadataRequest struct {
+	Data map[string]string `json:"data" validate:"required"`
+}
+
+type HealthzResponse struct {
+	Version string `json:"version"`
+}
+
+type UploadSupportBundleResponse struct {
+	BundleID string `json:"bundleId"`
+	Slug     string `json:"slug"`
+}
+
+type NotInitializedResponse struct {
+	ErrorResponse
+	Readiness readiness.Status `json:"readiness"`
 }
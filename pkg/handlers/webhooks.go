@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/handlers/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
+	"github.com/replicatedhq/replicated-sdk/pkg/meta"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"github.com/replicatedhq/replicated-sdk/pkg/webhook"
+	"k8s.io/client-go/kubernetes"
+)
+
+// TestWebhooks sends a sample event to every configured webhook and reports the outcome of each delivery
+func TestWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !webhook.IsEnabled() {
+		JSONError(w, r, NewAPIError(http.StatusBadRequest, types.ErrorCodeNoWebhooks, "no webhooks are configured"))
+		return
+	}
+
+	JSON(w, http.StatusOK, types.TestWebhooksResponse{
+		Results: webhook.SendTest(r.Context()),
+	})
+}
+
+// GetWebhookDeadLetters returns the most recent webhook events that could not be delivered
+func GetWebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	var clientset kubernetes.Interface
+	if testClientSet != nil {
+		clientset = testClientSet
+	} else {
+		var err error
+		clientset, err = k8sutil.GetClientset()
+		if err != nil {
+			JSONError(w, r, errors.Wrap(err, "failed to get clientset"))
+			return
+		}
+	}
+
+	deadLetters, err := meta.GetWebhookDeadLetters(r.Context(), clientset, store.GetStore().GetNamespace())
+	if err != nil {
+		JSONError(w, r, errors.Wrap(err, "failed to get webhook dead letters"))
+		return
+	}
+
+	JSON(w, http.StatusOK, types.GetWebhookDeadLettersResponse{
+		DeadLetters: deadLetters,
+	})
+}
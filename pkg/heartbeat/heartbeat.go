@@ -1,17 +1,18 @@
 package heartbeat
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
 	sdklicense "github.com/replicatedhq/replicated-sdk/pkg/license"
 	"github.com/replicatedhq/replicated-sdk/pkg/logger"
 	"github.com/replicatedhq/replicated-sdk/pkg/report"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
 	"github.com/replicatedhq/replicated-sdk/pkg/util"
+	"github.com/replicatedhq/replicated-sdk/pkg/webhook"
 	cron "github.com/robfig/cron/v3"
 )
 
@@ -57,20 +58,16 @@ func Start() error {
 			if err != nil {
 				logger.Error(errors.Wrap(err, "failed to get latest license"))
 			} else {
+				webhook.NotifyLicenseChanged(store.GetStore().GetLicense(), licenseData.License)
 				store.GetStore().SetLicense(licenseData.License)
+				sdklicense.RecordLicenseSync(licenseData)
 			}
 		}
 
-		go func() {
-			clientset, err := k8sutil.GetClientset()
-			if err != nil {
-				logger.Error(errors.Wrap(err, "failed to get clientset"))
-				return
-			}
-			if err := report.SendInstanceData(clientset, store.GetStore()); err != nil {
-				logger.Error(errors.Wrap(err, "failed to send instance data"))
-			}
-		}()
+		// re-evaluated in airgap mode as well, so that a license that expires at runtime is noticed
+		sdklicense.SyncLicenseExpiration(store.GetStore(), store.GetStore().GetLicense())
+
+		report.SendInstanceDataAsync(store.GetStore())
 	})
 	if err != nil {
 		return errors.Wrap(err, "failed to add func")
@@ -82,10 +79,19 @@ func Start() error {
 }
 
 // Stop will stop a running cron job (if exists) for the app
-func Stop() {
-	if job != nil {
-		job.Stop()
-	} else {
+// and wait for an in-progress heartbeat to complete or for the context to be done
+func Stop(ctx context.Context) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if job == nil {
 		logger.Debugf("cron job not found")
+		return
+	}
+
+	select {
+	case <-job.Stop().Done():
+	case <-ctx.Done():
+		logger.Infof("timed out waiting for heartbeat to complete")
 	}
 }
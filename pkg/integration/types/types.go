@@ -46,4 +46,5 @@ type MockRelease struct {
 	ChannelName          string `json:"channelName" yaml:"channelName"`
 	ChannelSequence      int64  `json:"channelSequence" yaml:"channelSequence"`
 	ReleaseSequence      int64  `json:"releaseSequence" yaml:"releaseSequence"`
+	IsRequired           bool   `json:"isRequired,omitempty" yaml:"isRequired,omitempty"`
 }
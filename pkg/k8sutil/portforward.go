@@ -0,0 +1,167 @@
+package k8sutil
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// PortForwardService forwards a random local port to the target port of the first port of the service, on a ready pod of the
+// service, or a running one if none are ready. It returns the local endpoint, which uses https if the service serves TLS,
+// and a function that stops forwarding.
+func PortForwardService(ctx context.Context, cfg *rest.Config, clientset kubernetes.Interface, namespace string, serviceName string) (string, func(), error) {
+	service, err := clientset.CoreV1().Services(namespace).Get(ctx, serviceName, metav1.GetOptions{})
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "failed to get service %s", serviceName)
+	}
+	if len(service.Spec.Selector) == 0 || len(service.Spec.Ports) == 0 {
+		return "", nil, errors.Errorf("service %s has no selector or ports", serviceName)
+	}
+
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(service.Spec.Selector).String(),
+	})
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "failed to list pods for service %s", serviceName)
+	}
+
+	pod := selectPod(pods.Items)
+	if pod == nil {
+		return "", nil, errors.Errorf("no running pods found for service %s", serviceName)
+	}
+
+	podPort, err := resolveTargetPort(pod, service.Spec.Ports[0].TargetPort)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to resolve target port")
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(cfg)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to create round tripper")
+	}
+
+	portForwardURL := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, portForwardURL)
+
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	forwarder, err := portforward.New(dialer, []string{"0:" + podPort}, stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to create port forwarder")
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- forwarder.ForwardPorts()
+	}()
+
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		return "", nil, errors.Wrap(err, "failed to forward ports")
+	case <-ctx.Done():
+		close(stopCh)
+		return "", nil, ctx.Err()
+	}
+
+	ports, err := forwarder.GetPorts()
+	if err != nil {
+		close(stopCh)
+		return "", nil, errors.Wrap(err, "failed to get forwarded ports")
+	}
+	if len(ports) == 0 {
+		close(stopCh)
+		return "", nil, errors.New("no ports were forwarded")
+	}
+
+	scheme := "http"
+	if servesTLS(pod, service.Spec.Ports[0], podPort) {
+		scheme = "https"
+	}
+
+	return fmt.Sprintf("%s://localhost:%d", scheme, ports[0].Local), func() { close(stopCh) }, nil
+}
+
+// selectPod returns the first ready pod, or the first running pod if none are ready, e.g. because the sdk can't reach the
+// Replicated API and is not ready yet
+func selectPod(pods []corev1.Pod) *corev1.Pod {
+	var running *corev1.Pod
+	for i := range pods {
+		if isPodReady(&pods[i]) {
+			return &pods[i]
+		}
+		if running == nil && isPodRunning(&pods[i]) {
+			running = &pods[i]
+		}
+	}
+	return running
+}
+
+func isPodRunning(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	if !isPodRunning(pod) {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// resolveTargetPort returns the container port number for a service target port, which can be a number or the name of a container port
+func resolveTargetPort(pod *corev1.Pod, targetPort intstr.IntOrString) (string, error) {
+	if targetPort.Type == intstr.Int {
+		return targetPort.String(), nil
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == targetPort.StrVal {
+				return strconv.Itoa(int(port.ContainerPort)), nil
+			}
+		}
+	}
+	return "", errors.Errorf("pod %s has no port named %s", pod.Name, targetPort.StrVal)
+}
+
+// servesTLS returns true if the service port has the https app protocol, or if a probe of the pod uses https on the
+// container port, which the chart sets when TLS is enabled
+func servesTLS(pod *corev1.Pod, servicePort corev1.ServicePort, podPort string) bool {
+	if servicePort.AppProtocol != nil && strings.EqualFold(*servicePort.AppProtocol, "https") {
+		return true
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, probe := range []*corev1.Probe{container.ReadinessProbe, container.LivenessProbe} {
+			if probe == nil || probe.HTTPGet == nil || probe.HTTPGet.Scheme != corev1.URISchemeHTTPS {
+				continue
+			}
+			probePort, err := resolveTargetPort(pod, probe.HTTPGet.Port)
+			if err == nil && probePort == podPort {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package k8sutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func testPod(name string, phase corev1.PodPhase, ready bool) corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.PodStatus{
+			Phase:      phase,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestSelectPod(t *testing.T) {
+	terminating := testPod("terminating", corev1.PodRunning, true)
+	terminating.DeletionTimestamp = &metav1.Time{}
+
+	tests := []struct {
+		name    string
+		pods    []corev1.Pod
+		wantPod string
+	}{
+		{
+			name:    "ready pod is preferred",
+			pods:    []corev1.Pod{testPod("running", corev1.PodRunning, false), testPod("ready", corev1.PodRunning, true)},
+			wantPod: "ready",
+		},
+		{
+			name:    "running pod is used if none are ready",
+			pods:    []corev1.Pod{testPod("pending", corev1.PodPending, false), terminating, testPod("running", corev1.PodRunning, false)},
+			wantPod: "running",
+		},
+		{
+			name: "no running pods",
+			pods: []corev1.Pod{testPod("pending", corev1.PodPending, false), terminating},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := selectPod(tt.pods)
+			if tt.wantPod == "" {
+				require.Nil(t, pod)
+				return
+			}
+			require.NotNil(t, pod)
+			require.Equal(t, tt.wantPod, pod.Name)
+		})
+	}
+}
+
+func TestServesTLS(t *testing.T) {
+	podWithProbe := func(scheme corev1.URIScheme, port intstr.IntOrString) *corev1.Pod {
+		return &corev1.Pod{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 3000}},
+					ReadinessProbe: &corev1.Probe{
+						ProbeHandler: corev1.ProbeHandler{
+							HTTPGet: &corev1.HTTPGetAction{Path: "/readyz", Port: port, Scheme: scheme},
+						},
+					},
+				}},
+			},
+		}
+	}
+
+	https := "https"
+
+	tests := []struct {
+		name        string
+		pod         *corev1.Pod
+		servicePort corev1.ServicePort
+		want        bool
+	}{
+		{
+			name:        "https app protocol",
+			pod:         &corev1.Pod{},
+			servicePort: corev1.ServicePort{AppProtocol: &https},
+			want:        true,
+		},
+		{
+			name: "https probe on the port",
+			pod:  podWithProbe(corev1.URISchemeHTTPS, intstr.FromInt32(3000)),
+			want: true,
+		},
+		{
+			name: "https probe on the named port",
+			pod:  podWithProbe(corev1.URISchemeHTTPS, intstr.FromString("http")),
+			want: true,
+		},
+		{
+			name: "https probe on another port",
+			pod:  podWithProbe(corev1.URISchemeHTTPS, intstr.FromInt32(8080)),
+		},
+		{
+			name: "http probe",
+			pod:  podWithProbe(corev1.URISchemeHTTP, intstr.FromInt32(3000)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, servesTLS(tt.pod, tt.servicePort, "3000"))
+		})
+	}
+}
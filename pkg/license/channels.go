@@ -0,0 +1,63 @@
+package license
+
+import (
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/types"
+)
+
+// GetLicenseChannels returns the channels that the license is entitled to. Licenses that don't list their channels
+// are only entitled to the channel of the license, which is their default channel.
+func GetLicenseChannels(wrapper licensewrapper.LicenseWrapper) []types.LicenseChannel {
+	channels := []types.LicenseChannel{}
+	if wrapper.IsV1() {
+		for _, channel := range wrapper.V1.Spec.Channels {
+			channels = append(channels, types.LicenseChannel{
+				ChannelID:        channel.ChannelID,
+				ChannelName:      channel.ChannelName,
+				ChannelSlug:      channel.ChannelSlug,
+				IsDefault:        channel.IsDefault,
+				IsSemverRequired: channel.IsSemverRequired,
+			})
+		}
+	} else if wrapper.IsV2() {
+		for _, channel := range wrapper.V2.Spec.Channels {
+			channels = append(channels, types.LicenseChannel{
+				ChannelID:        channel.ChannelID,
+				ChannelName:      channel.ChannelName,
+				ChannelSlug:      channel.ChannelSlug,
+				IsDefault:        channel.IsDefault,
+				IsSemverRequired: channel.IsSemverRequired,
+			})
+		}
+	}
+
+	if len(channels) == 0 && wrapper.GetChannelID() != "" {
+		channels = append(channels, types.LicenseChannel{
+			ChannelID:        wrapper.GetChannelID(),
+			ChannelName:      wrapper.GetChannelName(),
+			IsDefault:        true,
+			IsSemverRequired: wrapper.IsSemverRequired(),
+		})
+	}
+
+	return channels
+}
+
+// GetLicenseChannel returns the channel with the given ID if the license is entitled to it
+func GetLicenseChannel(wrapper licensewrapper.LicenseWrapper, channelID string) (types.LicenseChannel, bool) {
+	for _, channel := range GetLicenseChannels(wrapper) {
+		if channel.ChannelID == channelID {
+			return channel, true
+		}
+	}
+	return types.LicenseChannel{}, false
+}
+
+// GetUpdatesChannelID returns the channel that updates are checked on: the installed channel if the license is entitled to it,
+// since a license can be entitled to several channels and the app may not be installed from the default one, or the channel of the license otherwise.
+func GetUpdatesChannelID(wrapper licensewrapper.LicenseWrapper, installedChannelID string) string {
+	if _, ok := GetLicenseChannel(wrapper, installedChannelID); ok {
+		return installedChannelID
+	}
+	return wrapper.GetChannelID()
+}
@@ -0,0 +1,80 @@
+package license
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	"github.com/replicatedhq/kotskinds/apis/kots/v1beta2"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLicenseChannels(t *testing.T) {
+	singleChannel := licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+		Spec: v1beta1.LicenseSpec{
+			ChannelID:        "stable-id",
+			ChannelName:      "Stable",
+			IsSemverRequired: true,
+		},
+	}}
+	multiChannel := licensewrapper.LicenseWrapper{V2: &v1beta2.License{
+		Spec: v1beta2.LicenseSpec{
+			ChannelID:   "stable-id",
+			ChannelName: "Stable",
+			Channels: []v1beta2.Channel{
+				{ChannelID: "stable-id", ChannelName: "Stable", ChannelSlug: "stable", IsDefault: true},
+				{ChannelID: "beta-id", ChannelName: "Beta", ChannelSlug: "beta", IsSemverRequired: true},
+			},
+		},
+	}}
+
+	tests := []struct {
+		name               string
+		wrapper            licensewrapper.LicenseWrapper
+		installedChannelID string
+		want               []types.LicenseChannel
+		wantUpdatesChannel string
+	}{
+		{
+			name:               "license without channels",
+			wrapper:            singleChannel,
+			installedChannelID: "stable-id",
+			want: []types.LicenseChannel{
+				{ChannelID: "stable-id", ChannelName: "Stable", IsDefault: true, IsSemverRequired: true},
+			},
+			wantUpdatesChannel: "stable-id",
+		},
+		{
+			name:               "installed from another channel than the default",
+			wrapper:            multiChannel,
+			installedChannelID: "beta-id",
+			want: []types.LicenseChannel{
+				{ChannelID: "stable-id", ChannelName: "Stable", ChannelSlug: "stable", IsDefault: true},
+				{ChannelID: "beta-id", ChannelName: "Beta", ChannelSlug: "beta", IsSemverRequired: true},
+			},
+			wantUpdatesChannel: "beta-id",
+		},
+		{
+			name:               "installed from a channel the license is not entitled to",
+			wrapper:            multiChannel,
+			installedChannelID: "unstable-id",
+			want: []types.LicenseChannel{
+				{ChannelID: "stable-id", ChannelName: "Stable", ChannelSlug: "stable", IsDefault: true},
+				{ChannelID: "beta-id", ChannelName: "Beta", ChannelSlug: "beta", IsSemverRequired: true},
+			},
+			wantUpdatesChannel: "stable-id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			req.Equal(tt.want, GetLicenseChannels(tt.wrapper))
+			req.Equal(tt.wantUpdatesChannel, GetUpdatesChannelID(tt.wrapper, tt.installedChannelID))
+
+			_, ok := GetLicenseChannel(tt.wrapper, tt.installedChannelID)
+			req.Equal(tt.wantUpdatesChannel == tt.installedChannelID, ok)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package license
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+)
+
+// LicenseFieldsFromEntitlements returns the license fields of the entitlements of the license, with their signatures,
+// e.g. for a license that was reloaded or uploaded and whose fields were not fetched from the Replicated API
+func LicenseFieldsFromEntitlements(wrapper licensewrapper.LicenseWrapper) types.LicenseFields {
+	entitlements := wrapper.GetEntitlements()
+	if entitlements == nil {
+		return nil
+	}
+
+	fields := make(types.LicenseFields, len(entitlements))
+	for name, entitlement := range entitlements {
+		field := types.LicenseField{
+			Name:        name,
+			Title:       entitlement.GetTitle(),
+			Description: entitlement.GetDescription(),
+			Value:       entitlement.GetValue(),
+			ValueType:   entitlement.GetValueType(),
+			IsHidden:    entitlement.IsHidden(),
+		}
+		if entitlement.V1 != nil && len(entitlement.V1.Signature.V1) > 0 {
+			field.Signature.V1 = base64.StdEncoding.EncodeToString(entitlement.V1.Signature.V1)
+		}
+		if entitlement.V2 != nil && len(entitlement.V2.Signature.V2) > 0 {
+			field.Signature.V2 = base64.StdEncoding.EncodeToString(entitlement.V2.Signature.V2)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// SetLicense replaces the license in the store with one that was not synced from the Replicated API, e.g. a reloaded one.
+// The license fields are rebuilt from its entitlements and verified, and its expiration state is re-evaluated.
+func SetLicense(sdkStore store.Store, wrapper licensewrapper.LicenseWrapper) {
+	sdkStore.SetLicense(wrapper)
+	// the store merges license fields, so they are cleared first to drop the fields of entitlements that were removed
+	sdkStore.SetLicenseFields(nil)
+	sdkStore.SetLicenseFields(VerifyLicenseFields(wrapper, LicenseFieldsFromEntitlements(wrapper)))
+	SyncLicenseExpiration(sdkStore, wrapper)
+}
+
+// EvaluateEntitlements runs each check against the license fields. A check that can't be evaluated,
+// e.g. because the field does not exist, fails with an error rather than failing the whole evaluation.
+// The signature status of each field comes from the verification of its own signature, see VerifyLicenseFields.
+func EvaluateEntitlements(wrapper licensewrapper.LicenseWrapper, fields types.LicenseFields, checks []types.EntitlementCheck) []types.EntitlementCheckResult {
+	results := make([]types.EntitlementCheckResult, 0, len(checks))
+
+	for _, check := range checks {
+		result := types.EntitlementCheckResult{
+			Field:     check.Field,
+			Op:        check.Op,
+			Value:     check.Value,
+			Signature: types.SignatureStatusUnverified,
+		}
+
+		field, ok := fields[check.Field]
+		if !ok {
+			result.Error = fmt.Sprintf("license field %q not found", check.Field)
+			results = append(results, result)
+			continue
+		}
+		result.ValueType = field.ValueType
+		result.Signature = getFieldSignatureStatus(wrapper, check.Field, field)
+
+		passed, actual, err := field.Evaluate(check)
+		result.Passed = passed
+		result.ActualValue = actual
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// getFieldSignatureStatus returns whether the field signature was verified. Fields without a signature are compared
+// to the entitlement with the same name in the license, whose signature was verified when it was loaded.
+func getFieldSignatureStatus(wrapper licensewrapper.LicenseWrapper, name string, field types.LicenseField) types.SignatureStatus {
+	if field.Verified {
+		return types.SignatureStatusVerified
+	}
+	if isSigned(field) {
+		return types.SignatureStatusInvalid
+	}
+
+	entitlement, ok := wrapper.GetEntitlements()[name]
+	if !ok {
+		return types.SignatureStatusUnverified
+	}
+
+	signed, err := types.CoerceValue(field.ValueType, entitlement.GetValue())
+	if err != nil {
+		return types.SignatureStatusInvalid
+	}
+	value, err := types.CoerceValue(field.ValueType, field.Value)
+	if err != nil || value != signed {
+		return types.SignatureStatusInvalid
+	}
+
+	return types.SignatureStatusVerified
+}
+
+// DiffEntitlements returns the entitlements that were added, removed or changed in the current license, sorted by name
+func DiffEntitlements(previous licensewrapper.LicenseWrapper, current licensewrapper.LicenseWrapper) []types.EntitlementChange {
+	previousEntitlements := previous.GetEntitlements()
+	currentEntitlements := current.GetEntitlements()
+
+	changes := []types.EntitlementChange{}
+	for name, ent := range currentEntitlements {
+		previousEnt, ok := previousEntitlements[name]
+		if !ok {
+			changes = append(changes, types.EntitlementChange{Name: name, Current: ent.GetValue()})
+		} else if !reflect.DeepEqual(previousEnt.GetValue(), ent.GetValue()) {
+			changes = append(changes, types.EntitlementChange{Name: name, Previous: previousEnt.GetValue(), Current: ent.GetValue()})
+		}
+	}
+	for name, ent := range previousEntitlements {
+		if _, ok := currentEntitlements[name]; !ok {
+			changes = append(changes, types.EntitlementChange{Name: name, Previous: ent.GetValue()})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes
+}
@@ -0,0 +1,242 @@
+package license
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateEntitlements(t *testing.T) {
+	wrapper := licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+		Spec: v1beta1.LicenseSpec{
+			LicenseID: "license-id",
+			Entitlements: map[string]v1beta1.EntitlementField{
+				"seat_count": {ValueType: "Integer", Value: v1beta1.EntitlementValue{Type: v1beta1.Int, IntVal: 50}},
+				"feature_x":  {ValueType: "Boolean", Value: v1beta1.EntitlementValue{Type: v1beta1.Bool, BoolVal: true}},
+				"tier":       {ValueType: "String", Value: v1beta1.EntitlementValue{Type: v1beta1.String, StrVal: "silver"}},
+			},
+		},
+	}}
+	fields := types.LicenseFields{
+		"seat_count": {Name: "seat_count", ValueType: "Integer", Value: float64(50)},
+		"feature_x":  {Name: "feature_x", ValueType: "Boolean", Value: true},
+		"tier":       {Name: "tier", ValueType: "String", Value: "gold"},
+		"region":     {Name: "region", ValueType: "String", Value: "us-east"},
+	}
+
+	tests := []struct {
+		name  string
+		check types.EntitlementCheck
+		want  types.EntitlementCheckResult
+	}{
+		{
+			name:  "integer greater than or equal",
+			check: types.EntitlementCheck{Field: "seat_count", Op: types.EntitlementOperatorGreaterThanOrEqual, Value: json.Number("25")},
+			want: types.EntitlementCheckResult{
+				Field: "seat_count", Op: ">=", Value: json.Number("25"), ActualValue: int64(50), ValueType: "Integer",
+				Passed: true, Signature: types.SignatureStatusVerified,
+			},
+		},
+		{
+			name:  "integer less than",
+			check: types.EntitlementCheck{Field: "seat_count", Op: types.EntitlementOperatorLessThan, Value: "50"},
+			want: types.EntitlementCheckResult{
+				Field: "seat_count", Op: "<", Value: "50", ActualValue: int64(50), ValueType: "Integer",
+				Passed: false, Signature: types.SignatureStatusVerified,
+			},
+		},
+		{
+			name:  "integer compared to a fraction",
+			check: types.EntitlementCheck{Field: "seat_count", Op: types.EntitlementOperatorEqual, Value: 2.5},
+			want: types.EntitlementCheckResult{
+				Field: "seat_count", Op: "==", Value: 2.5, ActualValue: int64(50), ValueType: "Integer",
+				Passed: false, Signature: types.SignatureStatusVerified, Error: "invalid value: 2.5 is not an integer",
+			},
+		},
+		{
+			name:  "boolean enabled",
+			check: types.EntitlementCheck{Field: "feature_x", Op: types.EntitlementOperatorEnabled},
+			want: types.EntitlementCheckResult{
+				Field: "feature_x", Op: "enabled", ActualValue: true, ValueType: "Boolean",
+				Passed: true, Signature: types.SignatureStatusVerified,
+			},
+		},
+		{
+			name:  "boolean disabled",
+			check: types.EntitlementCheck{Field: "feature_x", Op: types.EntitlementOperatorDisabled},
+			want: types.EntitlementCheckResult{
+				Field: "feature_x", Op: "disabled", ActualValue: true, ValueType: "Boolean",
+				Passed: false, Signature: types.SignatureStatusVerified,
+			},
+		},
+		{
+			name:  "string that does not match the signed license",
+			check: types.EntitlementCheck{Field: "tier", Op: types.EntitlementOperatorEqual, Value: "gold"},
+			want: types.EntitlementCheckResult{
+				Field: "tier", Op: "==", Value: "gold", ActualValue: "gold", ValueType: "String",
+				Passed: true, Signature: types.SignatureStatusInvalid,
+			},
+		},
+		{
+			name:  "string that is not in the signed license",
+			check: types.EntitlementCheck{Field: "region", Op: types.EntitlementOperatorNotEqual, Value: "eu-west"},
+			want: types.EntitlementCheckResult{
+				Field: "region", Op: "!=", Value: "eu-west", ActualValue: "us-east", ValueType: "String",
+				Passed: true, Signature: types.SignatureStatusUnverified,
+			},
+		},
+		{
+			name:  "ordering a string",
+			check: types.EntitlementCheck{Field: "region", Op: types.EntitlementOperatorGreaterThan, Value: "a"},
+			want: types.EntitlementCheckResult{
+				Field: "region", Op: ">", Value: "a", ActualValue: "us-east", ValueType: "String",
+				Passed: false, Signature: types.SignatureStatusUnverified, Error: `operator ">" is only supported for Integer fields`,
+			},
+		},
+		{
+			name:  "enabling an integer",
+			check: types.EntitlementCheck{Field: "seat_count", Op: types.EntitlementOperatorEnabled},
+			want: types.EntitlementCheckResult{
+				Field: "seat_count", Op: "enabled", ActualValue: int64(50), ValueType: "Integer",
+				Passed: false, Signature: types.SignatureStatusVerified, Error: `operator "enabled" is only supported for Boolean fields`,
+			},
+		},
+		{
+			name:  "missing field",
+			check: types.EntitlementCheck{Field: "missing", Op: types.EntitlementOperatorEnabled},
+			want: types.EntitlementCheckResult{
+				Field: "missing", Op: "enabled",
+				Passed: false, Signature: types.SignatureStatusUnverified, Error: `license field "missing" not found`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			results := EvaluateEntitlements(wrapper, fields, []types.EntitlementCheck{tt.check})
+			req.Len(results, 1)
+			req.Equal(tt.want, results[0])
+		})
+	}
+}
+
+func TestEntitlementCheckValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   types.EntitlementCheck
+		wantErr string
+	}{
+		{
+			name:  "comparison",
+			check: types.EntitlementCheck{Field: "seat_count", Op: types.EntitlementOperatorLessThanOrEqual, Value: 10},
+		},
+		{
+			name:  "enabled",
+			check: types.EntitlementCheck{Field: "feature_x", Op: types.EntitlementOperatorEnabled},
+		},
+		{
+			name:    "missing field",
+			check:   types.EntitlementCheck{Op: types.EntitlementOperatorEnabled},
+			wantErr: "field is required",
+		},
+		{
+			name:    "missing value",
+			check:   types.EntitlementCheck{Field: "seat_count", Op: types.EntitlementOperatorEqual},
+			wantErr: `operator "==" requires a value`,
+		},
+		{
+			name:    "unexpected value",
+			check:   types.EntitlementCheck{Field: "feature_x", Op: types.EntitlementOperatorDisabled, Value: true},
+			wantErr: `operator "disabled" does not take a value`,
+		},
+		{
+			name:    "unsupported operator",
+			check:   types.EntitlementCheck{Field: "tier", Op: "contains", Value: "gold"},
+			wantErr: `unsupported operator "contains"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestDiffEntitlements(t *testing.T) {
+	previous := licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+		Spec: v1beta1.LicenseSpec{
+			Entitlements: map[string]v1beta1.EntitlementField{
+				"seat_count": {ValueType: "Integer", Value: v1beta1.EntitlementValue{Type: v1beta1.Int, IntVal: 50}},
+				"feature_x":  {ValueType: "Boolean", Value: v1beta1.EntitlementValue{Type: v1beta1.Bool, BoolVal: true}},
+				"tier":       {ValueType: "String", Value: v1beta1.EntitlementValue{Type: v1beta1.String, StrVal: "silver"}},
+			},
+		},
+	}}
+	current := licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+		Spec: v1beta1.LicenseSpec{
+			Entitlements: map[string]v1beta1.EntitlementField{
+				"seat_count": {ValueType: "Integer", Value: v1beta1.EntitlementValue{Type: v1beta1.Int, IntVal: 100}},
+				"tier":       {ValueType: "String", Value: v1beta1.EntitlementValue{Type: v1beta1.String, StrVal: "silver"}},
+				"region":     {ValueType: "String", Value: v1beta1.EntitlementValue{Type: v1beta1.String, StrVal: "us-east"}},
+			},
+		},
+	}}
+
+	require.Equal(t, []types.EntitlementChange{
+		{Name: "feature_x", Previous: true},
+		{Name: "region", Current: "us-east"},
+		{Name: "seat_count", Previous: int64(50), Current: int64(100)},
+	}, DiffEntitlements(previous, current))
+
+	require.Empty(t, DiffEntitlements(current, current))
+}
+
+func TestLicenseFieldsFromEntitlements(t *testing.T) {
+	req := require.New(t)
+
+	wrapper := licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+		Spec: v1beta1.LicenseSpec{
+			Entitlements: map[string]v1beta1.EntitlementField{
+				"seat_count": {
+					Title:     "Seats",
+					ValueType: "Integer",
+					Value:     v1beta1.EntitlementValue{Type: v1beta1.Int, IntVal: 50},
+					Signature: v1beta1.EntitlementFieldSignature{V1: []byte("signature")},
+				},
+				"secret": {
+					ValueType: "String",
+					Value:     v1beta1.EntitlementValue{Type: v1beta1.String, StrVal: "s3cr3t"},
+					IsHidden:  true,
+				},
+			},
+		},
+	}}
+
+	req.Equal(types.LicenseFields{
+		"seat_count": {
+			Name:      "seat_count",
+			Title:     "Seats",
+			ValueType: "Integer",
+			Value:     int64(50),
+			Signature: types.LicenseFieldSignature{V1: "c2lnbmF0dXJl"},
+		},
+		"secret": {
+			Name:      "secret",
+			ValueType: "String",
+			Value:     "s3cr3t",
+			IsHidden:  true,
+		},
+	}, LicenseFieldsFromEntitlements(wrapper))
+
+	req.Nil(LicenseFieldsFromEntitlements(licensewrapper.LicenseWrapper{V1: &v1beta1.License{}}))
+}
@@ -0,0 +1,113 @@
+package license
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+)
+
+// DefaultExpiringSoonThreshold is how long before the license expires that it is considered to be expiring soon
+const DefaultExpiringSoonThreshold = 30 * 24 * time.Hour
+
+type ExpirationOptions struct {
+	// ExpiringSoonThreshold is how long before the license expires that it is considered to be expiring soon
+	ExpiringSoonThreshold time.Duration
+	// GracePeriod is how long after the license expires that it is considered to be expired in grace rather than expired
+	GracePeriod time.Duration
+}
+
+var (
+	expirationOptions = ExpirationOptions{
+		ExpiringSoonThreshold: DefaultExpiringSoonThreshold,
+	}
+	expirationOptionsMtx sync.RWMutex
+)
+
+// SetExpirationOptions sets the thresholds used to evaluate the expiration state of the license.
+// A zero ExpiringSoonThreshold uses the default.
+func SetExpirationOptions(opts ExpirationOptions) {
+	if opts.ExpiringSoonThreshold == 0 {
+		opts.ExpiringSoonThreshold = DefaultExpiringSoonThreshold
+	}
+
+	expirationOptionsMtx.Lock()
+	defer expirationOptionsMtx.Unlock()
+	expirationOptions = opts
+}
+
+func GetExpirationOptions() ExpirationOptions {
+	expirationOptionsMtx.RLock()
+	defer expirationOptionsMtx.RUnlock()
+	return expirationOptions
+}
+
+// GetLicenseExpirationStatus evaluates the expiration state of the license at the given time
+func GetLicenseExpirationStatus(wrapper licensewrapper.LicenseWrapper, opts ExpirationOptions, now time.Time) (types.LicenseExpiration, error) {
+	expiresAt, err := GetLicenseExpiration(wrapper)
+	if err != nil {
+		return types.LicenseExpiration{}, errors.Wrap(err, "failed to get license expiration")
+	}
+
+	evaluatedAt := now.UTC()
+	status := types.LicenseExpiration{
+		State:       types.ExpirationStateValid,
+		ExpiresAt:   expiresAt,
+		EvaluatedAt: &evaluatedAt,
+	}
+	if expiresAt == nil {
+		return status, nil
+	}
+
+	gracePeriodEndsAt := expiresAt.Add(opts.GracePeriod)
+	if opts.GracePeriod > 0 {
+		status.GracePeriodEndsAt = &gracePeriodEndsAt
+	}
+
+	switch {
+	case !now.Before(gracePeriodEndsAt):
+		status.State = types.ExpirationStateExpired
+	case !now.Before(*expiresAt):
+		status.State = types.ExpirationStateExpiredInGrace
+	case expiresAt.Sub(now) <= opts.ExpiringSoonThreshold:
+		status.State = types.ExpirationStateExpiringSoon
+	}
+
+	return status, nil
+}
+
+// SyncLicenseExpiration re-evaluates the expiration state of the license and saves it in the store.
+// It is called whenever the license is synced, and logs when the state changes.
+func SyncLicenseExpiration(sdkStore store.Store, wrapper licensewrapper.LicenseWrapper) types.LicenseExpiration {
+	previous := sdkStore.GetLicenseExpiration()
+
+	status, err := GetLicenseExpirationStatus(wrapper, GetExpirationOptions(), time.Now())
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to evaluate license expiration"))
+		return previous
+	}
+	sdkStore.SetLicenseExpiration(status)
+
+	if status.State == previous.State {
+		return status
+	}
+
+	switch status.State {
+	case types.ExpirationStateExpiringSoon:
+		logger.Warnf("License expires soon, at %s", status.ExpiresAt.Format(time.RFC3339))
+	case types.ExpirationStateExpiredInGrace:
+		logger.Warnf("License expired at %s, the grace period ends at %s", status.ExpiresAt.Format(time.RFC3339), status.GracePeriodEndsAt.Format(time.RFC3339))
+	case types.ExpirationStateExpired:
+		logger.Warnf("License expired at %s", status.ExpiresAt.Format(time.RFC3339))
+	default:
+		if previous.State != "" {
+			logger.Info("License is valid")
+		}
+	}
+
+	return status
+}
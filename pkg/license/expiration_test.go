@@ -0,0 +1,143 @@
+package license
+
+import (
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"github.com/stretchr/testify/require"
+)
+
+func licenseExpiringAt(expiresAt string) licensewrapper.LicenseWrapper {
+	entitlements := map[string]v1beta1.EntitlementField{}
+	if expiresAt != "" {
+		entitlements["expires_at"] = v1beta1.EntitlementField{
+			Title:     "Expiration",
+			ValueType: "String",
+			Value:     v1beta1.EntitlementValue{Type: v1beta1.String, StrVal: expiresAt},
+		}
+	}
+	return licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+		Spec: v1beta1.LicenseSpec{
+			LicenseID:    "license-id",
+			Entitlements: entitlements,
+		},
+	}}
+}
+
+func TestGetLicenseExpirationStatus(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	opts := ExpirationOptions{
+		ExpiringSoonThreshold: 30 * 24 * time.Hour,
+		GracePeriod:           7 * 24 * time.Hour,
+	}
+
+	tests := []struct {
+		name                  string
+		expiresAt             string
+		opts                  ExpirationOptions
+		wantState             types.ExpirationState
+		wantGracePeriodEndsAt string
+		wantErr               bool
+	}{
+		{
+			name:      "does not expire",
+			opts:      opts,
+			wantState: types.ExpirationStateValid,
+		},
+		{
+			name:                  "expires after the threshold",
+			expiresAt:             "2024-08-01T00:00:00Z",
+			opts:                  opts,
+			wantState:             types.ExpirationStateValid,
+			wantGracePeriodEndsAt: "2024-08-08T00:00:00Z",
+		},
+		{
+			name:                  "expires within the threshold",
+			expiresAt:             "2024-06-20T00:00:00Z",
+			opts:                  opts,
+			wantState:             types.ExpirationStateExpiringSoon,
+			wantGracePeriodEndsAt: "2024-06-27T00:00:00Z",
+		},
+		{
+			name:                  "expired within the grace period",
+			expiresAt:             "2024-05-28T00:00:00Z",
+			opts:                  opts,
+			wantState:             types.ExpirationStateExpiredInGrace,
+			wantGracePeriodEndsAt: "2024-06-04T00:00:00Z",
+		},
+		{
+			name:                  "expired after the grace period",
+			expiresAt:             "2024-05-01T00:00:00Z",
+			opts:                  opts,
+			wantState:             types.ExpirationStateExpired,
+			wantGracePeriodEndsAt: "2024-05-08T00:00:00Z",
+		},
+		{
+			name:      "expired without a grace period",
+			expiresAt: "2024-05-31T00:00:00Z",
+			opts:      ExpirationOptions{ExpiringSoonThreshold: 30 * 24 * time.Hour},
+			wantState: types.ExpirationStateExpired,
+		},
+		{
+			name:      "invalid expiration",
+			expiresAt: "tomorrow",
+			opts:      opts,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			status, err := GetLicenseExpirationStatus(licenseExpiringAt(tt.expiresAt), tt.opts, now)
+			if tt.wantErr {
+				req.Error(err)
+				return
+			}
+			req.NoError(err)
+			req.Equal(tt.wantState, status.State)
+			req.Equal(now, *status.EvaluatedAt)
+
+			if tt.expiresAt == "" {
+				req.Nil(status.ExpiresAt)
+			} else {
+				req.Equal(tt.expiresAt, status.ExpiresAt.Format(time.RFC3339))
+			}
+
+			if tt.wantGracePeriodEndsAt == "" {
+				req.Nil(status.GracePeriodEndsAt)
+			} else {
+				req.Equal(tt.wantGracePeriodEndsAt, status.GracePeriodEndsAt.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestSyncLicenseExpiration(t *testing.T) {
+	req := require.New(t)
+
+	defer SetExpirationOptions(ExpirationOptions{})
+	SetExpirationOptions(ExpirationOptions{GracePeriod: 7 * 24 * time.Hour})
+	req.Equal(DefaultExpiringSoonThreshold, GetExpirationOptions().ExpiringSoonThreshold)
+
+	sdkStore := &store.InMemoryStore{}
+
+	expiresAt := time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339)
+	status := SyncLicenseExpiration(sdkStore, licenseExpiringAt(expiresAt))
+	req.Equal(types.ExpirationStateExpiredInGrace, status.State)
+	req.Equal(status, sdkStore.GetLicenseExpiration())
+
+	// a renewed license is valid again
+	expiresAt = time.Now().Add(365 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	status = SyncLicenseExpiration(sdkStore, licenseExpiringAt(expiresAt))
+	req.Equal(types.ExpirationStateValid, status.State)
+	req.Equal(status, sdkStore.GetLicenseExpiration())
+
+	// the previous state is kept if the expiration can't be evaluated
+	status = SyncLicenseExpiration(sdkStore, licenseExpiringAt("tomorrow"))
+	req.Equal(types.ExpirationStateValid, status.State)
+}
@@ -0,0 +1,176 @@
+package license
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	licensewrappertypes "github.com/replicatedhq/kotskinds/pkg/licensewrapper/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"github.com/replicatedhq/replicated-sdk/pkg/meta"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"k8s.io/client-go/kubernetes"
+)
+
+// lastKnownGoodRefreshInterval is how often an unchanged license is persisted again, so that the persisted sync time stays recent
+const lastKnownGoodRefreshInterval = time.Hour
+
+var (
+	licenseSyncMtx      sync.Mutex
+	licenseSyncedAt     *time.Time
+	licenseStale        bool
+	persistedLicense    []byte
+	persistedLicenseAt  time.Time
+	persistedFieldsJSON []byte
+)
+
+// LastKnownGoodLicense is a license from a previous successful sync, see LoadLastKnownGoodLicense
+type LastKnownGoodLicense struct {
+	License       licensewrapper.LicenseWrapper
+	LicenseFields types.LicenseFields
+	SyncedAt      time.Time
+}
+
+// RecordLicenseSync is called after the license was synced with the Replicated API. It clears the stale indicator, and persists the
+// license as the last known good license, which the SDK falls back to if the Replicated API is unavailable when it starts.
+func RecordLicenseSync(licenseData *LicenseData) {
+	now := time.Now()
+	if !markLicenseSynced(licenseData.LicenseBytes, now) {
+		return
+	}
+
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to get clientset to save last known good license"))
+		return
+	}
+	persistLicense(context.TODO(), clientset, store.GetStore().GetNamespace(), licenseData.LicenseBytes, now)
+}
+
+// markLicenseSynced clears the stale indicator, and returns true if the license changed since it was last persisted,
+// or if it was persisted more than lastKnownGoodRefreshInterval ago
+func markLicenseSynced(licenseBytes []byte, now time.Time) bool {
+	licenseSyncMtx.Lock()
+	defer licenseSyncMtx.Unlock()
+
+	licenseSyncedAt = &now
+	licenseStale = false
+
+	return !bytes.Equal(persistedLicense, licenseBytes) || now.Sub(persistedLicenseAt) >= lastKnownGoodRefreshInterval
+}
+
+func persistLicense(ctx context.Context, clientset kubernetes.Interface, namespace string, licenseBytes []byte, now time.Time) {
+	if err := meta.SaveLastKnownGoodLicense(ctx, clientset, namespace, licenseBytes, now); err != nil {
+		logger.Error(errors.Wrap(err, "failed to save last known good license"))
+		return
+	}
+
+	licenseSyncMtx.Lock()
+	defer licenseSyncMtx.Unlock()
+
+	persistedLicense = licenseBytes
+	persistedLicenseAt = now
+}
+
+// RecordLicenseFieldsSync persists the license fields after they were synced with the Replicated API, if they changed
+func RecordLicenseFieldsSync(licenseFields types.LicenseFields) {
+	fieldsJSON, err := json.Marshal(licenseFields)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to marshal license fields"))
+		return
+	}
+
+	licenseSyncMtx.Lock()
+	unchanged := bytes.Equal(persistedFieldsJSON, fieldsJSON)
+	licenseSyncMtx.Unlock()
+	if unchanged {
+		return
+	}
+
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to get clientset to save last known good license fields"))
+		return
+	}
+	persistLicenseFields(context.TODO(), clientset, store.GetStore().GetNamespace(), licenseFields, fieldsJSON)
+}
+
+func persistLicenseFields(ctx context.Context, clientset kubernetes.Interface, namespace string, licenseFields types.LicenseFields, fieldsJSON []byte) {
+	if err := meta.SaveLastKnownGoodLicenseFields(ctx, clientset, namespace, licenseFields); err != nil {
+		logger.Error(errors.Wrap(err, "failed to save last known good license fields"))
+		return
+	}
+
+	licenseSyncMtx.Lock()
+	defer licenseSyncMtx.Unlock()
+
+	persistedFieldsJSON = fieldsJSON
+}
+
+// MarkLicenseStale records that the license could not be synced, and that the license last synced at syncedAt is served instead
+func MarkLicenseStale(syncedAt time.Time) {
+	licenseSyncMtx.Lock()
+	defer licenseSyncMtx.Unlock()
+
+	licenseSyncedAt = &syncedAt
+	licenseStale = true
+}
+
+// GetLicenseSyncStatus returns whether the license is stale, and how long ago it was last synced.
+// The license is never synced in airgap mode, in which case LastSyncedAt is not set.
+func GetLicenseSyncStatus(now time.Time) types.LicenseSyncStatus {
+	licenseSyncMtx.Lock()
+	defer licenseSyncMtx.Unlock()
+
+	status := types.LicenseSyncStatus{
+		Stale: licenseStale,
+	}
+	if licenseSyncedAt != nil {
+		syncedAt := *licenseSyncedAt
+		status.LastSyncedAt = &syncedAt
+		status.AgeSeconds = int64(now.Sub(syncedAt).Seconds())
+	}
+	return status
+}
+
+// LoadLastKnownGoodLicense returns the persisted last known good license after verifying its signature, and checking that
+// it is the same license as the configured one, and that it is not older than the configured one
+func LoadLastKnownGoodLicense(ctx context.Context, clientset kubernetes.Interface, namespace string, configured licensewrapper.LicenseWrapper) (*LastKnownGoodLicense, error) {
+	persisted, err := meta.GetLastKnownGoodLicense(ctx, clientset, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get last known good license")
+	}
+
+	wrapper, err := LoadLicenseFromBytes(persisted.LicenseBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load last known good license")
+	}
+
+	if err := wrapper.VerifySignature(); err != nil {
+		if licensewrappertypes.IsLicenseDataValidationError(err) {
+			// the data inside the signature is valid and is used instead, same as for the configured license
+			logger.Info(err.Error())
+		} else {
+			return nil, errors.Wrap(err, "failed to verify last known good license signature")
+		}
+	}
+
+	if wrapper.GetLicenseID() != configured.GetLicenseID() || wrapper.GetAppSlug() != configured.GetAppSlug() {
+		return nil, errors.New("last known good license is not the configured license")
+	}
+	if wrapper.GetLicenseSequence() < configured.GetLicenseSequence() {
+		return nil, errors.Errorf("last known good license sequence %d is older than the configured license sequence %d", wrapper.GetLicenseSequence(), configured.GetLicenseSequence())
+	}
+
+	return &LastKnownGoodLicense{
+		License:       wrapper,
+		LicenseFields: persisted.LicenseFields,
+		SyncedAt:      persisted.SyncedAt,
+	}, nil
+}
@@ -0,0 +1,134 @@
+package license
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/licensetest"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func resetLicenseSync() {
+	licenseSyncMtx.Lock()
+	defer licenseSyncMtx.Unlock()
+
+	licenseSyncedAt = nil
+	licenseStale = false
+	persistedLicense = nil
+	persistedLicenseAt = time.Time{}
+	persistedFieldsJSON = nil
+}
+
+func TestLoadLastKnownGoodLicense(t *testing.T) {
+	resetLicenseSync()
+	defer resetLicenseSync()
+
+	store.InitInMemory(store.InitInMemoryStoreOptions{})
+	defer store.SetStore(nil)
+
+	ctx := context.Background()
+	syncedAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	licenseFields := types.LicenseFields{
+		"seats": {Name: "seats", ValueType: "Integer", Value: float64(10)},
+	}
+
+	configured, err := LoadLicenseFromBytes([]byte(licensetest.ValidSignedLicense))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		persist    bool
+		configured licensewrapper.LicenseWrapper
+		wantErr    string
+	}{
+		{
+			name:       "same license",
+			persist:    true,
+			configured: configured,
+		},
+		{
+			name:       "nothing persisted",
+			configured: configured,
+			wantErr:    "failed to get last known good license",
+		},
+		{
+			name:    "different license",
+			persist: true,
+			configured: licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+				Spec: v1beta1.LicenseSpec{LicenseID: "other-license-id", AppSlug: "my-app"},
+			}},
+			wantErr: "last known good license is not the configured license",
+		},
+		{
+			name:    "older than the configured license",
+			persist: true,
+			configured: licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+				Spec: v1beta1.LicenseSpec{LicenseID: configured.GetLicenseID(), AppSlug: "my-app", LicenseSequence: 8},
+			}},
+			wantErr: "last known good license sequence 7 is older than the configured license sequence 8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			clientset := fake.NewSimpleClientset(&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "replicated", Namespace: "test-ns", UID: "deployment-uid"},
+			})
+			if tt.persist {
+				persistLicense(ctx, clientset, "test-ns", []byte(licensetest.ValidSignedLicense), syncedAt)
+				persistLicenseFields(ctx, clientset, "test-ns", licenseFields, nil)
+			}
+
+			lastKnownGood, err := LoadLastKnownGoodLicense(ctx, clientset, "test-ns", tt.configured)
+			if tt.wantErr != "" {
+				req.ErrorContains(err, tt.wantErr)
+				return
+			}
+			req.NoError(err)
+			req.Equal(configured.GetLicenseID(), lastKnownGood.License.GetLicenseID())
+			req.Equal(licenseFields, lastKnownGood.LicenseFields)
+			req.True(syncedAt.Equal(lastKnownGood.SyncedAt))
+		})
+	}
+}
+
+func TestLicenseSyncStatus(t *testing.T) {
+	resetLicenseSync()
+	defer resetLicenseSync()
+
+	req := require.New(t)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	licenseBytes := []byte("license")
+
+	req.Equal(types.LicenseSyncStatus{}, GetLicenseSyncStatus(now))
+
+	// a license that was persisted is persisted again only if it changes or the refresh interval passed
+	req.True(markLicenseSynced(licenseBytes, now))
+	licenseSyncMtx.Lock()
+	persistedLicense, persistedLicenseAt = licenseBytes, now
+	licenseSyncMtx.Unlock()
+	req.False(markLicenseSynced(licenseBytes, now.Add(time.Minute)))
+	req.True(markLicenseSynced([]byte("updated license"), now.Add(time.Minute)))
+	req.True(markLicenseSynced(licenseBytes, now.Add(lastKnownGoodRefreshInterval)))
+
+	MarkLicenseStale(now)
+	status := GetLicenseSyncStatus(now.Add(26 * time.Hour))
+	req.True(status.Stale)
+	req.Equal(now, *status.LastSyncedAt)
+	req.Equal(int64(26*60*60), status.AgeSeconds)
+
+	// a successful sync clears the stale indicator
+	markLicenseSynced(licenseBytes, now.Add(27*time.Hour))
+	status = GetLicenseSyncStatus(now.Add(27 * time.Hour))
+	req.False(status.Stale)
+	req.Equal(int64(0), status.AgeSeconds)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/pkg/errors"
 	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
 	"github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/metrics"
 	"github.com/replicatedhq/replicated-sdk/pkg/report"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
 	"github.com/replicatedhq/replicated-sdk/pkg/util"
@@ -29,7 +30,7 @@ func GetLicenseByID(licenseID string, endpoint string) (licensewrapper.LicenseWr
 	}
 	url := fmt.Sprintf("%s/license", endpoint)
 
-	licenseData, err := getLicenseFromAPI(url, licenseID)
+	licenseData, err := getLicenseFromAPI(url, licenseID, util.UpstreamLicense)
 	if err != nil {
 		return licensewrapper.LicenseWrapper{}, errors.Wrap(err, "failed to get license from api")
 	}
@@ -37,13 +38,24 @@ func GetLicenseByID(licenseID string, endpoint string) (licensewrapper.LicenseWr
 	return licenseData.License, nil
 }
 
+// GetLatestLicense gets the latest license from the upstream, and retries if the upstream is temporarily unavailable
 func GetLatestLicense(wrapper licensewrapper.LicenseWrapper, endpoint string) (*LicenseData, error) {
+	return getLatestLicense(wrapper, endpoint, util.UpstreamLicense)
+}
+
+// GetLatestLicenseOnRequest gets the latest license from the upstream without retrying, for SDK API requests that
+// fall back to the last known license
+func GetLatestLicenseOnRequest(wrapper licensewrapper.LicenseWrapper, endpoint string) (*LicenseData, error) {
+	return getLatestLicense(wrapper, endpoint, util.UpstreamLicenseOnRequest)
+}
+
+func getLatestLicense(wrapper licensewrapper.LicenseWrapper, endpoint string, upstream util.UpstreamEndpoint) (*LicenseData, error) {
 	if endpoint == "" {
 		endpoint = wrapper.GetEndpoint()
 	}
 	url := fmt.Sprintf("%s/license/%s", endpoint, wrapper.GetAppSlug())
 
-	licenseData, err := getLicenseFromAPI(url, wrapper.GetLicenseID())
+	licenseData, err := getLicenseFromAPI(url, wrapper.GetLicenseID(), upstream)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get license from api")
 	}
@@ -51,7 +63,7 @@ func GetLatestLicense(wrapper licensewrapper.LicenseWrapper, endpoint string) (*
 	return licenseData, nil
 }
 
-func getLicenseFromAPI(url string, licenseID string) (*LicenseData, error) {
+func getLicenseFromAPI(url string, licenseID string, upstream util.UpstreamEndpoint) (*LicenseData, error) {
 	req, err := util.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to call newrequest")
@@ -62,7 +74,8 @@ func getLicenseFromAPI(url string, licenseID string) (*LicenseData, error) {
 	instanceData := report.GetInstanceData(store.GetStore())
 	report.InjectInstanceDataHeaders(req, instanceData)
 
-	resp, err := util.HttpClient().Do(req)
+	resp, err := util.DoUpstream(req, upstream)
+	metrics.ObserveUpstreamRequest("license", resp, err)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to execute get request")
 	}
@@ -74,7 +87,7 @@ func getLicenseFromAPI(url string, licenseID string) (*LicenseData, error) {
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, errors.Errorf("unexpected result from get request: %d, data: %s", resp.StatusCode, body)
+		return nil, util.ActionableError{Message: fmt.Sprintf("unexpected result from get request: %d, data: %s", resp.StatusCode, body), StatusCode: resp.StatusCode}
 	}
 
 	license, err := LoadLicenseFromBytes(body)
@@ -89,32 +102,33 @@ func getLicenseFromAPI(url string, licenseID string) (*LicenseData, error) {
 	return data, nil
 }
 
-func LicenseIsExpired(wrapper licensewrapper.LicenseWrapper) (bool, error) {
+// GetLicenseExpiration returns the time at which the license expires, or nil if the license does not expire
+func GetLicenseExpiration(wrapper licensewrapper.LicenseWrapper) (*time.Time, error) {
 	entitlements := wrapper.GetEntitlements()
 	if entitlements == nil {
-		return false, nil
+		return nil, nil
 	}
 
 	ent, found := entitlements["expires_at"]
 	if !found {
-		return false, nil
+		return nil, nil
 	}
 
 	valueType := ent.GetValueType()
 	if valueType != "" && valueType != "String" {
-		return false, errors.Errorf("expires_at must be type String: %s", valueType)
+		return nil, errors.Errorf("expires_at must be type String: %s", valueType)
 	}
 
 	expiresAtValue := ent.GetValue().(string)
 	if expiresAtValue == "" {
-		return false, nil
+		return nil, nil
 	}
 
 	parsed, err := time.Parse(time.RFC3339, expiresAtValue)
 	if err != nil {
-		return false, errors.Wrap(err, "failed to parse expiration time")
+		return nil, errors.Wrap(err, "failed to parse expiration time")
 	}
-	return parsed.Before(time.Now()), nil
+	return &parsed, nil
 }
 
 func GetLatestLicenseFields(wrapper licensewrapper.LicenseWrapper, endpoint string) (types.LicenseFields, error) {
@@ -133,7 +147,8 @@ func GetLatestLicenseFields(wrapper licensewrapper.LicenseWrapper, endpoint stri
 	instanceData := report.GetInstanceData(store.GetStore())
 	report.InjectInstanceDataHeaders(req, instanceData)
 
-	resp, err := util.HttpClient().Do(req)
+	resp, err := util.DoUpstream(req, util.UpstreamLicenseFields)
+	metrics.ObserveUpstreamRequest("license_fields", resp, err)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to execute get request")
 	}
@@ -172,7 +187,8 @@ func GetLatestLicenseField(wrapper licensewrapper.LicenseWrapper, endpoint strin
 	instanceData := report.GetInstanceData(store.GetStore())
 	report.InjectInstanceDataHeaders(req, instanceData)
 
-	resp, err := util.HttpClient().Do(req)
+	resp, err := util.DoUpstream(req, util.UpstreamLicenseField)
+	metrics.ObserveUpstreamRequest("license_field", resp, err)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to execute get request")
 	}
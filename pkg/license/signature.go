@@ -0,0 +1,155 @@
+package license
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/pkg/errors"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+)
+
+type outerSignature struct {
+	LicenseData    []byte `json:"licenseData"`
+	InnerSignature []byte `json:"innerSignature"`
+}
+
+type innerSignature struct {
+	LicenseSignature []byte `json:"licenseSignature"`
+	PublicKey        string `json:"publicKey"`
+	KeySignature     []byte `json:"keySignature"`
+}
+
+// VerifyLicenseFields returns a copy of the fields with Verified set on each field whose signature is valid.
+// Fields that are signed but fail verification are logged, since they have been tampered with.
+func VerifyLicenseFields(wrapper licensewrapper.LicenseWrapper, fields types.LicenseFields) types.LicenseFields {
+	if fields == nil {
+		return nil
+	}
+
+	publicKey, keyErr := getAppPublicKey(wrapper)
+	if keyErr != nil {
+		logger.Warnf("failed to get app public key, license fields can't be verified: %v", keyErr)
+	}
+
+	verified := make(types.LicenseFields, len(fields))
+	for name, field := range fields {
+		field.Verified = false
+		if keyErr == nil {
+			err := verifyLicenseField(publicKey, field)
+			if err == nil {
+				field.Verified = true
+			} else if isSigned(field) {
+				logger.Warnf("license field %q failed signature verification: %v", name, err)
+			}
+		}
+		verified[name] = field
+	}
+
+	return verified
+}
+
+// verifyLicenseField verifies the V2 (SHA-256) signature of the field if it has one, and the V1 (MD5) signature otherwise
+func verifyLicenseField(publicKey *rsa.PublicKey, field types.LicenseField) error {
+	message, err := licenseFieldMessage(field)
+	if err != nil {
+		return errors.Wrap(err, "failed to get signed value")
+	}
+
+	if field.Signature.V2 != "" {
+		return verifyPSS(publicKey, crypto.SHA256, message, field.Signature.V2)
+	}
+	if field.Signature.V1 != "" {
+		return verifyPSS(publicKey, crypto.MD5, message, field.Signature.V1)
+	}
+	return errors.New("field is not signed")
+}
+
+func isSigned(field types.LicenseField) bool {
+	return field.Signature.V1 != "" || field.Signature.V2 != ""
+}
+
+// licenseFieldMessage returns the signed representation of the field value, which is the value formatted as a string
+// after coercing it to its value type, so that e.g. an Integer decoded from json as 10.0 is signed as "10"
+func licenseFieldMessage(field types.LicenseField) ([]byte, error) {
+	value, err := types.CoerceValue(field.ValueType, field.Value)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprint(value)), nil
+}
+
+// VerifyAppSignature verifies data that the vendor signed with the app key, like the release index that is shipped with airgap bundles.
+// The signature is an RSA-PSS signature of the SHA-256 hash of the data, and is verified with the app public key from the license.
+func VerifyAppSignature(wrapper licensewrapper.LicenseWrapper, data []byte, signature []byte) error {
+	publicKey, err := getAppPublicKey(wrapper)
+	if err != nil {
+		return errors.Wrap(err, "failed to get app public key")
+	}
+	return verifyPSSSignature(publicKey, crypto.SHA256, data, signature)
+}
+
+func verifyPSS(publicKey *rsa.PublicKey, hash crypto.Hash, message []byte, encodedSignature string) error {
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode signature")
+	}
+	return verifyPSSSignature(publicKey, hash, message, signature)
+}
+
+func verifyPSSSignature(publicKey *rsa.PublicKey, hash crypto.Hash, message []byte, signature []byte) error {
+	h := hash.New()
+	h.Write(message)
+	hashed := h.Sum(nil)
+
+	if err := rsa.VerifyPSS(publicKey, hash, hashed, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}); err != nil {
+		return errors.Wrap(err, "invalid signature")
+	}
+	return nil
+}
+
+// getAppPublicKey returns the public key of the app from the license signature. The license signature, including the
+// signature of the app key by one of the PublicKeys, is verified when the license is loaded, so the key can be trusted.
+func getAppPublicKey(wrapper licensewrapper.LicenseWrapper) (*rsa.PublicKey, error) {
+	var signature []byte
+	if wrapper.IsV1() {
+		signature = wrapper.V1.Spec.Signature
+	} else if wrapper.IsV2() {
+		signature = wrapper.V2.Spec.Signature
+	}
+	if len(signature) == 0 {
+		return nil, errors.New("license is not signed")
+	}
+
+	outer := outerSignature{}
+	if err := json.Unmarshal(signature, &outer); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal license signature")
+	}
+
+	inner := innerSignature{}
+	if err := json.Unmarshal(outer.InnerSignature, &inner); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal inner signature")
+	}
+
+	block, _ := pem.Decode([]byte(inner.PublicKey))
+	if block == nil {
+		return nil, errors.New("failed to decode app public key")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse app public key")
+	}
+
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("app public key is %T, not an RSA key", key)
+	}
+	return publicKey, nil
+}
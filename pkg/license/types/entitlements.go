@@ -0,0 +1,207 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// EntitlementOperator is how a license field is compared in an entitlement check
+type EntitlementOperator string
+
+const (
+	EntitlementOperatorEqual              EntitlementOperator = "=="
+	EntitlementOperatorNotEqual           EntitlementOperator = "!="
+	EntitlementOperatorGreaterThan        EntitlementOperator = ">"
+	EntitlementOperatorGreaterThanOrEqual EntitlementOperator = ">="
+	EntitlementOperatorLessThan           EntitlementOperator = "<"
+	EntitlementOperatorLessThanOrEqual    EntitlementOperator = "<="
+	// EntitlementOperatorEnabled passes if a Boolean field is true, and takes no value
+	EntitlementOperatorEnabled EntitlementOperator = "enabled"
+	// EntitlementOperatorDisabled passes if a Boolean field is false, and takes no value
+	EntitlementOperatorDisabled EntitlementOperator = "disabled"
+)
+
+// EntitlementOperators lists the supported operators
+var EntitlementOperators = []EntitlementOperator{
+	EntitlementOperatorEqual,
+	EntitlementOperatorNotEqual,
+	EntitlementOperatorGreaterThan,
+	EntitlementOperatorGreaterThanOrEqual,
+	EntitlementOperatorLessThan,
+	EntitlementOperatorLessThanOrEqual,
+	EntitlementOperatorEnabled,
+	EntitlementOperatorDisabled,
+}
+
+// SignatureStatus is whether the value of a license field is backed by the license signature
+type SignatureStatus string
+
+const (
+	// SignatureStatusVerified is a field whose value matches the signed license
+	SignatureStatusVerified SignatureStatus = "verified"
+	// SignatureStatusUnverified is a field that is not part of the signed license, so its value can't be verified
+	SignatureStatusUnverified SignatureStatus = "unverified"
+	// SignatureStatusInvalid is a field whose value does not match the signed license
+	SignatureStatusInvalid SignatureStatus = "invalid"
+)
+
+// EntitlementCheck compares a license field to a value, e.g. {field: "seat_count", op: ">=", value: 25}
+type EntitlementCheck struct {
+	Field string              `json:"field" validate:"required"`
+	Op    EntitlementOperator `json:"op" validate:"required"`
+	Value interface{}         `json:"value,omitempty"`
+}
+
+type EntitlementCheckResult struct {
+	Field string              `json:"field"`
+	Op    EntitlementOperator `json:"op"`
+	Value interface{}         `json:"value,omitempty"`
+	// ActualValue is the value of the license field, coerced to its value type
+	ActualValue interface{}     `json:"actualValue,omitempty"`
+	ValueType   string          `json:"valueType,omitempty"`
+	Passed      bool            `json:"passed"`
+	Signature   SignatureStatus `json:"signature"`
+	// Error is why the check could not be evaluated, e.g. the field does not exist or the value has the wrong type
+	Error string `json:"error,omitempty"`
+}
+
+// Validate checks that the operator is supported and that a value is given if the operator needs one
+func (c EntitlementCheck) Validate() error {
+	if c.Field == "" {
+		return errors.New("field is required")
+	}
+
+	switch c.Op {
+	case EntitlementOperatorEnabled, EntitlementOperatorDisabled:
+		if c.Value != nil {
+			return errors.Errorf("operator %q does not take a value", c.Op)
+		}
+	case EntitlementOperatorEqual, EntitlementOperatorNotEqual,
+		EntitlementOperatorGreaterThan, EntitlementOperatorGreaterThanOrEqual,
+		EntitlementOperatorLessThan, EntitlementOperatorLessThanOrEqual:
+		if c.Value == nil {
+			return errors.Errorf("operator %q requires a value", c.Op)
+		}
+	default:
+		return errors.Errorf("unsupported operator %q", c.Op)
+	}
+
+	return nil
+}
+
+// Evaluate runs the check against the field. Both the field value and the check value are coerced to the field's value type.
+// It returns the coerced field value, and an error if the check can't be evaluated for this field.
+func (f LicenseField) Evaluate(check EntitlementCheck) (bool, interface{}, error) {
+	actual, err := CoerceValue(f.ValueType, f.Value)
+	if err != nil {
+		return false, nil, errors.Wrap(err, "invalid field value")
+	}
+
+	switch check.Op {
+	case EntitlementOperatorEnabled, EntitlementOperatorDisabled:
+		enabled, ok := actual.(bool)
+		if !ok {
+			return false, actual, errors.Errorf("operator %q is only supported for Boolean fields", check.Op)
+		}
+		return enabled == (check.Op == EntitlementOperatorEnabled), actual, nil
+	}
+
+	expected, err := CoerceValue(f.ValueType, check.Value)
+	if err != nil {
+		return false, actual, errors.Wrap(err, "invalid value")
+	}
+
+	switch check.Op {
+	case EntitlementOperatorEqual:
+		return actual == expected, actual, nil
+	case EntitlementOperatorNotEqual:
+		return actual != expected, actual, nil
+	}
+
+	a, ok := actual.(int64)
+	if !ok {
+		return false, actual, errors.Errorf("operator %q is only supported for Integer fields", check.Op)
+	}
+	e := expected.(int64)
+
+	switch check.Op {
+	case EntitlementOperatorGreaterThan:
+		return a > e, actual, nil
+	case EntitlementOperatorGreaterThanOrEqual:
+		return a >= e, actual, nil
+	case EntitlementOperatorLessThan:
+		return a < e, actual, nil
+	case EntitlementOperatorLessThanOrEqual:
+		return a <= e, actual, nil
+	}
+
+	return false, actual, errors.Errorf("unsupported operator %q", check.Op)
+}
+
+// CoerceValue converts a license field value to the Go type of its value type: int64 for Integer, bool for Boolean,
+// and string for String and Text. Numbers and booleans may also be given as strings, as they are in license yaml.
+func CoerceValue(valueType string, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, errors.New("value is missing")
+	}
+
+	switch valueType {
+	case "Integer":
+		return coerceInteger(value)
+	case "Boolean":
+		return coerceBoolean(value)
+	case "String", "Text", "":
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return nil, errors.Errorf("%v is not a string", value)
+	default:
+		return nil, errors.Errorf("unsupported value type %q", valueType)
+	}
+}
+
+func coerceInteger(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		if v != math.Trunc(v) || v > math.MaxInt64 || v < math.MinInt64 {
+			return 0, errors.Errorf("%v is not an integer", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		i, err := v.Int64()
+		if err != nil {
+			return 0, errors.Errorf("%v is not an integer", v)
+		}
+		return i, nil
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, errors.Errorf("%q is not an integer", v)
+		}
+		return i, nil
+	}
+	return 0, errors.Errorf("%v is not an integer", value)
+}
+
+func coerceBoolean(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, errors.Errorf("%q is not a boolean", v)
+		}
+		return b, nil
+	}
+	return false, errors.Errorf("%v is not a boolean", value)
+}
@@ -1,5 +1,12 @@
 package types
 
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
 type LicenseField struct {
 	Name        string                `json:"name,omitempty" yaml:"name,omitempty"`
 	Title       string                `json:"title,omitempty" yaml:"title,omitempty"`
@@ -8,6 +15,9 @@ type LicenseField struct {
 	ValueType   string                `json:"valueType,omitempty" yaml:"valueType,omitempty"`
 	IsHidden    bool                  `json:"isHidden,omitempty" yaml:"isHidden,omitempty"`
 	Signature   LicenseFieldSignature `json:"signature,omitempty" yaml:"signature,omitempty"`
+	// Verified is true if the signature of the field was verified against the public key of the app.
+	// It is set by the SDK whenever the field is loaded or fetched, and is never read from config.
+	Verified bool `json:"verified" yaml:"-"`
 }
 
 type LicenseFieldSignature struct {
@@ -16,3 +26,92 @@ type LicenseFieldSignature struct {
 }
 
 type LicenseFields map[string]LicenseField
+
+// ExpirationState is where the license is in its lifecycle relative to its expires_at entitlement
+type ExpirationState string
+
+const (
+	// ExpirationStateValid is a license that does not expire, or does not expire within the expiring soon threshold
+	ExpirationStateValid ExpirationState = "valid"
+	// ExpirationStateExpiringSoon is a license that expires within the expiring soon threshold
+	ExpirationStateExpiringSoon ExpirationState = "expiring_soon"
+	// ExpirationStateExpiredInGrace is an expired license that is still within the grace period
+	ExpirationStateExpiredInGrace ExpirationState = "expired_in_grace"
+	// ExpirationStateExpired is an expired license past the grace period. The SDK keeps serving the API as usual.
+	ExpirationStateExpired ExpirationState = "expired"
+)
+
+type LicenseExpiration struct {
+	State             ExpirationState `json:"state" yaml:"state"`
+	ExpiresAt         *time.Time      `json:"expiresAt,omitempty" yaml:"expiresAt,omitempty"`
+	GracePeriodEndsAt *time.Time      `json:"gracePeriodEndsAt,omitempty" yaml:"gracePeriodEndsAt,omitempty"`
+	EvaluatedAt       *time.Time      `json:"evaluatedAt,omitempty" yaml:"evaluatedAt,omitempty"`
+}
+
+// LicenseSyncStatus is when the license was last synced with the Replicated API
+type LicenseSyncStatus struct {
+	// Stale is true if the license could not be synced when the SDK started, and the last known good license is served instead.
+	// It is cleared by the next successful sync.
+	Stale        bool       `json:"stale" yaml:"stale"`
+	LastSyncedAt *time.Time `json:"lastSyncedAt,omitempty" yaml:"lastSyncedAt,omitempty"`
+	// AgeSeconds is how long ago the license was last synced
+	AgeSeconds int64 `json:"ageSeconds,omitempty" yaml:"ageSeconds,omitempty"`
+}
+
+// LicenseChannel is a channel that the license is entitled to install and update from
+type LicenseChannel struct {
+	ChannelID        string `json:"channelID" yaml:"channelID"`
+	ChannelName      string `json:"channelName,omitempty" yaml:"channelName,omitempty"`
+	ChannelSlug      string `json:"channelSlug,omitempty" yaml:"channelSlug,omitempty"`
+	IsDefault        bool   `json:"isDefault" yaml:"isDefault"`
+	IsSemverRequired bool   `json:"isSemverRequired" yaml:"isSemverRequired"`
+}
+
+// DefaultLicenseSourceSecretKey is the key of the license in the secret of a LicenseSource when SecretKey is not set
+const DefaultLicenseSourceSecretKey = "license.yaml"
+
+// LicenseSource is where the SDK reloads the license from when it changes, e.g. when a renewed license is
+// mounted into an airgap install. Exactly one of Path and SecretName must be set.
+type LicenseSource struct {
+	// Path is a license file, typically mounted from a secret or a config map
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// SecretName is a secret in the namespace of the SDK that contains the license
+	SecretName string `json:"secretName,omitempty" yaml:"secretName,omitempty"`
+	// SecretKey is the key of the license in the secret, DefaultLicenseSourceSecretKey if not set
+	SecretKey string `json:"secretKey,omitempty" yaml:"secretKey,omitempty"`
+}
+
+func (s LicenseSource) Validate() error {
+	if s.Path == "" && s.SecretName == "" {
+		return errors.New("one of path or secretName is required")
+	}
+	if s.Path != "" && s.SecretName != "" {
+		return errors.New("only one of path or secretName can be set")
+	}
+	if s.SecretKey != "" && s.SecretName == "" {
+		return errors.New("secretKey can only be set with secretName")
+	}
+	return nil
+}
+
+func (s LicenseSource) GetSecretKey() string {
+	if s.SecretKey == "" {
+		return DefaultLicenseSourceSecretKey
+	}
+	return s.SecretKey
+}
+
+func (s LicenseSource) String() string {
+	if s.Path != "" {
+		return fmt.Sprintf("file %s", s.Path)
+	}
+	return fmt.Sprintf("secret %s key %s", s.SecretName, s.GetSecretKey())
+}
+
+// EntitlementChange is an entitlement that was added, removed or changed between two licenses.
+// Previous is nil for an added entitlement, and Current is nil for a removed one.
+type EntitlementChange struct {
+	Name     string      `json:"name" yaml:"name"`
+	Previous interface{} `json:"previous" yaml:"previous"`
+	Current  interface{} `json:"current" yaml:"current"`
+}
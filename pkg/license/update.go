@@ -0,0 +1,42 @@
+package license
+
+import (
+	"github.com/pkg/errors"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	licensewrappertypes "github.com/replicatedhq/kotskinds/pkg/licensewrapper/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+)
+
+// ValidateLicenseUpdate checks that the updated license can replace the current one: its signature must be valid,
+// it must be for the same app, and it must not be older than the current license
+func ValidateLicenseUpdate(current licensewrapper.LicenseWrapper, updated licensewrapper.LicenseWrapper) error {
+	if err := updated.VerifySignature(); err != nil {
+		if licensewrappertypes.IsLicenseDataValidationError(err) {
+			// the data inside the signature is valid and is used instead, same as for the configured license
+			logger.Info(err.Error())
+		} else {
+			return errors.Wrap(err, "failed to verify license signature")
+		}
+	}
+
+	if updated.GetAppSlug() != current.GetAppSlug() {
+		return errors.Errorf("license is for app %q, not %q", updated.GetAppSlug(), current.GetAppSlug())
+	}
+	if updated.GetLicenseSequence() < current.GetLicenseSequence() {
+		return errors.Errorf("license sequence %d is older than the current license sequence %d", updated.GetLicenseSequence(), current.GetLicenseSequence())
+	}
+
+	return nil
+}
+
+// ValidateLicenseReplacement checks that a license that is uploaded to replace the current license can be served:
+// in addition to the checks of ValidateLicenseUpdate, it must be the same license, e.g. a renewal of the current license
+func ValidateLicenseReplacement(current licensewrapper.LicenseWrapper, replacement licensewrapper.LicenseWrapper) error {
+	if err := ValidateLicenseUpdate(current, replacement); err != nil {
+		return err
+	}
+	if replacement.GetLicenseID() != current.GetLicenseID() {
+		return errors.Errorf("license %s is not the current license %s", replacement.GetLicenseID(), current.GetLicenseID())
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package license
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/licensetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLicenseUpdate(t *testing.T) {
+	updated, err := LoadLicenseFromBytes([]byte(licensetest.ValidSignedLicense))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		current licensewrapper.LicenseWrapper
+		wantErr string
+	}{
+		{
+			name: "newer license",
+			current: licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+				Spec: v1beta1.LicenseSpec{AppSlug: "my-app", LicenseSequence: 6},
+			}},
+		},
+		{
+			name: "same sequence",
+			current: licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+				Spec: v1beta1.LicenseSpec{AppSlug: "my-app", LicenseSequence: 7},
+			}},
+		},
+		{
+			name: "different app",
+			current: licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+				Spec: v1beta1.LicenseSpec{AppSlug: "other-app", LicenseSequence: 6},
+			}},
+			wantErr: `license is for app "my-app", not "other-app"`,
+		},
+		{
+			name: "older license",
+			current: licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+				Spec: v1beta1.LicenseSpec{AppSlug: "my-app", LicenseSequence: 8},
+			}},
+			wantErr: "license sequence 7 is older than the current license sequence 8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLicenseUpdate(tt.current, updated)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestValidateLicenseReplacement(t *testing.T) {
+	replacement, err := LoadLicenseFromBytes([]byte(licensetest.ValidSignedLicense))
+	require.NoError(t, err)
+
+	current := licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+		Spec: v1beta1.LicenseSpec{LicenseID: replacement.GetLicenseID(), AppSlug: "my-app", LicenseSequence: 6},
+	}}
+	require.NoError(t, ValidateLicenseReplacement(current, replacement))
+
+	current.V1.Spec.LicenseID = "other-license-id"
+	require.EqualError(t, ValidateLicenseReplacement(current, replacement), "license 1vusOokxAVp1tkRGuyxnF23PJcq is not the current license other-license-id")
+}
@@ -0,0 +1,72 @@
+package license
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/util"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// uploadedLicenseKey is the key in the replicated secret of a license that was uploaded through the API.
+// It is kept separate from the config, so that the config can still be managed by Helm.
+const uploadedLicenseKey = "uploaded-license"
+
+var uploadedLicenseLock = sync.Mutex{}
+
+// SaveUploadedLicense persists an uploaded license in the replicated secret, so that it is still served after a restart
+func SaveUploadedLicense(ctx context.Context, clientset kubernetes.Interface, namespace string, licenseBytes []byte) error {
+	uploadedLicenseLock.Lock()
+	defer uploadedLicenseLock.Unlock()
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, util.GetReplicatedSecretName(), metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to get replicated secret")
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	secret.Data[uploadedLicenseKey] = licenseBytes
+	if _, err := clientset.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrap(err, "failed to update replicated secret")
+	}
+
+	return nil
+}
+
+// GetUploadedLicense returns the license that was uploaded to replace the configured license, if it can replace it.
+// An uploaded license that is older than the configured license, e.g. because a renewed license was configured with
+// a Helm upgrade since, is ignored. It returns nil if no license was uploaded.
+func GetUploadedLicense(ctx context.Context, clientset kubernetes.Interface, namespace string, configured licensewrapper.LicenseWrapper) (*licensewrapper.LicenseWrapper, error) {
+	uploadedLicenseLock.Lock()
+	defer uploadedLicenseLock.Unlock()
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, util.GetReplicatedSecretName(), metav1.GetOptions{})
+	if err != nil {
+		if kuberneteserrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to get replicated secret")
+	}
+
+	licenseBytes := secret.Data[uploadedLicenseKey]
+	if len(licenseBytes) == 0 {
+		return nil, nil
+	}
+
+	wrapper, err := LoadLicenseFromBytes(licenseBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load uploaded license")
+	}
+	if err := ValidateLicenseReplacement(configured, wrapper); err != nil {
+		return nil, errors.Wrap(err, "uploaded license can't replace the configured license")
+	}
+
+	return &wrapper, nil
+}
@@ -0,0 +1,79 @@
+package license
+
+import (
+	"context"
+	"testing"
+
+	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/licensetest"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetUploadedLicense(t *testing.T) {
+	ctx := context.Background()
+
+	configuredLicense := func(sequence int64) licensewrapper.LicenseWrapper {
+		return licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+			Spec: v1beta1.LicenseSpec{LicenseID: "1vusOokxAVp1tkRGuyxnF23PJcq", AppSlug: "my-app", LicenseSequence: sequence},
+		}}
+	}
+
+	tests := []struct {
+		name         string
+		upload       bool
+		configured   licensewrapper.LicenseWrapper
+		wantSequence int64
+		wantErr      string
+	}{
+		{
+			name:         "newer than the configured license",
+			upload:       true,
+			configured:   configuredLicense(6),
+			wantSequence: 7,
+		},
+		{
+			name:       "nothing uploaded",
+			configured: configuredLicense(6),
+		},
+		{
+			name:       "older than the configured license",
+			upload:     true,
+			configured: configuredLicense(8),
+			wantErr:    "uploaded license can't replace the configured license: license sequence 7 is older than the current license sequence 8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			clientset := fake.NewSimpleClientset(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "replicated", Namespace: "test-ns"},
+				Data:       map[string][]byte{"config.yaml": []byte("license: ...")},
+			})
+			if tt.upload {
+				req.NoError(SaveUploadedLicense(ctx, clientset, "test-ns", []byte(licensetest.ValidSignedLicense)))
+			}
+
+			uploaded, err := GetUploadedLicense(ctx, clientset, "test-ns", tt.configured)
+			if tt.wantErr != "" {
+				req.EqualError(err, tt.wantErr)
+				return
+			}
+			req.NoError(err)
+			if tt.wantSequence == 0 {
+				req.Nil(uploaded)
+				return
+			}
+			req.Equal(tt.wantSequence, uploaded.GetLicenseSequence())
+
+			// the config is left as is
+			secret, err := clientset.CoreV1().Secrets("test-ns").Get(ctx, "replicated", metav1.GetOptions{})
+			req.NoError(err)
+			req.Equal("license: ...", string(secret.Data["config.yaml"]))
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package meta
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/meta/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	lastKnownGoodLicenseSecretKey replicatedMetadataSecretKey = "last-known-good-license"
+)
+
+var lastKnownGoodLicenseLock = sync.Mutex{}
+
+func SaveLastKnownGoodLicense(ctx context.Context, clientset kubernetes.Interface, namespace string, licenseBytes []byte, syncedAt time.Time) error {
+	return updateLastKnownGoodLicense(ctx, clientset, namespace, func(lastKnownGood *types.LastKnownGoodLicense) {
+		lastKnownGood.LicenseBytes = licenseBytes
+		lastKnownGood.SyncedAt = syncedAt
+	})
+}
+
+func SaveLastKnownGoodLicenseFields(ctx context.Context, clientset kubernetes.Interface, namespace string, licenseFields sdklicensetypes.LicenseFields) error {
+	return updateLastKnownGoodLicense(ctx, clientset, namespace, func(lastKnownGood *types.LastKnownGoodLicense) {
+		lastKnownGood.LicenseFields = licenseFields
+	})
+}
+
+func updateLastKnownGoodLicense(ctx context.Context, clientset kubernetes.Interface, namespace string, update func(*types.LastKnownGoodLicense)) error {
+	lastKnownGoodLicenseLock.Lock()
+	defer lastKnownGoodLicenseLock.Unlock()
+
+	lastKnownGood := types.LastKnownGoodLicense{}
+	err := get(ctx, clientset, namespace, lastKnownGoodLicenseSecretKey, &lastKnownGood)
+	if err != nil && errors.Cause(err) != ErrReplicatedMetadataNotFound {
+		return errors.Wrap(err, "failed to get last known good license")
+	}
+
+	update(&lastKnownGood)
+
+	if err := save(ctx, clientset, namespace, lastKnownGoodLicenseSecretKey, lastKnownGood); err != nil {
+		return errors.Wrap(err, "failed to save last known good license")
+	}
+
+	return nil
+}
+
+// GetLastKnownGoodLicense returns the persisted license, or an error that wraps ErrReplicatedMetadataNotFound if there is none
+func GetLastKnownGoodLicense(ctx context.Context, clientset kubernetes.Interface, namespace string) (*types.LastKnownGoodLicense, error) {
+	lastKnownGood := types.LastKnownGoodLicense{}
+
+	if err := get(ctx, clientset, namespace, lastKnownGoodLicenseSecretKey, &lastKnownGood); err != nil {
+		return nil, errors.Wrap(err, "failed to get last known good license")
+	}
+	if len(lastKnownGood.LicenseBytes) == 0 {
+		return nil, errors.Wrap(ErrReplicatedMetadataNotFound, "last known good license has no license")
+	}
+
+	return &lastKnownGood, nil
+}
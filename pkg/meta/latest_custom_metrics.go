@@ -3,6 +3,7 @@ package meta
 import (
 	"context"
 	"maps"
+	"sync"
 
 	"github.com/pkg/errors"
 	"k8s.io/client-go/kubernetes"
@@ -12,6 +13,13 @@ const (
 	customMetricsSecretKey replicatedMetadataSecretKey = "latest-custom-metrics"
 )
 
+var (
+	// latestCustomMetrics is a copy of the last synced custom app metrics by namespace, so that reading them,
+	// e.g. on every metrics scrape, doesn't get the secret each time
+	latestCustomMetrics    = map[string]map[string]interface{}{}
+	latestCustomMetricsMtx sync.Mutex
+)
+
 func SyncCustomAppMetrics(ctx context.Context, clientset kubernetes.Interface, namespace string, inboundMetrics map[string]interface{}, overwrite bool) (map[string]interface{}, error) {
 	existing := map[string]interface{}{}
 
@@ -26,9 +34,48 @@ func SyncCustomAppMetrics(ctx context.Context, clientset kubernetes.Interface, n
 		return nil, errors.Wrap(err, "failed to save custom metrics")
 	}
 
+	setLatestCustomAppMetrics(namespace, modified)
+
 	return modified, nil
 }
 
+// GetLatestCustomAppMetrics returns the last synced custom app metrics, or an empty map if none have been reported.
+// The secret is only read the first time, after that the metrics are kept up to date when they are synced.
+func GetLatestCustomAppMetrics(ctx context.Context, clientset kubernetes.Interface, namespace string) (map[string]interface{}, error) {
+	latestCustomMetricsMtx.Lock()
+	cached, ok := latestCustomMetrics[namespace]
+	latestCustomMetricsMtx.Unlock()
+	if ok {
+		return maps.Clone(cached), nil
+	}
+
+	existing := map[string]interface{}{}
+
+	err := get(ctx, clientset, namespace, customMetricsSecretKey, &existing)
+	if err != nil && errors.Cause(err) != ErrReplicatedMetadataNotFound {
+		return nil, errors.Wrapf(err, "failed to get custom metrics data")
+	}
+
+	setLatestCustomAppMetrics(namespace, existing)
+
+	return existing, nil
+}
+
+// ResetLatestCustomAppMetrics clears the in-memory copy of the custom app metrics, so that they are read from the secret again
+func ResetLatestCustomAppMetrics() {
+	latestCustomMetricsMtx.Lock()
+	defer latestCustomMetricsMtx.Unlock()
+
+	latestCustomMetrics = map[string]map[string]interface{}{}
+}
+
+func setLatestCustomAppMetrics(namespace string, customMetrics map[string]interface{}) {
+	latestCustomMetricsMtx.Lock()
+	defer latestCustomMetricsMtx.Unlock()
+
+	latestCustomMetrics[namespace] = maps.Clone(customMetrics)
+}
+
 func mergeCustomAppMetrics(existingMetrics map[string]interface{}, inboundMetrics map[string]interface{}, overwrite bool) map[string]interface{} {
 	if existingMetrics == nil {
 		existingMetrics = map[string]interface{}{}
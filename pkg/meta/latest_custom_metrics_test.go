@@ -1,9 +1,17 @@
 package meta
 
 import (
+	"context"
 	"testing"
 
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func Test_mergeCustomAppMetrics(tst *testing.T) {
@@ -81,3 +89,40 @@ func Test_mergeCustomAppMetrics(tst *testing.T) {
 		tt.assertFn(tst, m)
 	}
 }
+
+func Test_GetLatestCustomAppMetrics(t *testing.T) {
+	req := require.New(t)
+
+	ResetLatestCustomAppMetrics()
+	defer ResetLatestCustomAppMetrics()
+
+	store.InitInMemory(store.InitInMemoryStoreOptions{})
+	defer store.SetStore(nil)
+
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: ReplicatedMetadataSecretName, Namespace: "default"},
+		Data: map[string][]byte{
+			"latest-custom-metrics": []byte(`{"numProjects": 10}`),
+		},
+	})
+
+	gets := 0
+	clientset.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		gets++
+		return false, nil, nil
+	})
+
+	customMetrics, err := GetLatestCustomAppMetrics(context.Background(), clientset, "default")
+	req.NoError(err)
+	req.Equal(map[string]interface{}{"numProjects": float64(10)}, customMetrics)
+
+	// the secret is only read once, synced metrics are kept in memory
+	_, err = SyncCustomAppMetrics(context.Background(), clientset, "default", map[string]interface{}{"isTrial": true}, false)
+	req.NoError(err)
+	gets = 0
+
+	customMetrics, err = GetLatestCustomAppMetrics(context.Background(), clientset, "default")
+	req.NoError(err)
+	req.Equal(map[string]interface{}{"numProjects": float64(10), "isTrial": true}, customMetrics)
+	req.Equal(0, gets)
+}
@@ -3,8 +3,10 @@ package types
 import (
 	"encoding/base64"
 	"encoding/json"
+	"time"
 
 	"github.com/pkg/errors"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
 )
 
 type InstanceTagData struct {
@@ -35,3 +37,23 @@ func (i *InstanceTagData) UnmarshalBase64(bs []byte) error {
 	}
 	return nil
 }
+
+// WebhookDeadLetter is a webhook event that could not be delivered after all retries
+type WebhookDeadLetter struct {
+	EventID   string          `json:"eventId"`
+	EventType string          `json:"eventType"`
+	Target    string          `json:"target"`
+	URL       string          `json:"url"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"lastError"`
+	FailedAt  time.Time       `json:"failedAt"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// LastKnownGoodLicense is the license and license fields from the last successful syncs with the Replicated API
+type LastKnownGoodLicense struct {
+	LicenseBytes  []byte                        `json:"licenseBytes"`
+	LicenseFields sdklicensetypes.LicenseFields `json:"licenseFields,omitempty"`
+	// SyncedAt is when the license was synced
+	SyncedAt time.Time `json:"syncedAt"`
+}
@@ -0,0 +1,51 @@
+package meta
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/meta/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	webhookDeadLettersSecretKey replicatedMetadataSecretKey = "webhook-dead-letters"
+
+	// maxWebhookDeadLetters is the number of undelivered webhook events kept, oldest are dropped first
+	maxWebhookDeadLetters = 50
+)
+
+var webhookDeadLettersLock = sync.Mutex{}
+
+func AppendWebhookDeadLetter(ctx context.Context, clientset kubernetes.Interface, namespace string, deadLetter types.WebhookDeadLetter) error {
+	webhookDeadLettersLock.Lock()
+	defer webhookDeadLettersLock.Unlock()
+
+	deadLetters, err := GetWebhookDeadLetters(ctx, clientset, namespace)
+	if err != nil {
+		return errors.Wrap(err, "failed to get webhook dead letters")
+	}
+
+	deadLetters = append(deadLetters, deadLetter)
+	if len(deadLetters) > maxWebhookDeadLetters {
+		deadLetters = deadLetters[len(deadLetters)-maxWebhookDeadLetters:]
+	}
+
+	if err := save(ctx, clientset, namespace, webhookDeadLettersSecretKey, deadLetters); err != nil {
+		return errors.Wrap(err, "failed to save webhook dead letters")
+	}
+
+	return nil
+}
+
+func GetWebhookDeadLetters(ctx context.Context, clientset kubernetes.Interface, namespace string) ([]types.WebhookDeadLetter, error) {
+	deadLetters := []types.WebhookDeadLetter{}
+
+	err := get(ctx, clientset, namespace, webhookDeadLettersSecretKey, &deadLetters)
+	if err != nil && errors.Cause(err) != ErrReplicatedMetadataNotFound {
+		return nil, errors.Wrap(err, "failed to get webhook dead letters")
+	}
+
+	return deadLetters, nil
+}
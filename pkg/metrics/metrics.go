@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type MetricType string
+
+const (
+	MetricTypeGauge   MetricType = "gauge"
+	MetricTypeCounter MetricType = "counter"
+)
+
+type Label struct {
+	Name  string
+	Value string
+}
+
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// Family is a set of samples sharing a metric name, help text and type
+type Family struct {
+	Name    string
+	Help    string
+	Type    MetricType
+	Samples []Sample
+}
+
+// Write writes the families in the Prometheus text exposition format. Families without samples are skipped.
+func Write(w io.Writer, families []Family) error {
+	bw := bufio.NewWriter(w)
+
+	for _, family := range families {
+		if len(family.Samples) == 0 {
+			continue
+		}
+
+		bw.WriteString("# HELP " + family.Name + " " + escapeHelp(family.Help) + "\n")
+		bw.WriteString("# TYPE " + family.Name + " " + string(family.Type) + "\n")
+
+		for _, sample := range family.Samples {
+			bw.WriteString(family.Name)
+			if len(sample.Labels) > 0 {
+				bw.WriteString("{")
+				for i, label := range sample.Labels {
+					if i > 0 {
+						bw.WriteString(",")
+					}
+					bw.WriteString(label.Name + `="` + escapeLabelValue(label.Value) + `"`)
+				}
+				bw.WriteString("}")
+			}
+			bw.WriteString(" " + formatValue(sample.Value) + "\n")
+		}
+	}
+
+	return bw.Flush()
+}
+
+// SortSamples orders samples by their label values so that the output is stable between scrapes
+func SortSamples(samples []Sample) {
+	sort.SliceStable(samples, func(i, j int) bool {
+		a, b := samples[i].Labels, samples[j].Labels
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k].Value != b[k].Value {
+				return a[k].Value < b[k].Value
+			}
+		}
+		return len(a) < len(b)
+	})
+}
+
+func escapeHelp(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+func escapeLabelValue(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
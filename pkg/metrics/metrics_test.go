@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		families []Family
+		want     string
+	}{
+		{
+			name: "gauge without labels",
+			families: []Family{
+				{
+					Name:    "replicated_ready",
+					Help:    "Whether the SDK is ready.",
+					Type:    MetricTypeGauge,
+					Samples: []Sample{{Value: 1}},
+				},
+			},
+			want: `# HELP replicated_ready Whether the SDK is ready.
+# TYPE replicated_ready gauge
+replicated_ready 1
+`,
+		},
+		{
+			name: "labels are escaped",
+			families: []Family{
+				{
+					Name: "replicated_custom_metric",
+					Help: "Custom metrics.\nWith a newline and a \\ backslash.",
+					Type: MetricTypeGauge,
+					Samples: []Sample{
+						{Labels: []Label{{Name: "name", Value: "with \"quotes\""}}, Value: 1.5},
+						{Labels: []Label{{Name: "name", Value: "with\nnewline\\"}}, Value: -2},
+					},
+				},
+			},
+			want: `# HELP replicated_custom_metric Custom metrics.\nWith a newline and a \\ backslash.
+# TYPE replicated_custom_metric gauge
+replicated_custom_metric{name="with \"quotes\""} 1.5
+replicated_custom_metric{name="with\nnewline\\"} -2
+`,
+		},
+		{
+			name: "special values",
+			families: []Family{
+				{
+					Name: "replicated_values",
+					Help: "Values.",
+					Type: MetricTypeGauge,
+					Samples: []Sample{
+						{Labels: []Label{{Name: "v", Value: "nan"}}, Value: math.NaN()},
+						{Labels: []Label{{Name: "v", Value: "inf"}}, Value: math.Inf(1)},
+						{Labels: []Label{{Name: "v", Value: "large"}}, Value: 1e21},
+					},
+				},
+			},
+			want: `# HELP replicated_values Values.
+# TYPE replicated_values gauge
+replicated_values{v="nan"} NaN
+replicated_values{v="inf"} +Inf
+replicated_values{v="large"} 1e+21
+`,
+		},
+		{
+			name: "families without samples are skipped",
+			families: []Family{
+				{Name: "replicated_empty", Help: "Empty.", Type: MetricTypeGauge},
+				{Name: "replicated_total", Help: "Total.", Type: MetricTypeCounter, Samples: []Sample{{Value: 3}}},
+			},
+			want: `# HELP replicated_total Total.
+# TYPE replicated_total counter
+replicated_total 3
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Write(&buf, tt.families))
+			require.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestObserveUpstreamRequest(t *testing.T) {
+	req := require.New(t)
+
+	ResetUpstream()
+	defer ResetUpstream()
+
+	ObserveUpstreamRequest("updates", &http.Response{StatusCode: http.StatusOK}, nil)
+	ObserveUpstreamRequest("updates", &http.Response{StatusCode: http.StatusOK}, nil)
+	ObserveUpstreamRequest("updates", &http.Response{StatusCode: http.StatusForbidden}, nil)
+	ObserveUpstreamRequest("instance_data", nil, errors.New("connection refused"))
+
+	families := UpstreamFamilies()
+	req.Len(families, 2)
+
+	req.Equal([]Sample{
+		{Labels: []Label{{Name: "operation", Value: "instance_data"}, {Name: "outcome", Value: UpstreamOutcomeConnectionError}}, Value: 1},
+		{Labels: []Label{{Name: "operation", Value: "updates"}, {Name: "outcome", Value: UpstreamOutcomeHTTPError}}, Value: 1},
+		{Labels: []Label{{Name: "operation", Value: "updates"}, {Name: "outcome", Value: UpstreamOutcomeSuccess}}, Value: 2},
+	}, families[0].Samples)
+
+	req.Len(families[1].Samples, 1)
+	req.Equal([]Label{{Name: "operation", Value: "updates"}}, families[1].Samples[0].Labels)
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	UpstreamOutcomeSuccess         = "success"
+	UpstreamOutcomeHTTPError       = "http_error"
+	UpstreamOutcomeConnectionError = "connection_error"
+)
+
+type upstreamRequestKey struct {
+	operation string
+	outcome   string
+}
+
+var (
+	upstreamRequests    = map[upstreamRequestKey]float64{}
+	upstreamLastSuccess = map[string]time.Time{}
+	upstreamMtx         sync.Mutex
+)
+
+// ObserveUpstreamRequest records the outcome of a request to the Replicated upstream for the given operation
+// (e.g. "instance_data" or "updates"). It takes the result of http.Client.Do as-is.
+func ObserveUpstreamRequest(operation string, resp *http.Response, err error) {
+	outcome := UpstreamOutcomeSuccess
+	if err != nil {
+		outcome = UpstreamOutcomeConnectionError
+	} else if resp.StatusCode >= 400 {
+		outcome = UpstreamOutcomeHTTPError
+	}
+
+	upstreamMtx.Lock()
+	defer upstreamMtx.Unlock()
+
+	upstreamRequests[upstreamRequestKey{operation: operation, outcome: outcome}]++
+	if outcome == UpstreamOutcomeSuccess {
+		upstreamLastSuccess[operation] = time.Now()
+	}
+}
+
+// UpstreamFamilies returns the upstream request counters and the time of the last successful request per operation
+func UpstreamFamilies() []Family {
+	upstreamMtx.Lock()
+	defer upstreamMtx.Unlock()
+
+	requests := Family{
+		Name: "replicated_upstream_requests_total",
+		Help: "Requests made to the Replicated upstream, by operation and outcome.",
+		Type: MetricTypeCounter,
+	}
+	for key, count := range upstreamRequests {
+		requests.Samples = append(requests.Samples, Sample{
+			Labels: []Label{{Name: "operation", Value: key.operation}, {Name: "outcome", Value: key.outcome}},
+			Value:  count,
+		})
+	}
+	SortSamples(requests.Samples)
+
+	lastSuccess := Family{
+		Name: "replicated_upstream_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful request to the Replicated upstream, by operation.",
+		Type: MetricTypeGauge,
+	}
+	for operation, t := range upstreamLastSuccess {
+		lastSuccess.Samples = append(lastSuccess.Samples, Sample{
+			Labels: []Label{{Name: "operation", Value: operation}},
+			Value:  float64(t.UnixNano()) / 1e9,
+		})
+	}
+	SortSamples(lastSuccess.Samples)
+
+	return []Family{requests, lastSuccess}
+}
+
+// ResetUpstream clears all upstream request metrics
+func ResetUpstream() {
+	upstreamMtx.Lock()
+	defer upstreamMtx.Unlock()
+
+	upstreamRequests = map[upstreamRequestKey]float64{}
+	upstreamLastSuccess = map[string]time.Time{}
+}
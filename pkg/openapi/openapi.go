@@ -0,0 +1,137 @@
+package openapi
+
+import (
+	"strings"
+)
+
+const (
+	Version = "3.0.3"
+
+	ContentTypeJSON = "application/json"
+)
+
+// Document is the subset of an OpenAPI 3 document that is needed to describe the SDK API
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+	operations map[string]*Operation
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower case http methods to operations
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+func NewDocument(info Info) *Document {
+	return &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      map[string]PathItem{},
+		operations: map[string]*Operation{},
+	}
+}
+
+// Add adds an operation for the method and path. The path uses the same {name} template syntax as the router.
+func (d *Document) Add(method string, path string, op *Operation) {
+	if d.Paths[path] == nil {
+		d.Paths[path] = PathItem{}
+	}
+	d.Paths[path][strings.ToLower(method)] = op
+	d.operations[operationKey(method, path)] = op
+}
+
+// Operation returns the operation for the method and path template, or nil if it is not documented
+func (d *Document) Operation(method string, path string) *Operation {
+	return d.operations[operationKey(method, path)]
+}
+
+// Resolve follows a reference to a component schema
+func (d *Document) Resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = d.Components.Schemas[strings.TrimPrefix(schema.Ref, schemaRefPrefix)]
+	}
+	return schema
+}
+
+func operationKey(method string, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// JSONBody returns a required request body with the given schema
+func JSONBody(description string, schema *Schema) *RequestBody {
+	return &RequestBody{
+		Description: description,
+		Required:    true,
+		Content: map[string]MediaType{
+			ContentTypeJSON: {Schema: schema},
+		},
+	}
+}
+
+// JSONResponse returns a response with the given schema
+func JSONResponse(description string, schema *Schema) Response {
+	return Response{
+		Description: description,
+		Content: map[string]MediaType{
+			ContentTypeJSON: {Schema: schema},
+		},
+	}
+}
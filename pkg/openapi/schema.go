@@ -0,0 +1,194 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const schemaRefPrefix = "#/components/schemas/"
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	MinProperties        *int               `json:"minProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Generator builds schemas from Go types using the same field names and embedding rules as encoding/json.
+// Named struct types are added to the components and referenced, so that generated clients share them.
+// Fields tagged with `validate:"required"` are required.
+type Generator struct {
+	schemas   map[string]*Schema
+	names     map[reflect.Type]string
+	overrides map[reflect.Type]*Schema
+}
+
+func NewGenerator() *Generator {
+	return &Generator{
+		schemas:   map[string]*Schema{},
+		names:     map[reflect.Type]string{},
+		overrides: map[reflect.Type]*Schema{},
+	}
+}
+
+// Override uses the given schema for the type of v instead of the one derived from it,
+// for types whose constraints can't be expressed in Go, like enums
+func (g *Generator) Override(v interface{}, schema *Schema) {
+	g.overrides[reflect.TypeOf(v)] = schema
+}
+
+// SchemaOf returns the schema for the type of v
+func (g *Generator) SchemaOf(v interface{}) *Schema {
+	return g.schemaFor(reflect.TypeOf(v))
+}
+
+// Schemas returns the component schemas of the named struct types seen so far
+func (g *Generator) Schemas() map[string]*Schema {
+	return g.schemas
+}
+
+func (g *Generator) schemaFor(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if override, ok := g.overrides[t]; ok {
+		return override
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem := g.schemaFor(t.Elem())
+		if elem.Ref != "" {
+			return &Schema{AllOf: []*Schema{elem}, Nullable: true}
+		}
+		nullable := *elem
+		nullable.Nullable = true
+		return &nullable
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.namedStructSchema(t)
+	default:
+		// interfaces and anything else that can hold arbitrary json
+		return &Schema{}
+	}
+}
+
+func (g *Generator) namedStructSchema(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = g.componentName(t)
+		g.names[t] = name
+		// registered before the properties are generated so that recursive types terminate
+		g.schemas[name] = &Schema{}
+		*g.schemas[name] = *g.structSchema(t)
+	}
+	return &Schema{Ref: schemaRefPrefix + name}
+}
+
+// componentName returns the type name, qualified with its package name if another type already uses it
+func (g *Generator) componentName(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := g.schemas[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	if pkg == "" {
+		return name
+	}
+	return strings.ToUpper(pkg[:1]) + pkg[1:] + name
+}
+
+func (g *Generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{},
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if field.Anonymous && name == "" {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				embedded := g.structSchema(fieldType)
+				for propertyName, property := range embedded.Properties {
+					if _, ok := schema.Properties[propertyName]; !ok {
+						schema.Properties[propertyName] = property
+					}
+				}
+				schema.Required = append(schema.Required, embedded.Required...)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = g.schemaFor(field.Type)
+		if field.Tag.Get("validate") == "required" {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
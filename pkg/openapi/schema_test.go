@@ -0,0 +1,75 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testBase struct {
+	Version string `json:"version,omitempty"`
+}
+
+type testNode struct {
+	testBase   `json:",inline"`
+	Name       string            `json:"name" validate:"required"`
+	Count      int64             `json:"count,omitempty"`
+	Ratio      float64           `json:"ratio"`
+	Enabled    bool              `json:"enabled"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	Labels     map[string]string `json:"labels"`
+	Children   []testNode        `json:"children"`
+	Parent     *testNode         `json:"parent,omitempty"`
+	Value      interface{}       `json:"value"`
+	Raw        json.RawMessage   `json:"raw"`
+	Ignored    string            `json:"-"`
+	NoTag      string
+	unexported string
+}
+
+func TestGenerator(t *testing.T) {
+	req := require.New(t)
+
+	g := NewGenerator()
+	schema := g.SchemaOf(testNode{})
+	req.Equal(&Schema{Ref: "#/components/schemas/testNode"}, schema)
+
+	nodeRef := &Schema{Ref: "#/components/schemas/testNode"}
+	req.Equal(map[string]*Schema{
+		"testNode": {
+			Type: "object",
+			Properties: map[string]*Schema{
+				"version":   {Type: "string"},
+				"name":      {Type: "string"},
+				"count":     {Type: "integer", Format: "int64"},
+				"ratio":     {Type: "number", Format: "double"},
+				"enabled":   {Type: "boolean"},
+				"createdAt": {Type: "string", Format: "date-time"},
+				"labels":    {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+				"children":  {Type: "array", Items: nodeRef},
+				"parent":    {AllOf: []*Schema{nodeRef}, Nullable: true},
+				"value":     {},
+				"raw":       {},
+				"NoTag":     {Type: "string"},
+			},
+			Required: []string{"name"},
+		},
+	}, g.Schemas())
+}
+
+func TestGeneratorOverride(t *testing.T) {
+	req := require.New(t)
+
+	type state string
+	type status struct {
+		State state `json:"state"`
+	}
+
+	g := NewGenerator()
+	g.Override(state(""), &Schema{Type: "string", Enum: []interface{}{"ready", "missing"}})
+
+	g.SchemaOf(status{})
+	req.Equal(&Schema{Type: "string", Enum: []interface{}{"ready", "missing"}}, g.Schemas()["status"].Properties["state"])
+}
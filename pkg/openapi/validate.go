@@ -0,0 +1,259 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FieldError describes a value in a request that does not match the document.
+// Field is the path of the value in the request body, or the name of the parameter, and is empty for the body itself.
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidateParameters validates the query and path parameters of a request against the operation
+func (d *Document) ValidateParameters(op *Operation, query url.Values, pathParams map[string]string) []FieldError {
+	fieldErrors := []FieldError{}
+
+	for _, param := range op.Parameters {
+		var value string
+		var ok bool
+		switch param.In {
+		case "query":
+			ok = query.Has(param.Name)
+			value = query.Get(param.Name)
+		case "path":
+			value, ok = pathParams[param.Name]
+		default:
+			continue
+		}
+
+		if !ok || value == "" {
+			if param.Required {
+				fieldErrors = append(fieldErrors, FieldError{Field: param.Name, Message: "is required"})
+			}
+			continue
+		}
+
+		parsed, err := parseParameter(d.Resolve(param.Schema), value)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: param.Name, Message: err.Error()})
+			continue
+		}
+		fieldErrors = append(fieldErrors, d.ValidateValue(param.Schema, parsed, param.Name)...)
+	}
+
+	return fieldErrors
+}
+
+// ValidateJSONBody validates a JSON request body against the operation's request body schema
+func (d *Document) ValidateJSONBody(requestBody *RequestBody, body []byte) []FieldError {
+	if len(bytes.TrimSpace(body)) == 0 {
+		if requestBody.Required {
+			return []FieldError{{Message: "request body is required"}}
+		}
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []FieldError{{Message: fmt.Sprintf("request body is not valid JSON: %v", err)}}
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return []FieldError{{Message: "request body must contain a single JSON value"}}
+	}
+
+	return d.ValidateValue(requestBody.Content[ContentTypeJSON].Schema, value, "")
+}
+
+// ValidateValue validates a value decoded from JSON with json.Number for numbers against the schema.
+// Errors are sorted by field.
+func (d *Document) ValidateValue(schema *Schema, value interface{}, field string) []FieldError {
+	fieldErrors := d.validate(schema, value, field)
+	sort.SliceStable(fieldErrors, func(i, j int) bool {
+		return fieldErrors[i].Field < fieldErrors[j].Field
+	})
+	return fieldErrors
+}
+
+func (d *Document) validate(schema *Schema, value interface{}, field string) []FieldError {
+	schema = d.Resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	if value == nil {
+		if schema.Nullable || (schema.Type == "" && len(schema.AllOf) == 0 && len(schema.AnyOf) == 0) {
+			return nil
+		}
+		return []FieldError{{Field: field, Message: "must not be null"}}
+	}
+
+	fieldErrors := []FieldError{}
+
+	for _, s := range schema.AllOf {
+		fieldErrors = append(fieldErrors, d.validate(s, value, field)...)
+	}
+
+	if len(schema.AnyOf) > 0 && !d.matchesAny(schema.AnyOf, value, field) {
+		fieldErrors = append(fieldErrors, FieldError{Field: field, Message: d.anyOfMessage(schema.AnyOf)})
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return append(fieldErrors, FieldError{Field: field, Message: "must be an object"})
+		}
+		fieldErrors = append(fieldErrors, d.validateObject(schema, object, field)...)
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return append(fieldErrors, FieldError{Field: field, Message: "must be an array"})
+		}
+		for i, item := range array {
+			fieldErrors = append(fieldErrors, d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", field, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return append(fieldErrors, FieldError{Field: field, Message: "must be a string"})
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return append(fieldErrors, FieldError{Field: field, Message: "must be an integer"})
+		}
+		if _, err := n.Int64(); err != nil {
+			return append(fieldErrors, FieldError{Field: field, Message: "must be an integer"})
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return append(fieldErrors, FieldError{Field: field, Message: "must be a number"})
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return append(fieldErrors, FieldError{Field: field, Message: "must be a boolean"})
+		}
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		fieldErrors = append(fieldErrors, FieldError{Field: field, Message: fmt.Sprintf("must be one of %s", formatEnum(schema.Enum))})
+	}
+
+	return fieldErrors
+}
+
+func (d *Document) validateObject(schema *Schema, object map[string]interface{}, field string) []FieldError {
+	fieldErrors := []FieldError{}
+
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			fieldErrors = append(fieldErrors, FieldError{Field: joinField(field, name), Message: "is required"})
+		}
+	}
+
+	if schema.MinProperties != nil && len(object) < *schema.MinProperties {
+		fieldErrors = append(fieldErrors, FieldError{Field: field, Message: fmt.Sprintf("must have at least %d properties", *schema.MinProperties)})
+	}
+
+	for name, value := range object {
+		if property, ok := schema.Properties[name]; ok {
+			fieldErrors = append(fieldErrors, d.validate(property, value, joinField(field, name))...)
+		} else if schema.AdditionalProperties != nil {
+			fieldErrors = append(fieldErrors, d.validate(schema.AdditionalProperties, value, joinField(field, name))...)
+		}
+	}
+
+	return fieldErrors
+}
+
+func (d *Document) matchesAny(schemas []*Schema, value interface{}, field string) bool {
+	for _, s := range schemas {
+		if len(d.validate(s, value, field)) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Document) anyOfMessage(schemas []*Schema) string {
+	types := []string{}
+	for _, s := range schemas {
+		s = d.Resolve(s)
+		if s == nil || s.Type == "" {
+			return "does not match any of the allowed schemas"
+		}
+		types = append(types, s.Type)
+	}
+	return fmt.Sprintf("must be one of these types: %s", strings.Join(types, ", "))
+}
+
+func parseParameter(schema *Schema, value string) (interface{}, error) {
+	if schema == nil {
+		return value, nil
+	}
+
+	switch schema.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return json.Number(value), nil
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return json.Number(value), nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("must be a boolean")
+		}
+		return b, nil
+	default:
+		return value, nil
+	}
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []interface{}) string {
+	values := []string{}
+	for _, e := range enum {
+		values = append(values, fmt.Sprintf("%q", fmt.Sprint(e)))
+	}
+	return strings.Join(values, ", ")
+}
+
+func joinField(parent string, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,181 @@
+package openapi
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testDocument() *Document {
+	g := NewGenerator()
+
+	type tags struct {
+		Force bool              `json:"force"`
+		Tags  map[string]string `json:"tags"`
+	}
+	type request struct {
+		Data    tags     `json:"data" validate:"required"`
+		Names   []string `json:"names"`
+		Count   int      `json:"count"`
+		Ratio   float64  `json:"ratio"`
+		Version string   `json:"version"`
+	}
+
+	doc := NewDocument(Info{Title: "test", Version: "1.0.0"})
+	doc.Add("POST", "/things/{id}", &Operation{
+		OperationID: "createThing",
+		Parameters: []Parameter{
+			{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+			{Name: "limit", In: "query", Schema: &Schema{Type: "integer"}},
+			{Name: "dryRun", In: "query", Schema: &Schema{Type: "boolean"}},
+			{Name: "order", In: "query", Schema: &Schema{Type: "string", Enum: []interface{}{"asc", "desc"}}},
+		},
+		RequestBody: JSONBody("the thing", g.SchemaOf(request{})),
+	})
+	doc.Components.Schemas = g.Schemas()
+
+	return doc
+}
+
+func TestValidateJSONBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []FieldError
+	}{
+		{
+			name: "valid",
+			body: `{"data": {"force": true, "tags": {"a": "b"}}, "names": ["x"], "count": 2, "ratio": 0.5}`,
+			want: []FieldError{},
+		},
+		{
+			name: "unknown fields are allowed",
+			body: `{"data": {}, "other": [1, 2]}`,
+			want: []FieldError{},
+		},
+		{
+			name: "empty body",
+			body: "  ",
+			want: []FieldError{{Message: "request body is required"}},
+		},
+		{
+			name: "invalid json",
+			body: `{"data": `,
+			want: []FieldError{{Message: "request body is not valid JSON: unexpected EOF"}},
+		},
+		{
+			name: "multiple values",
+			body: `{"data": {}} {}`,
+			want: []FieldError{{Message: "request body must contain a single JSON value"}},
+		},
+		{
+			name: "not an object",
+			body: `[]`,
+			want: []FieldError{{Message: "must be an object"}},
+		},
+		{
+			name: "missing required field",
+			body: `{"names": []}`,
+			want: []FieldError{{Field: "data", Message: "is required"}},
+		},
+		{
+			name: "null required field",
+			body: `{"data": null}`,
+			want: []FieldError{{Field: "data", Message: "must not be null"}},
+		},
+		{
+			name: "nested field errors are sorted",
+			body: `{"data": {"force": "yes", "tags": {"a": 1}}, "names": ["x", 2], "count": 1.5, "ratio": "half", "version": 1}`,
+			want: []FieldError{
+				{Field: "count", Message: "must be an integer"},
+				{Field: "data.force", Message: "must be a boolean"},
+				{Field: "data.tags.a", Message: "must be a string"},
+				{Field: "names[1]", Message: "must be a string"},
+				{Field: "ratio", Message: "must be a number"},
+				{Field: "version", Message: "must be a string"},
+			},
+		},
+	}
+
+	doc := testDocument()
+	op := doc.Operation("POST", "/things/{id}")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := doc.ValidateJSONBody(op.RequestBody, []byte(tt.body))
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		pathParams map[string]string
+		want       []FieldError
+	}{
+		{
+			name:       "valid",
+			query:      "limit=10&dryRun=true&order=asc",
+			pathParams: map[string]string{"id": "abc"},
+			want:       []FieldError{},
+		},
+		{
+			name:       "optional parameters can be omitted",
+			pathParams: map[string]string{"id": "abc"},
+			want:       []FieldError{},
+		},
+		{
+			name:  "missing path parameter",
+			query: "",
+			want:  []FieldError{{Field: "id", Message: "is required"}},
+		},
+		{
+			name:       "invalid values",
+			query:      "limit=ten&dryRun=maybe&order=random",
+			pathParams: map[string]string{"id": "abc"},
+			want: []FieldError{
+				{Field: "limit", Message: "must be an integer"},
+				{Field: "dryRun", Message: "must be a boolean"},
+				{Field: "order", Message: `must be one of "asc", "desc"`},
+			},
+		},
+	}
+
+	doc := testDocument()
+	op := doc.Operation("POST", "/things/{id}")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			require.NoError(t, err)
+
+			got := doc.ValidateParameters(op, query, tt.pathParams)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateAnyOf(t *testing.T) {
+	scalar := &Schema{
+		Type: "object",
+		AdditionalProperties: &Schema{
+			AnyOf: []*Schema{{Type: "string"}, {Type: "number"}, {Type: "boolean"}},
+		},
+	}
+
+	doc := NewDocument(Info{})
+
+	got := doc.ValidateValue(scalar, map[string]interface{}{
+		"a": "x",
+		"b": true,
+		"c": []interface{}{},
+		"d": nil,
+	}, "data")
+	require.Equal(t, []FieldError{
+		{Field: "data.c", Message: "must be one of these types: string, number, boolean"},
+		{Field: "data.d", Message: "must not be null"},
+	}, got)
+}
@@ -0,0 +1,124 @@
+package readiness
+
+import (
+	"sync"
+	"time"
+)
+
+// Phase is a step of the SDK bootstrap process
+type Phase string
+
+const (
+	PhaseClientset     Phase = "clientset"
+	PhaseIDs           Phase = "ids"
+	PhaseLicenseVerify Phase = "licenseVerify"
+	PhaseLicenseSync   Phase = "licenseSync"
+	PhaseUpdates       Phase = "updates"
+	PhaseInformers     Phase = "informers"
+	PhaseHeartbeat     Phase = "heartbeat"
+)
+
+// Phases lists all bootstrap phases in the order in which they run
+var Phases = []Phase{
+	PhaseClientset,
+	PhaseIDs,
+	PhaseLicenseVerify,
+	PhaseLicenseSync,
+	PhaseUpdates,
+	PhaseInformers,
+	PhaseHeartbeat,
+}
+
+type PhaseState string
+
+const (
+	PhaseStatePending   PhaseState = "pending"
+	PhaseStateSucceeded PhaseState = "succeeded"
+	PhaseStateFailed    PhaseState = "failed"
+	PhaseStateSkipped   PhaseState = "skipped"
+)
+
+type PhaseStatus struct {
+	Name      Phase      `json:"name"`
+	State     PhaseState `json:"state"`
+	LastError string     `json:"lastError,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+type Status struct {
+	Ready  bool          `json:"ready"`
+	Phases []PhaseStatus `json:"phases"`
+}
+
+var (
+	phases = map[Phase]PhaseStatus{}
+	mtx    sync.RWMutex
+)
+
+// Succeeded marks a bootstrap phase as completed. The last error is kept so that
+// flapping phases can still be diagnosed.
+func Succeeded(phase Phase) {
+	set(phase, PhaseStateSucceeded, nil)
+}
+
+// Skipped marks a bootstrap phase as not applicable to this installation (e.g. license sync in airgap mode).
+func Skipped(phase Phase) {
+	set(phase, PhaseStateSkipped, nil)
+}
+
+// Failed records the error for a bootstrap phase and returns it unchanged,
+// so that it can be used inline when returning from the bootstrap function.
+func Failed(phase Phase, err error) error {
+	set(phase, PhaseStateFailed, err)
+	return err
+}
+
+func set(phase Phase, state PhaseState, err error) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	now := time.Now().UTC()
+	status := phases[phase]
+	status.Name = phase
+	status.State = state
+	status.UpdatedAt = &now
+	if err != nil {
+		status.LastError = err.Error()
+	}
+	phases[phase] = status
+}
+
+// GetStatus returns the state of every bootstrap phase. The SDK is ready once every phase has either succeeded or been skipped.
+func GetStatus() Status {
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	s := Status{
+		Ready:  true,
+		Phases: make([]PhaseStatus, 0, len(Phases)),
+	}
+	for _, phase := range Phases {
+		status, ok := phases[phase]
+		if !ok {
+			status = PhaseStatus{Name: phase, State: PhaseStatePending}
+		}
+		if status.State != PhaseStateSucceeded && status.State != PhaseStateSkipped {
+			s.Ready = false
+		}
+		s.Phases = append(s.Phases, status)
+	}
+	return s
+}
+
+// IsReady returns true once bootstrap has completed
+func IsReady() bool {
+	return GetStatus().Ready
+}
+
+// Reset clears the state of all phases
+func Reset() {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	phases = map[Phase]PhaseStatus{}
+}
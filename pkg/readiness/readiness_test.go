@@ -0,0 +1,42 @@
+package readiness
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStatus(t *testing.T) {
+	req := require.New(t)
+
+	Reset()
+	defer Reset()
+
+	status := GetStatus()
+	req.False(status.Ready)
+	req.Len(status.Phases, len(Phases))
+	for _, phase := range status.Phases {
+		req.Equal(PhaseStatePending, phase.State)
+	}
+
+	for _, phase := range Phases {
+		Succeeded(phase)
+	}
+	req.True(IsReady())
+
+	err := Failed(PhaseLicenseSync, errors.New("connection refused"))
+	req.EqualError(err, "connection refused")
+
+	status = GetStatus()
+	req.False(status.Ready)
+	req.Equal(PhaseStateFailed, status.Phases[3].State)
+	req.Equal("connection refused", status.Phases[3].LastError)
+
+	// a skipped phase does not block readiness, and the last error is retained
+	Skipped(PhaseLicenseSync)
+	status = GetStatus()
+	req.True(status.Ready)
+	req.Equal(PhaseStateSkipped, status.Phases[3].State)
+	req.Equal("connection refused", status.Phases[3].LastError)
+}
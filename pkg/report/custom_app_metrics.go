@@ -12,6 +12,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/replicated-sdk/pkg/logger"
 	"github.com/replicatedhq/replicated-sdk/pkg/meta"
+	"github.com/replicatedhq/replicated-sdk/pkg/metrics"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
 	"github.com/replicatedhq/replicated-sdk/pkg/util"
 	"k8s.io/client-go/kubernetes"
@@ -92,7 +93,8 @@ func SendOnlineCustomAppMetrics(sdkStore store.Store, data map[string]interface{
 	instanceData := GetInstanceData(sdkStore)
 	InjectInstanceDataHeaders(req, instanceData)
 
-	resp, err := util.HttpClient().Do(req)
+	resp, err := util.DoUpstream(req, util.UpstreamCustomMetrics)
+	metrics.ObserveUpstreamRequest("custom_metrics", resp, err)
 	if err != nil {
 		return errors.Wrap(err, "failed to execute get request")
 	}
@@ -105,7 +107,7 @@ func SendOnlineCustomAppMetrics(sdkStore store.Store, data map[string]interface{
 
 	if resp.StatusCode >= 400 {
 		if len(body) > 0 {
-			return util.ActionableError{Message: string(body)}
+			return util.ActionableError{Message: string(body), StatusCode: resp.StatusCode}
 		}
 		return errors.Errorf("unexpected result from get request: %d", resp.StatusCode)
 	}
@@ -0,0 +1,497 @@
+package report
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	ExportManifestFileName = "manifest.json"
+	ExportVersion          = 1
+)
+
+var ReportTypes = []ReportType{ReportTypeInstance, ReportTypeCustomAppMetrics}
+
+type ExportManifest struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exportedAt"`
+	Source     string           `json:"source"`
+	Namespace  string           `json:"namespace,omitempty"`
+	Reports    []ExportedReport `json:"reports"`
+}
+
+type ExportedReport struct {
+	Type       ReportType `json:"type"`
+	FileName   string     `json:"fileName"`
+	EventCount int        `json:"eventCount"`
+}
+
+type ReportSummary struct {
+	Type             ReportType     `json:"type"`
+	EventCount       int            `json:"eventCount"`
+	FirstReportedAt  *time.Time     `json:"firstReportedAt,omitempty"`
+	LastReportedAt   *time.Time     `json:"lastReportedAt,omitempty"`
+	InstanceIDs      []string       `json:"instanceIds,omitempty"`
+	AppStatuses      map[string]int `json:"appStatuses,omitempty"`
+	ChannelSequences []int64        `json:"channelSequences,omitempty"`
+	MetricKeys       []string       `json:"metricKeys,omitempty"`
+}
+
+// supportBundleSecret is the file written by the troubleshoot secret collector
+type supportBundleSecret struct {
+	Namespace    string `json:"namespace"`
+	Name         string `json:"name"`
+	Key          string `json:"key"`
+	SecretExists bool   `json:"secretExists"`
+	KeyExists    bool   `json:"keyExists"`
+	Value        string `json:"value,omitempty"`
+}
+
+func NewReport(reportType ReportType) (Report, error) {
+	switch reportType {
+	case ReportTypeInstance:
+		return &InstanceReport{}, nil
+	case ReportTypeCustomAppMetrics:
+		return &CustomAppMetricsReport{}, nil
+	default:
+		return nil, errors.Errorf("unknown report type %q", reportType)
+	}
+}
+
+func GetReportFileName(reportType ReportType) string {
+	return fmt.Sprintf("%s-report.json", reportType)
+}
+
+// GetReportsFromCluster reads the reports from the report secrets in the namespace. Reports whose secret does not exist are skipped.
+func GetReportsFromCluster(clientset kubernetes.Interface, namespace string) ([]Report, error) {
+	reports := []Report{}
+	for _, reportType := range ReportTypes {
+		r, err := NewReport(reportType)
+		if err != nil {
+			return nil, err
+		}
+
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), r.GetSecretName(), metav1.GetOptions{})
+		if kuberneteserrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get %s report secret", reportType)
+		}
+
+		data := secret.Data[r.GetSecretKey()]
+		if len(data) == 0 {
+			continue
+		}
+
+		decoded, err := DecodeReport(data, reportType)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode %s report", reportType)
+		}
+		reports = append(reports, decoded)
+	}
+
+	return reports, nil
+}
+
+// GetReportsFromSupportBundle reads the reports from the report secrets collected in a support bundle.
+// bundlePath can be either a support bundle archive or an extracted support bundle directory.
+func GetReportsFromSupportBundle(bundlePath string) ([]Report, error) {
+	files := map[string][]byte{}
+	collect := func(name string, read func() ([]byte, error)) error {
+		name = filepath.ToSlash(name)
+		for _, reportType := range ReportTypes {
+			if isSupportBundleReportFile(name, reportType) {
+				data, err := read()
+				if err != nil {
+					return errors.Wrapf(err, "failed to read %s", name)
+				}
+				files[string(reportType)] = data
+			}
+		}
+		return nil
+	}
+
+	fi, err := os.Stat(bundlePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to stat support bundle")
+	}
+
+	if fi.IsDir() {
+		err = filepath.Walk(bundlePath, func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(bundlePath, p)
+			if err != nil {
+				return err
+			}
+			return collect(rel, func() ([]byte, error) { return os.ReadFile(p) })
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to walk support bundle directory")
+		}
+	} else {
+		f, err := os.Open(bundlePath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open support bundle")
+		}
+		defer f.Close()
+
+		err = walkTarGz(f, func(hdr *tar.Header, tr *tar.Reader) error {
+			return collect(hdr.Name, func() ([]byte, error) { return io.ReadAll(tr) })
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read support bundle archive")
+		}
+	}
+
+	reports := []Report{}
+	for _, reportType := range ReportTypes {
+		data, ok := files[string(reportType)]
+		if !ok {
+			continue
+		}
+
+		secret := supportBundleSecret{}
+		if err := json.Unmarshal(data, &secret); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal %s report secret", reportType)
+		}
+		if !secret.SecretExists || !secret.KeyExists || secret.Value == "" {
+			continue
+		}
+
+		decoded, err := DecodeReport([]byte(secret.Value), reportType)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode %s report", reportType)
+		}
+		reports = append(reports, decoded)
+	}
+
+	return reports, nil
+}
+
+// isSupportBundleReportFile returns true if the file is the collected report secret, which troubleshoot
+// writes to secrets/<namespace>/<name>/<key>.json under the bundle's top-level directory
+func isSupportBundleReportFile(name string, reportType ReportType) bool {
+	secretName := fmt.Sprintf(ReportSecretNameFormat, reportType)
+	if path.Base(name) != ReportSecretKey+".json" || path.Base(path.Dir(name)) != secretName {
+		return false
+	}
+	parts := strings.Split(name, "/")
+	return len(parts) >= 4 && parts[len(parts)-4] == "secrets"
+}
+
+// WriteExportArchive writes the reports, decoded, to a gzipped tar archive along with a manifest
+func WriteExportArchive(w io.Writer, manifest ExportManifest, reports []Report) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	manifest.Version = ExportVersion
+	manifest.Reports = []ExportedReport{}
+
+	for _, r := range reports {
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal %s report", r.GetType())
+		}
+
+		fileName := GetReportFileName(r.GetType())
+		if err := writeTarFile(tw, fileName, data, manifest.ExportedAt); err != nil {
+			return errors.Wrapf(err, "failed to write %s", fileName)
+		}
+
+		manifest.Reports = append(manifest.Reports, ExportedReport{
+			Type:       r.GetType(),
+			FileName:   fileName,
+			EventCount: GetEventCount(r),
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal manifest")
+	}
+	if err := writeTarFile(tw, ExportManifestFileName, data, manifest.ExportedAt); err != nil {
+		return errors.Wrap(err, "failed to write manifest")
+	}
+
+	if err := tw.Close(); err != nil {
+		return errors.Wrap(err, "failed to close tar writer")
+	}
+	if err := gw.Close(); err != nil {
+		return errors.Wrap(err, "failed to close gzip writer")
+	}
+
+	return nil
+}
+
+// ReadExportArchive reads an archive written by WriteExportArchive
+func ReadExportArchive(r io.Reader) (*ExportManifest, []Report, error) {
+	files := map[string][]byte{}
+	err := walkTarGz(r, func(hdr *tar.Header, tr *tar.Reader) error {
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", hdr.Name)
+		}
+		files[path.Clean(hdr.Name)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read archive")
+	}
+
+	data, ok := files[ExportManifestFileName]
+	if !ok {
+		return nil, nil, errors.Errorf("archive does not contain %s", ExportManifestFileName)
+	}
+
+	manifest := ExportManifest{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to unmarshal manifest")
+	}
+	if manifest.Version != ExportVersion {
+		return nil, nil, errors.Errorf("unsupported export version %d", manifest.Version)
+	}
+
+	reports := []Report{}
+	for _, exported := range manifest.Reports {
+		data, ok := files[path.Clean(exported.FileName)]
+		if !ok {
+			return nil, nil, errors.Errorf("archive does not contain %s", exported.FileName)
+		}
+
+		r, err := NewReport(exported.Type)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal(data, r); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to unmarshal %s report", exported.Type)
+		}
+		reports = append(reports, r)
+	}
+
+	return &manifest, reports, nil
+}
+
+// PruneReport removes the events of the exported report from the report secret, and returns the number of events removed.
+// Since events are appended in order, every event reported at or before the last exported event is removed,
+// which also covers events that were already dropped from the secret because of the event or size limits.
+func PruneReport(clientset kubernetes.Interface, namespace string, exported Report) (int, error) {
+	timestamps := getEventTimestamps(exported)
+	if len(timestamps) == 0 {
+		return 0, nil
+	}
+	cutoff := timestamps[len(timestamps)-1]
+
+	pruned := 0
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), exported.GetSecretName(), metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrap(err, "failed to get report secret")
+		}
+
+		data := secret.Data[exported.GetSecretKey()]
+		if len(data) == 0 {
+			return nil
+		}
+
+		existing, err := DecodeReport(data, exported.GetType())
+		if err != nil {
+			return errors.Wrap(err, "failed to decode report")
+		}
+
+		pruned = pruneEvents(existing, cutoff)
+		if pruned == 0 {
+			return nil
+		}
+
+		encoded, err := EncodeReport(existing)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode report")
+		}
+		secret.Data[exported.GetSecretKey()] = encoded
+
+		_, err = clientset.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to prune %s report", exported.GetType())
+	}
+
+	return pruned, nil
+}
+
+func SummarizeReport(r Report) ReportSummary {
+	summary := ReportSummary{
+		Type:       r.GetType(),
+		EventCount: GetEventCount(r),
+	}
+
+	timestamps := getEventTimestamps(r)
+	if len(timestamps) > 0 {
+		first := time.UnixMilli(timestamps[0]).UTC()
+		last := time.UnixMilli(timestamps[len(timestamps)-1]).UTC()
+		summary.FirstReportedAt = &first
+		summary.LastReportedAt = &last
+	}
+
+	instanceIDs := map[string]struct{}{}
+
+	switch r := r.(type) {
+	case *InstanceReport:
+		appStatuses := map[string]int{}
+		sequences := map[int64]struct{}{}
+		for _, event := range r.Events {
+			instanceIDs[event.InstanceID] = struct{}{}
+			if event.AppStatus != "" {
+				appStatuses[event.AppStatus]++
+			}
+			sequences[event.DownstreamChannelSequence] = struct{}{}
+		}
+		if len(appStatuses) > 0 {
+			summary.AppStatuses = appStatuses
+		}
+		for sequence := range sequences {
+			summary.ChannelSequences = append(summary.ChannelSequences, sequence)
+		}
+		sort.Slice(summary.ChannelSequences, func(i, j int) bool {
+			return summary.ChannelSequences[i] < summary.ChannelSequences[j]
+		})
+	case *CustomAppMetricsReport:
+		metricKeys := map[string]struct{}{}
+		for _, event := range r.Events {
+			instanceIDs[event.InstanceID] = struct{}{}
+			for key := range event.Data {
+				metricKeys[key] = struct{}{}
+			}
+		}
+		summary.MetricKeys = sortedKeys(metricKeys)
+	}
+
+	summary.InstanceIDs = sortedKeys(instanceIDs)
+
+	return summary
+}
+
+func GetEventCount(r Report) int {
+	switch r := r.(type) {
+	case *InstanceReport:
+		return len(r.Events)
+	case *CustomAppMetricsReport:
+		return len(r.Events)
+	default:
+		return 0
+	}
+}
+
+// getEventTimestamps returns the reported at timestamps of the events, in milliseconds, sorted in ascending order
+func getEventTimestamps(r Report) []int64 {
+	timestamps := []int64{}
+	switch r := r.(type) {
+	case *InstanceReport:
+		for _, event := range r.Events {
+			timestamps = append(timestamps, event.ReportedAt)
+		}
+	case *CustomAppMetricsReport:
+		for _, event := range r.Events {
+			timestamps = append(timestamps, event.ReportedAt)
+		}
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return timestamps
+}
+
+// pruneEvents removes the events reported at or before the cutoff from the report, and returns the number of events removed
+func pruneEvents(r Report, cutoff int64) int {
+	switch r := r.(type) {
+	case *InstanceReport:
+		kept := []InstanceReportEvent{}
+		for _, event := range r.Events {
+			if event.ReportedAt > cutoff {
+				kept = append(kept, event)
+			}
+		}
+		pruned := len(r.Events) - len(kept)
+		r.Events = kept
+		return pruned
+	case *CustomAppMetricsReport:
+		kept := []CustomAppMetricsReportEvent{}
+		for _, event := range r.Events {
+			if event.ReportedAt > cutoff {
+				kept = append(kept, event)
+			}
+		}
+		pruned := len(r.Events) - len(kept)
+		r.Events = kept
+		return pruned
+	default:
+		return 0
+	}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func walkTarGz(r io.Reader, fn func(hdr *tar.Header, tr *tar.Reader) error) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return errors.Wrap(err, "failed to create gzip reader")
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read tar header")
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return errors.Wrap(err, "failed to write tar header")
+	}
+	if _, err := tw.Write(data); err != nil {
+		return errors.Wrap(err, "failed to write tar data")
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package report
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_GetReportsFromCluster(t *testing.T) {
+	req := require.New(t)
+
+	instanceReport := &InstanceReport{
+		Events: []InstanceReportEvent{createTestInstanceEvent(1000), createTestInstanceEvent(2000)},
+	}
+
+	clientset := fake.NewSimpleClientset(createTestReportSecret(t, "default", instanceReport))
+
+	reports, err := GetReportsFromCluster(clientset, "default")
+	req.NoError(err)
+	req.Equal([]Report{instanceReport}, reports)
+
+	// missing secrets are skipped
+	reports, err = GetReportsFromCluster(clientset, "other")
+	req.NoError(err)
+	req.Empty(reports)
+}
+
+func Test_GetReportsFromSupportBundle(t *testing.T) {
+	req := require.New(t)
+
+	instanceReport := &InstanceReport{
+		Events: []InstanceReportEvent{createTestInstanceEvent(1000)},
+	}
+	encoded, err := EncodeReport(instanceReport)
+	req.NoError(err)
+
+	files := map[string][]byte{
+		"support-bundle-2024/secrets/default/replicated-instance-report/report.json": marshalTestSecret(t, supportBundleSecret{
+			Namespace:    "default",
+			Name:         "replicated-instance-report",
+			Key:          "report",
+			SecretExists: true,
+			KeyExists:    true,
+			Value:        string(encoded),
+		}),
+		"support-bundle-2024/secrets/default/replicated-custom-app-metrics-report/report.json": marshalTestSecret(t, supportBundleSecret{
+			Namespace: "default",
+			Name:      "replicated-custom-app-metrics-report",
+			Key:       "report",
+		}),
+		"support-bundle-2024/cluster-resources/pods/default.json": []byte("[]"),
+	}
+
+	// extracted directory
+	dir := t.TempDir()
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		req.NoError(os.MkdirAll(filepath.Dir(p), 0755))
+		req.NoError(os.WriteFile(p, data, 0644))
+	}
+
+	reports, err := GetReportsFromSupportBundle(dir)
+	req.NoError(err)
+	req.Equal([]Report{instanceReport}, reports)
+
+	// archive
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for name, data := range files {
+		req.NoError(writeTarFile(tw, name, data, time.Now()))
+	}
+	req.NoError(tw.Close())
+	req.NoError(gw.Close())
+
+	archivePath := filepath.Join(t.TempDir(), "support-bundle.tar.gz")
+	req.NoError(os.WriteFile(archivePath, buf.Bytes(), 0644))
+
+	reports, err = GetReportsFromSupportBundle(archivePath)
+	req.NoError(err)
+	req.Equal([]Report{instanceReport}, reports)
+}
+
+func Test_ExportArchive(t *testing.T) {
+	req := require.New(t)
+
+	reports := []Report{
+		&InstanceReport{
+			Events: []InstanceReportEvent{createTestInstanceEvent(1000), createTestInstanceEvent(2000)},
+		},
+		&CustomAppMetricsReport{
+			Events: []CustomAppMetricsReportEvent{createTestCustomAppMetricsEvent(1500)},
+		},
+	}
+	exportedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	buf := &bytes.Buffer{}
+	err := WriteExportArchive(buf, ExportManifest{ExportedAt: exportedAt, Source: "cluster", Namespace: "default"}, reports)
+	req.NoError(err)
+
+	manifest, got, err := ReadExportArchive(buf)
+	req.NoError(err)
+	req.Equal(&ExportManifest{
+		Version:    ExportVersion,
+		ExportedAt: exportedAt,
+		Source:     "cluster",
+		Namespace:  "default",
+		Reports: []ExportedReport{
+			{Type: ReportTypeInstance, FileName: "instance-report.json", EventCount: 2},
+			{Type: ReportTypeCustomAppMetrics, FileName: "custom-app-metrics-report.json", EventCount: 1},
+		},
+	}, manifest)
+
+	// since values are an interface, compare the json representation
+	wantJSON, err := json.Marshal(reports)
+	req.NoError(err)
+	gotJSON, err := json.Marshal(got)
+	req.NoError(err)
+	req.Equal(string(wantJSON), string(gotJSON))
+
+	_, _, err = ReadExportArchive(bytes.NewReader([]byte("not an archive")))
+	req.Error(err)
+}
+
+func Test_PruneReport(t *testing.T) {
+	tests := []struct {
+		name           string
+		existingEvents []int64
+		exportedEvents []int64
+		wantEvents     []int64
+		wantPruned     int
+	}{
+		{
+			name:           "events appended after the export are kept",
+			existingEvents: []int64{1000, 2000, 3000, 4000},
+			exportedEvents: []int64{1000, 2000, 3000},
+			wantEvents:     []int64{4000},
+			wantPruned:     3,
+		},
+		{
+			name:           "events dropped by the limits since the export are ignored",
+			existingEvents: []int64{2000, 3000, 4000},
+			exportedEvents: []int64{1000, 2000},
+			wantEvents:     []int64{3000, 4000},
+			wantPruned:     1,
+		},
+		{
+			name:           "nothing exported",
+			existingEvents: []int64{1000},
+			exportedEvents: []int64{},
+			wantEvents:     []int64{1000},
+			wantPruned:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			existing := &InstanceReport{Events: []InstanceReportEvent{}}
+			for _, reportedAt := range tt.existingEvents {
+				existing.Events = append(existing.Events, createTestInstanceEvent(reportedAt))
+			}
+			exported := &InstanceReport{Events: []InstanceReportEvent{}}
+			for _, reportedAt := range tt.exportedEvents {
+				exported.Events = append(exported.Events, createTestInstanceEvent(reportedAt))
+			}
+
+			clientset := fake.NewSimpleClientset(createTestReportSecret(t, "default", existing))
+
+			pruned, err := PruneReport(clientset, "default", exported)
+			req.NoError(err)
+			req.Equal(tt.wantPruned, pruned)
+
+			secret, err := clientset.CoreV1().Secrets("default").Get(context.TODO(), existing.GetSecretName(), metav1.GetOptions{})
+			req.NoError(err)
+
+			got, err := DecodeReport(secret.Data[ReportSecretKey], ReportTypeInstance)
+			req.NoError(err)
+			req.Equal(tt.wantEvents, getEventTimestamps(got))
+		})
+	}
+}
+
+func Test_SummarizeReport(t *testing.T) {
+	req := require.New(t)
+
+	degraded := createTestInstanceEvent(2000)
+	degraded.AppStatus = "degraded"
+	degraded.DownstreamChannelSequence = 2
+
+	first := time.UnixMilli(1000).UTC()
+	last := time.UnixMilli(3000).UTC()
+
+	summary := SummarizeReport(&InstanceReport{
+		Events: []InstanceReportEvent{createTestInstanceEvent(1000), degraded, createTestInstanceEvent(3000)},
+	})
+	req.Equal(ReportSummary{
+		Type:             ReportTypeInstance,
+		EventCount:       3,
+		FirstReportedAt:  &first,
+		LastReportedAt:   &last,
+		InstanceIDs:      []string{"test-instance-id"},
+		AppStatuses:      map[string]int{"ready": 2, "degraded": 1},
+		ChannelSequences: []int64{1, 2},
+	}, summary)
+
+	summary = SummarizeReport(&CustomAppMetricsReport{
+		Events: []CustomAppMetricsReportEvent{createTestCustomAppMetricsEvent(1000)},
+	})
+	req.Equal([]string{"key1_string", "key2_int", "key3_float", "key4_numeric_string", "key5_bool"}, summary.MetricKeys)
+	req.Equal(1, summary.EventCount)
+
+	summary = SummarizeReport(&CustomAppMetricsReport{})
+	req.Equal(ReportSummary{Type: ReportTypeCustomAppMetrics, InstanceIDs: []string{}, MetricKeys: []string{}}, summary)
+}
+
+func createTestReportSecret(t *testing.T, namespace string, r Report) runtime.Object {
+	encoded, err := EncodeReport(r)
+	require.NoError(t, err)
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.GetSecretName(),
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			r.GetSecretKey(): encoded,
+		},
+	}
+}
+
+func marshalTestSecret(t *testing.T, secret supportBundleSecret) []byte {
+	data, err := json.Marshal(secret)
+	require.NoError(t, err)
+	return data
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
 	"github.com/replicatedhq/replicated-sdk/pkg/logger"
 	meta "github.com/replicatedhq/replicated-sdk/pkg/meta"
+	"github.com/replicatedhq/replicated-sdk/pkg/metrics"
 	"github.com/replicatedhq/replicated-sdk/pkg/report/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
 	"github.com/replicatedhq/replicated-sdk/pkg/util"
@@ -24,6 +25,63 @@ import (
 
 var instanceDataMtx sync.Mutex
 
+var (
+	// pendingReports tracks instance data reports that have been handed off to a goroutine
+	// so that they can be flushed before the process exits
+	pendingReports sync.WaitGroup
+	// pendingReportsMtx guards adding to pendingReports against Flush waiting for it, and
+	// shuttingDown is set once Flush has started so that no more reports are added
+	pendingReportsMtx sync.Mutex
+	shuttingDown      bool
+)
+
+// SendInstanceDataAsync sends instance data in the background. Use Flush to wait for
+// all reports sent this way to complete. Reports are dropped once Flush has started.
+func SendInstanceDataAsync(sdkStore store.Store) {
+	pendingReportsMtx.Lock()
+	if shuttingDown {
+		pendingReportsMtx.Unlock()
+		logger.Debugf("not sending instance data, shutting down")
+		return
+	}
+	pendingReports.Add(1)
+	pendingReportsMtx.Unlock()
+
+	go func() {
+		defer pendingReports.Done()
+
+		clientset, err := k8sutil.GetClientset()
+		if err != nil {
+			logger.Error(errors.Wrap(err, "failed to get clientset"))
+			return
+		}
+		if err := SendInstanceData(clientset, sdkStore); err != nil {
+			logger.Error(errors.Wrap(err, "failed to send instance data"))
+		}
+	}()
+}
+
+// Flush blocks until all reports started with SendInstanceDataAsync have completed or the context is done.
+// Reports that are sent with SendInstanceDataAsync after Flush has started are dropped.
+func Flush(ctx context.Context) error {
+	pendingReportsMtx.Lock()
+	shuttingDown = true
+	pendingReportsMtx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		pendingReports.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "timed out waiting for pending reports")
+	}
+}
+
 func SendInstanceData(clientset kubernetes.Interface, sdkStore store.Store) error {
 	wrapper := sdkStore.GetLicense()
 
@@ -66,6 +124,7 @@ func SendAirgapInstanceData(clientset kubernetes.Interface, namespace string, li
 		DownstreamChannelSequence: instanceData.ChannelSequence,
 		EmbeddedClusterID:         os.Getenv("EMBEDDED_CLUSTER_ID"),
 		EmbeddedClusterVersion:    os.Getenv("EMBEDDED_CLUSTER_VERSION"),
+		LicenseExpirationState:    instanceData.LicenseExpirationState,
 	}
 
 	if instanceData.ResourceStates != nil {
@@ -119,7 +178,8 @@ func SendOnlineInstanceData(wrapper licensewrapper.LicenseWrapper, instanceData
 
 	InjectInstanceDataHeaders(postReq, instanceData)
 
-	resp, err := util.HttpClient().Do(postReq)
+	resp, err := util.DoUpstream(postReq, util.UpstreamInstanceData)
+	metrics.ObserveUpstreamRequest("instance_data", resp, err)
 	if err != nil {
 		return errors.Wrap(err, "failed to post request")
 	}
@@ -142,6 +202,8 @@ func GetInstanceData(sdkStore store.Store) *types.InstanceData {
 		AppStatus:       string(sdkStore.GetAppStatus().State),
 		ResourceStates:  sdkStore.GetAppStatus().ResourceStates,
 		RunningImages:   sdkStore.GetRunningImages(),
+
+		LicenseExpirationState: string(sdkStore.GetLicenseExpiration().State),
 	}
 
 	clientset, err := k8sutil.GetClientset()
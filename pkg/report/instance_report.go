@@ -29,6 +29,7 @@ type InstanceReportEvent struct {
 	EmbeddedClusterID         string `json:"embedded_cluster_id,omitempty"`
 	EmbeddedClusterVersion    string `json:"embedded_cluster_version,omitempty"`
 	Tags                      string `json:"tags"`
+	LicenseExpirationState    string `json:"license_expiration_state,omitempty"`
 }
 
 func (r *InstanceReport) GetType() ReportType {
@@ -1,15 +1,18 @@
 package report
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/mux"
 	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
 	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/k8sutil"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
 	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
 	mock_store "github.com/replicatedhq/replicated-sdk/pkg/store/mock"
@@ -29,6 +32,10 @@ func Test_SendInstanceData(t *testing.T) {
 	mockServer := httptest.NewServer(mockRouter)
 	defer mockServer.Close()
 	mockRouter.Methods("POST").Path("/kots_metrics/license_instance/info").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Replicated-LicenseExpirationState") != "valid" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
 		respRecorder.Write([]byte("received instance data"))
 		w.WriteHeader(http.StatusOK)
 	})
@@ -79,6 +86,7 @@ func Test_SendInstanceData(t *testing.T) {
 					ResourceStates: []appstatetypes.ResourceState{},
 				})
 				mockStore.EXPECT().GetRunningImages().AnyTimes().Return(map[string][]string{})
+				mockStore.EXPECT().GetLicenseExpiration().Return(sdklicensetypes.LicenseExpiration{State: sdklicensetypes.ExpirationStateValid})
 			},
 		},
 		{
@@ -116,6 +124,7 @@ func Test_SendInstanceData(t *testing.T) {
 					ResourceStates: []appstatetypes.ResourceState{},
 				})
 				mockStore.EXPECT().GetRunningImages().AnyTimes().Return(map[string][]string{})
+				mockStore.EXPECT().GetLicenseExpiration().Return(sdklicensetypes.LicenseExpiration{State: sdklicensetypes.ExpirationStateValid})
 			},
 		},
 	}
@@ -142,3 +151,47 @@ func Test_SendInstanceData(t *testing.T) {
 		})
 	}
 }
+
+func resetShuttingDown(t *testing.T) {
+	t.Cleanup(func() {
+		pendingReportsMtx.Lock()
+		shuttingDown = false
+		pendingReportsMtx.Unlock()
+	})
+}
+
+func Test_Flush(t *testing.T) {
+	resetShuttingDown(t)
+	req := require.New(t)
+
+	// nothing pending
+	req.NoError(Flush(context.Background()))
+
+	// a pending report blocks flush until it completes
+	pendingReports.Add(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req.Error(Flush(ctx))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		pendingReports.Done()
+	}()
+	req.NoError(Flush(context.Background()))
+}
+
+func Test_SendInstanceDataAsync_AfterFlush(t *testing.T) {
+	resetShuttingDown(t)
+	req := require.New(t)
+
+	req.NoError(Flush(context.Background()))
+
+	// reports are dropped once flush has started, so that nothing is added to the wait group while it is waited for
+	SendInstanceDataAsync(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req.NoError(Flush(ctx))
+}
+
@@ -38,6 +38,8 @@ type InstanceData struct {
 	K8sDistribution string                       `json:"k8s_distribution"`
 	Tags            metatypes.InstanceTagData    `json:"tags"`
 	RunningImages   map[string][]string          `json:"running_images"`
+	// LicenseExpirationState is the expiration state of the license at the time of the report
+	LicenseExpirationState string `json:"license_expiration_state"`
 }
 
 func (d Distribution) String() string {
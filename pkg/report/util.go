@@ -97,6 +97,10 @@ func GetInstanceDataHeaders(instanceData *types.InstanceData) map[string]string
 		headers["X-Replicated-K8sDistribution"] = instanceData.K8sDistribution
 	}
 
+	if instanceData.LicenseExpirationState != "" {
+		headers["X-Replicated-LicenseExpirationState"] = instanceData.LicenseExpirationState
+	}
+
 	if ecID := os.Getenv("EMBEDDED_CLUSTER_ID"); ecID != "" {
 		headers["X-Replicated-EmbeddedClusterID"] = ecID
 	}
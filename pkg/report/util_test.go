@@ -73,6 +73,8 @@ func TestGetInstanceDataHeaders(t *testing.T) {
 		K8sVersion:      "v1.20.2+k3s1",
 		K8sDistribution: "k3s",
 		Tags:            metatypes.InstanceTagData{Force: true, Tags: map[string]string{"key": "value"}},
+
+		LicenseExpirationState: "expiring_soon",
 	}
 
 	headers := GetInstanceDataHeaders(instanceData)
@@ -86,6 +88,7 @@ func TestGetInstanceDataHeaders(t *testing.T) {
 		"X-Replicated-DownstreamChannelSequence": "42",
 		"X-Replicated-K8sDistribution":           "k3s",
 		"X-Replicated-InstanceTagData":           "eyJmb3JjZSI6dHJ1ZSwidGFncyI6eyJrZXkiOiJ2YWx1ZSJ9fQ==",
+		"X-Replicated-LicenseExpirationState":    "expiring_soon",
 	}
 	assert.Equal(t, expectedHeaders, headers)
 
@@ -0,0 +1,43 @@
+// Package retry has the helpers that the SDK and its client share to retry http requests.
+package retry
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// IsReplayable returns true if the body of the request can be sent again
+func IsReplayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// Wait waits for the given duration, or returns the error of the context if it is done first
+func Wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ParseRetryAfter parses a Retry-After header, which is either a number of seconds or an http date
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package retry
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	require.Equal(t, 3*time.Second, ParseRetryAfter("3"))
+	require.Equal(t, time.Duration(0), ParseRetryAfter(""))
+	require.Equal(t, time.Duration(0), ParseRetryAfter("soon"))
+
+	d := ParseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	require.Greater(t, d, 30*time.Second)
+}
+
+func TestIsReplayable(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://replicated:3000", nil)
+	require.NoError(t, err)
+	require.True(t, IsReplayable(req))
+
+	req, err = http.NewRequest("POST", "http://replicated:3000", strings.NewReader("body"))
+	require.NoError(t, err)
+	require.True(t, IsReplayable(req))
+
+	req.GetBody = nil
+	require.False(t, IsReplayable(req))
+}
+
+func TestWait(t *testing.T) {
+	require.NoError(t, Wait(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, Wait(ctx, time.Minute), context.Canceled)
+}
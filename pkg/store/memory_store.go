@@ -2,6 +2,7 @@ package store
 
 import (
 	"strings"
+	"sync"
 
 	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
 	licensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
@@ -14,6 +15,9 @@ type InMemoryStore struct {
 	appID                 string
 	license               licensewrapper.LicenseWrapper
 	licenseFields         licensetypes.LicenseFields
+	licenseExpiration     licensetypes.LicenseExpiration
+	// licenseExpirationMtx guards licenseExpiration, which is re-evaluated by the background license syncs
+	licenseExpirationMtx sync.RWMutex
 	appSlug               string
 	appName               string
 	channelID             string
@@ -29,9 +33,10 @@ type InMemoryStore struct {
 	appStatus             appstatetypes.AppStatus
 	updates               []upstreamtypes.ChannelRelease
 	// podImages holds namespace -> podUID -> []ImageInfo
-	podImages       map[string]map[string][]appstatetypes.ImageInfo
-	reportAllImages bool
-	readOnlyMode    bool
+	podImages           map[string]map[string][]appstatetypes.ImageInfo
+	reportAllImages     bool
+	readOnlyMode        bool
+	strictLicenseFields bool
 }
 
 type InitInMemoryStoreOptions struct {
@@ -52,6 +57,7 @@ type InitInMemoryStoreOptions struct {
 	Namespace             string
 	ReportAllImages       bool
 	ReadOnlyMode          bool
+	StrictLicenseFields   bool
 }
 
 func InitInMemory(options InitInMemoryStoreOptions) {
@@ -74,6 +80,7 @@ func InitInMemory(options InitInMemoryStoreOptions) {
 		namespace:             options.Namespace,
 		reportAllImages:       options.ReportAllImages,
 		readOnlyMode:          options.ReadOnlyMode,
+		strictLicenseFields:   options.StrictLicenseFields,
 	})
 }
 
@@ -120,6 +127,18 @@ func (s *InMemoryStore) SetLicenseFields(licenseFields licensetypes.LicenseField
 	}
 }
 
+func (s *InMemoryStore) GetLicenseExpiration() licensetypes.LicenseExpiration {
+	s.licenseExpirationMtx.RLock()
+	defer s.licenseExpirationMtx.RUnlock()
+	return s.licenseExpiration
+}
+
+func (s *InMemoryStore) SetLicenseExpiration(expiration licensetypes.LicenseExpiration) {
+	s.licenseExpirationMtx.Lock()
+	defer s.licenseExpirationMtx.Unlock()
+	s.licenseExpiration = expiration
+}
+
 func (s *InMemoryStore) IsDevLicense() bool {
 	return s.license.GetLicenseType() == "dev"
 }
@@ -334,3 +353,7 @@ func (s *InMemoryStore) GetReportAllImages() bool {
 func (s *InMemoryStore) GetReadOnlyMode() bool {
 	return s.readOnlyMode
 }
+
+func (s *InMemoryStore) GetStrictLicenseFields() bool {
+	return s.strictLicenseFields
+}
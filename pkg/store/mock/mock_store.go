@@ -161,6 +161,20 @@ func (mr *MockStoreMockRecorder) GetLicense() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLicense", reflect.TypeOf((*MockStore)(nil).GetLicense))
 }
 
+// GetLicenseExpiration mocks base method.
+func (m *MockStore) GetLicenseExpiration() types0.LicenseExpiration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLicenseExpiration")
+	ret0, _ := ret[0].(types0.LicenseExpiration)
+	return ret0
+}
+
+// GetLicenseExpiration indicates an expected call of GetLicenseExpiration.
+func (mr *MockStoreMockRecorder) GetLicenseExpiration() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLicenseExpiration", reflect.TypeOf((*MockStore)(nil).GetLicenseExpiration))
+}
+
 // GetLicenseFields mocks base method.
 func (m *MockStore) GetLicenseFields() types0.LicenseFields {
 	m.ctrl.T.Helper()
@@ -315,6 +329,20 @@ func (mr *MockStoreMockRecorder) GetRunningImages() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRunningImages", reflect.TypeOf((*MockStore)(nil).GetRunningImages))
 }
 
+// GetStrictLicenseFields mocks base method.
+func (m *MockStore) GetStrictLicenseFields() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStrictLicenseFields")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// GetStrictLicenseFields indicates an expected call of GetStrictLicenseFields.
+func (mr *MockStoreMockRecorder) GetStrictLicenseFields() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStrictLicenseFields", reflect.TypeOf((*MockStore)(nil).GetStrictLicenseFields))
+}
+
 // GetUpdates mocks base method.
 func (m *MockStore) GetUpdates() []types1.ChannelRelease {
 	m.ctrl.T.Helper()
@@ -381,6 +409,18 @@ func (mr *MockStoreMockRecorder) SetLicense(license interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLicense", reflect.TypeOf((*MockStore)(nil).SetLicense), license)
 }
 
+// SetLicenseExpiration mocks base method.
+func (m *MockStore) SetLicenseExpiration(expiration types0.LicenseExpiration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetLicenseExpiration", expiration)
+}
+
+// SetLicenseExpiration indicates an expected call of SetLicenseExpiration.
+func (mr *MockStoreMockRecorder) SetLicenseExpiration(expiration interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLicenseExpiration", reflect.TypeOf((*MockStore)(nil).SetLicenseExpiration), expiration)
+}
+
 // SetLicenseFields mocks base method.
 func (m *MockStore) SetLicenseFields(licenseFields types0.LicenseFields) {
 	m.ctrl.T.Helper()
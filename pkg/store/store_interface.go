@@ -1,6 +1,8 @@
 package store
 
 import (
+	"sync"
+
 	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
 	licensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
 	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
@@ -8,7 +10,8 @@ import (
 )
 
 var (
-	store Store
+	store    Store
+	storeMtx sync.RWMutex
 
 	_ Store = (*InMemoryStore)(nil)
 )
@@ -20,6 +23,8 @@ type Store interface {
 	SetLicense(license licensewrapper.LicenseWrapper)
 	GetLicenseFields() licensetypes.LicenseFields
 	SetLicenseFields(licenseFields licensetypes.LicenseFields)
+	GetLicenseExpiration() licensetypes.LicenseExpiration
+	SetLicenseExpiration(expiration licensetypes.LicenseExpiration)
 	IsDevLicense() bool
 	GetAppSlug() string
 	GetAppName() string
@@ -43,15 +48,28 @@ type Store interface {
 	SetUpdates(updates []upstreamtypes.ChannelRelease)
 	GetReportAllImages() bool
 	GetReadOnlyMode() bool
+	GetStrictLicenseFields() bool
 }
 
 func SetStore(s Store) {
+	storeMtx.Lock()
+	defer storeMtx.Unlock()
 	store = s
 }
 
 func GetStore() Store {
+	storeMtx.RLock()
+	defer storeMtx.RUnlock()
 	if store == nil {
 		return &InMemoryStore{}
 	}
 	return store
 }
+
+// IsInitialized returns true once the store has been set by bootstrap.
+// Until then, GetStore returns an empty store.
+func IsInitialized() bool {
+	storeMtx.RLock()
+	defer storeMtx.RUnlock()
+	return store != nil
+}
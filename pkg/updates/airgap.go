@@ -0,0 +1,229 @@
+package updates
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	sdklicense "github.com/replicatedhq/replicated-sdk/pkg/license"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/util"
+	"github.com/replicatedhq/replicated-sdk/pkg/webhook"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// releaseIndexKey is the key in the replicated secret of the last imported release index
+const releaseIndexKey = "release-index"
+
+var (
+	releaseIndexLock = sync.Mutex{}
+	// releaseIndex is the last imported release index, to get the updates on other channels from
+	releaseIndex *upstreamtypes.ReleaseIndex
+)
+
+// ParseSignedReleaseIndex parses a release index file, without verifying it
+func ParseSignedReleaseIndex(signedIndexBytes []byte) (*upstreamtypes.SignedReleaseIndex, error) {
+	signedIndex := upstreamtypes.SignedReleaseIndex{}
+	if err := json.Unmarshal(signedIndexBytes, &signedIndex); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal release index file")
+	}
+	if len(signedIndex.Index) == 0 {
+		return nil, errors.New("release index file has no index")
+	}
+	if len(signedIndex.Signature) == 0 {
+		return nil, errors.New("release index file has no signature")
+	}
+	return &signedIndex, nil
+}
+
+// VerifyReleaseIndex verifies the signature of the release index with the app public key from the license,
+// and checks that the index is for the app of the license
+func VerifyReleaseIndex(wrapper licensewrapper.LicenseWrapper, signedIndex *upstreamtypes.SignedReleaseIndex) (*upstreamtypes.ReleaseIndex, error) {
+	if err := sdklicense.VerifyAppSignature(wrapper, signedIndex.Index, signedIndex.Signature); err != nil {
+		return nil, errors.Wrap(err, "failed to verify release index signature")
+	}
+
+	index := upstreamtypes.ReleaseIndex{}
+	if err := json.Unmarshal(signedIndex.Index, &index); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal release index")
+	}
+
+	if index.AppSlug != wrapper.GetAppSlug() {
+		return nil, errors.Errorf("release index is for app %q, not %q", index.AppSlug, wrapper.GetAppSlug())
+	}
+
+	return &index, nil
+}
+
+// GetReleaseIndexUpdates returns the releases in the index that are available to update to, the same way the Replicated API does:
+// the releases on the given channel that are newer than the current release, or all of them if the current release is from
+// another channel, and that are available to the license sequence. Releases are ordered by channel sequence, newest first.
+func GetReleaseIndexUpdates(index *upstreamtypes.ReleaseIndex, wrapper licensewrapper.LicenseWrapper, currentCursor upstreamtypes.ReplicatedCursor, channelID string) []upstreamtypes.ChannelRelease {
+	channelChanged := currentCursor.ChannelID != channelID
+
+	entries := []upstreamtypes.ReleaseIndexEntry{}
+	for _, entry := range index.Releases {
+		if entry.ChannelID != channelID {
+			continue
+		}
+		if !channelChanged && entry.ChannelSequence <= currentCursor.ChannelSequence {
+			continue
+		}
+		if entry.MinLicenseSequence > wrapper.GetLicenseSequence() {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ChannelSequence > entries[j].ChannelSequence
+	})
+
+	releases := []upstreamtypes.ChannelRelease{}
+	for _, entry := range entries {
+		release := entry.ChannelRelease
+		// the bump is relative to the current release, and is set when the updates are served
+		release.Bump = ""
+		releases = append(releases, release)
+	}
+	return releases
+}
+
+// ValidateReleaseIndexUpdate checks that the release index can replace the last imported one, which it can't if it was
+// created before it, e.g. because the index of an older airgap bundle is imported again
+func ValidateReleaseIndexUpdate(index *upstreamtypes.ReleaseIndex) error {
+	releaseIndexLock.Lock()
+	defer releaseIndexLock.Unlock()
+
+	return validateReleaseIndexUpdate(releaseIndex, index)
+}
+
+func validateReleaseIndexUpdate(current *upstreamtypes.ReleaseIndex, updated *upstreamtypes.ReleaseIndex) error {
+	if current == nil {
+		return nil
+	}
+	currentCreatedAt, err := time.Parse(time.RFC3339, current.CreatedAt)
+	if err != nil {
+		// the imported index can't be compared to
+		return nil
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, updated.CreatedAt)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse release index creation time %q", updated.CreatedAt)
+	}
+	if createdAt.Before(currentCreatedAt) {
+		return errors.Errorf("release index was created at %s, before the imported release index created at %s", updated.CreatedAt, current.CreatedAt)
+	}
+	return nil
+}
+
+// ImportReleaseIndex stores the releases in a verified release index that are available to update to, and returns them
+func ImportReleaseIndex(index *upstreamtypes.ReleaseIndex) []upstreamtypes.ChannelRelease {
+	releaseIndexLock.Lock()
+	defer releaseIndexLock.Unlock()
+
+	releaseIndex = index
+
+	sdkStore := store.GetStore()
+	releases := getReleaseIndexUpdates(index, sdkStore)
+
+	webhook.NotifyReleasesChanged(sdkStore.GetUpdates(), releases)
+	sdkStore.SetUpdates(releases)
+
+	return releases
+}
+
+// RefilterReleaseIndexUpdates stores the releases in the last imported release index that are available to update to again,
+// e.g. after the license was replaced with one with another sequence, which the releases that are available depend on.
+// Webhooks are notified if the updates changed.
+func RefilterReleaseIndexUpdates() {
+	releaseIndexLock.Lock()
+	defer releaseIndexLock.Unlock()
+
+	if releaseIndex == nil {
+		return
+	}
+
+	sdkStore := store.GetStore()
+	releases := getReleaseIndexUpdates(releaseIndex, sdkStore)
+
+	webhook.NotifyReleasesChanged(sdkStore.GetUpdates(), releases)
+	sdkStore.SetUpdates(releases)
+}
+
+// getReleaseIndexUpdates returns the releases in the index that are available to update to on the channel that updates are checked on
+func getReleaseIndexUpdates(index *upstreamtypes.ReleaseIndex, sdkStore store.Store) []upstreamtypes.ChannelRelease {
+	license := sdkStore.GetLicense()
+	currentCursor := getCurrentCursor(sdkStore)
+	return GetReleaseIndexUpdates(index, license, currentCursor, sdklicense.GetUpdatesChannelID(license, currentCursor.ChannelID))
+}
+
+// SaveReleaseIndex persists an imported release index file in the replicated secret, so that it is imported again after a restart
+func SaveReleaseIndex(ctx context.Context, clientset kubernetes.Interface, namespace string, signedIndexBytes []byte) error {
+	releaseIndexLock.Lock()
+	defer releaseIndexLock.Unlock()
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, util.GetReplicatedSecretName(), metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to get replicated secret")
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	secret.Data[releaseIndexKey] = signedIndexBytes
+	if _, err := clientset.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrap(err, "failed to update replicated secret")
+	}
+
+	return nil
+}
+
+// ImportSavedReleaseIndex imports the release index that was last imported through the API, if any, when the SDK starts.
+// It is verified again, since the license may have changed since it was imported. Webhooks are not notified of the
+// updates, since they were already notified when the index was imported.
+func ImportSavedReleaseIndex(ctx context.Context, clientset kubernetes.Interface, namespace string) error {
+	releaseIndexLock.Lock()
+	defer releaseIndexLock.Unlock()
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, util.GetReplicatedSecretName(), metav1.GetOptions{})
+	if err != nil {
+		if kuberneteserrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrap(err, "failed to get replicated secret")
+	}
+
+	signedIndexBytes := secret.Data[releaseIndexKey]
+	if len(signedIndexBytes) == 0 {
+		return nil
+	}
+
+	signedIndex, err := ParseSignedReleaseIndex(signedIndexBytes)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse saved release index")
+	}
+	index, err := VerifyReleaseIndex(store.GetStore().GetLicense(), signedIndex)
+	if err != nil {
+		return errors.Wrap(err, "failed to verify saved release index")
+	}
+
+	releaseIndex = index
+
+	sdkStore := store.GetStore()
+	releases := getReleaseIndexUpdates(index, sdkStore)
+	sdkStore.SetUpdates(releases)
+	logger.Infof("Imported %d available updates from the release index created at %s", len(releases), index.CreatedAt)
+
+	return nil
+}
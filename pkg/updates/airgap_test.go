@@ -0,0 +1,331 @@
+package updates
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"testing"
+
+	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/license/licensetest"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// appKeyLicense returns a license for my-app on channel-id whose signature holds the public key of a new app key
+func appKeyLicense(t *testing.T, sequence int64) (licensewrapper.LicenseWrapper, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	signature, err := licensetest.AppKeySignature(&key.PublicKey)
+	require.NoError(t, err)
+
+	return licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+		Spec: v1beta1.LicenseSpec{
+			LicenseID:       "license-id",
+			AppSlug:         "my-app",
+			ChannelID:       "channel-id",
+			LicenseSequence: sequence,
+			Signature:       signature,
+		},
+	}}, key
+}
+
+func signReleaseIndex(t *testing.T, key *rsa.PrivateKey, index upstreamtypes.ReleaseIndex) []byte {
+	indexBytes, err := json.Marshal(index)
+	require.NoError(t, err)
+
+	hashed := sha256.Sum256(indexBytes)
+	signature, err := rsa.SignPSS(rand.Reader, key, crypto.SHA256, hashed[:], nil)
+	require.NoError(t, err)
+
+	signedIndexBytes, err := json.Marshal(upstreamtypes.SignedReleaseIndex{Index: indexBytes, Signature: signature})
+	require.NoError(t, err)
+	return signedIndexBytes
+}
+
+func TestParseSignedReleaseIndex(t *testing.T) {
+	_, err := ParseSignedReleaseIndex([]byte(`{"index": "e30=", "signature": "c2ln"}`))
+	require.NoError(t, err)
+
+	_, err = ParseSignedReleaseIndex([]byte(`not json`))
+	require.ErrorContains(t, err, "failed to unmarshal release index file")
+
+	_, err = ParseSignedReleaseIndex([]byte(`{"signature": "c2ln"}`))
+	require.EqualError(t, err, "release index file has no index")
+
+	_, err = ParseSignedReleaseIndex([]byte(`{"index": "e30="}`))
+	require.EqualError(t, err, "release index file has no signature")
+}
+
+func TestVerifyReleaseIndex(t *testing.T) {
+	wrapper, key := appKeyLicense(t, 1)
+	_, otherKey := appKeyLicense(t, 1)
+
+	index := upstreamtypes.ReleaseIndex{
+		AppSlug:   "my-app",
+		CreatedAt: "2026-10-01T00:00:00Z",
+		Releases: []upstreamtypes.ReleaseIndexEntry{
+			{ChannelRelease: upstreamtypes.ChannelRelease{ChannelSequence: 2, VersionLabel: "1.1.0"}, ChannelID: "channel-id"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		signedIndex func() []byte
+		wantErr     string
+	}{
+		{
+			name: "valid",
+			signedIndex: func() []byte {
+				return signReleaseIndex(t, key, index)
+			},
+		},
+		{
+			name: "signed with another key",
+			signedIndex: func() []byte {
+				return signReleaseIndex(t, otherKey, index)
+			},
+			wantErr: "failed to verify release index signature",
+		},
+		{
+			name: "tampered",
+			signedIndex: func() []byte {
+				signedIndex, err := ParseSignedReleaseIndex(signReleaseIndex(t, key, index))
+				require.NoError(t, err)
+
+				tampered := index
+				tampered.Releases = nil
+				signedIndex.Index, err = json.Marshal(tampered)
+				require.NoError(t, err)
+
+				signedIndexBytes, err := json.Marshal(signedIndex)
+				require.NoError(t, err)
+				return signedIndexBytes
+			},
+			wantErr: "failed to verify release index signature",
+		},
+		{
+			name: "another app",
+			signedIndex: func() []byte {
+				otherApp := index
+				otherApp.AppSlug = "other-app"
+				return signReleaseIndex(t, key, otherApp)
+			},
+			wantErr: `release index is for app "other-app", not "my-app"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			signedIndex, err := ParseSignedReleaseIndex(tt.signedIndex())
+			req.NoError(err)
+
+			got, err := VerifyReleaseIndex(wrapper, signedIndex)
+			if tt.wantErr != "" {
+				req.ErrorContains(err, tt.wantErr)
+				return
+			}
+			req.NoError(err)
+			req.Equal(&index, got)
+		})
+	}
+}
+
+func TestGetReleaseIndexUpdates(t *testing.T) {
+	wrapper, _ := appKeyLicense(t, 3)
+
+	index := &upstreamtypes.ReleaseIndex{
+		AppSlug: "my-app",
+		Releases: []upstreamtypes.ReleaseIndexEntry{
+			{ChannelRelease: upstreamtypes.ChannelRelease{ChannelSequence: 1, VersionLabel: "1.0.0"}, ChannelID: "channel-id"},
+			{ChannelRelease: upstreamtypes.ChannelRelease{ChannelSequence: 2, VersionLabel: "1.1.0", IsRequired: true}, ChannelID: "channel-id"},
+			{ChannelRelease: upstreamtypes.ChannelRelease{ChannelSequence: 4, VersionLabel: "1.3.0"}, ChannelID: "channel-id", MinLicenseSequence: 4},
+			{ChannelRelease: upstreamtypes.ChannelRelease{ChannelSequence: 3, VersionLabel: "1.2.0", Bump: upstreamtypes.SemverBumpMajor}, ChannelID: "channel-id", MinLicenseSequence: 3},
+			{ChannelRelease: upstreamtypes.ChannelRelease{ChannelSequence: 9, VersionLabel: "2.0.0-beta.1"}, ChannelID: "beta-channel-id"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		currentCursor upstreamtypes.ReplicatedCursor
+		channelID     string
+		want          []upstreamtypes.ChannelRelease
+	}{
+		{
+			name:          "newer releases on the license channel",
+			currentCursor: upstreamtypes.ReplicatedCursor{ChannelID: "channel-id", ChannelSequence: 1},
+			channelID:     "channel-id",
+			want: []upstreamtypes.ChannelRelease{
+				{ChannelSequence: 3, VersionLabel: "1.2.0"},
+				{ChannelSequence: 2, VersionLabel: "1.1.0", IsRequired: true},
+			},
+		},
+		{
+			name:          "up to date",
+			currentCursor: upstreamtypes.ReplicatedCursor{ChannelID: "channel-id", ChannelSequence: 3},
+			channelID:     "channel-id",
+			want:          []upstreamtypes.ChannelRelease{},
+		},
+		{
+			name:          "current release from another channel",
+			currentCursor: upstreamtypes.ReplicatedCursor{ChannelID: "beta-channel-id", ChannelSequence: 9},
+			channelID:     "channel-id",
+			want: []upstreamtypes.ChannelRelease{
+				{ChannelSequence: 3, VersionLabel: "1.2.0"},
+				{ChannelSequence: 2, VersionLabel: "1.1.0", IsRequired: true},
+				{ChannelSequence: 1, VersionLabel: "1.0.0"},
+			},
+		},
+		{
+			name:          "another channel",
+			currentCursor: upstreamtypes.ReplicatedCursor{ChannelID: "channel-id", ChannelSequence: 3},
+			channelID:     "beta-channel-id",
+			want: []upstreamtypes.ChannelRelease{
+				{ChannelSequence: 9, VersionLabel: "2.0.0-beta.1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, GetReleaseIndexUpdates(index, wrapper, tt.currentCursor, tt.channelID))
+		})
+	}
+}
+
+func TestImportSavedReleaseIndex(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	wrapper, key := appKeyLicense(t, 1)
+	store.InitInMemory(store.InitInMemoryStoreOptions{
+		License:         wrapper,
+		ChannelID:       "channel-id",
+		ChannelSequence: 1,
+		Namespace:       "default",
+	})
+	defer store.SetStore(nil)
+
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "replicated", Namespace: "default"},
+	})
+
+	// nothing is imported if no release index was saved
+	req.NoError(ImportSavedReleaseIndex(ctx, clientset, "default"))
+	req.Empty(store.GetStore().GetUpdates())
+
+	signedIndexBytes := signReleaseIndex(t, key, upstreamtypes.ReleaseIndex{
+		AppSlug: "my-app",
+		Releases: []upstreamtypes.ReleaseIndexEntry{
+			{ChannelRelease: upstreamtypes.ChannelRelease{ChannelSequence: 2, VersionLabel: "1.1.0"}, ChannelID: "channel-id"},
+		},
+	})
+	req.NoError(SaveReleaseIndex(ctx, clientset, "default", signedIndexBytes))
+
+	req.NoError(ImportSavedReleaseIndex(ctx, clientset, "default"))
+	req.Equal([]upstreamtypes.ChannelRelease{{ChannelSequence: 2, VersionLabel: "1.1.0"}}, store.GetStore().GetUpdates())
+
+	// the saved release index is rejected if it is not signed with the key of the current license
+	otherWrapper, _ := appKeyLicense(t, 1)
+	store.GetStore().SetLicense(otherWrapper)
+	store.GetStore().SetUpdates(nil)
+
+	req.ErrorContains(ImportSavedReleaseIndex(ctx, clientset, "default"), "failed to verify saved release index")
+	req.Empty(store.GetStore().GetUpdates())
+}
+
+func TestValidateReleaseIndexUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		current *upstreamtypes.ReleaseIndex
+		updated upstreamtypes.ReleaseIndex
+		wantErr string
+	}{
+		{
+			name:    "no imported index",
+			updated: upstreamtypes.ReleaseIndex{CreatedAt: "2026-10-01T00:00:00Z"},
+		},
+		{
+			name:    "newer index",
+			current: &upstreamtypes.ReleaseIndex{CreatedAt: "2026-10-01T00:00:00Z"},
+			updated: upstreamtypes.ReleaseIndex{CreatedAt: "2026-10-02T00:00:00Z"},
+		},
+		{
+			name:    "same index",
+			current: &upstreamtypes.ReleaseIndex{CreatedAt: "2026-10-01T00:00:00Z"},
+			updated: upstreamtypes.ReleaseIndex{CreatedAt: "2026-10-01T00:00:00Z"},
+		},
+		{
+			name:    "older index",
+			current: &upstreamtypes.ReleaseIndex{CreatedAt: "2026-10-02T00:00:00Z"},
+			updated: upstreamtypes.ReleaseIndex{CreatedAt: "2026-10-01T00:00:00Z"},
+			wantErr: "release index was created at 2026-10-01T00:00:00Z, before the imported release index created at 2026-10-02T00:00:00Z",
+		},
+		{
+			name:    "index without a creation time",
+			current: &upstreamtypes.ReleaseIndex{CreatedAt: "2026-10-01T00:00:00Z"},
+			updated: upstreamtypes.ReleaseIndex{},
+			wantErr: `failed to parse release index creation time ""`,
+		},
+		{
+			name:    "imported index without a creation time",
+			current: &upstreamtypes.ReleaseIndex{},
+			updated: upstreamtypes.ReleaseIndex{CreatedAt: "2026-10-01T00:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateReleaseIndexUpdate(tt.current, &tt.updated)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRefilterReleaseIndexUpdates(t *testing.T) {
+	req := require.New(t)
+
+	wrapper, _ := appKeyLicense(t, 1)
+	store.InitInMemory(store.InitInMemoryStoreOptions{
+		License:         wrapper,
+		ChannelID:       "channel-id",
+		ChannelSequence: 1,
+	})
+	defer store.SetStore(nil)
+
+	index := &upstreamtypes.ReleaseIndex{
+		AppSlug: "my-app",
+		Releases: []upstreamtypes.ReleaseIndexEntry{
+			{ChannelRelease: upstreamtypes.ChannelRelease{ChannelSequence: 2, VersionLabel: "1.1.0"}, ChannelID: "channel-id"},
+			{ChannelRelease: upstreamtypes.ChannelRelease{ChannelSequence: 3, VersionLabel: "1.2.0"}, ChannelID: "channel-id", MinLicenseSequence: 2},
+		},
+	}
+	ImportReleaseIndex(index)
+	defer func() {
+		releaseIndexLock.Lock()
+		releaseIndex = nil
+		releaseIndexLock.Unlock()
+	}()
+	req.Equal([]upstreamtypes.ChannelRelease{{ChannelSequence: 2, VersionLabel: "1.1.0"}}, store.GetStore().GetUpdates())
+
+	// the release that requires a later license sequence is available once the license is renewed
+	renewed, _ := appKeyLicense(t, 2)
+	store.GetStore().SetLicense(renewed)
+	RefilterReleaseIndexUpdates()
+	req.Equal([]upstreamtypes.ChannelRelease{
+		{ChannelSequence: 3, VersionLabel: "1.2.0"},
+		{ChannelSequence: 2, VersionLabel: "1.1.0"},
+	}, store.GetStore().GetUpdates())
+}
@@ -0,0 +1,305 @@
+package updates
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	sdklicense "github.com/replicatedhq/replicated-sdk/pkg/license"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"github.com/replicatedhq/replicated-sdk/pkg/upstream"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/util"
+	"github.com/replicatedhq/replicated-sdk/pkg/webhook"
+)
+
+// DefaultCheckInterval is how often updates are checked for if no interval is configured
+const DefaultCheckInterval = 15 * time.Minute
+
+// checkJitter is the fraction of the interval that each wait is randomly shortened or lengthened by,
+// so that instances that started together don't check for updates at the same time
+const checkJitter = 0.1
+
+var (
+	mtx           sync.Mutex
+	interval      = DefaultCheckInterval
+	cancelPoll    context.CancelFunc
+	pollDone      chan struct{}
+	inFlight      *check
+	lastCheckedAt *time.Time
+	lastErr       error
+	etag          string
+	etagQuery     string
+)
+
+// check is a check for updates that callers wait on, so that concurrent refreshes result in a single upstream request
+type check struct {
+	done chan struct{}
+	err  error
+}
+
+// Status describes how recent the updates in the store are
+type Status struct {
+	// LastCheckedAt is when the updates were last checked successfully
+	LastCheckedAt *time.Time
+	// Stale is true if the last check failed, or if the updates were not checked for more than two intervals
+	Stale bool
+	// Err is the error of the last check, if it failed
+	Err error
+}
+
+// Start checks for updates and stores them, and then keeps checking every checkInterval in the background until Stop is called.
+// The license is synced during bootstrap right before, so the first check only gets the updates. An interval of 0 uses DefaultCheckInterval.
+// The background checks are started even if the first check fails, so that the updates recover once the Replicated API can be
+// reached again, and the error of the first check is returned and reported in the status until then.
+func Start(checkInterval time.Duration) error {
+	checkInterval = resetPolling(checkInterval)
+	err := checkForUpdates(true)
+	startPolling(checkInterval)
+	return err
+}
+
+// StartStale keeps checking for updates every checkInterval in the background until Stop is called, without checking now,
+// e.g. because the license could not be synced either. err is reported in the status as the reason that the updates are
+// stale until a check succeeds.
+func StartStale(checkInterval time.Duration, err error) {
+	checkInterval = resetPolling(checkInterval)
+	recordCheck(err, nil, "")
+	startPolling(checkInterval)
+}
+
+// resetPolling stops the background checks and sets the interval, which defaults to DefaultCheckInterval
+func resetPolling(checkInterval time.Duration) time.Duration {
+	if checkInterval <= 0 {
+		checkInterval = DefaultCheckInterval
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	stopPolling()
+	interval = checkInterval
+	return checkInterval
+}
+
+func startPolling(checkInterval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	mtx.Lock()
+	cancelPoll = cancel
+	pollDone = done
+	mtx.Unlock()
+
+	go poll(ctx, checkInterval, done)
+}
+
+// Stop stops checking for updates, and waits for an in-progress check to complete or for the context to be done
+func Stop(ctx context.Context) {
+	mtx.Lock()
+	done := pollDone
+	stopPolling()
+	mtx.Unlock()
+
+	if done == nil {
+		return
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Infof("timed out waiting for update check to complete")
+	}
+}
+
+// stopPolling cancels the background checks, mtx must be held
+func stopPolling() {
+	if cancelPoll != nil {
+		cancelPoll()
+	}
+	cancelPoll = nil
+	pollDone = nil
+}
+
+func poll(ctx context.Context, checkInterval time.Duration, done chan struct{}) {
+	defer close(done)
+
+	for {
+		timer := time.NewTimer(withJitter(checkInterval))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// not canceled with ctx, so that stopping waits for the check to complete
+		if err := Refresh(context.Background()); err != nil {
+			logger.Error(errors.Wrap(err, "failed to check for updates"))
+		}
+	}
+}
+
+func withJitter(d time.Duration) time.Duration {
+	return d + time.Duration((rand.Float64()*2-1)*checkJitter*float64(d))
+}
+
+// Refresh syncs the license and checks for updates now. If a check is already in progress, it waits for that check instead of starting another one.
+// The check continues if ctx is done, so that other callers waiting on it are not affected.
+func Refresh(ctx context.Context) error {
+	mtx.Lock()
+	c := inFlight
+	if c == nil {
+		c = &check{done: make(chan struct{})}
+		inFlight = c
+		go func() {
+			c.err = checkForUpdates(false)
+
+			mtx.Lock()
+			inFlight = nil
+			mtx.Unlock()
+			close(c.done)
+		}()
+	}
+	mtx.Unlock()
+
+	select {
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkForUpdates gets the pending releases with a conditional request, and stores them if they changed.
+// The initial check happens during bootstrap right after the license was synced, and records the updates that are
+// available at startup without syncing the license again or notifying webhooks of them.
+func checkForUpdates(initial bool) error {
+	sdkStore := store.GetStore()
+	license := sdkStore.GetLicense()
+
+	if !initial {
+		licenseData, err := sdklicense.GetLatestLicense(license, sdkStore.GetReplicatedAppEndpoint())
+		if err != nil {
+			recordCheck(errors.Wrap(err, "failed to get latest license"), nil, "")
+			return errors.Wrap(err, "failed to get latest license")
+		}
+
+		webhook.NotifyLicenseChanged(license, licenseData.License)
+		license = licenseData.License
+		sdkStore.SetLicense(license)
+		sdklicense.SyncLicenseExpiration(sdkStore, license)
+		sdklicense.RecordLicenseSync(licenseData)
+	}
+
+	currentCursor := getCurrentCursor(sdkStore)
+	channelID := sdklicense.GetUpdatesChannelID(license, currentCursor.ChannelID)
+
+	// the etag is only sent with the same query it was returned for, since the pending releases depend on the license, cursor and channel
+	query := fmt.Sprintf("%s/%d/%s/%d/%s", license.GetLicenseID(), license.GetLicenseSequence(), currentCursor.ChannelID, currentCursor.ChannelSequence, channelID)
+
+	mtx.Lock()
+	sentETag := ""
+	if query == etagQuery {
+		sentETag = etag
+	}
+	mtx.Unlock()
+
+	result, err := upstream.GetUpdatesIfChanged(sdkStore, license, currentCursor, channelID, sentETag)
+	if err != nil {
+		recordCheck(errors.Wrap(err, "failed to get updates"), nil, "")
+		return errors.Wrap(err, "failed to get updates")
+	}
+
+	if !result.NotModified {
+		// the updates that are available when the first check succeeds are not new, even if it is not the initial one
+		if !initial && hasChecked() {
+			webhook.NotifyReleasesChanged(sdkStore.GetUpdates(), result.ChannelReleases)
+		}
+		sdkStore.SetUpdates(result.ChannelReleases)
+	}
+
+	recordCheck(nil, result, query)
+
+	return nil
+}
+
+// GetChannelUpdates gets the releases that are available to update to on another channel that the license is entitled to,
+// e.g. to check the releases of a channel before switching to it. They are not stored, and webhooks are not notified of them.
+// In airgap mode, they are read from the last imported release index.
+func GetChannelUpdates(channelID string) ([]upstreamtypes.ChannelRelease, error) {
+	sdkStore := store.GetStore()
+	license := sdkStore.GetLicense()
+
+	if util.IsAirgap() {
+		releaseIndexLock.Lock()
+		index := releaseIndex
+		releaseIndexLock.Unlock()
+
+		if index == nil {
+			return []upstreamtypes.ChannelRelease{}, nil
+		}
+		return GetReleaseIndexUpdates(index, license, getCurrentCursor(sdkStore), channelID), nil
+	}
+
+	releases, err := upstream.GetUpdates(sdkStore, license, getCurrentCursor(sdkStore), channelID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get updates")
+	}
+	return releases, nil
+}
+
+func getCurrentCursor(sdkStore store.Store) upstreamtypes.ReplicatedCursor {
+	return upstreamtypes.ReplicatedCursor{
+		ChannelID:       sdkStore.GetChannelID(),
+		ChannelName:     sdkStore.GetChannelName(),
+		ChannelSequence: sdkStore.GetChannelSequence(),
+	}
+}
+
+// hasChecked returns true if updates were checked successfully since the sdk started
+func hasChecked() bool {
+	mtx.Lock()
+	defer mtx.Unlock()
+	return lastCheckedAt != nil
+}
+
+func recordCheck(err error, result *upstreamtypes.UpdatesResult, query string) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	lastErr = err
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	lastCheckedAt = &now
+	etag = result.ETag
+	etagQuery = query
+}
+
+// GetStatus returns when the updates were last checked, and whether they are stale
+func GetStatus(now time.Time) Status {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	status := Status{
+		Err:   lastErr,
+		Stale: lastErr != nil,
+	}
+	if lastCheckedAt == nil {
+		status.Stale = true
+		return status
+	}
+
+	checkedAt := *lastCheckedAt
+	status.LastCheckedAt = &checkedAt
+	if now.Sub(checkedAt) > 2*interval {
+		status.Stale = true
+	}
+	return status
+}
@@ -0,0 +1,290 @@
+package updates
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+	"github.com/stretchr/testify/require"
+)
+
+func resetUpdates() {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	stopPolling()
+	interval = DefaultCheckInterval
+	inFlight = nil
+	lastCheckedAt = nil
+	lastErr = nil
+	etag = ""
+	etagQuery = ""
+}
+
+func testLicense(endpoint string, sequence int64) licensewrapper.LicenseWrapper {
+	return licensewrapper.LicenseWrapper{V1: &v1beta1.License{
+		Spec: v1beta1.LicenseSpec{
+			LicenseID:       "license-id",
+			AppSlug:         "my-app",
+			Endpoint:        endpoint,
+			LicenseSequence: sequence,
+		},
+	}}
+}
+
+func TestCheckForUpdates(t *testing.T) {
+	resetUpdates()
+	defer resetUpdates()
+
+	req := require.New(t)
+
+	var serverMtx sync.Mutex
+	sentETags := []string{}
+	failing := false
+
+	router := mux.NewRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+	router.Methods("POST").Path("/release/my-app/pending").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverMtx.Lock()
+		defer serverMtx.Unlock()
+
+		sentETags = append(sentETags, r.Header.Get("If-None-Match"))
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"releases-v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"releases-v1"`)
+		w.Write([]byte(`{"channelReleases": [{"versionLabel": "1.1.0"}]}`))
+	})
+
+	store.InitInMemory(store.InitInMemoryStoreOptions{
+		License:               testLicense(server.URL, 1),
+		ReplicatedAppEndpoint: server.URL,
+		ChannelID:             "channel-id",
+		ChannelSequence:       1,
+	})
+	defer store.SetStore(nil)
+
+	wantUpdates := []upstreamtypes.ChannelRelease{{VersionLabel: "1.1.0"}}
+	now := time.Now()
+
+	req.True(GetStatus(now).Stale)
+
+	// the first check gets the releases and their etag
+	req.NoError(checkForUpdates(true))
+	req.Equal(wantUpdates, store.GetStore().GetUpdates())
+
+	status := GetStatus(time.Now())
+	req.False(status.Stale)
+	req.NotNil(status.LastCheckedAt)
+	req.True(GetStatus(status.LastCheckedAt.Add(3 * DefaultCheckInterval)).Stale)
+
+	// the releases are kept if they did not change
+	req.NoError(checkForUpdates(true))
+	req.Equal(wantUpdates, store.GetStore().GetUpdates())
+
+	// the etag is not sent once the license changes, since the pending releases depend on it
+	store.GetStore().SetLicense(testLicense(server.URL, 2))
+	req.NoError(checkForUpdates(true))
+	req.Equal(wantUpdates, store.GetStore().GetUpdates())
+
+	serverMtx.Lock()
+	req.Equal([]string{"", `"releases-v1"`, ""}, sentETags)
+	failing = true
+	serverMtx.Unlock()
+
+	// a failed check keeps the last releases, and marks them as stale
+	req.Error(checkForUpdates(true))
+	req.Equal(wantUpdates, store.GetStore().GetUpdates())
+
+	status = GetStatus(time.Now())
+	req.True(status.Stale)
+	req.Error(status.Err)
+	req.NotNil(status.LastCheckedAt)
+}
+
+func TestCheckForUpdatesOnInstalledChannel(t *testing.T) {
+	resetUpdates()
+	defer resetUpdates()
+
+	req := require.New(t)
+
+	var serverMtx sync.Mutex
+	queries := []string{}
+
+	router := mux.NewRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+	router.Methods("POST").Path("/release/my-app/pending").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverMtx.Lock()
+		defer serverMtx.Unlock()
+
+		queries = append(queries, r.URL.Query().Get("channelSequence")+"/"+r.URL.Query().Get("selectedChannelId"))
+		w.Write([]byte(`{"channelReleases": []}`))
+	})
+
+	license := testLicense(server.URL, 1)
+	license.V1.Spec.ChannelID = "stable-id"
+	license.V1.Spec.Channels = []v1beta1.Channel{
+		{ChannelID: "stable-id", IsDefault: true},
+		{ChannelID: "beta-id"},
+	}
+
+	store.InitInMemory(store.InitInMemoryStoreOptions{
+		License:               license,
+		ReplicatedAppEndpoint: server.URL,
+		ChannelID:             "beta-id",
+		ChannelSequence:       5,
+	})
+	defer store.SetStore(nil)
+
+	// updates are checked on the installed channel from its sequence, not on the default channel of the license
+	req.NoError(checkForUpdates(true))
+
+	// the releases on the default channel are all pending, since it is not the installed channel
+	_, err := GetChannelUpdates("stable-id")
+	req.NoError(err)
+
+	serverMtx.Lock()
+	req.Equal([]string{"5/beta-id", "/"}, queries)
+	serverMtx.Unlock()
+}
+
+func TestRefresh(t *testing.T) {
+	resetUpdates()
+	defer resetUpdates()
+
+	req := require.New(t)
+
+	var serverMtx sync.Mutex
+	licenseRequests := 0
+	release := make(chan struct{})
+
+	router := mux.NewRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+	router.Methods("GET").Path("/license/my-app").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverMtx.Lock()
+		licenseRequests++
+		serverMtx.Unlock()
+
+		<-release
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	store.InitInMemory(store.InitInMemoryStoreOptions{
+		License:               testLicense(server.URL, 1),
+		ReplicatedAppEndpoint: server.URL,
+	})
+	defer store.SetStore(nil)
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- Refresh(context.Background())
+	}()
+
+	req.Eventually(func() bool {
+		serverMtx.Lock()
+		defer serverMtx.Unlock()
+		return licenseRequests == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mtx.Lock()
+	first := inFlight
+	mtx.Unlock()
+	req.NotNil(first)
+
+	// a refresh while a check is in progress waits for that check, and returns when its context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req.ErrorIs(Refresh(ctx), context.Canceled)
+
+	mtx.Lock()
+	req.Same(first, inFlight)
+	mtx.Unlock()
+
+	close(release)
+	req.ErrorContains(<-errCh, "failed to get latest license")
+
+	serverMtx.Lock()
+	req.Equal(1, licenseRequests)
+	serverMtx.Unlock()
+
+	// the next refresh checks again
+	req.Error(Refresh(context.Background()))
+
+	serverMtx.Lock()
+	req.Equal(2, licenseRequests)
+	serverMtx.Unlock()
+
+	status := GetStatus(time.Now())
+	req.True(status.Stale)
+	req.Nil(status.LastCheckedAt)
+}
+
+func TestStart(t *testing.T) {
+	resetUpdates()
+	defer resetUpdates()
+
+	req := require.New(t)
+
+	router := mux.NewRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+	router.Methods("POST").Path("/release/my-app/pending").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	store.InitInMemory(store.InitInMemoryStoreOptions{
+		License:               testLicense(server.URL, 1),
+		ReplicatedAppEndpoint: server.URL,
+	})
+	defer store.SetStore(nil)
+
+	// the background checks are started even if the first check fails
+	req.ErrorContains(Start(time.Hour), "failed to get updates")
+
+	mtx.Lock()
+	req.NotNil(pollDone)
+	mtx.Unlock()
+
+	status := GetStatus(time.Now())
+	req.True(status.Stale)
+	req.ErrorContains(status.Err, "failed to get updates")
+
+	// the updates are reported as stale with the given error until a background check succeeds
+	StartStale(time.Hour, errors.New("failed to get latest license"))
+
+	mtx.Lock()
+	req.NotNil(pollDone)
+	mtx.Unlock()
+
+	status = GetStatus(time.Now())
+	req.True(status.Stale)
+	req.Nil(status.LastCheckedAt)
+	req.EqualError(status.Err, "failed to get latest license")
+
+	Stop(context.Background())
+}
+
+func TestWithJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := withJitter(time.Minute)
+		require.GreaterOrEqual(t, d, 54*time.Second)
+		require.LessOrEqual(t, d, 66*time.Second)
+	}
+}
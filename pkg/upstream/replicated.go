@@ -6,17 +6,30 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 
 	"github.com/pkg/errors"
 	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"github.com/replicatedhq/replicated-sdk/pkg/metrics"
 	"github.com/replicatedhq/replicated-sdk/pkg/report"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
 	types "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
 	"github.com/replicatedhq/replicated-sdk/pkg/util"
 )
 
-func GetUpdates(sdkStore store.Store, wrapper licensewrapper.LicenseWrapper, currentCursor types.ReplicatedCursor) ([]types.ChannelRelease, error) {
+// GetUpdates gets the pending releases on the given channel, or on the channel of the license if channelID is empty
+func GetUpdates(sdkStore store.Store, wrapper licensewrapper.LicenseWrapper, currentCursor types.ReplicatedCursor, channelID string) ([]types.ChannelRelease, error) {
+	result, err := GetUpdatesIfChanged(sdkStore, wrapper, currentCursor, channelID, "")
+	if err != nil {
+		return nil, err
+	}
+	return result.ChannelReleases, nil
+}
+
+// GetUpdatesIfChanged gets the pending releases with a conditional request. If etag is set and the releases did not change
+// since it was returned, NotModified is set on the result and no releases are returned.
+func GetUpdatesIfChanged(sdkStore store.Store, wrapper licensewrapper.LicenseWrapper, currentCursor types.ReplicatedCursor, channelID string, etag string) (*types.UpdatesResult, error) {
 	endpoint := sdkStore.GetReplicatedAppEndpoint()
 	if endpoint == "" {
 		endpoint = wrapper.GetEndpoint()
@@ -32,15 +45,23 @@ func GetUpdates(sdkStore store.Store, wrapper licensewrapper.LicenseWrapper, cur
 		hostname = fmt.Sprintf("%s:%s", u.Hostname(), u.Port())
 	}
 
+	if channelID == "" {
+		channelID = wrapper.GetChannelID()
+	}
+
 	// build the request url query params
 	channelSequenceStr := fmt.Sprintf("%d", currentCursor.ChannelSequence)
-	if currentCursor.ChannelID != wrapper.GetChannelID() {
+	if currentCursor.ChannelID != channelID {
 		// channel has changed, so we need to reset the channel sequence
 		channelSequenceStr = ""
 	}
 
 	urlValues := url.Values{}
 	urlValues.Set("channelSequence", channelSequenceStr)
+	if channelID != wrapper.GetChannelID() {
+		// licenses that are entitled to several channels get the releases of the channel of the license unless another is selected
+		urlValues.Set("selectedChannelId", channelID)
+	}
 	urlValues.Add("licenseSequence", fmt.Sprintf("%d", wrapper.GetLicenseSequence()))
 	urlValues.Add("isSemverSupported", "true")
 	urlValues.Add("sortOrder", "desc")
@@ -65,15 +86,23 @@ func GetUpdates(sdkStore store.Store, wrapper licensewrapper.LicenseWrapper, cur
 	}
 	req.Header.Set("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", wrapper.GetLicenseID(), wrapper.GetLicenseID())))))
 	req.Header.Set("Content-Type", "application/json")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
 
 	report.InjectInstanceDataHeaders(req, instanceData)
 
-	resp, err := util.HttpClient().Do(req)
+	resp, err := util.DoUpstream(req, util.UpstreamUpdates)
+	metrics.ObserveUpstreamRequest("updates", resp, err)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to execute get request")
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return &types.UpdatesResult{ETag: etag, NotModified: true}, nil
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to read response body")
@@ -81,7 +110,7 @@ func GetUpdates(sdkStore store.Store, wrapper licensewrapper.LicenseWrapper, cur
 
 	if resp.StatusCode >= 400 {
 		if len(body) > 0 {
-			return nil, util.ActionableError{Message: string(body)}
+			return nil, util.ActionableError{Message: string(body), StatusCode: resp.StatusCode}
 		}
 		return nil, errors.Errorf("unexpected result from get request: %d", resp.StatusCode)
 	}
@@ -93,5 +122,8 @@ func GetUpdates(sdkStore store.Store, wrapper licensewrapper.LicenseWrapper, cur
 		return nil, errors.Wrap(err, "failed to unmarshal response")
 	}
 
-	return channelReleases.ChannelReleases, nil
+	return &types.UpdatesResult{
+		ChannelReleases: channelReleases.ChannelReleases,
+		ETag:            resp.Header.Get("ETag"),
+	}, nil
 }
@@ -7,9 +7,9 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"time"
 
 	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/metrics"
 	"github.com/replicatedhq/replicated-sdk/pkg/report"
 	"github.com/replicatedhq/replicated-sdk/pkg/store"
 	"github.com/replicatedhq/replicated-sdk/pkg/util"
@@ -58,7 +58,8 @@ func GetSupportBundleUploadURL(sdkStore store.Store) (*SupportBundleUploadURL, e
 	instanceData := report.GetInstanceData(sdkStore)
 	report.InjectInstanceDataHeaders(req, instanceData)
 
-	resp, err := util.HttpClient().Do(req)
+	resp, err := util.DoUpstream(req, util.UpstreamSupportBundleUploadURL)
+	metrics.ObserveUpstreamRequest("support_bundle_upload_url", resp, err)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to execute request")
 	}
@@ -71,7 +72,7 @@ func GetSupportBundleUploadURL(sdkStore store.Store) (*SupportBundleUploadURL, e
 
 	if resp.StatusCode >= 400 {
 		if len(body) > 0 {
-			return nil, util.ActionableError{Message: string(body)}
+			return nil, util.ActionableError{Message: string(body), StatusCode: resp.StatusCode}
 		}
 		return nil, errors.Errorf("unexpected status code from upload url request: %d", resp.StatusCode)
 	}
@@ -92,11 +93,8 @@ func UploadToS3(uploadURL string, body io.Reader, contentLength int64) error {
 
 	req.ContentLength = contentLength
 
-	client := &http.Client{
-		Timeout: 30 * time.Minute,
-	}
-
-	resp, err := client.Do(req)
+	resp, err := util.DoUpstream(req, util.UpstreamSupportBundleUpload)
+	metrics.ObserveUpstreamRequest("support_bundle_upload", resp, err)
 	if err != nil {
 		return errors.Wrap(err, "failed to upload to S3")
 	}
@@ -153,7 +151,8 @@ func MarkSupportBundleUploaded(sdkStore store.Store, bundleID string) (string, e
 	instanceData := report.GetInstanceData(sdkStore)
 	report.InjectInstanceDataHeaders(req, instanceData)
 
-	resp, err := util.HttpClient().Do(req)
+	resp, err := util.DoUpstream(req, util.UpstreamSupportBundleMarkUploaded)
+	metrics.ObserveUpstreamRequest("support_bundle_mark_uploaded", resp, err)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to execute request")
 	}
@@ -166,7 +165,7 @@ func MarkSupportBundleUploaded(sdkStore store.Store, bundleID string) (string, e
 
 	if resp.StatusCode >= 400 {
 		if len(body) > 0 {
-			return "", util.ActionableError{Message: string(body)}
+			return "", util.ActionableError{Message: string(body), StatusCode: resp.StatusCode}
 		}
 		return "", errors.Errorf("unexpected status code from mark uploaded request: %d", resp.StatusCode)
 	}
@@ -0,0 +1,90 @@
+package types
+
+import (
+	"sort"
+
+	"github.com/blang/semver"
+)
+
+// UpdatesOptions filters and orders the releases that are available to update to
+type UpdatesOptions struct {
+	// MaxBump leaves out releases with a larger semver bump from the current version, if set.
+	// Releases without a bump, because either version is not semver or the release is not newer, are not left out.
+	MaxBump SemverBump
+	// IncludePrerelease includes releases with a semver prerelease version
+	IncludePrerelease bool
+	// SemverRequired orders the releases by semver, newest first, instead of by channel sequence
+	SemverRequired bool
+}
+
+// SelectUpdates returns the releases that match the options, with the semver bump from the current version set on each release.
+// Required releases are always returned, since they can't be skipped when updating.
+func SelectUpdates(releases []ChannelRelease, currentVersionLabel string, opts UpdatesOptions) []ChannelRelease {
+	currentVersion, currentErr := semver.ParseTolerant(currentVersionLabel)
+
+	type selectedRelease struct {
+		release ChannelRelease
+		version *semver.Version
+	}
+
+	selected := []selectedRelease{}
+	for _, release := range releases {
+		var version *semver.Version
+		if v, err := semver.ParseTolerant(release.VersionLabel); err == nil {
+			version = &v
+			if currentErr == nil {
+				release.Bump = GetSemverBump(currentVersion, v)
+			}
+		}
+
+		if !release.IsRequired {
+			if !opts.IncludePrerelease && version != nil && len(version.Pre) > 0 {
+				continue
+			}
+			if opts.MaxBump != "" && release.Bump.Rank() > opts.MaxBump.Rank() {
+				continue
+			}
+		}
+
+		selected = append(selected, selectedRelease{release: release, version: version})
+	}
+
+	if opts.SemverRequired {
+		// releases that are not semver are sorted after the ones that are, and keep their order
+		sort.SliceStable(selected, func(i, j int) bool {
+			vi, vj := selected[i].version, selected[j].version
+			if vi == nil || vj == nil {
+				return vi != nil
+			}
+			if !vi.EQ(*vj) {
+				return vi.GT(*vj)
+			}
+			return selected[i].release.ChannelSequence > selected[j].release.ChannelSequence
+		})
+	}
+
+	updates := make([]ChannelRelease, 0, len(selected))
+	for _, s := range selected {
+		updates = append(updates, s.release)
+	}
+	return updates
+}
+
+// GetSemverBump returns the largest part of the version that changed from current to next,
+// or an empty bump if next is not newer than current
+func GetSemverBump(current semver.Version, next semver.Version) SemverBump {
+	if !next.GT(current) {
+		return ""
+	}
+
+	switch {
+	case next.Major != current.Major:
+		return SemverBumpMajor
+	case next.Minor != current.Minor:
+		return SemverBumpMinor
+	case next.Patch != current.Patch:
+		return SemverBumpPatch
+	default:
+		return SemverBumpPrerelease
+	}
+}
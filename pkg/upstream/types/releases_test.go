@@ -0,0 +1,103 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectUpdates(t *testing.T) {
+	// as returned by the Replicated API, newest channel sequence first
+	releases := []ChannelRelease{
+		{ChannelSequence: 7, VersionLabel: "1.3.0-beta.1"},
+		{ChannelSequence: 6, VersionLabel: "nightly"},
+		{ChannelSequence: 5, VersionLabel: "1.2.1"},
+		{ChannelSequence: 4, VersionLabel: "2.0.0"},
+		{ChannelSequence: 3, VersionLabel: "v1.2.0", IsRequired: true},
+		{ChannelSequence: 2, VersionLabel: "1.1.1"},
+	}
+
+	tests := []struct {
+		name                string
+		currentVersionLabel string
+		opts                UpdatesOptions
+		want                []ChannelRelease
+	}{
+		{
+			name:                "all releases in channel sequence order",
+			currentVersionLabel: "1.1.0",
+			opts:                UpdatesOptions{IncludePrerelease: true},
+			want: []ChannelRelease{
+				{ChannelSequence: 7, VersionLabel: "1.3.0-beta.1", Bump: SemverBumpMinor},
+				{ChannelSequence: 6, VersionLabel: "nightly"},
+				{ChannelSequence: 5, VersionLabel: "1.2.1", Bump: SemverBumpMinor},
+				{ChannelSequence: 4, VersionLabel: "2.0.0", Bump: SemverBumpMajor},
+				{ChannelSequence: 3, VersionLabel: "v1.2.0", IsRequired: true, Bump: SemverBumpMinor},
+				{ChannelSequence: 2, VersionLabel: "1.1.1", Bump: SemverBumpPatch},
+			},
+		},
+		{
+			name:                "semver required",
+			currentVersionLabel: "1.1.0",
+			opts:                UpdatesOptions{IncludePrerelease: true, SemverRequired: true},
+			want: []ChannelRelease{
+				{ChannelSequence: 4, VersionLabel: "2.0.0", Bump: SemverBumpMajor},
+				{ChannelSequence: 7, VersionLabel: "1.3.0-beta.1", Bump: SemverBumpMinor},
+				{ChannelSequence: 5, VersionLabel: "1.2.1", Bump: SemverBumpMinor},
+				{ChannelSequence: 3, VersionLabel: "v1.2.0", IsRequired: true, Bump: SemverBumpMinor},
+				{ChannelSequence: 2, VersionLabel: "1.1.1", Bump: SemverBumpPatch},
+				{ChannelSequence: 6, VersionLabel: "nightly"},
+			},
+		},
+		{
+			name:                "max bump patch without prereleases keeps required releases",
+			currentVersionLabel: "1.1.0",
+			opts:                UpdatesOptions{MaxBump: SemverBumpPatch},
+			want: []ChannelRelease{
+				{ChannelSequence: 6, VersionLabel: "nightly"},
+				{ChannelSequence: 3, VersionLabel: "v1.2.0", IsRequired: true, Bump: SemverBumpMinor},
+				{ChannelSequence: 2, VersionLabel: "1.1.1", Bump: SemverBumpPatch},
+			},
+		},
+		{
+			name:                "current version is not semver",
+			currentVersionLabel: "latest",
+			opts:                UpdatesOptions{MaxBump: SemverBumpPatch, IncludePrerelease: true},
+			want: []ChannelRelease{
+				{ChannelSequence: 7, VersionLabel: "1.3.0-beta.1"},
+				{ChannelSequence: 6, VersionLabel: "nightly"},
+				{ChannelSequence: 5, VersionLabel: "1.2.1"},
+				{ChannelSequence: 4, VersionLabel: "2.0.0"},
+				{ChannelSequence: 3, VersionLabel: "v1.2.0", IsRequired: true},
+				{ChannelSequence: 2, VersionLabel: "1.1.1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, SelectUpdates(releases, tt.currentVersionLabel, tt.opts))
+		})
+	}
+}
+
+func TestGetSemverBump(t *testing.T) {
+	tests := []struct {
+		current string
+		next    string
+		want    SemverBump
+	}{
+		{current: "1.0.0", next: "2.0.0", want: SemverBumpMajor},
+		{current: "1.0.0", next: "1.1.0", want: SemverBumpMinor},
+		{current: "1.0.0", next: "1.0.1", want: SemverBumpPatch},
+		{current: "1.1.0-beta.1", next: "1.1.0-beta.2", want: SemverBumpPrerelease},
+		{current: "1.1.0-beta.1", next: "1.1.0", want: SemverBumpPrerelease},
+		{current: "1.1.0", next: "1.1.0", want: ""},
+		{current: "1.1.0", next: "1.0.9", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.current+" to "+tt.next, func(t *testing.T) {
+			require.Equal(t, tt.want, GetSemverBump(semver.MustParse(tt.current), semver.MustParse(tt.next)))
+		})
+	}
+}
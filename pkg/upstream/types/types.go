@@ -14,7 +14,70 @@ func (rc1 ReplicatedCursor) Equal(rc2 ReplicatedCursor) bool {
 }
 
 type ChannelRelease struct {
-	VersionLabel string `json:"versionLabel"`
-	CreatedAt    string `json:"createdAt"`
-	ReleaseNotes string `json:"releaseNotes"`
+	ChannelSequence       int64  `json:"channelSequence"`
+	VersionLabel          string `json:"versionLabel"`
+	CreatedAt             string `json:"createdAt"`
+	ReleaseNotes          string `json:"releaseNotes"`
+	IsRequired            bool   `json:"isRequired"`
+	AirgapBundleAvailable bool   `json:"airgapBundleAvailable"`
+	// Bump is the semver distance from the current version. It is set when the release is served,
+	// and is empty if either version is not semver or if the release is not newer than the current version.
+	Bump SemverBump `json:"bump,omitempty"`
+}
+
+// SemverBump is the largest part of the version that changed between two semver versions
+type SemverBump string
+
+const (
+	SemverBumpMajor      SemverBump = "major"
+	SemverBumpMinor      SemverBump = "minor"
+	SemverBumpPatch      SemverBump = "patch"
+	SemverBumpPrerelease SemverBump = "prerelease"
+)
+
+// Rank orders bumps from prerelease (1) to major (4), and is 0 for an unknown bump
+func (b SemverBump) Rank() int {
+	switch b {
+	case SemverBumpPrerelease:
+		return 1
+	case SemverBumpPatch:
+		return 2
+	case SemverBumpMinor:
+		return 3
+	case SemverBumpMajor:
+		return 4
+	}
+	return 0
+}
+
+// UpdatesResult is the result of a conditional request for the pending releases
+type UpdatesResult struct {
+	ChannelReleases []ChannelRelease
+	// ETag identifies the returned releases, and is sent with the next request to only get the releases if they changed
+	ETag string
+	// NotModified is true if the releases did not change since the ETag that was sent
+	NotModified bool
+}
+
+// SignedReleaseIndex is the release index file that is shipped with airgap bundles, so that updates can be discovered
+// without reaching the Replicated API. Index is the JSON encoded ReleaseIndex, and Signature is its RSA-PSS SHA-256
+// signature made with the app key, so that it can be verified with the public key in the license.
+type SignedReleaseIndex struct {
+	Index     []byte `json:"index"`
+	Signature []byte `json:"signature"`
+}
+
+// ReleaseIndex lists the releases of an app on each of its channels
+type ReleaseIndex struct {
+	AppSlug   string              `json:"appSlug"`
+	CreatedAt string              `json:"createdAt"`
+	Releases  []ReleaseIndexEntry `json:"releases"`
+}
+
+type ReleaseIndexEntry struct {
+	ChannelRelease `json:",inline"`
+	ChannelID      string `json:"channelID"`
+	// MinLicenseSequence is the license sequence that the release is available from, e.g. because it was promoted
+	// after the license was renewed. The release is available to every license if it is not set.
+	MinLicenseSequence int64 `json:"minLicenseSequence,omitempty"`
 }
@@ -1,12 +1,265 @@
 package util
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/retry"
+	"golang.org/x/net/http/httpproxy"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultUpstreamTimeout is the timeout of requests made with HttpClient
+const DefaultUpstreamTimeout = 10 * time.Second
+
+// DefaultPrivateCASecretKey is the key of the CA bundle in the private CA secret if no key is configured
+const DefaultPrivateCASecretKey = "ca.crt"
+
+const (
+	// maxUpstreamRetries is how many times an idempotent upstream request is retried
+	maxUpstreamRetries = 3
+	// maxUpstreamRetryAfter is the longest Retry-After that is waited for before retrying, longer ones are returned as-is
+	maxUpstreamRetryAfter = 30 * time.Second
+	// maxIdleConnsPerHost is higher than the default of 2, since most requests go to the same upstream host
+	maxIdleConnsPerHost = 10
+)
+
+// UpstreamEndpoint describes how requests to an endpoint of the Replicated upstream are made
+type UpstreamEndpoint struct {
+	// Timeout is the timeout of a single attempt, including reading the response body
+	Timeout time.Duration
+	// Idempotent requests are retried after connection errors and transient error responses
+	Idempotent bool
+}
+
+// The endpoints that are requested while an SDK API request waits for them are not retried and time out sooner, since
+// the SDK API falls back to the last known data if the upstream is unavailable. Background syncs retry instead.
+var (
+	UpstreamLicense                   = UpstreamEndpoint{Timeout: 10 * time.Second, Idempotent: true}
+	UpstreamLicenseOnRequest          = UpstreamEndpoint{Timeout: 5 * time.Second}
+	UpstreamLicenseFields             = UpstreamEndpoint{Timeout: 5 * time.Second}
+	UpstreamLicenseField              = UpstreamEndpoint{Timeout: 5 * time.Second}
+	UpstreamUpdates                   = UpstreamEndpoint{Timeout: 30 * time.Second, Idempotent: true}
+	UpstreamInstanceData              = UpstreamEndpoint{Timeout: 10 * time.Second}
+	UpstreamCustomMetrics             = UpstreamEndpoint{Timeout: 10 * time.Second}
+	UpstreamSupportBundleUploadURL    = UpstreamEndpoint{Timeout: 10 * time.Second}
+	UpstreamSupportBundleUpload       = UpstreamEndpoint{Timeout: 30 * time.Minute}
+	UpstreamSupportBundleMarkUploaded = UpstreamEndpoint{Timeout: 10 * time.Second, Idempotent: true}
 )
 
+// UpstreamClientOptions configures the client that requests to the Replicated upstream are made with
+type UpstreamClientOptions struct {
+	// HTTPSProxy, HTTPProxy and NoProxy take precedence over the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables
+	HTTPSProxy string
+	HTTPProxy  string
+	NoProxy    string
+	// CACerts are PEM encoded certificates of private certificate authorities that are trusted in addition to the
+	// system ones, e.g. of a proxy that intercepts TLS connections
+	CACerts []byte
+}
+
+var (
+	upstreamTransport    *http.Transport
+	upstreamTransportMtx sync.Mutex
+
+	// newUpstreamBackOff returns the retry policy for an idempotent upstream request, it is a variable so that tests can shorten it.
+	// The intervals are randomized, so that instances that failed together don't retry at the same time.
+	newUpstreamBackOff = func() backoff.BackOff {
+		b := backoff.NewExponentialBackOff()
+		b.InitialInterval = 500 * time.Millisecond
+		b.MaxInterval = 5 * time.Second
+		b.MaxElapsedTime = time.Minute
+		return backoff.WithMaxRetries(b, maxUpstreamRetries)
+	}
+)
+
+// ConfigureUpstreamClient replaces the transport that upstream requests are made with. Requests that are in progress
+// complete with the previous transport.
+func ConfigureUpstreamClient(opts UpstreamClientOptions) error {
+	proxyConfig := httpproxy.FromEnvironment()
+	if opts.HTTPSProxy != "" {
+		proxyConfig.HTTPSProxy = opts.HTTPSProxy
+	}
+	if opts.HTTPProxy != "" {
+		proxyConfig.HTTPProxy = opts.HTTPProxy
+	}
+	if opts.NoProxy != "" {
+		proxyConfig.NoProxy = opts.NoProxy
+	}
+
+	var rootCAs *x509.CertPool
+	if len(opts.CACerts) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			return errors.Wrap(err, "failed to load system cert pool")
+		}
+		if !pool.AppendCertsFromPEM(opts.CACerts) {
+			return errors.New("no certificates found in CA bundle")
+		}
+		rootCAs = pool
+	}
+
+	transport := newUpstreamTransport(proxyConfig, rootCAs)
+
+	upstreamTransportMtx.Lock()
+	previous := upstreamTransport
+	upstreamTransport = transport
+	upstreamTransportMtx.Unlock()
+
+	if previous != nil {
+		previous.CloseIdleConnections()
+	}
+
+	return nil
+}
+
+// getUpstreamTransport returns the shared upstream transport, which uses the proxy environment variables and the
+// system certificate authorities until ConfigureUpstreamClient is called
+func getUpstreamTransport() *http.Transport {
+	upstreamTransportMtx.Lock()
+	defer upstreamTransportMtx.Unlock()
+
+	if upstreamTransport == nil {
+		upstreamTransport = newUpstreamTransport(httpproxy.FromEnvironment(), nil)
+	}
+	return upstreamTransport
+}
+
+func newUpstreamTransport(proxyConfig *httpproxy.Config, rootCAs *x509.CertPool) *http.Transport {
+	proxyFunc := proxyConfig.ProxyFunc()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	if rootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs: rootCAs,
+		}
+	}
+	return transport
+}
+
+// HttpClient returns a client with the default timeout that shares the connections, proxy and certificate authorities
+// of the upstream client
 func HttpClient() *http.Client {
 	return &http.Client{
-		Timeout: 10 * time.Second,
+		Transport: getUpstreamTransport(),
+		Timeout:   DefaultUpstreamTimeout,
+	}
+}
+
+// webhookTransport is shared by webhook deliveries. It is separate from the upstream transport, since webhooks go to
+// in-cluster or customer endpoints that the proxy and certificate authorities for the Replicated upstream are not meant for.
+var webhookTransport = http.DefaultTransport.(*http.Transport).Clone()
+
+// WebhookHttpClient returns a client with the default timeout for webhook deliveries, which only uses the proxy environment
+// variables and the system certificate authorities
+func WebhookHttpClient() *http.Client {
+	return &http.Client{
+		Transport: webhookTransport,
+		Timeout:   DefaultUpstreamTimeout,
+	}
+}
+
+// DoUpstream sends a request to an endpoint of the Replicated upstream. Requests to idempotent endpoints are retried with
+// exponential backoff after connection errors and transient error responses, if their body can be sent again.
+// The response of the last attempt is returned, and the caller must close its body.
+func DoUpstream(req *http.Request, endpoint UpstreamEndpoint) (*http.Response, error) {
+	client := &http.Client{
+		Transport: getUpstreamTransport(),
+		Timeout:   endpoint.Timeout,
+	}
+
+	if !endpoint.Idempotent || !retry.IsReplayable(req) {
+		return client.Do(req)
+	}
+
+	b := newUpstreamBackOff()
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to rewind request body")
+			}
+			req.Body = body
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if req.Context().Err() != nil {
+				return nil, err
+			}
+			delay := b.NextBackOff()
+			if delay == backoff.Stop {
+				return nil, err
+			}
+			if waitErr := retry.Wait(req.Context(), delay); waitErr != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !isTransientStatusCode(resp.StatusCode) {
+			return resp, nil
+		}
+
+		delay := b.NextBackOff()
+		if delay == backoff.Stop {
+			return resp, nil
+		}
+		if retryAfter := retry.ParseRetryAfter(resp.Header.Get("Retry-After")); retryAfter > delay {
+			if retryAfter > maxUpstreamRetryAfter {
+				return resp, nil
+			}
+			delay = retryAfter
+		}
+
+		// drain the body so that the connection can be reused
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+
+		if waitErr := retry.Wait(req.Context(), delay); waitErr != nil {
+			return nil, waitErr
+		}
+	}
+}
+
+// GetPrivateCACerts returns the PEM encoded CA bundle in the given key of the secret
+func GetPrivateCACerts(ctx context.Context, clientset kubernetes.Interface, namespace string, secretName string, key string) ([]byte, error) {
+	if key == "" {
+		key = DefaultPrivateCASecretKey
+	}
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get private ca secret")
+	}
+
+	caCerts := secret.Data[key]
+	if len(caCerts) == 0 {
+		return nil, errors.Errorf("private ca secret %s has no %s key", secretName, key)
+	}
+
+	return caCerts, nil
+}
+
+// isTransientStatusCode returns true for error responses that may succeed when the request is retried
+func isTransientStatusCode(statusCode int) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
 	}
+	return false
 }
@@ -0,0 +1,227 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func resetUpstreamClient(t *testing.T) {
+	origBackOff := newUpstreamBackOff
+	newUpstreamBackOff = func() backoff.BackOff {
+		return backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Millisecond), maxUpstreamRetries)
+	}
+	t.Cleanup(func() {
+		newUpstreamBackOff = origBackOff
+
+		upstreamTransportMtx.Lock()
+		upstreamTransport = nil
+		upstreamTransportMtx.Unlock()
+	})
+}
+
+func TestDoUpstream(t *testing.T) {
+	tests := []struct {
+		name           string
+		endpoint       UpstreamEndpoint
+		statusCodes    []int
+		wantStatusCode int
+		wantAttempts   int
+	}{
+		{
+			name:           "idempotent request is retried after transient errors",
+			endpoint:       UpstreamEndpoint{Timeout: time.Second, Idempotent: true},
+			statusCodes:    []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			wantStatusCode: http.StatusOK,
+			wantAttempts:   3,
+		},
+		{
+			name:           "idempotent request returns the last response when retries are exhausted",
+			endpoint:       UpstreamEndpoint{Timeout: time.Second, Idempotent: true},
+			statusCodes:    []int{http.StatusBadGateway},
+			wantStatusCode: http.StatusBadGateway,
+			wantAttempts:   maxUpstreamRetries + 1,
+		},
+		{
+			name:           "idempotent request is not retried after other errors",
+			endpoint:       UpstreamEndpoint{Timeout: time.Second, Idempotent: true},
+			statusCodes:    []int{http.StatusInternalServerError},
+			wantStatusCode: http.StatusInternalServerError,
+			wantAttempts:   1,
+		},
+		{
+			name:           "request that is not idempotent is not retried",
+			endpoint:       UpstreamEndpoint{Timeout: time.Second},
+			statusCodes:    []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantAttempts:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetUpstreamClient(t)
+			req := require.New(t)
+
+			var mtx sync.Mutex
+			bodies := []string{}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+
+				mtx.Lock()
+				defer mtx.Unlock()
+
+				statusCode := tt.statusCodes[len(tt.statusCodes)-1]
+				if len(bodies) < len(tt.statusCodes) {
+					statusCode = tt.statusCodes[len(bodies)]
+				}
+				bodies = append(bodies, string(body))
+				w.WriteHeader(statusCode)
+			}))
+			defer server.Close()
+
+			httpReq, err := NewRequest("POST", server.URL, bytes.NewBufferString(`{"a": "b"}`))
+			req.NoError(err)
+
+			resp, err := DoUpstream(httpReq, tt.endpoint)
+			req.NoError(err)
+			defer resp.Body.Close()
+			req.Equal(tt.wantStatusCode, resp.StatusCode)
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			req.Len(bodies, tt.wantAttempts)
+			for _, body := range bodies {
+				// the body is sent again with every attempt
+				req.Equal(`{"a": "b"}`, body)
+			}
+		})
+	}
+}
+
+func TestDoUpstream_Canceled(t *testing.T) {
+	resetUpstreamClient(t)
+	req := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		cancel()
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	req.NoError(err)
+
+	// the request is not retried once its context is done
+	_, err = DoUpstream(httpReq, UpstreamEndpoint{Timeout: time.Second, Idempotent: true})
+	req.ErrorIs(err, context.Canceled)
+	req.Equal(int32(1), attempts.Load())
+}
+
+func TestConfigureUpstreamClient_Proxy(t *testing.T) {
+	resetUpstreamClient(t)
+	req := require.New(t)
+
+	t.Setenv("HTTPS_PROXY", "http://env-proxy.example.com:3128")
+	t.Setenv("NO_PROXY", "")
+
+	proxyFor := func(target string) string {
+		u, err := url.Parse(target)
+		req.NoError(err)
+		proxyURL, err := getUpstreamTransport().Proxy(&http.Request{URL: u})
+		req.NoError(err)
+		if proxyURL == nil {
+			return ""
+		}
+		return proxyURL.String()
+	}
+
+	// the environment is used if no proxy is configured
+	req.NoError(ConfigureUpstreamClient(UpstreamClientOptions{}))
+	req.Equal("http://env-proxy.example.com:3128", proxyFor("https://replicated.app/license"))
+
+	// the configured proxy takes precedence over the environment
+	req.NoError(ConfigureUpstreamClient(UpstreamClientOptions{
+		HTTPSProxy: "http://proxy.example.com:3128",
+		NoProxy:    "internal.example.com",
+	}))
+	req.Equal("http://proxy.example.com:3128", proxyFor("https://replicated.app/license"))
+	req.Equal("", proxyFor("https://internal.example.com/license"))
+	req.Equal("", proxyFor("http://replicated.app/license"))
+}
+
+func TestConfigureUpstreamClient_CACerts(t *testing.T) {
+	resetUpstreamClient(t)
+	req := require.New(t)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	get := func() error {
+		httpReq, err := NewRequest("GET", server.URL, nil)
+		req.NoError(err)
+		resp, err := DoUpstream(httpReq, UpstreamEndpoint{Timeout: time.Second})
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	// the certificate of the server is not trusted by default
+	req.ErrorContains(get(), "certificate")
+
+	caCerts := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	req.NoError(ConfigureUpstreamClient(UpstreamClientOptions{CACerts: caCerts}))
+	req.NoError(get())
+
+	req.EqualError(ConfigureUpstreamClient(UpstreamClientOptions{CACerts: []byte("not a certificate")}), "no certificates found in CA bundle")
+}
+
+func TestGetPrivateCACerts(t *testing.T) {
+	req := require.New(t)
+
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-ca", Namespace: "default"},
+		Data: map[string][]byte{
+			"ca.crt": []byte("default-key"),
+			"ca.pem": []byte("custom-key"),
+		},
+	})
+
+	caCerts, err := GetPrivateCACerts(context.Background(), clientset, "default", "my-ca", "")
+	req.NoError(err)
+	req.Equal("default-key", string(caCerts))
+
+	caCerts, err = GetPrivateCACerts(context.Background(), clientset, "default", "my-ca", "ca.pem")
+	req.NoError(err)
+	req.Equal("custom-key", string(caCerts))
+
+	_, err = GetPrivateCACerts(context.Background(), clientset, "default", "my-ca", "missing")
+	req.EqualError(err, "private ca secret my-ca has no missing key")
+
+	_, err = GetPrivateCACerts(context.Background(), clientset, "default", "other", "")
+	req.ErrorContains(err, "failed to get private ca secret")
+}
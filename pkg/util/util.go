@@ -1,9 +1,35 @@
 package util
 
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ActionableError is an error returned by the Replicated API, with a message that can be shown to the user
 type ActionableError struct {
-	Message string
+	Message    string
+	StatusCode int
 }
 
 func (e ActionableError) Error() string {
 	return e.Message
 }
+
+// IsUpstreamUnavailable returns true if the error means that the Replicated API could not be reached or failed, rather than
+// that it rejected the request, i.e. a connection error, a timeout or a 5xx response
+func IsUpstreamUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var actionableErr ActionableError
+	if errors.As(err, &actionableErr) {
+		return actionableErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsUpstreamUnavailable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "no error",
+		},
+		{
+			name: "connection error",
+			err:  errors.Wrap(&net.OpError{Op: "dial", Err: errors.New("connection refused")}, "failed to execute get request"),
+			want: true,
+		},
+		{
+			name: "timeout",
+			err:  errors.Wrap(context.DeadlineExceeded, "failed to execute get request"),
+			want: true,
+		},
+		{
+			name: "server error",
+			err:  errors.Wrap(ActionableError{Message: "unavailable", StatusCode: http.StatusServiceUnavailable}, "failed to get license from api"),
+			want: true,
+		},
+		{
+			name: "unauthorized",
+			err:  errors.Wrap(ActionableError{Message: "unauthorized", StatusCode: http.StatusUnauthorized}, "failed to get license from api"),
+		},
+		{
+			name: "forbidden",
+			err:  ActionableError{Message: "license is archived", StatusCode: http.StatusForbidden},
+		},
+		{
+			name: "other error",
+			err:  errors.New("failed to load license from bytes"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, IsUpstreamUnavailable(tt.err))
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	sdklicense "github.com/replicatedhq/replicated-sdk/pkg/license"
+	sdklicensetypes "github.com/replicatedhq/replicated-sdk/pkg/license/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/webhook/types"
+)
+
+var (
+	// notifiedExpiration is the expiration date the license.expiring event was last sent for,
+	// so that it is sent once per expiration date rather than on every license sync
+	notifiedExpiration    time.Time
+	notifiedExpirationMtx sync.Mutex
+)
+
+type AppStateChangedData struct {
+	PreviousState appstatetypes.State     `json:"previousState"`
+	State         appstatetypes.State     `json:"state"`
+	AppStatus     appstatetypes.AppStatus `json:"appStatus"`
+}
+
+type LicenseChangedData struct {
+	LicenseID           string   `json:"licenseId"`
+	PreviousSequence    int64    `json:"previousSequence"`
+	Sequence            int64    `json:"sequence"`
+	ChangedEntitlements []string `json:"changedEntitlements"`
+}
+
+type LicenseExpiringData struct {
+	LicenseID     string    `json:"licenseId"`
+	ExpiresAt     time.Time `json:"expiresAt"`
+	DaysRemaining int       `json:"daysRemaining"`
+}
+
+// NotifyAppStateChanged sends an app.stateChanged event if the overall app state changed
+func NotifyAppStateChanged(previous appstatetypes.AppStatus, current appstatetypes.AppStatus) {
+	if !IsEnabled() || previous.State == current.State {
+		return
+	}
+
+	Send(types.EventAppStateChanged, AppStateChangedData{
+		PreviousState: previous.State,
+		State:         current.State,
+		AppStatus:     current,
+	})
+}
+
+// NotifyReleasesChanged sends a release.available event for each release that was not previously available.
+// Releases are identified by their channel sequence, so that changes to the metadata or notes of a release
+// that was already available don't send the event again.
+func NotifyReleasesChanged(previous []upstreamtypes.ChannelRelease, current []upstreamtypes.ChannelRelease) {
+	if !IsEnabled() {
+		return
+	}
+
+	known := map[int64]bool{}
+	for _, release := range previous {
+		known[release.ChannelSequence] = true
+	}
+
+	for _, release := range current {
+		if known[release.ChannelSequence] {
+			continue
+		}
+		Send(types.EventReleaseAvailable, release)
+	}
+}
+
+// NotifyLicenseChanged sends a license.changed event if the license sequence or any entitlement changed,
+// and a license.expiring event if the updated license expires soon
+func NotifyLicenseChanged(previous licensewrapper.LicenseWrapper, current licensewrapper.LicenseWrapper) {
+	if !IsEnabled() {
+		return
+	}
+
+	changedEntitlements := diffEntitlements(previous, current)
+	if previous.GetLicenseSequence() != current.GetLicenseSequence() || len(changedEntitlements) > 0 {
+		Send(types.EventLicenseChanged, LicenseChangedData{
+			LicenseID:           current.GetLicenseID(),
+			PreviousSequence:    previous.GetLicenseSequence(),
+			Sequence:            current.GetLicenseSequence(),
+			ChangedEntitlements: changedEntitlements,
+		})
+	}
+
+	NotifyLicenseExpiring(current)
+}
+
+// NotifyLicenseExpiring sends a license.expiring event once per expiration date when the license is expiring soon
+func NotifyLicenseExpiring(wrapper licensewrapper.LicenseWrapper) {
+	if !IsEnabled() {
+		return
+	}
+
+	now := time.Now()
+	status, err := sdklicense.GetLicenseExpirationStatus(wrapper, sdklicense.GetExpirationOptions(), now)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to get license expiration status"))
+		return
+	}
+	if status.State != sdklicensetypes.ExpirationStateExpiringSoon {
+		return
+	}
+	expiresAt := status.ExpiresAt
+	remaining := expiresAt.Sub(now)
+
+	notifiedExpirationMtx.Lock()
+	defer notifiedExpirationMtx.Unlock()
+
+	if notifiedExpiration.Equal(*expiresAt) {
+		return
+	}
+	notifiedExpiration = *expiresAt
+
+	Send(types.EventLicenseExpiring, LicenseExpiringData{
+		LicenseID:     wrapper.GetLicenseID(),
+		ExpiresAt:     *expiresAt,
+		DaysRemaining: int(remaining.Hours() / 24),
+	})
+}
+
+func diffEntitlements(previous licensewrapper.LicenseWrapper, current licensewrapper.LicenseWrapper) []string {
+	changed := []string{}
+	for _, change := range sdklicense.DiffEntitlements(previous, current) {
+		changed = append(changed, change.Name)
+	}
+	return changed
+}
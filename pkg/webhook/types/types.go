@@ -0,0 +1,105 @@
+package types
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type EventType string
+
+const (
+	EventAppStateChanged  EventType = "app.stateChanged"
+	EventReleaseAvailable EventType = "release.available"
+	EventLicenseChanged   EventType = "license.changed"
+	EventLicenseExpiring  EventType = "license.expiring"
+	EventTest             EventType = "webhook.test"
+)
+
+var EventTypes = []EventType{
+	EventAppStateChanged,
+	EventReleaseAvailable,
+	EventLicenseChanged,
+	EventLicenseExpiring,
+	EventTest,
+}
+
+// Target is a webhook endpoint that events are delivered to
+type Target struct {
+	Name string `yaml:"name" json:"name"`
+	URL  string `yaml:"url" json:"url"`
+	// Secret is the key used to sign the payloads sent to this target with HMAC-SHA256
+	Secret string `yaml:"secret" json:"-"`
+	// Events limits the events delivered to this target. All events are delivered if empty.
+	Events []EventType `yaml:"events" json:"events,omitempty"`
+}
+
+func (t Target) Validate() error {
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return errors.Wrapf(err, "webhook %s has an invalid url", t.Name)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("webhook %s url must be http or https", t.Name)
+	}
+	if u.Host == "" {
+		return errors.Errorf("webhook %s url must have a host", t.Name)
+	}
+
+	if t.Secret == "" {
+		return errors.Errorf("webhook %s secret is required", t.Name)
+	}
+
+	for _, event := range t.Events {
+		if !isKnownEventType(event) {
+			return errors.Errorf("webhook %s has unknown event type %q", t.Name, event)
+		}
+	}
+
+	return nil
+}
+
+// Subscribes returns true if the target should receive the given event type. Test events are always delivered.
+func (t Target) Subscribes(eventType EventType) bool {
+	if len(t.Events) == 0 || eventType == EventTest {
+		return true
+	}
+	for _, e := range t.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func isKnownEventType(eventType EventType) bool {
+	for _, e := range EventTypes {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type Event struct {
+	ID        string      `json:"id"`
+	Type      EventType   `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	AppSlug   string      `json:"appSlug"`
+	AppID     string      `json:"appId"`
+	Data      interface{} `json:"data"`
+}
+
+// DeliveryResult is the outcome of delivering an event to a single target
+type DeliveryResult struct {
+	Target     string `json:"target"`
+	URL        string `json:"url"`
+	Attempts   int    `json:"attempts"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
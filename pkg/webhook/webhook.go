@@ -0,0 +1,293 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/replicated-sdk/pkg/logger"
+	"github.com/replicatedhq/replicated-sdk/pkg/meta"
+	metatypes "github.com/replicatedhq/replicated-sdk/pkg/meta/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	"github.com/replicatedhq/replicated-sdk/pkg/util"
+	"github.com/replicatedhq/replicated-sdk/pkg/webhook/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	EventHeader     = "X-Replicated-Event"
+	DeliveryHeader  = "X-Replicated-Delivery"
+	TimestampHeader = "X-Replicated-Timestamp"
+	// SignatureHeader holds "sha256=" followed by the hex encoded HMAC-SHA256 of "<timestamp>.<body>" using the target secret
+	SignatureHeader = "X-Replicated-Signature"
+
+	maxDeliveryAttempts = 5
+)
+
+type InitOptions struct {
+	Clientset kubernetes.Interface
+	Namespace string
+	Targets   []types.Target
+}
+
+var (
+	targets   []types.Target
+	clientset kubernetes.Interface
+	namespace string
+	// deliveryCtx is cancelled when pending deliveries are abandoned during shutdown
+	deliveryCtx    context.Context
+	cancelDelivery context.CancelFunc
+	pending        sync.WaitGroup
+	mtx            sync.RWMutex
+
+	// newBackOff returns the retry policy for a single delivery, it is a variable so that tests can shorten it
+	newBackOff = func() backoff.BackOff {
+		b := backoff.NewExponentialBackOff()
+		b.InitialInterval = time.Second
+		b.MaxInterval = time.Minute
+		b.MaxElapsedTime = 10 * time.Minute
+		return backoff.WithMaxRetries(b, maxDeliveryAttempts-1)
+	}
+)
+
+// Init configures the webhook targets that events are delivered to. Invalid targets are logged and skipped.
+func Init(opts InitOptions) {
+	valid := []types.Target{}
+	for _, target := range opts.Targets {
+		if err := target.Validate(); err != nil {
+			logger.Error(errors.Wrap(err, "skipping invalid webhook"))
+			continue
+		}
+		valid = append(valid, target)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	targets = valid
+	clientset = opts.Clientset
+	namespace = opts.Namespace
+	if deliveryCtx == nil || deliveryCtx.Err() != nil {
+		deliveryCtx, cancelDelivery = context.WithCancel(context.Background())
+	}
+}
+
+func getTargets() []types.Target {
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	return targets
+}
+
+func IsEnabled() bool {
+	return len(getTargets()) > 0
+}
+
+// NewEvent returns an event of the given type for the current app
+func NewEvent(eventType types.EventType, data interface{}) types.Event {
+	return types.Event{
+		ID:        newEventID(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		AppSlug:   store.GetStore().GetAppSlug(),
+		AppID:     store.GetStore().GetAppID(),
+		Data:      data,
+	}
+}
+
+// Send delivers an event in the background to every target subscribed to its type.
+// Deliveries are retried with backoff, and events that could not be delivered are recorded as dead letters.
+func Send(eventType types.EventType, data interface{}) {
+	event := NewEvent(eventType, data)
+
+	for _, target := range getTargets() {
+		if !target.Subscribes(eventType) {
+			continue
+		}
+
+		target := target
+		pending.Add(1)
+		go func() {
+			defer pending.Done()
+
+			mtx.RLock()
+			ctx := deliveryCtx
+			mtx.RUnlock()
+
+			if err := deliverWithRetries(ctx, target, event); err != nil {
+				logger.Error(errors.Wrapf(err, "failed to deliver %s event to webhook %s", event.Type, target.Name))
+			}
+		}()
+	}
+}
+
+// SendTest delivers a sample event to every target once, without retries, and returns the outcome for each target
+func SendTest(ctx context.Context) []types.DeliveryResult {
+	event := NewEvent(types.EventTest, map[string]interface{}{
+		"message": "This is a test event from the Replicated SDK",
+	})
+
+	results := []types.DeliveryResult{}
+	for _, target := range getTargets() {
+		result := types.DeliveryResult{
+			Target:   target.Name,
+			URL:      target.URL,
+			Attempts: 1,
+		}
+		statusCode, err := deliver(ctx, target, event)
+		result.StatusCode = statusCode
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// Flush waits for pending deliveries to complete or for the context to be done.
+// Deliveries that are still pending when the context is done are cancelled.
+func Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		mtx.RLock()
+		if cancelDelivery != nil {
+			cancelDelivery()
+		}
+		mtx.RUnlock()
+		return errors.Wrap(ctx.Err(), "timed out waiting for pending webhook deliveries")
+	}
+}
+
+func deliverWithRetries(ctx context.Context, target types.Target, event types.Event) error {
+	attempts := 0
+	var lastErr error
+
+	err := backoff.Retry(func() error {
+		attempts++
+		statusCode, err := deliver(ctx, target, event)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		// other client errors won't succeed on retry
+		if statusCode >= 400 && statusCode < 500 && statusCode != http.StatusTooManyRequests && statusCode != http.StatusRequestTimeout {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, backoff.WithContext(newBackOff(), ctx))
+	if err == nil {
+		return nil
+	}
+	if lastErr == nil {
+		lastErr = err
+	}
+
+	if deadLetterErr := recordDeadLetter(target, event, attempts, lastErr); deadLetterErr != nil {
+		logger.Error(errors.Wrap(deadLetterErr, "failed to record webhook dead letter"))
+	}
+
+	return errors.Wrapf(lastErr, "giving up after %d attempts", attempts)
+}
+
+// deliver makes a single delivery attempt and returns the response status code, if any
+func deliver(ctx context.Context, target types.Target, event types.Event) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to marshal event")
+	}
+
+	req, err := util.NewRequest("POST", target.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create request")
+	}
+	req = req.WithContext(ctx)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(event.Type))
+	req.Header.Set(DeliveryHeader, event.ID)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(target.Secret, timestamp, body))
+
+	resp, err := util.WebhookHttpClient().Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to execute request")
+	}
+	defer resp.Body.Close()
+
+	// drain the body so that the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, errors.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 signature of the timestamp and body.
+// Receivers should compute the same signature and compare it in constant time, and reject stale timestamps.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func recordDeadLetter(target types.Target, event types.Event, attempts int, deliveryErr error) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal event")
+	}
+
+	mtx.RLock()
+	cs, ns := clientset, namespace
+	mtx.RUnlock()
+
+	deadLetter := metatypes.WebhookDeadLetter{
+		EventID:   event.ID,
+		EventType: string(event.Type),
+		Target:    target.Name,
+		URL:       target.URL,
+		Attempts:  attempts,
+		LastError: deliveryErr.Error(),
+		FailedAt:  time.Now().UTC(),
+		Payload:   payload,
+	}
+
+	// use a fresh context since the delivery context may have been cancelled during shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return meta.AppendWebhookDeadLetter(ctx, cs, ns, deadLetter)
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
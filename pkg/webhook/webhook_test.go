@@ -0,0 +1,295 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cenkalti/backoff/v4"
+	appstatetypes "github.com/replicatedhq/replicated-sdk/pkg/appstate/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/meta"
+	"github.com/replicatedhq/replicated-sdk/pkg/store"
+	upstreamtypes "github.com/replicatedhq/replicated-sdk/pkg/upstream/types"
+	"github.com/replicatedhq/replicated-sdk/pkg/util"
+	"github.com/replicatedhq/replicated-sdk/pkg/webhook/types"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+type testReceiver struct {
+	*httptest.Server
+	mtx      sync.Mutex
+	requests []receivedRequest
+	// statusCodes are returned in order, the last one is repeated
+	statusCodes []int
+}
+
+func newTestReceiver(statusCodes ...int) *testReceiver {
+	r := &testReceiver{statusCodes: statusCodes}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mtx.Lock()
+		r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body})
+		statusCode := r.statusCodes[len(r.statusCodes)-1]
+		if len(r.requests) <= len(r.statusCodes) {
+			statusCode = r.statusCodes[len(r.requests)-1]
+		}
+		r.mtx.Unlock()
+
+		w.WriteHeader(statusCode)
+	}))
+	return r
+}
+
+func (r *testReceiver) received() []receivedRequest {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]receivedRequest{}, r.requests...)
+}
+
+func setupTest(t *testing.T, targets ...types.Target) *fake.Clientset {
+	store.InitInMemory(store.InitInMemoryStoreOptions{Namespace: "default", AppID: "app-id"})
+	t.Cleanup(func() { store.SetStore(nil) })
+
+	origBackOff := newBackOff
+	newBackOff = func() backoff.BackOff {
+		return backoff.WithMaxRetries(&backoff.ZeroBackOff{}, maxDeliveryAttempts-1)
+	}
+	t.Cleanup(func() { newBackOff = origBackOff })
+
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      meta.ReplicatedMetadataSecretName,
+			Namespace: "default",
+		},
+	})
+
+	Init(InitOptions{Clientset: clientset, Namespace: "default", Targets: targets})
+	t.Cleanup(func() { Init(InitOptions{}) })
+
+	return clientset
+}
+
+func TestSend(t *testing.T) {
+	req := require.New(t)
+
+	receiver := newTestReceiver(http.StatusOK)
+	defer receiver.Close()
+
+	setupTest(t, types.Target{Name: "receiver", URL: receiver.URL, Secret: "my-secret"})
+
+	Send(types.EventAppStateChanged, AppStateChangedData{PreviousState: appstatetypes.StateUpdating, State: appstatetypes.StateReady})
+	req.NoError(Flush(context.Background()))
+
+	requests := receiver.received()
+	req.Len(requests, 1)
+
+	header := requests[0].header
+	req.Equal(string(types.EventAppStateChanged), header.Get(EventHeader))
+	req.NotEmpty(header.Get(DeliveryHeader))
+	req.Equal("sha256="+Sign("my-secret", header.Get(TimestampHeader), requests[0].body), header.Get(SignatureHeader))
+
+	var event map[string]interface{}
+	req.NoError(json.Unmarshal(requests[0].body, &event))
+	req.Equal(string(types.EventAppStateChanged), event["type"])
+	req.Equal(header.Get(DeliveryHeader), event["id"])
+	req.Equal("app-id", event["appId"])
+	req.Equal(map[string]interface{}{
+		"previousState": "updating",
+		"state":         "ready",
+		"appStatus": map[string]interface{}{
+			"appSlug":        "",
+			"resourceStates": nil,
+			"updatedAt":      "0001-01-01T00:00:00Z",
+			"state":          "",
+			"sequence":       float64(0),
+		},
+	}, event["data"])
+}
+
+func TestSendFiltersEvents(t *testing.T) {
+	req := require.New(t)
+
+	all := newTestReceiver(http.StatusOK)
+	defer all.Close()
+	licenseOnly := newTestReceiver(http.StatusOK)
+	defer licenseOnly.Close()
+
+	setupTest(t,
+		types.Target{Name: "all", URL: all.URL, Secret: "secret"},
+		types.Target{Name: "license-only", URL: licenseOnly.URL, Secret: "secret", Events: []types.EventType{types.EventLicenseExpiring}},
+	)
+
+	Send(types.EventReleaseAvailable, upstreamtypes.ChannelRelease{VersionLabel: "1.0.0"})
+	req.NoError(Flush(context.Background()))
+
+	req.Len(all.received(), 1)
+	req.Len(licenseOnly.received(), 0)
+}
+
+func TestSendRetries(t *testing.T) {
+	tests := []struct {
+		name            string
+		statusCodes     []int
+		wantAttempts    int
+		wantDeadLetters int
+	}{
+		{
+			name:         "succeeds after server errors",
+			statusCodes:  []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK},
+			wantAttempts: 3,
+		},
+		{
+			name:            "gives up after max attempts",
+			statusCodes:     []int{http.StatusInternalServerError},
+			wantAttempts:    maxDeliveryAttempts,
+			wantDeadLetters: 1,
+		},
+		{
+			name:            "client errors are not retried",
+			statusCodes:     []int{http.StatusUnauthorized},
+			wantAttempts:    1,
+			wantDeadLetters: 1,
+		},
+		{
+			name:         "rate limited requests are retried",
+			statusCodes:  []int{http.StatusTooManyRequests, http.StatusAccepted},
+			wantAttempts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			receiver := newTestReceiver(tt.statusCodes...)
+			defer receiver.Close()
+
+			clientset := setupTest(t, types.Target{Name: "receiver", URL: receiver.URL, Secret: "secret"})
+
+			Send(types.EventLicenseChanged, LicenseChangedData{Sequence: 2})
+			req.NoError(Flush(context.Background()))
+
+			req.Len(receiver.received(), tt.wantAttempts)
+
+			deadLetters, err := meta.GetWebhookDeadLetters(context.Background(), clientset, "default")
+			req.NoError(err)
+			req.Len(deadLetters, tt.wantDeadLetters)
+			if tt.wantDeadLetters > 0 {
+				req.Equal("receiver", deadLetters[0].Target)
+				req.Equal(string(types.EventLicenseChanged), deadLetters[0].EventType)
+				req.Equal(tt.wantAttempts, deadLetters[0].Attempts)
+				req.True(strings.Contains(deadLetters[0].LastError, "unexpected status code"))
+				req.Contains(string(deadLetters[0].Payload), `"sequence":2`)
+			}
+		})
+	}
+}
+
+func TestSendTest(t *testing.T) {
+	req := require.New(t)
+
+	ok := newTestReceiver(http.StatusOK)
+	defer ok.Close()
+	failing := newTestReceiver(http.StatusInternalServerError)
+	defer failing.Close()
+
+	setupTest(t,
+		types.Target{Name: "ok", URL: ok.URL, Secret: "secret", Events: []types.EventType{types.EventLicenseExpiring}},
+		types.Target{Name: "failing", URL: failing.URL, Secret: "secret"},
+	)
+
+	results := SendTest(context.Background())
+	req.Equal([]types.DeliveryResult{
+		{Target: "ok", URL: ok.URL, Attempts: 1, StatusCode: http.StatusOK},
+		{Target: "failing", URL: failing.URL, Attempts: 1, StatusCode: http.StatusInternalServerError, Error: "unexpected status code 500"},
+	}, results)
+
+	/* test events are not retried */
+	req.Len(failing.received(), 1)
+}
+
+func TestSendIgnoresUpstreamProxy(t *testing.T) {
+	req := require.New(t)
+
+	proxy := newTestReceiver(http.StatusOK)
+	defer proxy.Close()
+
+	req.NoError(util.ConfigureUpstreamClient(util.UpstreamClientOptions{HTTPProxy: proxy.URL, HTTPSProxy: proxy.URL}))
+	t.Cleanup(func() { util.ConfigureUpstreamClient(util.UpstreamClientOptions{}) })
+
+	// the .invalid domain never resolves, so the delivery can only succeed through the proxy
+	target := "http://hooks.invalid/replicated"
+
+	/* the upstream client sends the request through the configured proxy */
+	httpReq, err := util.NewRequest("POST", target, nil)
+	req.NoError(err)
+	resp, err := util.HttpClient().Do(httpReq)
+	req.NoError(err)
+	resp.Body.Close()
+	req.Len(proxy.received(), 1)
+
+	/* webhooks are not sent through it */
+	setupTest(t, types.Target{Name: "receiver", URL: target, Secret: "secret"})
+
+	results := SendTest(context.Background())
+	req.Len(results, 1)
+	req.NotEmpty(results[0].Error)
+	req.Len(proxy.received(), 1)
+}
+
+func TestNotifyReleasesChanged(t *testing.T) {
+	req := require.New(t)
+
+	receiver := newTestReceiver(http.StatusOK)
+	defer receiver.Close()
+
+	setupTest(t, types.Target{Name: "receiver", URL: receiver.URL, Secret: "secret"})
+
+	previous := []upstreamtypes.ChannelRelease{{ChannelSequence: 1, VersionLabel: "1.0.1", CreatedAt: "2023-01-01T00:00:00Z"}}
+	current := []upstreamtypes.ChannelRelease{
+		{ChannelSequence: 2, VersionLabel: "1.0.2", CreatedAt: "2023-02-01T00:00:00Z"},
+		// a release that was already available is not sent again if its notes or metadata changed
+		{ChannelSequence: 1, VersionLabel: "1.0.1", CreatedAt: "2023-01-01T00:00:00Z", ReleaseNotes: "updated notes", IsRequired: true},
+	}
+
+	NotifyReleasesChanged(previous, current)
+	req.NoError(Flush(context.Background()))
+
+	requests := receiver.received()
+	req.Len(requests, 1)
+	req.Contains(string(requests[0].body), `"versionLabel":"1.0.2"`)
+
+	/* unchanged app states are not sent */
+	NotifyAppStateChanged(appstatetypes.AppStatus{State: appstatetypes.StateReady}, appstatetypes.AppStatus{State: appstatetypes.StateReady})
+	req.NoError(Flush(context.Background()))
+	req.Len(receiver.received(), 1)
+}
+
+func TestInitSkipsInvalidTargets(t *testing.T) {
+	req := require.New(t)
+
+	setupTest(t,
+		types.Target{Name: "valid", URL: "https://example.com/hook", Secret: "secret"},
+		types.Target{Name: "no-secret", URL: "https://example.com/hook"},
+		types.Target{Name: "bad-url", URL: "ftp://example.com", Secret: "secret"},
+		types.Target{Name: "bad-event", URL: "https://example.com/hook", Secret: "secret", Events: []types.EventType{"app.deleted"}},
+	)
+
+	targets := getTargets()
+	req.Len(targets, 1)
+	req.Equal("valid", targets[0].Name)
+}